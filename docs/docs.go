@@ -15,82 +15,176 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/v1/createSights": {
-            "post": {
+        "/.well-known/jwks.json": {
+            "get": {
+                "description": "Public keys partner services use to verify tokens issued by this API.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "JSON Web Key Set",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/keyring.JWKS"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/email-templates": {
+            "get": {
                 "security": [
                     {
                         "Authorization": []
+                    },
+                    {
+                        "APIKey": []
                     }
                 ],
-                "description": "Create a new tiger sighting with the provided information.",
-                "consumes": [
-                    "multipart/form-data"
-                ],
+                "description": "Admins see the emails rendered from templates and the languages each is written in.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Sighting"
+                    "Admin"
                 ],
-                "summary": "Create a new tiger sighting",
-                "parameters": [
+                "summary": "List email templates",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/templates.Template"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Caller lacks the notifications:manage permission",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/email-templates/reload": {
+            "post": {
+                "security": [
                     {
-                        "type": "string",
-                        "description": "Name of the tiger",
-                        "name": "tiger_name",
-                        "in": "formData",
-                        "required": true
+                        "Authorization": []
                     },
                     {
-                        "type": "string",
-                        "description": "Timestamp of the sighting (unix epoch utc format example:1705147765)",
-                        "name": "last_seen_timestamp",
-                        "in": "formData",
-                        "required": true
+                        "APIKey": []
+                    }
+                ],
+                "description": "Admins load changed templates from EMAIL_TEMPLATES_DIR right away instead of waiting for the next check. Every template is rendered with the preview data, templates that fail to parse or render, or emails without a subject or text in a language, are reported and the previous templates stay in use.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Reload the email templates",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/templates.Template"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Caller lacks the notifications:manage permission",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
                     },
+                    "422": {
+                        "description": "A template failed to parse or render",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/email-templates/{name}/preview": {
+            "get": {
+                "security": [
                     {
-                        "type": "number",
-                        "description": "Latitude of the sighting coordinates",
-                        "name": "last_seen_coordinates_lat",
-                        "in": "formData",
-                        "required": true
+                        "Authorization": []
                     },
                     {
-                        "type": "number",
-                        "description": "Longitude of the sighting coordinates",
-                        "name": "last_seen_coordinates_lon",
-                        "in": "formData",
+                        "APIKey": []
+                    }
+                ],
+                "description": "Admins render a template with sample data, in the language asked for or the one it falls back to. With format=html or format=text the part is returned as is, e.g. to open it in a browser. Inline images are not included.",
+                "produces": [
+                    "application/json",
+                    "text/html",
+                    "text/plain"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Preview an email template",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Template name, e.g. sighting_notification or digest",
+                        "name": "name",
+                        "in": "path",
                         "required": true
                     },
                     {
-                        "type": "file",
-                        "description": "Image of the sighting",
-                        "name": "image",
-                        "in": "formData",
-                        "required": true
+                        "type": "string",
+                        "default": "en",
+                        "description": "Language, e.g. en, hi or bn",
+                        "name": "language",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "json",
+                        "description": "json, html or text",
+                        "name": "format",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.SightingsResponse"
+                            "$ref": "#/definitions/templates.Rendered"
                         }
                     },
                     "400": {
-                        "description": "Invalid input format",
+                        "description": "Unknown format",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "403": {
+                        "description": "Caller lacks the notifications:manage permission",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown template",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Template failed to render",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -98,41 +192,30 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/createTigers": {
-            "post": {
+        "/api/v1/admin/lockouts": {
+            "get": {
                 "security": [
                     {
                         "Authorization": []
                     }
                 ],
-                "description": "Create a new tiger using either JSON or multipart form data",
-                "consumes": [
+                "description": "Admins list usernames and client IPs with recent failed logins, including current lockouts.",
+                "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Tiger"
-                ],
-                "summary": "Create a new tiger",
-                "parameters": [
-                    {
-                        "description": "Create Tiger",
-                        "name": "tiger",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.CreateTigerRequest"
-                        }
-                    }
+                    "Admin"
                 ],
+                "summary": "List failed logins",
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.CreateTigerRequest"
+                            "$ref": "#/definitions/models.LoginThrottlesResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "403": {
+                        "description": "Caller is not an admin",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -140,48 +223,52 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/listTigers": {
-            "get": {
-                "description": "Retrieve a list of tigers with optional pagination.",
-                "consumes": [
-                    "application/json"
+        "/api/v1/admin/lockouts/{scope}/{key}": {
+            "delete": {
+                "security": [
+                    {
+                        "Authorization": []
+                    }
                 ],
+                "description": "Admins clear the failed logins of a username or client IP, lifting a lockout.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Tiger"
+                    "Admin"
                 ],
-                "summary": "List all tigers",
+                "summary": "Unlock a username or client IP",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Number of tigers per page",
-                        "name": "pageSize",
-                        "in": "query"
+                        "type": "string",
+                        "description": "user or ip",
+                        "name": "scope",
+                        "in": "path",
+                        "required": true
                     },
                     {
-                        "type": "integer",
-                        "description": "Pagination offset",
-                        "name": "offset",
-                        "in": "query"
+                        "type": "string",
+                        "description": "Username or IP address",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.TigersResponse"
+                            "$ref": "#/definitions/models.GeneralResponse"
                         }
                     },
-                    "400": {
-                        "description": "Invalid input format",
+                    "403": {
+                        "description": "Caller is not an admin",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "No failed logins",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -189,51 +276,36 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/login": {
-            "post": {
-                "description": "Log in a user with the provided credentials",
-                "consumes": [
-                    "application/json"
+        "/api/v1/admin/messaging": {
+            "get": {
+                "security": [
+                    {
+                        "Authorization": []
+                    },
+                    {
+                        "APIKey": []
+                    }
                 ],
+                "description": "Admins see the depth, capacity, delivered and dropped message counts of every subscriber queue.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "User"
-                ],
-                "summary": "Log in a user",
-                "parameters": [
-                    {
-                        "description": "User credentials",
-                        "name": "credentials",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.Credentials"
-                        }
-                    }
+                    "Admin"
                 ],
+                "summary": "Metrics of the in-process message queues",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.LoginResponse"
-                        }
-                    },
-                    "400": {
-                        "description": "Invalid JSON format",
-                        "schema": {
-                            "$ref": "#/definitions/models.ErrorResponse"
-                        }
-                    },
-                    "401": {
-                        "description": "Invalid user credentials",
-                        "schema": {
-                            "$ref": "#/definitions/models.ErrorResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/messaging.SubscriptionStats"
+                            }
                         }
                     },
-                    "500": {
-                        "description": "Failed to log in. Please try again",
+                    "403": {
+                        "description": "Caller lacks the notifications:manage permission",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -241,40 +313,43 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/logout": {
+        "/api/v1/admin/mfa-policy": {
             "get": {
                 "security": [
                     {
-                        "BearerAuth": []
+                        "Authorization": []
                     }
                 ],
-                "description": "Logout the authenticated user and invalidate the session",
+                "description": "List the roles that are only granted to users with two-factor authentication.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "User"
+                    "Admin"
                 ],
-                "summary": "Logout the authenticated user",
+                "summary": "Get the two-factor authentication policy",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.GeneralResponse"
+                            "$ref": "#/definitions/models.MFAPolicy"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "403": {
+                        "description": "Caller is not an admin",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/api/v1/register": {
-            "post": {
-                "description": "Create a new user with the input paylod",
+            },
+            "put": {
+                "security": [
+                    {
+                        "Authorization": []
+                    }
+                ],
+                "description": "Admins choose the roles that need two-factor authentication. Users holding them without an authenticator app log in without these roles until they enrol.",
                 "consumes": [
                     "application/json"
                 ],
@@ -282,35 +357,35 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "User"
+                    "Admin"
                 ],
-                "summary": "Create a new user",
+                "summary": "Set the two-factor authentication policy",
                 "parameters": [
                     {
-                        "description": "Create user",
-                        "name": "user",
+                        "description": "Roles requiring two-factor authentication",
+                        "name": "policy",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.User"
+                            "$ref": "#/definitions/models.MFAPolicy"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.User"
+                            "$ref": "#/definitions/models.MFAPolicy"
                         }
                     },
                     "400": {
-                        "description": "Invalid JSON format",
+                        "description": "Unknown role",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Failed to create user. Please try again",
+                    "403": {
+                        "description": "Caller is not an admin",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -318,31 +393,34 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/tigers/:id/listSightings": {
+        "/api/v1/admin/outbox": {
             "get": {
-                "description": "Get a paginated list of all sightings",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
+                "security": [
+                    {
+                        "Authorization": []
+                    },
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Admins inspect the notification outbox, e.g. the dead messages that failed too often to be retried.",
+                "produces": [
+                    "application/json"
                 ],
                 "tags": [
-                    "Sighting"
+                    "Admin"
                 ],
-                "summary": "List all sightings",
-                "operationId": "list-tiger-sightings",
+                "summary": "List notification outbox messages",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Tiger ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "type": "string",
+                        "description": "pending, sent or dead",
+                        "name": "status",
+                        "in": "query"
                     },
                     {
                         "type": "integer",
-                        "description": "Number of sightings to retrieve per page",
+                        "description": "Number of messages to retrieve per page",
                         "name": "pageSize",
                         "in": "query"
                     },
@@ -355,101 +433,3550 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "List of sightings",
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.SightingsResponse"
+                            "$ref": "#/definitions/models.OutboxResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid status or pagination",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller lacks the notifications:manage permission",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/outbox/{id}/replay": {
+            "post": {
+                "security": [
+                    {
+                        "Authorization": []
+                    },
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Admins send a dead message back to the outbox, e.g. once the mail server is fixed. It gets a fresh retry budget.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Replay a dead outbox message",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Message ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.OutboxMessage"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid message id",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller lacks the notifications:manage permission",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "No dead message with this id",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/users/{username}/roles": {
+            "post": {
+                "security": [
+                    {
+                        "Authorization": []
+                    }
+                ],
+                "description": "Admins grant one of viewer, reporter, ranger, researcher or admin. Roles are read again when the user's access token is refreshed, so the role takes effect within ACCESS_TOKEN_TTL_MINUTES.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Grant a role to a user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username",
+                        "name": "username",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Role to grant",
+                        "name": "role",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.RoleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.UserRolesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Unknown role",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller is not an admin",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal server error",
+                        "description": "Failed to update the roles",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "models.CreateTigerRequest": {
-            "type": "object",
-            "properties": {
-                "date_of_birth": {
-                    "description": "Date of Birth of the tiger\n\nRequired: true\nExample: \"18/02/1998\"",
-                    "type": "string"
-                },
-                "last_coordinates": {
-                    "description": "Last coordinates where the tiger was seen\n\nRequired: true",
-                    "type": "object",
-                    "properties": {
-                        "last_seen_coordinates_lat": {
-                            "description": "Latitude of the last seen coordinates\n\nRequired: true\nExample: 37.7749",
-                            "type": "number"
-                        },
-                        "last_seen_coordinates_lon": {
-                            "description": "Longitude of the last seen coordinates\n\nRequired: true\nExample: -122.4194",
-                            "type": "number"
+        },
+        "/api/v1/admin/users/{username}/roles/{role}": {
+            "delete": {
+                "security": [
+                    {
+                        "Authorization": []
+                    }
+                ],
+                "description": "Admins revoke a role from a user. Admins cannot revoke their own admin role. Access tokens issued before keep the role until they are refreshed, within ACCESS_TOKEN_TTL_MINUTES.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Revoke a role from a user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username",
+                        "name": "username",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Role to revoke",
+                        "name": "role",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.UserRolesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Unknown role",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller is not an admin",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to update the roles",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
-                },
-                "last_seen_timestamp": {
-                    "description": "Last seen timestamp of the tiger in Unix Epoch Time UTC format\n\nRequired: true\nExample: 1705147765",
-                    "type": "integer"
-                },
-                "name": {
-                    "description": "Name of the tiger\n\nRequired: true\nExample: RajahuliBangalore",
+                }
+            }
+        },
+        "/api/v1/api-keys": {
+            "get": {
+                "security": [
+                    {
+                        "Authorization": []
+                    }
+                ],
+                "description": "List the active API keys of the authenticated user. The keys themselves are not returned.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "API keys"
+                ],
+                "summary": "List API keys",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.APIKeysResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "Authorization": []
+                    }
+                ],
+                "description": "Create a personal API key for integrations, sent in the X-API-Key header. The key is limited to its scopes and the roles of its owner. It is only shown in this response.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "API keys"
+                ],
+                "summary": "Create an API key",
+                "parameters": [
+                    {
+                        "description": "Name, scopes and expiry of the key",
+                        "name": "apiKey",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateAPIKeyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateAPIKeyResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid name, scope or expiry",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/api-keys/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "Authorization": []
+                    }
+                ],
+                "description": "Revoke an API key of the authenticated user. It stops working immediately.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "API keys"
+                ],
+                "summary": "Revoke an API key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.GeneralResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "API key not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/createSights": {
+            "post": {
+                "security": [
+                    {
+                        "Authorization": []
+                    },
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Create a new tiger sighting with the provided information.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Sighting"
+                ],
+                "summary": "Create a new tiger sighting",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name of the tiger",
+                        "name": "tiger_name",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Timestamp of the sighting (unix epoch utc format example:1705147765)",
+                        "name": "last_seen_timestamp",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "number",
+                        "description": "Latitude of the sighting coordinates",
+                        "name": "last_seen_coordinates_lat",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "number",
+                        "description": "Longitude of the sighting coordinates",
+                        "name": "last_seen_coordinates_lon",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "Image of the sighting",
+                        "name": "image",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.SightingsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input format",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/createTigers": {
+            "post": {
+                "security": [
+                    {
+                        "Authorization": []
+                    },
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Create a new tiger using either JSON or multipart form data",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tiger"
+                ],
+                "summary": "Create a new tiger",
+                "parameters": [
+                    {
+                        "description": "Create Tiger",
+                        "name": "tiger",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateTigerRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateTigerRequest"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/health/notifications": {
+            "get": {
+                "description": "Reports whether notifications are being delivered, for liveness probes and monitoring.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Health"
+                ],
+                "summary": "Health of the notification dispatcher",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.DispatcherHealth"
+                        }
+                    },
+                    "503": {
+                        "description": "Dispatcher stopped or stalled",
+                        "schema": {
+                            "$ref": "#/definitions/models.DispatcherHealth"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/listTigers": {
+            "get": {
+                "description": "Retrieve a list of tigers with optional pagination. Anonymous callers get coordinates snapped to a coarse grid and positions newer than the embargo period are withheld, researchers get exact coordinates.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tiger"
+                ],
+                "summary": "List all tigers",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Number of tigers per page",
+                        "name": "pageSize",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Pagination offset",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.TigersResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input format",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/live": {
+            "get": {
+                "security": [
+                    {
+                        "Authorization": []
+                    }
+                ],
+                "description": "WebSocket carrying live sightings for the tigers and regions the app subscribes to, the in-app notifications of the user and the progress of their uploads. Send {\"type\":\"subscribe\",\"subscription\":\"\u003cid\u003e\",\"tiger_ids\":[1],\"bbox\":[min_lon,min_lat,max_lon,max_lat]} and {\"type\":\"unsubscribe\",\"subscription\":\"\u003cid\u003e\"}, and {\"type\":\"auth\",\"token\":\"\u003caccess token\u003e\"} with a refreshed token before expires_at. The server pings every heartbeat_seconds and closes the connection if the app stays silent for twice as long, with code 1000 once the token expired, with 1008 if the session was revoked, or with 1013 if the app reads too slowly.",
+                "tags": [
+                    "Live"
+                ],
+                "summary": "Live channel for the mobile app",
+                "responses": {
+                    "101": {
+                        "description": "Switching to the WebSocket protocol",
+                        "schema": {
+                            "$ref": "#/definitions/models.LiveServerMessage"
+                        }
+                    },
+                    "400": {
+                        "description": "Not a WebSocket handshake",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Origin not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "User could not be loaded",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/login": {
+            "post": {
+                "description": "Log in a user with the provided credentials",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Log in a user",
+                "parameters": [
+                    {
+                        "description": "User credentials",
+                        "name": "credentials",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Credentials"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.LoginResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid JSON format",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid user credentials",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many failed login attempts",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to log in. Please try again",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/login/mfa": {
+            "post": {
+                "description": "Exchange the MFA token returned by /api/v1/login and a code from the authenticator app (or a recovery code) for the access and refresh tokens.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Complete a login with the second factor",
+                "parameters": [
+                    {
+                        "description": "MFA token and code",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.MFALoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.LoginResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid JSON format",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired MFA token or code",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many failed login attempts",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/logout": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Logout the authenticated user and invalidate the session",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Logout the authenticated user",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.GeneralResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/me": {
+            "get": {
+                "security": [
+                    {
+                        "Authorization": []
+                    },
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Returns username, display name, email and its verification state, roles, whether two-factor authentication is enabled and the followed tigers.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Account"
+                ],
+                "summary": "Get the account of the authenticated user",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Profile"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "Authorization": []
+                    }
+                ],
+                "description": "Deletes the account along with its sessions, API keys, linked identities and data exports. Reported sightings are kept without a reporter.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Account"
+                ],
+                "summary": "Delete the account of the authenticated user",
+                "parameters": [
+                    {
+                        "description": "Current password, or a TOTP code for accounts without one",
+                        "name": "confirmation",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.DeleteAccountRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.GeneralResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Current password or authentication code is incorrect, or the provider login is too old",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many failed attempts",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "Authorization": []
+                    }
+                ],
+                "description": "Change the display name, email, password or whether to be notified about tigers the user reported. Fields left out are not changed. Changing the email or password requires the current password. Accounts without a password send a totp_code instead, or without two-factor authentication log in with their provider again shortly before.\nA new email has to be verified again before notifications are sent to it. A new password logs out every other session and revokes all API keys.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Account"
+                ],
+                "summary": "Update the account of the authenticated user",
+                "parameters": [
+                    {
+                        "description": "Changes to the account",
+                        "name": "profile",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateProfileRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Profile"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid display name, email, password or language",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Current password or authentication code is incorrect, or the provider login is too old",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Email already taken",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many failed attempts",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/me/export": {
+            "get": {
+                "security": [
+                    {
+                        "Authorization": []
+                    }
+                ],
+                "description": "Starts building a zip with the profile, all sightings with their images, the session history and the notification history.\nA download link is emailed once the export is ready.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Account"
+                ],
+                "summary": "Export the personal data of the authenticated user",
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.DataExport"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "An export is already being prepared",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/me/export/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "Authorization": []
+                    }
+                ],
+                "description": "Returns the zip once the export is ready. While it is being prepared the state of the export is returned instead.",
+                "produces": [
+                    "application/zip",
+                    "application/json"
+                ],
+                "tags": [
+                    "Account"
+                ],
+                "summary": "Download a personal data export",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Export ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Zip of the personal data",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "202": {
+                        "description": "Export is being prepared",
+                        "schema": {
+                            "$ref": "#/definitions/models.DataExport"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Export not found or expired",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Export failed",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/me/notification-preferences": {
+            "get": {
+                "security": [
+                    {
+                        "Authorization": []
+                    },
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Returns the channels, frequency, quiet hours and muted tigers of the authenticated user.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Account"
+                ],
+                "summary": "Get the notification preferences",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.NotificationPreferences"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "Authorization": []
+                    }
+                ],
+                "description": "Replace the notification preferences of the authenticated user. An empty channel list stops all sighting notifications.\nNo immediate notifications are sent during quiet hours, which are given in the time zone of the user.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Account"
+                ],
+                "summary": "Update the notification preferences",
+                "parameters": [
+                    {
+                        "description": "Notification preferences",
+                        "name": "preferences",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.NotificationPreferences"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.NotificationPreferences"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid channel, frequency, quiet hours or time zone",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/me/sightings": {
+            "get": {
+                "security": [
+                    {
+                        "Authorization": []
+                    },
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Lists the sightings reported by the caller in every moderation state, newest first, with exact coordinates and review reasons.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Account"
+                ],
+                "summary": "List the sightings of the authenticated user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page size",
+                        "name": "pageSize",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SightingsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid page size or offset",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/mfa/recovery-codes": {
+            "post": {
+                "security": [
+                    {
+                        "Authorization": []
+                    }
+                ],
+                "description": "Replace all recovery codes. Requires a current code from the authenticator app.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "MFA"
+                ],
+                "summary": "Regenerate recovery codes",
+                "parameters": [
+                    {
+                        "description": "Code from the authenticator app",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.TOTPCodeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RecoveryCodesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid authentication code",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/mfa/totp": {
+            "delete": {
+                "security": [
+                    {
+                        "Authorization": []
+                    }
+                ],
+                "description": "Turn off two-factor authentication. Requires a current code from the authenticator app.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "MFA"
+                ],
+                "summary": "Disable TOTP",
+                "parameters": [
+                    {
+                        "description": "Code from the authenticator app",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.TOTPCodeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.GeneralResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid authentication code",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/mfa/totp/confirm": {
+            "post": {
+                "security": [
+                    {
+                        "Authorization": []
+                    }
+                ],
+                "description": "Enable two-factor authentication with a code from the authenticator app. The response contains recovery codes, they are only shown once.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "MFA"
+                ],
+                "summary": "Confirm TOTP enrolment",
+                "parameters": [
+                    {
+                        "description": "Code from the authenticator app",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.TOTPCodeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RecoveryCodesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid authentication code",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Two-factor authentication already enabled or not enrolled",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/mfa/totp/enroll": {
+            "post": {
+                "security": [
+                    {
+                        "Authorization": []
+                    }
+                ],
+                "description": "Create a secret for an authenticator app. Two-factor authentication is enabled once a code is confirmed at /api/v1/mfa/totp/confirm.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "MFA"
+                ],
+                "summary": "Start TOTP enrolment",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.TOTPEnrollmentResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Two-factor authentication already enabled",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/oidc/{provider}/callback": {
+            "get": {
+                "description": "The provider redirects here after login. The identity is linked to an existing user with the same verified email or a new user is created.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Complete an OpenID Connect login",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Provider name",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization code",
+                        "name": "code",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "State",
+                        "name": "state",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.LoginResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid or expired login",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Provider rejected the login",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Email belongs to another account",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/oidc/{provider}/login": {
+            "get": {
+                "description": "Redirect to the login page of the provider (authorization code flow with PKCE). Logged in users calling it with their access token link the identity to their account, API keys never link.",
+                "tags": [
+                    "User"
+                ],
+                "summary": "Log in with an OpenID Connect provider",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Provider name",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "302": {
+                        "description": "Redirect to the provider"
+                    },
+                    "404": {
+                        "description": "Unknown provider",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Provider not reachable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/password/forgot": {
+            "post": {
+                "description": "Mail a single-use password reset token. The response is the same whether or not an account exists for the email.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Request a password reset",
+                "parameters": [
+                    {
+                        "description": "Email of the account",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ForgotPasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.GeneralResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid JSON format",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/password/reset": {
+            "get": {
+                "description": "The link in the password reset email opens a form to choose a new password, which is submitted to POST /api/v1/password/reset.",
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Open the password reset form",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Reset token from the email",
+                        "name": "token",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Password reset form",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Set a new password with a mailed reset token. The token can be used once and all sessions and API keys of the user are revoked. The form opened by the reset link posts here too and gets an HTML page back.",
+                "consumes": [
+                    "application/json",
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "application/json",
+                    "text/html"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Reset the password",
+                "parameters": [
+                    {
+                        "description": "Reset token and new password",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ResetPasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.GeneralResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid or expired reset token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to reset the password",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/register": {
+            "post": {
+                "description": "Create a new user with the input paylod. A verification link is mailed to the email address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Create a new user",
+                "parameters": [
+                    {
+                        "description": "Create user",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid JSON format",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to create user. Please try again",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/sessions": {
+            "get": {
+                "security": [
+                    {
+                        "Authorization": []
+                    }
+                ],
+                "description": "List the devices and browsers the authenticated user is logged in from.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "List active sessions",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SessionsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/sessions/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "Authorization": []
+                    }
+                ],
+                "description": "Log out a device or browser of the authenticated user. Its access and refresh tokens stop working immediately.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Revoke a session",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.GeneralResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Session not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/sightings/pending": {
+            "get": {
+                "security": [
+                    {
+                        "Authorization": []
+                    },
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Reviewers get the queue of pending and disputed sightings, oldest first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Sighting"
+                ],
+                "summary": "List sightings awaiting review",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Restrict the queue to pending or disputed sightings",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of sightings to retrieve per page",
+                        "name": "pageSize",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset for paginating the list",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SightingsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller lacks the sightings:review permission",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/sightings/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "Authorization": []
+                    },
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Admins delete a sighting regardless of its moderation state.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Sighting"
+                ],
+                "summary": "Delete a sighting",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Sighting ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.GeneralResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid sighting id",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller lacks the sightings:delete permission",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Sighting not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/sightings/{id}/dispute": {
+            "post": {
+                "security": [
+                    {
+                        "Authorization": []
+                    },
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Any authenticated user can flag a verified sighting for another review. Disputed sightings are hidden from the public listing.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Sighting"
+                ],
+                "summary": "Dispute a verified sighting",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Sighting ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Dispute reason",
+                        "name": "dispute",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.DisputeSightingRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Sighting"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input format",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Sighting not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Only verified sightings can be disputed",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/sightings/{id}/image": {
+            "get": {
+                "description": "Returns the JPEG image of a verified sighting, e.g. for thumbnails in digest emails.",
+                "produces": [
+                    "image/jpeg"
+                ],
+                "tags": [
+                    "Sighting"
+                ],
+                "summary": "Get the image of a sighting",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Sighting ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "JPEG image",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid sighting id",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Sighting or image not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/sightings/{id}/review": {
+            "post": {
+                "security": [
+                    {
+                        "Authorization": []
+                    },
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Reviewers move a pending or disputed sighting to verified or rejected. Subscribers are notified when a pending sighting gets verified.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Sighting"
+                ],
+                "summary": "Verify or reject a sighting",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Sighting ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Review decision",
+                        "name": "review",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ReviewSightingRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Sighting"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input format",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller lacks the sightings:review permission",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Sighting not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Sighting cannot be reviewed in its current state",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/stream/sightings": {
+            "get": {
+                "description": "Server-Sent Events stream of sightings as they are verified, and for reviewers also as they are reported. Each event has the sighting as JSON data, its type as the event name and an ID to resume from with the Last-Event-ID header. A reset event means events were missed and the listing should be reloaded. Anonymous callers get coarse coordinates and no coordinates for positions under embargo. Streams of authenticated callers end when the token expires, or once a minute check finds the session revoked or the permissions changed; reconnect with fresh credentials.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "Sighting"
+                ],
+                "summary": "Live feed of sightings",
+                "parameters": [
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "Only sightings of these tigers, repeated or comma separated",
+                        "name": "tiger_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only sightings in the area min_lon,min_lat,max_lon,max_lat",
+                        "name": "bbox",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the last event received, to resume after a disconnect",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Stream of sighting.created, sighting.verified and reset events",
+                        "schema": {
+                            "$ref": "#/definitions/models.StreamSighting"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid tiger_id or bbox",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Live feed not available",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/tigers/:id/listSightings": {
+            "get": {
+                "description": "Get a paginated list of all sightings. Anonymous callers get coordinates snapped to a coarse grid and do not see sightings newer than the embargo period, researchers get exact coordinates.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Sighting"
+                ],
+                "summary": "List all sightings",
+                "operationId": "list-tiger-sightings",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Tiger ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Moderation status to list, only reviewers may request anything other than verified",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of sightings to retrieve per page",
+                        "name": "pageSize",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset for paginating the list",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "List of sightings",
+                        "schema": {
+                            "$ref": "#/definitions/models.SightingsResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Only reviewers can list unverified sightings",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/tigers/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "Authorization": []
+                    },
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Admins delete a tiger along with all its sightings.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tiger"
+                ],
+                "summary": "Delete a tiger",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Tiger ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.GeneralResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid tiger id",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller lacks the tigers:delete permission",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Tiger not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/tigers/{id}/follow": {
+            "post": {
+                "security": [
+                    {
+                        "Authorization": []
+                    }
+                ],
+                "description": "Get notified about every verified sighting of the tiger. The followed tigers are listed at /api/v1/me.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tiger"
+                ],
+                "summary": "Follow a tiger",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Tiger ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.GeneralResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid tiger id",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Tiger not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to follow the tiger",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "Authorization": []
+                    }
+                ],
+                "description": "Stop notifications about sightings of the tiger, unless the user reported it and keeps notify_reported_tigers enabled.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tiger"
+                ],
+                "summary": "Unfollow a tiger",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Tiger ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.GeneralResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid tiger id",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Tiger not followed",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to unfollow the tiger",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/token/refresh": {
+            "post": {
+                "description": "Exchange a refresh token for a new access token. The refresh token is rotated, the old one stops working. Presenting a rotated refresh token again revokes the session.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Refresh the access token",
+                "parameters": [
+                    {
+                        "description": "Refresh token, optional when sent as cookie",
+                        "name": "refresh",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.RefreshRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.LoginResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid, expired or revoked refresh token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to refresh the token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/unsubscribe": {
+            "get": {
+                "description": "Unsubscribe links opened in a browser show a page to confirm with, opening the link changes nothing. Link scanners of mail providers open links too.",
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "Account"
+                ],
+                "summary": "Confirm unsubscribing from sighting notifications",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Unsubscribe token from the email",
+                        "name": "token",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Confirmation page",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid unsubscribe link",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "One-click unsubscribe with the signed link included in every sighting notification. Mail clients POST to it as described in RFC 8058, as does the confirmation page, which gets a page back.",
+                "produces": [
+                    "application/json",
+                    "text/html"
+                ],
+                "tags": [
+                    "Account"
+                ],
+                "summary": "Unsubscribe from sighting notifications",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Unsubscribe token from the email",
+                        "name": "token",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.GeneralResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid unsubscribe link",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/verify-email": {
+            "get": {
+                "description": "Confirm the email address with the signed link mailed on registration. Only verified addresses receive notifications.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Verify the email address of a user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Verification token from the email",
+                        "name": "token",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.GeneralResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid or expired verification link",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/verify-email/resend": {
+            "post": {
+                "security": [
+                    {
+                        "Authorization": []
+                    }
+                ],
+                "description": "Send a new verification link to the authenticated user, e.g. after the previous one expired.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Resend the email verification link",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.GeneralResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Email already verified",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to send the verification email",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/webhooks": {
+            "get": {
+                "security": [
+                    {
+                        "Authorization": []
+                    },
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "List the webhooks of the authenticated user. Secrets are not returned.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "List webhooks",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.WebhooksResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller lacks the webhooks:manage permission",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "Authorization": []
+                    },
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Subscribe an endpoint to sighting and tiger events. Deliveries are signed with the secret, which is generated when omitted and only shown in this response.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "Create a webhook",
+                "parameters": [
+                    {
+                        "description": "URL, event types and optional secret",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateWebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateWebhookResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid URL, event type or secret",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller lacks the webhooks:manage permission",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/webhooks/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "Authorization": []
+                    },
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Get a webhook of the authenticated user, including whether it was disabled after failed deliveries.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "Get a webhook",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Webhook"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller lacks the webhooks:manage permission",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "Authorization": []
+                    },
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Delete a webhook of the authenticated user along with its delivery log. Pending deliveries are not sent.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "Delete a webhook",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.GeneralResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller lacks the webhooks:manage permission",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "Authorization": []
+                    },
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Change the URL or event types of a webhook, or disable and enable it. Enabling a webhook resets its failures, deliveries failed while it was disabled are not retried.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "Update a webhook",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to change",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateWebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Webhook"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid URL or event type",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller lacks the webhooks:manage permission",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/webhooks/{id}/deliveries": {
+            "get": {
+                "security": [
+                    {
+                        "Authorization": []
+                    },
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "The delivery log of a webhook, newest first, with the payload, attempts and the outcome of the last attempt.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "List the deliveries of a webhook",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of deliveries to retrieve per page",
+                        "name": "pageSize",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset for paginating the list",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.WebhookDeliveriesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid pagination",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller lacks the webhooks:manage permission",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "keyring.JWK": {
+            "type": "object",
+            "properties": {
+                "alg": {
+                    "type": "string"
+                },
+                "crv": {
+                    "description": "EC keys",
+                    "type": "string"
+                },
+                "e": {
+                    "type": "string"
+                },
+                "kid": {
+                    "type": "string"
+                },
+                "kty": {
+                    "type": "string"
+                },
+                "n": {
+                    "description": "RSA keys",
+                    "type": "string"
+                },
+                "use": {
+                    "type": "string"
+                },
+                "x": {
+                    "type": "string"
+                },
+                "y": {
+                    "type": "string"
+                }
+            }
+        },
+        "keyring.JWKS": {
+            "type": "object",
+            "properties": {
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/keyring.JWK"
+                    }
+                }
+            }
+        },
+        "messaging.SubscriptionStats": {
+            "type": "object",
+            "properties": {
+                "capacity": {
+                    "type": "integer"
+                },
+                "delivered": {
+                    "type": "integer"
+                },
+                "depth": {
+                    "description": "Depth is the number of messages waiting to be received",
+                    "type": "integer"
+                },
+                "dropped": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "overflow": {
+                    "type": "string"
+                },
+                "topic": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.APIKey": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "description": "Prefix is the start of the key, to tell keys apart",
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.APIKeysResponse": {
+            "type": "object",
+            "properties": {
+                "api_keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.APIKey"
+                    }
+                }
+            }
+        },
+        "models.CreateAPIKeyRequest": {
+            "type": "object",
+            "required": [
+                "name",
+                "scopes"
+            ],
+            "properties": {
+                "expires_in_days": {
+                    "description": "Days until the key expires, the key never expires when omitted",
+                    "type": "integer"
+                },
+                "name": {
+                    "description": "Name to recognise the key by, e.g. the integration using it",
+                    "type": "string"
+                },
+                "scopes": {
+                    "description": "Permissions the key is limited to, e.g. sightings:write",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.CreateAPIKeyResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "description": "Prefix is the start of the key, to tell keys apart",
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.CreateTigerRequest": {
+            "type": "object",
+            "properties": {
+                "date_of_birth": {
+                    "description": "Date of Birth of the tiger\n\nRequired: true\nExample: \"18/02/1998\"",
+                    "type": "string"
+                },
+                "last_coordinates": {
+                    "description": "Last coordinates where the tiger was seen\n\nRequired: true",
+                    "type": "object",
+                    "properties": {
+                        "last_seen_coordinates_lat": {
+                            "description": "Latitude of the last seen coordinates\n\nRequired: true\nExample: 37.7749",
+                            "type": "number"
+                        },
+                        "last_seen_coordinates_lon": {
+                            "description": "Longitude of the last seen coordinates\n\nRequired: true\nExample: -122.4194",
+                            "type": "number"
+                        }
+                    }
+                },
+                "last_seen_timestamp": {
+                    "description": "Last seen timestamp of the tiger in Unix Epoch Time UTC format\n\nRequired: true\nExample: 1705147765",
+                    "type": "integer"
+                },
+                "name": {
+                    "description": "Name of the tiger\n\nRequired: true\nExample: RajahuliBangalore",
+                    "type": "string"
+                }
+            }
+        },
+        "models.CreateWebhookRequest": {
+            "type": "object",
+            "required": [
+                "event_types",
+                "url"
+            ],
+            "properties": {
+                "event_types": {
+                    "description": "Events to receive: sighting.created, sighting.verified or tiger.created",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "secret": {
+                    "description": "Secret the deliveries are signed with, generated when omitted",
+                    "type": "string"
+                },
+                "url": {
+                    "description": "http(s) URL events are POSTed to",
+                    "type": "string"
+                }
+            }
+        },
+        "models.CreateWebhookResponse": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "description": "Active is false once the webhook was disabled, by its owner or after too many failed deliveries",
+                    "type": "boolean"
+                },
+                "consecutive_failures": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "disabled_at": {
+                    "type": "string"
+                },
+                "event_types": {
+                    "description": "example: [\"sighting.verified\"]",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                },
+                "webhook_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Credentials": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "description": "Password of the user.\nrequired: true\nexample: MySecretPassword\nmin length: 6",
+                    "type": "string"
+                },
+                "username": {
+                    "description": "Username of the user.\nrequired: true\nexample: john_doe",
+                    "type": "string"
+                }
+            }
+        },
+        "models.CustomTime": {
+            "type": "object",
+            "properties": {
+                "time.Time": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.DataExport": {
+            "type": "object",
+            "properties": {
+                "completed_at": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "description": "ExpiresAt is when the zip is deleted, set once it is ready",
+                    "type": "string"
+                },
+                "export_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.DeleteAccountRequest": {
+            "type": "object",
+            "properties": {
+                "current_password": {
+                    "type": "string"
+                },
+                "totp_code": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.DispatcherHealth": {
+            "type": "object",
+            "properties": {
+                "in_flight": {
+                    "description": "InFlight is true while either loop is delivering notifications",
+                    "type": "boolean"
+                },
+                "last_digest_run_at": {
+                    "description": "LastDigestRunAt is when due digests were last sent",
+                    "type": "string"
+                },
+                "last_outbox_run_at": {
+                    "description": "LastOutboxRunAt is when the outbox was last worked through",
+                    "type": "string"
+                },
+                "last_webhook_run_at": {
+                    "description": "LastWebhookRunAt is when the webhook deliveries were last worked through",
+                    "type": "string"
+                },
+                "outbox_run_started_at": {
+                    "description": "OutboxRunStartedAt is when the outbox loop started its current run, unset between runs",
+                    "type": "string"
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "Status is ok, stalled or stopped\n\nexample: ok",
+                    "type": "string"
+                },
+                "webhook_run_started_at": {
+                    "description": "WebhookRunStartedAt is when the webhook loop started its current run, unset between runs",
+                    "type": "string"
+                }
+            }
+        },
+        "models.DisputeSightingRequest": {
+            "type": "object",
+            "properties": {
+                "reason": {
+                    "description": "Reason why the sighting is disputed\n\nrequired: true\nexample: Same animal was reported twice",
+                    "type": "string"
+                }
+            }
+        },
+        "models.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.FollowedTiger": {
+            "type": "object",
+            "properties": {
+                "followed_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "tiger_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ForgotPasswordRequest": {
+            "type": "object",
+            "required": [
+                "email"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.GeneralResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.LiveServerMessage": {
+            "type": "object",
+            "properties": {
+                "event": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "description": "ExpiresAt is sent on ready and authenticated, the channel is closed then unless the app sends a fresh token",
+                    "type": "string"
+                },
+                "heartbeat_seconds": {
+                    "description": "HeartbeatSeconds is sent on ready, the app is disconnected if it does not answer pings for twice as long",
+                    "type": "integer"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "notification": {
+                    "$ref": "#/definitions/models.UserNotification"
+                },
+                "sighting": {
+                    "$ref": "#/definitions/models.StreamSighting"
+                },
+                "subscription": {
+                    "description": "Subscription is set on subscribed, unsubscribed and errors about a subscription",
+                    "type": "string"
+                },
+                "subscriptions": {
+                    "description": "Subscriptions are those a sighting matched",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "type": {
+                    "type": "string"
+                },
+                "upload": {
+                    "$ref": "#/definitions/models.UploadProgress"
+                }
+            }
+        },
+        "models.LoginResponse": {
+            "type": "object",
+            "properties": {
+                "expires_in": {
+                    "description": "ExpiresIn is the lifetime of the access token in seconds",
+                    "type": "integer"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "mfa_required": {
+                    "description": "MFARequired is set when the password was correct but a second factor is needed.\nThe MFA token is then exchanged for the access token at /api/v1/login/mfa.",
+                    "type": "boolean"
+                },
+                "mfa_token": {
+                    "type": "string"
+                },
+                "refresh_token": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.LoginThrottle": {
+            "type": "object",
+            "properties": {
+                "failures": {
+                    "type": "integer"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "last_failure_at": {
+                    "type": "string"
+                },
+                "locked": {
+                    "description": "Locked is set once the failures reached the lockout threshold",
+                    "type": "boolean"
+                },
+                "locked_until": {
+                    "description": "LockedUntil is the time the next attempt is accepted, zero if attempts are accepted right away",
+                    "type": "string"
+                },
+                "scope": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.LoginThrottlesResponse": {
+            "type": "object",
+            "properties": {
+                "throttles": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.LoginThrottle"
+                    }
+                }
+            }
+        },
+        "models.MFALoginRequest": {
+            "type": "object",
+            "required": [
+                "mfa_token"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "mfa_token": {
+                    "type": "string"
+                },
+                "recovery_code": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.MFAPolicy": {
+            "type": "object",
+            "properties": {
+                "required_roles": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.NotificationPreferences": {
+            "type": "object",
+            "properties": {
+                "channels": {
+                    "description": "Channels notifications are sent on, empty to receive none\n\nexample: [\"email\",\"in_app\"]",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "digest_hour": {
+                    "description": "Hour of the day (0-23) daily and weekly digests are sent at, in the time zone of the user\n\nexample: 8",
+                    "type": "integer"
+                },
+                "digest_weekday": {
+                    "description": "Day of the week weekly digests are sent on, 0 is Sunday\n\nexample: 1",
+                    "type": "integer"
+                },
+                "frequency": {
+                    "description": "Frequency is immediate, daily or weekly\n\nexample: immediate",
+                    "type": "string"
+                },
+                "muted_tigers": {
+                    "description": "Tigers the user gets no notifications about, even when following or having reported them",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "quiet_hours_end": {
+                    "description": "Hour of the day (0-23) at which quiet hours end\n\nexample: 7",
+                    "type": "integer"
+                },
+                "quiet_hours_start": {
+                    "description": "Hour of the day (0-23) from which immediate notifications are held back until the quiet hours end. Quiet hours may wrap past midnight.\n\nexample: 22",
+                    "type": "integer"
+                },
+                "timezone": {
+                    "description": "IANA time zone the quiet hours are in\n\nexample: Asia/Kolkata",
+                    "type": "string"
+                }
+            }
+        },
+        "models.OutboxMessage": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "message_id": {
+                    "type": "integer"
+                },
+                "next_attempt_at": {
+                    "type": "string"
+                },
+                "payload": {
+                    "description": "Payload is a SightingVerifiedEvent, an OutboxEmailMessage or a UserEvent, depending on the kind",
+                    "type": "object"
+                },
+                "processed_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.OutboxResponse": {
+            "type": "object",
+            "properties": {
+                "messages": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.OutboxMessage"
+                    }
+                },
+                "offset": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Profile": {
+            "type": "object",
+            "properties": {
+                "display_name": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "email_verified": {
+                    "type": "boolean"
+                },
+                "followed_tigers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.FollowedTiger"
+                    }
+                },
+                "has_password": {
+                    "description": "HasPassword is false for accounts created through an OpenID Connect provider until a password is set",
+                    "type": "boolean"
+                },
+                "language": {
+                    "description": "Language emails are sent in, e.g. en, hi or bn",
+                    "type": "string"
+                },
+                "notify_reported_tigers": {
+                    "description": "NotifyReportedTigers sends notifications about tigers the user reported, not only those they follow",
+                    "type": "boolean"
+                },
+                "roles": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "two_factor_enabled": {
+                    "type": "boolean"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.RecoveryCodesResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "recovery_codes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.RefreshRequest": {
+            "type": "object",
+            "properties": {
+                "refresh_token": {
+                    "description": "Refresh token issued at login, can also be sent as the refresh_token cookie\n\nexample: 3q2-7wX0...",
                     "type": "string"
                 }
             }
         },
-        "models.Credentials": {
+        "models.ResetPasswordRequest": {
             "type": "object",
+            "required": [
+                "password",
+                "token"
+            ],
             "properties": {
                 "password": {
-                    "description": "Password of the user.\nrequired: true\nexample: MySecretPassword\nmin length: 6",
                     "type": "string"
                 },
-                "username": {
-                    "description": "Username of the user.\nrequired: true\nexample: john_doe",
+                "token": {
                     "type": "string"
                 }
             }
         },
-        "models.CustomTime": {
+        "models.ReviewSightingRequest": {
             "type": "object",
             "properties": {
-                "time.Time": {
+                "decision": {
+                    "description": "Decision of the reviewer, either verified or rejected\n\nrequired: true\nexample: verified",
+                    "type": "string"
+                },
+                "reason": {
+                    "description": "Reason for the decision, required when rejecting\n\nexample: Image does not show a tiger",
                     "type": "string"
                 }
             }
         },
-        "models.ErrorResponse": {
+        "models.RoleRequest": {
             "type": "object",
             "properties": {
-                "message": {
+                "role": {
+                    "description": "Role to grant, one of viewer, reporter, ranger, researcher, admin\n\nrequired: true\nexample: ranger",
                     "type": "string"
-                },
-                "status": {
-                    "type": "integer"
                 }
             }
         },
-        "models.GeneralResponse": {
+        "models.Session": {
             "type": "object",
             "properties": {
-                "message": {
+                "created_at": {
+                    "type": "string"
+                },
+                "current": {
+                    "description": "Current is set for the session the request was made with",
+                    "type": "boolean"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "ip_address": {
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "description": "RevokedAt is set for sessions that were logged out, only listed in data exports",
+                    "type": "string"
+                },
+                "session_id": {
+                    "type": "string"
+                },
+                "user_agent": {
                     "type": "string"
                 }
             }
         },
-        "models.LoginResponse": {
+        "models.SessionsResponse": {
             "type": "object",
             "properties": {
-                "message": {
-                    "type": "string"
-                },
-                "token": {
-                    "type": "string"
+                "sessions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Session"
+                    }
                 }
             }
         },
@@ -479,9 +4006,16 @@ const docTemplate = `{
                 "last_seen_timestamp": {
                     "$ref": "#/definitions/models.UnixTime"
                 },
+                "review_reason": {
+                    "type": "string"
+                },
                 "sighting_id": {
                     "type": "integer"
                 },
+                "status": {
+                    "description": "Moderation state of the sighting (pending, verified, rejected, disputed)",
+                    "type": "string"
+                },
                 "tiger_id": {
                     "type": "integer"
                 },
@@ -501,6 +4035,10 @@ const docTemplate = `{
         "models.SightingsResponse": {
             "type": "object",
             "properties": {
+                "location_precision": {
+                    "description": "Precision of the returned coordinates, either exact or coarse\n\nexample: coarse",
+                    "type": "string"
+                },
                 "offset": {
                     "description": "Offset for paginating through the list of sightings\n\nrequired: true\nexample: 0",
                     "type": "integer"
@@ -514,6 +4052,62 @@ const docTemplate = `{
                 }
             }
         },
+        "models.StreamSighting": {
+            "type": "object",
+            "properties": {
+                "latitude": {
+                    "type": "number"
+                },
+                "location_precision": {
+                    "description": "LocationPrecision is exact or coarse, depending on the caller",
+                    "type": "string"
+                },
+                "location_withheld": {
+                    "description": "LocationWithheld is set instead of coordinates for positions still under embargo",
+                    "type": "boolean"
+                },
+                "longitude": {
+                    "type": "number"
+                },
+                "seen_at": {
+                    "type": "string"
+                },
+                "sighting_id": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "tiger_id": {
+                    "type": "integer"
+                },
+                "tiger_name": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.TOTPCodeRequest": {
+            "type": "object",
+            "required": [
+                "code"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.TOTPEnrollmentResponse": {
+            "type": "object",
+            "properties": {
+                "provisioning_uri": {
+                    "type": "string"
+                },
+                "secret": {
+                    "type": "string"
+                }
+            }
+        },
         "models.Tiger": {
             "type": "object",
             "required": [
@@ -550,6 +4144,10 @@ const docTemplate = `{
                         }
                     ]
                 },
+                "location_withheld": {
+                    "description": "LocationWithheld is set when the last position is still under embargo for the caller",
+                    "type": "boolean"
+                },
                 "name": {
                     "type": "string"
                 }
@@ -558,6 +4156,10 @@ const docTemplate = `{
         "models.TigersResponse": {
             "type": "object",
             "properties": {
+                "location_precision": {
+                    "description": "LocationPrecision is either exact or coarse depending on the caller.",
+                    "type": "string"
+                },
                 "offset": {
                     "description": "Offset is the offset for paginating the list.",
                     "type": "integer"
@@ -579,6 +4181,73 @@ const docTemplate = `{
                 }
             }
         },
+        "models.UpdateProfileRequest": {
+            "type": "object",
+            "properties": {
+                "current_password": {
+                    "type": "string"
+                },
+                "display_name": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "language": {
+                    "type": "string"
+                },
+                "notify_reported_tigers": {
+                    "type": "boolean"
+                },
+                "password": {
+                    "type": "string"
+                },
+                "totp_code": {
+                    "description": "TOTPCode confirms the change instead of the password for accounts without one that use two-factor authentication",
+                    "type": "string"
+                }
+            }
+        },
+        "models.UpdateWebhookRequest": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "description": "Set to true to enable a disabled webhook again, which also resets its failures",
+                    "type": "boolean"
+                },
+                "event_types": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.UploadProgress": {
+            "type": "object",
+            "properties": {
+                "received": {
+                    "type": "integer"
+                },
+                "state": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "Status is the HTTP status of the finished upload",
+                    "type": "integer"
+                },
+                "total": {
+                    "description": "Total is the size of the upload, -1 if the client did not send it",
+                    "type": "integer"
+                },
+                "upload_id": {
+                    "type": "string"
+                }
+            }
+        },
         "models.User": {
             "type": "object",
             "required": [
@@ -600,9 +4269,188 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "models.UserNotification": {
+            "type": "object",
+            "properties": {
+                "latitude": {
+                    "type": "number"
+                },
+                "location_precision": {
+                    "type": "string"
+                },
+                "location_withheld": {
+                    "description": "LocationWithheld is set instead of coordinates for positions still under embargo",
+                    "type": "boolean"
+                },
+                "longitude": {
+                    "type": "number"
+                },
+                "seen_at": {
+                    "type": "string"
+                },
+                "sighting_id": {
+                    "type": "integer"
+                },
+                "tiger_id": {
+                    "type": "integer"
+                },
+                "tiger_name": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.UserRolesResponse": {
+            "type": "object",
+            "properties": {
+                "roles": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Webhook": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "description": "Active is false once the webhook was disabled, by its owner or after too many failed deliveries",
+                    "type": "boolean"
+                },
+                "consecutive_failures": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "disabled_at": {
+                    "type": "string"
+                },
+                "event_types": {
+                    "description": "example: [\"sighting.verified\"]",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "url": {
+                    "type": "string"
+                },
+                "webhook_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.WebhookDeliveriesResponse": {
+            "type": "object",
+            "properties": {
+                "deliveries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.WebhookDelivery"
+                    }
+                },
+                "offset": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.WebhookDelivery": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "delivered_at": {
+                    "type": "string"
+                },
+                "delivery_id": {
+                    "type": "integer"
+                },
+                "event_id": {
+                    "type": "string"
+                },
+                "event_type": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "next_attempt_at": {
+                    "type": "string"
+                },
+                "payload": {
+                    "type": "object"
+                },
+                "response_status": {
+                    "description": "ResponseStatus is the HTTP status of the last attempt, 0 if no response was received",
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.WebhooksResponse": {
+            "type": "object",
+            "properties": {
+                "webhooks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Webhook"
+                    }
+                }
+            }
+        },
+        "templates.Rendered": {
+            "type": "object",
+            "properties": {
+                "html": {
+                    "type": "string"
+                },
+                "language": {
+                    "description": "Language the templates were found in, the default language if the requested one has none",
+                    "type": "string"
+                },
+                "subject": {
+                    "type": "string"
+                },
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "templates.Template": {
+            "type": "object",
+            "properties": {
+                "languages": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
         }
     },
     "securityDefinitions": {
+        "APIKey": {
+            "type": "apiKey",
+            "name": "X-API-Key",
+            "in": "header"
+        },
         "Authorization": {
             "type": "apiKey",
             "name": "Authorization",
@@ -98,6 +98,11 @@ func GetDB() *sql.DB {
 	return dbConn
 }
 
+// SetDB replaces the connection GetDB returns. Tests use it to run the services against their own database.
+func SetDB(db *sql.DB) {
+	dbConn = db
+}
+
 // CloseDB closes the database connection.
 func CloseDB() {
 	if dbConn != nil {
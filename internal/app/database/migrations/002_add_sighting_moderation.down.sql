@@ -0,0 +1,10 @@
+-- 002_add_sighting_moderation.down.sql
+ALTER TABLE tigerhall.users DROP COLUMN IF EXISTS is_reviewer;
+
+DROP INDEX IF EXISTS tigerhall.idx_sightings_tiger_status;
+
+ALTER TABLE tigerhall.sightings
+    DROP COLUMN IF EXISTS reviewed_at,
+    DROP COLUMN IF EXISTS reviewed_by,
+    DROP COLUMN IF EXISTS review_reason,
+    DROP COLUMN IF EXISTS status;
@@ -0,0 +1,18 @@
+-- 002_add_sighting_moderation.up.sql
+-- Track the moderation lifecycle of each sighting
+ALTER TABLE tigerhall.sightings
+    ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'verified', 'rejected', 'disputed')),
+    ADD COLUMN IF NOT EXISTS review_reason TEXT,
+    ADD COLUMN IF NOT EXISTS reviewed_by INT REFERENCES tigerhall.users(user_id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS reviewed_at TIMESTAMP WITH TIME ZONE;
+
+-- Sightings reported before moderation existed were already public
+UPDATE tigerhall.sightings SET status = 'verified';
+
+-- Index used by the public listing and the review queue
+CREATE INDEX IF NOT EXISTS idx_sightings_tiger_status ON tigerhall.sightings(tiger_id, status, last_seen_timestamp);
+
+-- Users allowed to verify or reject sightings
+ALTER TABLE tigerhall.users
+    ADD COLUMN IF NOT EXISTS is_reviewer BOOLEAN NOT NULL DEFAULT FALSE;
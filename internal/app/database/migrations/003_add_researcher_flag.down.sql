@@ -0,0 +1,2 @@
+-- 003_add_researcher_flag.down.sql
+ALTER TABLE tigerhall.users DROP COLUMN IF EXISTS is_researcher;
@@ -0,0 +1,4 @@
+-- 003_add_researcher_flag.up.sql
+-- Researchers are allowed to see exact tiger coordinates
+ALTER TABLE tigerhall.users
+    ADD COLUMN IF NOT EXISTS is_researcher BOOLEAN NOT NULL DEFAULT FALSE;
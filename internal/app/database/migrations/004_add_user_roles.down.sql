@@ -0,0 +1,9 @@
+-- 004_add_user_roles.down.sql
+ALTER TABLE tigerhall.users
+    ADD COLUMN IF NOT EXISTS is_reviewer BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS is_researcher BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE tigerhall.users SET is_reviewer = roles && ARRAY['ranger', 'admin']::TEXT[];
+UPDATE tigerhall.users SET is_researcher = 'researcher' = ANY(roles);
+
+ALTER TABLE tigerhall.users DROP COLUMN IF EXISTS roles;
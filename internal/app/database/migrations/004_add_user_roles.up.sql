@@ -0,0 +1,13 @@
+-- 004_add_user_roles.up.sql
+-- Replace the reviewer and researcher flags with a list of roles
+ALTER TABLE tigerhall.users
+    ADD COLUMN IF NOT EXISTS roles TEXT[] NOT NULL DEFAULT '{reporter}'
+        CHECK (roles <@ ARRAY['viewer', 'reporter', 'ranger', 'researcher', 'admin']::TEXT[]);
+
+-- Reviewers were field staff verifying sightings, which is what rangers do now
+UPDATE tigerhall.users SET roles = array_append(roles, 'ranger') WHERE is_reviewer;
+UPDATE tigerhall.users SET roles = array_append(roles, 'researcher') WHERE is_researcher;
+
+ALTER TABLE tigerhall.users
+    DROP COLUMN IF EXISTS is_reviewer,
+    DROP COLUMN IF EXISTS is_researcher;
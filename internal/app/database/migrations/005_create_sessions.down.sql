@@ -0,0 +1,4 @@
+-- 005_create_sessions.down.sql
+DROP TABLE IF EXISTS tigerhall.revoked_tokens;
+
+DROP TABLE IF EXISTS tigerhall.sessions;
@@ -0,0 +1,21 @@
+-- 005_create_sessions.up.sql
+-- Login sessions, each backed by a rotating refresh token stored as a SHA-256 hash
+CREATE TABLE IF NOT EXISTS tigerhall.sessions (
+    session_id VARCHAR(64) PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES tigerhall.users(user_id) ON DELETE CASCADE,
+    refresh_token_hash CHAR(64) UNIQUE NOT NULL,
+    user_agent TEXT,
+    ip_address VARCHAR(64),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON tigerhall.sessions(user_id);
+
+-- Access tokens revoked before their expiry, e.g. on logout
+CREATE TABLE IF NOT EXISTS tigerhall.revoked_tokens (
+    token_id VARCHAR(64) PRIMARY KEY,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
@@ -0,0 +1,2 @@
+-- 006_add_email_verification.down.sql
+ALTER TABLE tigerhall.users DROP COLUMN IF EXISTS email_verified_at;
@@ -0,0 +1,6 @@
+-- 006_add_email_verification.up.sql
+-- New users stay unverified until they open the link mailed on registration
+ALTER TABLE tigerhall.users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP WITH TIME ZONE;
+
+-- Existing users have been receiving notifications already, keep them subscribed
+UPDATE tigerhall.users SET email_verified_at = NOW() WHERE email_verified_at IS NULL;
@@ -0,0 +1,2 @@
+-- 007_create_password_resets.down.sql
+DROP TABLE IF EXISTS tigerhall.password_resets;
@@ -0,0 +1,11 @@
+-- 007_create_password_resets.up.sql
+-- Password reset tokens, stored as SHA-256 hashes. A token can be used once.
+CREATE TABLE IF NOT EXISTS tigerhall.password_resets (
+    token_hash CHAR(64) PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES tigerhall.users(user_id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_resets_user_id ON tigerhall.password_resets(user_id);
@@ -0,0 +1,6 @@
+-- 008_create_user_identities.down.sql
+-- Users without password cannot log in anymore, lock them with an invalid hash
+UPDATE tigerhall.users SET password_hash = '!' WHERE password_hash IS NULL;
+ALTER TABLE tigerhall.users ALTER COLUMN password_hash SET NOT NULL;
+
+DROP TABLE IF EXISTS tigerhall.user_identities;
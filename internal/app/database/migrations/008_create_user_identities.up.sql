@@ -0,0 +1,16 @@
+-- 008_create_user_identities.up.sql
+-- Identities at external OpenID Connect providers linked to our users
+CREATE TABLE IF NOT EXISTS tigerhall.user_identities (
+    provider VARCHAR(50) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    user_id INT NOT NULL REFERENCES tigerhall.users(user_id) ON DELETE CASCADE,
+    email VARCHAR(100),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_login_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON tigerhall.user_identities(user_id);
+
+-- Users signing up through a provider have no password
+ALTER TABLE tigerhall.users ALTER COLUMN password_hash DROP NOT NULL;
@@ -0,0 +1,9 @@
+-- 009_add_totp.down.sql
+DROP TABLE IF EXISTS tigerhall.mfa_required_roles;
+
+DROP TABLE IF EXISTS tigerhall.recovery_codes;
+
+ALTER TABLE tigerhall.users
+    DROP COLUMN IF EXISTS totp_last_step,
+    DROP COLUMN IF EXISTS totp_enabled_at,
+    DROP COLUMN IF EXISTS totp_secret;
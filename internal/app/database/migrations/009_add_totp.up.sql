@@ -0,0 +1,21 @@
+-- 009_add_totp.up.sql
+-- TOTP second factor. The secret is stored on enrolment and only used once the user confirmed a code.
+ALTER TABLE tigerhall.users
+    ADD COLUMN IF NOT EXISTS totp_secret VARCHAR(64),
+    ADD COLUMN IF NOT EXISTS totp_enabled_at TIMESTAMP WITH TIME ZONE,
+    -- Last accepted time step, codes of this or earlier steps cannot be replayed
+    ADD COLUMN IF NOT EXISTS totp_last_step BIGINT;
+
+-- Single use recovery codes, stored as SHA-256 hashes
+CREATE TABLE IF NOT EXISTS tigerhall.recovery_codes (
+    user_id INT NOT NULL REFERENCES tigerhall.users(user_id) ON DELETE CASCADE,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (user_id, code_hash)
+);
+
+-- Roles whose privileges are only granted to users with two-factor authentication
+CREATE TABLE IF NOT EXISTS tigerhall.mfa_required_roles (
+    role VARCHAR(20) PRIMARY KEY
+        CHECK (role IN ('viewer', 'reporter', 'ranger', 'researcher', 'admin'))
+);
@@ -0,0 +1,2 @@
+-- 010_create_login_throttles.down.sql
+DROP TABLE IF EXISTS tigerhall.login_throttles;
@@ -0,0 +1,11 @@
+-- 010_create_login_throttles.up.sql
+-- Failed login attempts per username and per client IP
+CREATE TABLE IF NOT EXISTS tigerhall.login_throttles (
+    scope VARCHAR(10) NOT NULL CHECK (scope IN ('user', 'ip')),
+    key VARCHAR(255) NOT NULL,
+    failures INT NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    -- No attempts are accepted before this time
+    locked_until TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (scope, key)
+);
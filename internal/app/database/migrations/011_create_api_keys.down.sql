@@ -0,0 +1,2 @@
+-- 011_create_api_keys.down.sql
+DROP TABLE IF EXISTS tigerhall.api_keys;
@@ -0,0 +1,16 @@
+-- 011_create_api_keys.up.sql
+-- Personal API keys for integrations, stored as SHA-256 hashes
+CREATE TABLE IF NOT EXISTS tigerhall.api_keys (
+    key_id VARCHAR(32) PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES tigerhall.users(user_id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    key_hash CHAR(64) UNIQUE NOT NULL,
+    -- Permissions the key is limited to, on top of the roles of its owner
+    scopes TEXT[] NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON tigerhall.api_keys(user_id);
@@ -0,0 +1,10 @@
+-- 012_add_user_profiles.down.sql
+DROP INDEX IF EXISTS tigerhall.idx_sightings_user_id;
+
+-- Anonymised sightings stay, only new deletions cascade again
+ALTER TABLE tigerhall.sightings DROP CONSTRAINT IF EXISTS sightings_user_id_fkey;
+ALTER TABLE tigerhall.sightings
+    ADD CONSTRAINT sightings_user_id_fkey FOREIGN KEY (user_id) REFERENCES tigerhall.users(user_id) ON DELETE CASCADE;
+
+ALTER TABLE tigerhall.users
+    DROP COLUMN IF EXISTS display_name;
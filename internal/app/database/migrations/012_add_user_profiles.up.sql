@@ -0,0 +1,11 @@
+-- 012_add_user_profiles.up.sql
+-- Name shown instead of the username, optional
+ALTER TABLE tigerhall.users
+    ADD COLUMN IF NOT EXISTS display_name VARCHAR(100);
+
+-- Sightings outlive the account that reported them, they are anonymised instead of deleted
+ALTER TABLE tigerhall.sightings DROP CONSTRAINT IF EXISTS sightings_user_id_fkey;
+ALTER TABLE tigerhall.sightings
+    ADD CONSTRAINT sightings_user_id_fkey FOREIGN KEY (user_id) REFERENCES tigerhall.users(user_id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_sightings_user_id ON tigerhall.sightings(user_id);
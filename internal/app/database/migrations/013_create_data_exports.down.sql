@@ -0,0 +1,3 @@
+-- 013_create_data_exports.down.sql
+DROP TABLE IF EXISTS tigerhall.data_exports;
+DROP TABLE IF EXISTS tigerhall.notification_log;
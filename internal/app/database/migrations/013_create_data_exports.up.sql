@@ -0,0 +1,25 @@
+-- 013_create_data_exports.up.sql
+-- Notifications sent to users, kept so they can be included in data exports
+CREATE TABLE IF NOT EXISTS tigerhall.notification_log (
+    notification_id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES tigerhall.users(user_id) ON DELETE CASCADE,
+    channel VARCHAR(20) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('sent', 'failed')),
+    sent_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_log_user_id ON tigerhall.notification_log(user_id);
+
+-- Personal data exports, the zip files themselves live in DATA_EXPORT_DIR
+CREATE TABLE IF NOT EXISTS tigerhall.data_exports (
+    export_id VARCHAR(32) PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES tigerhall.users(user_id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'ready', 'failed')),
+    file_path TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_data_exports_user_id ON tigerhall.data_exports(user_id);
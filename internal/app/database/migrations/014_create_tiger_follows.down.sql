@@ -0,0 +1,5 @@
+-- 014_create_tiger_follows.down.sql
+ALTER TABLE tigerhall.users
+    DROP COLUMN IF EXISTS notify_reported_tigers;
+
+DROP TABLE IF EXISTS tigerhall.tiger_follows;
@@ -0,0 +1,14 @@
+-- 014_create_tiger_follows.up.sql
+-- Tigers users follow, followers are notified about every verified sighting
+CREATE TABLE IF NOT EXISTS tigerhall.tiger_follows (
+    user_id INT NOT NULL REFERENCES tigerhall.users(user_id) ON DELETE CASCADE,
+    tiger_id INT NOT NULL REFERENCES tigerhall.tigers(tiger_id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, tiger_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_tiger_follows_tiger_id ON tigerhall.tiger_follows(tiger_id);
+
+-- Reporters keep being notified about tigers they reported unless they opt out
+ALTER TABLE tigerhall.users
+    ADD COLUMN IF NOT EXISTS notify_reported_tigers BOOLEAN NOT NULL DEFAULT TRUE;
@@ -0,0 +1,3 @@
+-- 015_create_notification_preferences.down.sql
+DROP TABLE IF EXISTS tigerhall.tiger_mutes;
+DROP TABLE IF EXISTS tigerhall.notification_preferences;
@@ -0,0 +1,18 @@
+-- 015_create_notification_preferences.up.sql
+-- Notification preferences, users without a row get the defaults: email, immediately, no quiet hours
+CREATE TABLE IF NOT EXISTS tigerhall.notification_preferences (
+    user_id INT PRIMARY KEY REFERENCES tigerhall.users(user_id) ON DELETE CASCADE,
+    channels TEXT[] NOT NULL DEFAULT '{email}',
+    frequency VARCHAR(20) NOT NULL DEFAULT 'immediate' CHECK (frequency IN ('immediate', 'daily', 'weekly')),
+    quiet_hours_start SMALLINT CHECK (quiet_hours_start BETWEEN 0 AND 23),
+    quiet_hours_end SMALLINT CHECK (quiet_hours_end BETWEEN 0 AND 23),
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Tigers a user does not want to hear about, whether they follow or reported them
+CREATE TABLE IF NOT EXISTS tigerhall.tiger_mutes (
+    user_id INT NOT NULL REFERENCES tigerhall.users(user_id) ON DELETE CASCADE,
+    tiger_id INT NOT NULL REFERENCES tigerhall.tigers(tiger_id) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, tiger_id)
+);
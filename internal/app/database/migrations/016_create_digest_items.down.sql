@@ -0,0 +1,7 @@
+-- 016_create_digest_items.down.sql
+ALTER TABLE tigerhall.notification_preferences
+    DROP COLUMN IF EXISTS last_digest_at,
+    DROP COLUMN IF EXISTS digest_weekday,
+    DROP COLUMN IF EXISTS digest_hour;
+
+DROP TABLE IF EXISTS tigerhall.digest_items;
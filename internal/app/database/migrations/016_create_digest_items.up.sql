@@ -0,0 +1,15 @@
+-- 016_create_digest_items.up.sql
+-- Sightings waiting to be sent in the next daily or weekly digest of a user
+CREATE TABLE IF NOT EXISTS tigerhall.digest_items (
+    item_id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES tigerhall.users(user_id) ON DELETE CASCADE,
+    sighting_id INT NOT NULL REFERENCES tigerhall.sightings(sighting_id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, sighting_id)
+);
+
+-- Hour of the day and day of the week (0 is Sunday) digests are sent at, in the time zone of the user
+ALTER TABLE tigerhall.notification_preferences
+    ADD COLUMN IF NOT EXISTS digest_hour SMALLINT NOT NULL DEFAULT 8 CHECK (digest_hour BETWEEN 0 AND 23),
+    ADD COLUMN IF NOT EXISTS digest_weekday SMALLINT NOT NULL DEFAULT 1 CHECK (digest_weekday BETWEEN 0 AND 6),
+    ADD COLUMN IF NOT EXISTS last_digest_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,2 @@
+-- 017_create_notification_outbox.down.sql
+DROP TABLE IF EXISTS tigerhall.notification_outbox;
@@ -0,0 +1,19 @@
+-- 017_create_notification_outbox.up.sql
+-- Notifications waiting to be delivered. Sighting events are written in the transaction that verifies the
+-- sighting and expanded into one email per recipient by the outbox worker, which retries failed sends.
+CREATE TABLE IF NOT EXISTS tigerhall.notification_outbox (
+    message_id SERIAL PRIMARY KEY,
+    kind VARCHAR(32) NOT NULL,
+    -- Recipient of emails, their pending emails are dropped when the account is deleted
+    user_id INT REFERENCES tigerhall.users(user_id) ON DELETE CASCADE,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'dead')),
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    processed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_outbox_due ON tigerhall.notification_outbox(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_notification_outbox_status ON tigerhall.notification_outbox(status);
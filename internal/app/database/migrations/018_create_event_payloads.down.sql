@@ -0,0 +1,2 @@
+-- 018_create_event_payloads.down.sql
+DROP TABLE IF EXISTS tigerhall.event_payloads;
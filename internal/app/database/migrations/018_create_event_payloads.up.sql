@@ -0,0 +1,10 @@
+-- 018_create_event_payloads.up.sql
+-- Events too large for a NOTIFY payload, other instances read them by id. Rows are deleted after an hour.
+CREATE TABLE IF NOT EXISTS tigerhall.event_payloads (
+    event_id BIGSERIAL PRIMARY KEY,
+    topic VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_payloads_created_at ON tigerhall.event_payloads(created_at);
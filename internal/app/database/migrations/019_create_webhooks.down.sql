@@ -0,0 +1,3 @@
+-- 019_create_webhooks.down.sql
+DROP TABLE IF EXISTS tigerhall.webhook_deliveries;
+DROP TABLE IF EXISTS tigerhall.webhooks;
@@ -0,0 +1,36 @@
+-- 019_create_webhooks.up.sql
+-- Endpoints of partner organisations receiving sighting and tiger events
+CREATE TABLE IF NOT EXISTS tigerhall.webhooks (
+    webhook_id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES tigerhall.users(user_id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    event_types TEXT[] NOT NULL,
+    -- Kept in clear text, it is needed to sign every delivery
+    secret TEXT NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    -- Failed delivery attempts in a row, the webhook is disabled when there are too many
+    consecutive_failures INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    disabled_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhooks_user_id ON tigerhall.webhooks(user_id);
+
+-- One event sent to one webhook, kept as delivery log
+CREATE TABLE IF NOT EXISTS tigerhall.webhook_deliveries (
+    delivery_id SERIAL PRIMARY KEY,
+    webhook_id INT NOT NULL REFERENCES tigerhall.webhooks(webhook_id) ON DELETE CASCADE,
+    event_id VARCHAR(32) NOT NULL,
+    event_type VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'failed')),
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    response_status INT,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    delivered_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON tigerhall.webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON tigerhall.webhook_deliveries(webhook_id);
@@ -0,0 +1,2 @@
+-- 020_add_user_language.down.sql
+ALTER TABLE tigerhall.users DROP COLUMN IF EXISTS language;
@@ -0,0 +1,5 @@
+-- 020_add_user_language.up.sql
+-- Language emails are sent in, a BCP 47 tag such as en, hi or bn. Emails fall back to English for languages
+-- without templates.
+ALTER TABLE tigerhall.users
+    ADD COLUMN IF NOT EXISTS language VARCHAR(35) NOT NULL DEFAULT 'en';
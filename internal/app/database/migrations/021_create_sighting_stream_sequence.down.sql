@@ -0,0 +1,2 @@
+-- 021_create_sighting_stream_sequence.down.sql
+DROP SEQUENCE IF EXISTS tigerhall.sighting_stream_event_seq;
@@ -0,0 +1,3 @@
+-- 021_create_sighting_stream_sequence.up.sql
+-- IDs of the live sighting feed events, shared by all instances so clients can resume from any of them
+CREATE SEQUENCE IF NOT EXISTS tigerhall.sighting_stream_event_seq;
@@ -0,0 +1,2 @@
+-- 022_create_rotated_refresh_tokens.down.sql
+DROP TABLE IF EXISTS tigerhall.rotated_refresh_tokens;
@@ -0,0 +1,9 @@
+-- 022_create_rotated_refresh_tokens.up.sql
+-- Refresh tokens that were exchanged already. One presented again was copied by someone, so its session is revoked.
+CREATE TABLE IF NOT EXISTS tigerhall.rotated_refresh_tokens (
+    refresh_token_hash CHAR(64) PRIMARY KEY,
+    session_id VARCHAR(64) NOT NULL REFERENCES tigerhall.sessions(session_id) ON DELETE CASCADE,
+    rotated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_rotated_refresh_tokens_session_id ON tigerhall.rotated_refresh_tokens(session_id);
@@ -0,0 +1,111 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	"github.com/lib/pq"
+	log "github.com/sirupsen/logrus"
+)
+
+type APIKeyRepository struct {
+	db     *sql.DB
+	logger *log.Logger
+}
+
+func NewAPIKeyRepository(db *sql.DB, logger *log.Logger) *APIKeyRepository {
+	return &APIKeyRepository{db: db, logger: logger}
+}
+
+// CreateAPIKey stores a new API key of the user along with the hash of the key.
+func (ar *APIKeyRepository) CreateAPIKey(apiKey *models.APIKey, userID uint, keyHash string) error {
+	err := ar.db.QueryRow(`
+		INSERT INTO tigerhall.api_keys (key_id, user_id, name, key_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING created_at`,
+		apiKey.ID, userID, apiKey.Name, keyHash, pq.Array(apiKey.Scopes), apiKey.ExpiresAt,
+	).Scan(&apiKey.CreatedAt)
+	if err != nil {
+		ar.logger.Error("Error inserting API key:", err)
+	}
+	return err
+}
+
+// ListAPIKeys lists the keys of the user that are neither revoked nor expired, newest first.
+func (ar *APIKeyRepository) ListAPIKeys(userID uint) ([]models.APIKey, error) {
+	rows, err := ar.db.Query(`
+		SELECT key_id, name, scopes, created_at, expires_at, last_used_at
+		FROM tigerhall.api_keys
+		WHERE user_id = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())
+		ORDER BY created_at DESC`,
+		userID,
+	)
+	if err != nil {
+		ar.logger.Error("Error querying API keys:", err)
+		return nil, err
+	}
+	defer rows.Close()
+	apiKeys := []models.APIKey{}
+	for rows.Next() {
+		var apiKey models.APIKey
+		if err := rows.Scan(&apiKey.ID, &apiKey.Name, pq.Array(&apiKey.Scopes), &apiKey.CreatedAt, &apiKey.ExpiresAt, &apiKey.LastUsedAt); err != nil {
+			ar.logger.Error("Error scanning row:", err)
+			return nil, err
+		}
+		apiKeys = append(apiKeys, apiKey)
+	}
+	return apiKeys, rows.Err()
+}
+
+// RevokeAPIKey revokes a key of the user.
+func (ar *APIKeyRepository) RevokeAPIKey(keyID string, userID uint) error {
+	result, err := ar.db.Exec(
+		"UPDATE tigerhall.api_keys SET revoked_at = NOW() WHERE key_id = $1 AND user_id = $2 AND revoked_at IS NULL",
+		keyID, userID,
+	)
+	if err != nil {
+		ar.logger.Error("Error revoking API key:", err)
+		return err
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return fmt.Errorf("API key %s not found", keyID)
+	}
+	return nil
+}
+
+// GetActiveAPIKey finds the key with the hash if it is neither revoked nor expired, along with its owner.
+func (ar *APIKeyRepository) GetActiveAPIKey(keyHash string) (*models.APIKey, *models.User, error) {
+	query := `
+		SELECT k.key_id, k.name, k.scopes, u.user_id, u.username, u.email, u.roles, u.totp_enabled_at IS NOT NULL
+		FROM tigerhall.api_keys k
+		JOIN tigerhall.users u ON u.user_id = k.user_id
+		WHERE k.key_hash = $1 AND k.revoked_at IS NULL AND (k.expires_at IS NULL OR k.expires_at > NOW())
+	`
+	apiKey := &models.APIKey{}
+	user := &models.User{}
+	err := ar.db.QueryRow(query, keyHash).Scan(
+		&apiKey.ID, &apiKey.Name, pq.Array(&apiKey.Scopes),
+		&user.ID, &user.Username, &user.Email, pq.Array(&user.Roles), &user.TOTPEnabled,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, fmt.Errorf("API key not found")
+		}
+		ar.logger.Error("Error fetching API key:", err)
+		return nil, nil, err
+	}
+	return apiKey, user, nil
+}
+
+// TouchAPIKey records the use of a key. Writes are limited to one per minute per key.
+func (ar *APIKeyRepository) TouchAPIKey(keyID string) {
+	_, err := ar.db.Exec(
+		"UPDATE tigerhall.api_keys SET last_used_at = NOW() WHERE key_id = $1 AND (last_used_at IS NULL OR last_used_at < $2)",
+		keyID, time.Now().Add(-time.Minute),
+	)
+	if err != nil {
+		ar.logger.Warn("Error recording API key use:", err)
+	}
+}
@@ -0,0 +1,139 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	log "github.com/sirupsen/logrus"
+)
+
+type DataExportRepository struct {
+	db     *sql.DB
+	logger *log.Logger
+}
+
+func NewDataExportRepository(db *sql.DB, logger *log.Logger) *DataExportRepository {
+	return &DataExportRepository{db: db, logger: logger}
+}
+
+// CreateDataExport stores a new pending export of the user. It fails while another export of the user is pending.
+// Exports pending for longer than timeout were lost, e.g. in a restart, and are marked failed instead.
+func (dr *DataExportRepository) CreateDataExport(export *models.DataExport, userID uint, timeout time.Duration) error {
+	err := dr.db.QueryRow(`
+		WITH stale AS (
+			UPDATE tigerhall.data_exports SET status = 'failed', completed_at = NOW()
+			WHERE user_id = $2 AND status = 'pending' AND created_at < NOW() - $3 * INTERVAL '1 second'
+		)
+		INSERT INTO tigerhall.data_exports (export_id, user_id)
+		SELECT $1, $2
+		WHERE NOT EXISTS (
+			SELECT 1 FROM tigerhall.data_exports
+			WHERE user_id = $2 AND status = 'pending' AND created_at >= NOW() - $3 * INTERVAL '1 second'
+		)
+		RETURNING status, created_at`,
+		export.ID, userID, timeout.Seconds(),
+	).Scan(&export.Status, &export.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("an export of user %d is already pending", userID)
+		}
+		dr.logger.Error("Error inserting data export:", err)
+		return err
+	}
+	return nil
+}
+
+// MarkExportReady records where the zip was written and until when it can be downloaded. It fails if the export
+// is no longer pending because it took longer than the timeout and was marked failed meanwhile.
+func (dr *DataExportRepository) MarkExportReady(exportID string, filePath string, expiresAt time.Time) error {
+	result, err := dr.db.Exec(
+		"UPDATE tigerhall.data_exports SET status = 'ready', file_path = $2, completed_at = NOW(), expires_at = $3 WHERE export_id = $1 AND status = 'pending'",
+		exportID, filePath, expiresAt,
+	)
+	if err != nil {
+		dr.logger.Error("Error updating data export:", err)
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("data export %s is no longer pending", exportID)
+	}
+	return nil
+}
+
+// MarkExportFailed records that the export could not be built.
+func (dr *DataExportRepository) MarkExportFailed(exportID string) error {
+	_, err := dr.db.Exec("UPDATE tigerhall.data_exports SET status = 'failed', completed_at = NOW() WHERE export_id = $1", exportID)
+	if err != nil {
+		dr.logger.Error("Error updating data export:", err)
+	}
+	return err
+}
+
+// GetDataExport fetches an export of the given user.
+func (dr *DataExportRepository) GetDataExport(exportID string, userID uint) (*models.DataExport, error) {
+	export := &models.DataExport{}
+	var filePath sql.NullString
+	err := dr.db.QueryRow(`
+		SELECT export_id, status, file_path, created_at, completed_at, expires_at
+		FROM tigerhall.data_exports WHERE export_id = $1 AND user_id = $2`,
+		exportID, userID,
+	).Scan(&export.ID, &export.Status, &filePath, &export.CreatedAt, &export.CompletedAt, &export.ExpiresAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("data export %s not found", exportID)
+		}
+		dr.logger.Error("Error fetching data export:", err)
+		return nil, err
+	}
+	export.FilePath = filePath.String
+	return export, nil
+}
+
+// DeleteExpiredExports forgets expired and failed exports and returns the files that have to be removed. Exports
+// pending for longer than timeout count as failed.
+func (dr *DataExportRepository) DeleteExpiredExports(timeout time.Duration) ([]string, error) {
+	if _, err := dr.db.Exec(
+		"UPDATE tigerhall.data_exports SET status = 'failed', completed_at = NOW() WHERE status = 'pending' AND created_at < NOW() - $1 * INTERVAL '1 second'",
+		timeout.Seconds(),
+	); err != nil {
+		dr.logger.Error("Error failing stale data exports:", err)
+		return nil, err
+	}
+	rows, err := dr.db.Query(`
+		DELETE FROM tigerhall.data_exports
+		WHERE expires_at < NOW() OR (status = 'failed' AND completed_at < NOW() - INTERVAL '1 day')
+		RETURNING COALESCE(file_path, '')`)
+	if err != nil {
+		dr.logger.Error("Error deleting expired data exports:", err)
+		return nil, err
+	}
+	defer rows.Close()
+	return scanFilePaths(rows)
+}
+
+// ListExportFiles returns the files of every export of the user.
+func (dr *DataExportRepository) ListExportFiles(userID uint) ([]string, error) {
+	rows, err := dr.db.Query("SELECT COALESCE(file_path, '') FROM tigerhall.data_exports WHERE user_id = $1", userID)
+	if err != nil {
+		dr.logger.Error("Error querying data exports:", err)
+		return nil, err
+	}
+	defer rows.Close()
+	return scanFilePaths(rows)
+}
+
+func scanFilePaths(rows *sql.Rows) ([]string, error) {
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths, rows.Err()
+}
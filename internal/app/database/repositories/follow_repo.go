@@ -0,0 +1,82 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	"github.com/lib/pq"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrTigerNotFound is returned when following a tiger that does not exist.
+	ErrTigerNotFound = errors.New("tiger not found")
+	// ErrTigerNotFollowed is returned when unfollowing a tiger the user does not follow.
+	ErrTigerNotFollowed = errors.New("tiger not followed")
+)
+
+type FollowRepository struct {
+	db     *sql.DB
+	logger *log.Logger
+}
+
+func NewFollowRepository(db *sql.DB, logger *log.Logger) *FollowRepository {
+	return &FollowRepository{db: db, logger: logger}
+}
+
+// FollowTiger subscribes the user to sightings of the tiger. Following a tiger twice is not an error.
+func (fr *FollowRepository) FollowTiger(userID uint, tigerID int) error {
+	_, err := fr.db.Exec(
+		"INSERT INTO tigerhall.tiger_follows (user_id, tiger_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+		userID, tigerID,
+	)
+	if err != nil {
+		// foreign_key_violation, the tiger does not exist
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+			return ErrTigerNotFound
+		}
+		fr.logger.Error("Error following tiger:", err)
+	}
+	return err
+}
+
+// UnfollowTiger removes the subscription of the user to the tiger.
+func (fr *FollowRepository) UnfollowTiger(userID uint, tigerID int) error {
+	result, err := fr.db.Exec("DELETE FROM tigerhall.tiger_follows WHERE user_id = $1 AND tiger_id = $2", userID, tigerID)
+	if err != nil {
+		fr.logger.Error("Error unfollowing tiger:", err)
+		return err
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return ErrTigerNotFollowed
+	}
+	return nil
+}
+
+// ListFollowedTigers returns the tigers the user follows, most recently followed first.
+func (fr *FollowRepository) ListFollowedTigers(userID uint) ([]models.FollowedTiger, error) {
+	rows, err := fr.db.Query(`
+		SELECT t.tiger_id, t.name, f.created_at
+		FROM tigerhall.tiger_follows f
+		JOIN tigerhall.tigers t ON t.tiger_id = f.tiger_id
+		WHERE f.user_id = $1
+		ORDER BY f.created_at DESC`,
+		userID,
+	)
+	if err != nil {
+		fr.logger.Error("Error querying followed tigers:", err)
+		return nil, err
+	}
+	defer rows.Close()
+	tigers := []models.FollowedTiger{}
+	for rows.Next() {
+		var tiger models.FollowedTiger
+		if err := rows.Scan(&tiger.TigerID, &tiger.Name, &tiger.FollowedAt); err != nil {
+			fr.logger.Error("Error scanning row:", err)
+			return nil, err
+		}
+		tigers = append(tigers, tiger)
+	}
+	return tigers, rows.Err()
+}
@@ -0,0 +1,95 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	"github.com/lib/pq"
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrDuplicateUser is returned when the username or email of a new user is taken.
+var ErrDuplicateUser = errors.New("username or email already taken")
+
+type IdentityRepository struct {
+	db     *sql.DB
+	logger *log.Logger
+}
+
+func NewIdentityRepository(db *sql.DB, logger *log.Logger) *IdentityRepository {
+	return &IdentityRepository{db: db, logger: logger}
+}
+
+// GetUserByIdentity returns the user linked to the identity at the provider and records the login.
+func (ir *IdentityRepository) GetUserByIdentity(provider string, subject string) (*models.User, error) {
+	query := `
+		UPDATE tigerhall.user_identities i SET last_login_at = NOW()
+		FROM tigerhall.users u
+		WHERE u.user_id = i.user_id AND i.provider = $1 AND i.subject = $2
+		RETURNING u.user_id, u.username, u.email, u.roles, u.email_verified_at IS NOT NULL, u.totp_enabled_at IS NOT NULL
+	`
+	user := &models.User{}
+	err := ir.db.QueryRow(query, provider, subject).Scan(&user.ID, &user.Username, &user.Email, pq.Array(&user.Roles), &user.EmailVerified, &user.TOTPEnabled)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("no user linked to %s identity %s", provider, subject)
+		}
+		ir.logger.Error("Error fetching identity:", err)
+		return nil, err
+	}
+	return user, nil
+}
+
+// LinkIdentity links the identity at the provider to an existing user.
+func (ir *IdentityRepository) LinkIdentity(userID uint, provider string, subject string, email string) error {
+	_, err := ir.db.Exec(
+		"INSERT INTO tigerhall.user_identities (provider, subject, user_id, email) VALUES ($1, $2, $3, NULLIF($4, ''))",
+		provider, subject, userID, email,
+	)
+	if err != nil {
+		ir.logger.Error("Error linking identity:", err)
+	}
+	return err
+}
+
+// CreateUserWithIdentity creates a user without password and links the identity to it.
+// The email is marked verified when the provider verified it.
+func (ir *IdentityRepository) CreateUserWithIdentity(user *models.User, provider string, subject string) (err error) {
+	tx, err := ir.db.Begin()
+	if err != nil {
+		ir.logger.Error("Error beginning transaction:", err)
+		return err
+	}
+	defer func() { database.RollBack(tx, err) }()
+
+	err = tx.QueryRow(`
+		INSERT INTO tigerhall.users (username, email, email_verified_at)
+		VALUES ($1, $2, CASE WHEN $3 THEN NOW() END)
+		RETURNING user_id, roles`,
+		user.Username, user.Email, user.EmailVerified,
+	).Scan(&user.ID, pq.Array(&user.Roles))
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			err = ErrDuplicateUser
+			return err
+		}
+		ir.logger.Error("Error inserting user:", err)
+		return err
+	}
+	_, err = tx.Exec(
+		"INSERT INTO tigerhall.user_identities (provider, subject, user_id, email) VALUES ($1, $2, $3, NULLIF($4, ''))",
+		provider, subject, user.ID, user.Email,
+	)
+	if err != nil {
+		ir.logger.Error("Error linking identity:", err)
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		ir.logger.Error("Error committing transaction:", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	log "github.com/sirupsen/logrus"
+)
+
+type LoginThrottleRepository struct {
+	db     *sql.DB
+	logger *log.Logger
+}
+
+func NewLoginThrottleRepository(db *sql.DB, logger *log.Logger) *LoginThrottleRepository {
+	return &LoginThrottleRepository{db: db, logger: logger}
+}
+
+// GetLockedUntil returns the time the next login attempt for the key is accepted, zero if there is no restriction.
+func (lr *LoginThrottleRepository) GetLockedUntil(scope string, key string) (time.Time, error) {
+	var lockedUntil sql.NullTime
+	err := lr.db.QueryRow(
+		"SELECT locked_until FROM tigerhall.login_throttles WHERE scope = $1 AND key = $2 AND locked_until > NOW()",
+		scope, key,
+	).Scan(&lockedUntil)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return time.Time{}, nil
+		}
+		lr.logger.Error("Error fetching login throttle:", err)
+		return time.Time{}, err
+	}
+	return lockedUntil.Time, nil
+}
+
+// RecordFailure counts a failed login for the key and blocks further attempts for the delay returned by
+// delayFor. Failures older than the window are forgotten.
+func (lr *LoginThrottleRepository) RecordFailure(scope string, key string, window time.Duration, delayFor func(failures int) time.Duration) (throttle *models.LoginThrottle, err error) {
+	tx, err := lr.db.Begin()
+	if err != nil {
+		lr.logger.Error("Error beginning transaction:", err)
+		return nil, err
+	}
+	defer func() { database.RollBack(tx, err) }()
+
+	// Make sure there is a row to lock, concurrent failures must not get lost
+	_, err = tx.Exec("INSERT INTO tigerhall.login_throttles (scope, key) VALUES ($1, $2) ON CONFLICT (scope, key) DO NOTHING", scope, key)
+	if err != nil {
+		lr.logger.Error("Error inserting login throttle:", err)
+		return nil, err
+	}
+	throttle = &models.LoginThrottle{Scope: scope, Key: key}
+	err = tx.QueryRow(
+		"SELECT failures, last_failure_at FROM tigerhall.login_throttles WHERE scope = $1 AND key = $2 FOR UPDATE",
+		scope, key,
+	).Scan(&throttle.Failures, &throttle.LastFailureAt)
+	if err != nil {
+		lr.logger.Error("Error fetching login throttle:", err)
+		return nil, err
+	}
+	now := time.Now()
+	if now.Sub(throttle.LastFailureAt) > window {
+		throttle.Failures = 0
+	}
+	throttle.Failures++
+	throttle.LastFailureAt = now
+	var lockedUntil sql.NullTime
+	if delay := delayFor(throttle.Failures); delay > 0 {
+		throttle.LockedUntil = now.Add(delay)
+		lockedUntil = sql.NullTime{Time: throttle.LockedUntil, Valid: true}
+	}
+	_, err = tx.Exec(
+		"UPDATE tigerhall.login_throttles SET failures = $3, last_failure_at = $4, locked_until = $5 WHERE scope = $1 AND key = $2",
+		scope, key, throttle.Failures, now, lockedUntil,
+	)
+	if err != nil {
+		lr.logger.Error("Error updating login throttle:", err)
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		lr.logger.Error("Error committing transaction:", err)
+		return nil, err
+	}
+	return throttle, nil
+}
+
+// Reset forgets the failed logins of the key, e.g. after a successful login or when an admin unlocks an account.
+func (lr *LoginThrottleRepository) Reset(scope string, key string) error {
+	result, err := lr.db.Exec("DELETE FROM tigerhall.login_throttles WHERE scope = $1 AND key = $2", scope, key)
+	if err != nil {
+		lr.logger.Error("Error resetting login throttle:", err)
+		return err
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return fmt.Errorf("no failed logins for %s %s", scope, key)
+	}
+	return nil
+}
+
+// ListThrottles lists keys that are blocked or failed to log in within the window, most recent first.
+func (lr *LoginThrottleRepository) ListThrottles(window time.Duration) ([]models.LoginThrottle, error) {
+	rows, err := lr.db.Query(`
+		SELECT scope, key, failures, last_failure_at, locked_until
+		FROM tigerhall.login_throttles
+		WHERE locked_until > NOW() OR last_failure_at > $1
+		ORDER BY last_failure_at DESC`,
+		time.Now().Add(-window),
+	)
+	if err != nil {
+		lr.logger.Error("Error querying login throttles:", err)
+		return nil, err
+	}
+	defer rows.Close()
+	throttles := []models.LoginThrottle{}
+	for rows.Next() {
+		var throttle models.LoginThrottle
+		var lockedUntil sql.NullTime
+		if err := rows.Scan(&throttle.Scope, &throttle.Key, &throttle.Failures, &throttle.LastFailureAt, &lockedUntil); err != nil {
+			lr.logger.Error("Error scanning row:", err)
+			return nil, err
+		}
+		throttle.LockedUntil = lockedUntil.Time
+		throttles = append(throttles, throttle)
+	}
+	return throttles, rows.Err()
+}
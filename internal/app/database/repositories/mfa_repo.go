@@ -0,0 +1,207 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database"
+	"github.com/lib/pq"
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrTOTPAlreadyEnabled is returned when enrolling a user who already has an authenticator app.
+var ErrTOTPAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+
+type MFARepository struct {
+	db     *sql.DB
+	logger *log.Logger
+}
+
+func NewMFARepository(db *sql.DB, logger *log.Logger) *MFARepository {
+	return &MFARepository{db: db, logger: logger}
+}
+
+// GetTOTPSecret returns the TOTP secret of the user and whether it was confirmed.
+func (mr *MFARepository) GetTOTPSecret(userID uint) (secret string, enabled bool, err error) {
+	err = mr.db.QueryRow(
+		"SELECT COALESCE(totp_secret, ''), totp_enabled_at IS NOT NULL FROM tigerhall.users WHERE user_id = $1",
+		userID,
+	).Scan(&secret, &enabled)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", false, fmt.Errorf("user not found for id %d", userID)
+		}
+		mr.logger.Error("Error fetching TOTP secret:", err)
+		return "", false, err
+	}
+	return secret, enabled, nil
+}
+
+// SetPendingTOTPSecret stores a new secret awaiting confirmation, replacing an unconfirmed one.
+func (mr *MFARepository) SetPendingTOTPSecret(userID uint, secret string) error {
+	result, err := mr.db.Exec(
+		"UPDATE tigerhall.users SET totp_secret = $2, totp_last_step = NULL WHERE user_id = $1 AND totp_enabled_at IS NULL",
+		userID, secret,
+	)
+	if err != nil {
+		mr.logger.Error("Error storing TOTP secret:", err)
+		return err
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return ErrTOTPAlreadyEnabled
+	}
+	return nil
+}
+
+// EnableTOTP confirms the pending secret and replaces the recovery codes of the user.
+func (mr *MFARepository) EnableTOTP(userID uint, step int64, recoveryCodeHashes []string) (err error) {
+	tx, err := mr.db.Begin()
+	if err != nil {
+		mr.logger.Error("Error beginning transaction:", err)
+		return err
+	}
+	defer func() { database.RollBack(tx, err) }()
+
+	result, err := tx.Exec(
+		"UPDATE tigerhall.users SET totp_enabled_at = NOW(), totp_last_step = $2 WHERE user_id = $1 AND totp_secret IS NOT NULL AND totp_enabled_at IS NULL",
+		userID, step,
+	)
+	if err != nil {
+		mr.logger.Error("Error enabling TOTP:", err)
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		err = ErrTOTPAlreadyEnabled
+		return err
+	}
+	if err = replaceRecoveryCodes(tx, userID, recoveryCodeHashes); err != nil {
+		mr.logger.Error("Error storing recovery codes:", err)
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		mr.logger.Error("Error committing transaction:", err)
+		return err
+	}
+	return nil
+}
+
+// DisableTOTP removes the secret and recovery codes of the user.
+func (mr *MFARepository) DisableTOTP(userID uint) (err error) {
+	tx, err := mr.db.Begin()
+	if err != nil {
+		mr.logger.Error("Error beginning transaction:", err)
+		return err
+	}
+	defer func() { database.RollBack(tx, err) }()
+
+	_, err = tx.Exec("UPDATE tigerhall.users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL WHERE user_id = $1", userID)
+	if err != nil {
+		mr.logger.Error("Error disabling TOTP:", err)
+		return err
+	}
+	if _, err = tx.Exec("DELETE FROM tigerhall.recovery_codes WHERE user_id = $1", userID); err != nil {
+		mr.logger.Error("Error deleting recovery codes:", err)
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		mr.logger.Error("Error committing transaction:", err)
+		return err
+	}
+	return nil
+}
+
+// ReplaceRecoveryCodes invalidates the recovery codes of the user and stores new ones.
+func (mr *MFARepository) ReplaceRecoveryCodes(userID uint, recoveryCodeHashes []string) (err error) {
+	tx, err := mr.db.Begin()
+	if err != nil {
+		mr.logger.Error("Error beginning transaction:", err)
+		return err
+	}
+	defer func() { database.RollBack(tx, err) }()
+
+	if err = replaceRecoveryCodes(tx, userID, recoveryCodeHashes); err != nil {
+		mr.logger.Error("Error storing recovery codes:", err)
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		mr.logger.Error("Error committing transaction:", err)
+		return err
+	}
+	return nil
+}
+
+func replaceRecoveryCodes(tx *sql.Tx, userID uint, recoveryCodeHashes []string) error {
+	if _, err := tx.Exec("DELETE FROM tigerhall.recovery_codes WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+	_, err := tx.Exec(
+		"INSERT INTO tigerhall.recovery_codes (user_id, code_hash) SELECT $1, unnest($2::TEXT[])",
+		userID, pq.Array(recoveryCodeHashes),
+	)
+	return err
+}
+
+// AcceptTOTPStep records the time step of a valid code. It returns false if a code of this or a later step
+// was accepted before, so every code can only be used once.
+func (mr *MFARepository) AcceptTOTPStep(userID uint, step int64) (bool, error) {
+	result, err := mr.db.Exec(
+		"UPDATE tigerhall.users SET totp_last_step = $2 WHERE user_id = $1 AND (totp_last_step IS NULL OR totp_last_step < $2)",
+		userID, step,
+	)
+	if err != nil {
+		mr.logger.Error("Error recording TOTP step:", err)
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows == 1, err
+}
+
+// UseRecoveryCode marks an unused recovery code of the user as used. It returns false if there is none.
+func (mr *MFARepository) UseRecoveryCode(userID uint, codeHash string) (bool, error) {
+	result, err := mr.db.Exec(
+		"UPDATE tigerhall.recovery_codes SET used_at = NOW() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL",
+		userID, codeHash,
+	)
+	if err != nil {
+		mr.logger.Error("Error using recovery code:", err)
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows == 1, err
+}
+
+// GetRequiredRoles returns the roles that need two-factor authentication.
+func (mr *MFARepository) GetRequiredRoles() ([]string, error) {
+	var roles []string
+	err := mr.db.QueryRow("SELECT COALESCE(array_agg(role ORDER BY role), '{}') FROM tigerhall.mfa_required_roles").Scan(pq.Array(&roles))
+	if err != nil {
+		mr.logger.Error("Error fetching MFA policy:", err)
+		return nil, err
+	}
+	return roles, nil
+}
+
+// SetRequiredRoles replaces the roles that need two-factor authentication.
+func (mr *MFARepository) SetRequiredRoles(roles []string) (err error) {
+	tx, err := mr.db.Begin()
+	if err != nil {
+		mr.logger.Error("Error beginning transaction:", err)
+		return err
+	}
+	defer func() { database.RollBack(tx, err) }()
+
+	if _, err = tx.Exec("DELETE FROM tigerhall.mfa_required_roles"); err != nil {
+		mr.logger.Error("Error updating MFA policy:", err)
+		return err
+	}
+	if _, err = tx.Exec("INSERT INTO tigerhall.mfa_required_roles (role) SELECT DISTINCT unnest($1::TEXT[])", pq.Array(roles)); err != nil {
+		mr.logger.Error("Error updating MFA policy:", err)
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		mr.logger.Error("Error committing transaction:", err)
+		return err
+	}
+	return nil
+}
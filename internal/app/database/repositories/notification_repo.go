@@ -0,0 +1,294 @@
+package repositories
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	"github.com/lib/pq"
+	log "github.com/sirupsen/logrus"
+)
+
+type NotificationRepository struct {
+	db     *sql.DB
+	logger *log.Logger
+}
+
+func NewNotificationRepository(db *sql.DB, logger *log.Logger) *NotificationRepository {
+	return &NotificationRepository{db: db, logger: logger}
+}
+
+// LogNotifications records a notification sent to each of the users.
+func (nr *NotificationRepository) LogNotifications(userIDs []uint, channel string, subject string, status string) error {
+	ids := make([]int64, len(userIDs))
+	for i, id := range userIDs {
+		ids[i] = int64(id)
+	}
+	_, err := nr.db.Exec(`
+		INSERT INTO tigerhall.notification_log (user_id, channel, subject, status)
+		SELECT user_id, $2, $3, $4 FROM UNNEST($1::INT[]) AS user_id`,
+		pq.Array(ids), channel, subject, status,
+	)
+	if err != nil {
+		nr.logger.Error("Error logging notifications:", err)
+	}
+	return err
+}
+
+// ListNotifications returns the notification history of the user, newest first.
+func (nr *NotificationRepository) ListNotifications(userID uint) ([]models.Notification, error) {
+	rows, err := nr.db.Query(
+		"SELECT channel, subject, status, sent_at FROM tigerhall.notification_log WHERE user_id = $1 ORDER BY sent_at DESC",
+		userID,
+	)
+	if err != nil {
+		nr.logger.Error("Error querying notifications:", err)
+		return nil, err
+	}
+	defer rows.Close()
+	notifications := []models.Notification{}
+	for rows.Next() {
+		var notification models.Notification
+		if err := rows.Scan(&notification.Channel, &notification.Subject, &notification.Status, &notification.SentAt); err != nil {
+			nr.logger.Error("Error scanning row:", err)
+			return nil, err
+		}
+		notifications = append(notifications, notification)
+	}
+	return notifications, rows.Err()
+}
+
+// GetPreferences returns the notification preferences of the user, the defaults if they never changed them.
+func (nr *NotificationRepository) GetPreferences(userID uint) (*models.NotificationPreferences, error) {
+	preferences := models.DefaultNotificationPreferences()
+	var quietStart, quietEnd sql.NullInt16
+	err := nr.db.QueryRow(
+		`SELECT channels, frequency, quiet_hours_start, quiet_hours_end, timezone, digest_hour, digest_weekday
+		FROM tigerhall.notification_preferences WHERE user_id = $1`,
+		userID,
+	).Scan(pq.Array(&preferences.Channels), &preferences.Frequency, &quietStart, &quietEnd, &preferences.Timezone,
+		&preferences.DigestHour, &preferences.DigestWeekday)
+	if err != nil && err != sql.ErrNoRows {
+		nr.logger.Error("Error fetching notification preferences:", err)
+		return nil, err
+	}
+	preferences.QuietHoursStart, preferences.QuietHoursEnd = nullHour(quietStart), nullHour(quietEnd)
+	rows, err := nr.db.Query("SELECT tiger_id FROM tigerhall.tiger_mutes WHERE user_id = $1 ORDER BY tiger_id", userID)
+	if err != nil {
+		nr.logger.Error("Error querying muted tigers:", err)
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var tigerID int
+		if err := rows.Scan(&tigerID); err != nil {
+			return nil, err
+		}
+		preferences.MutedTigers = append(preferences.MutedTigers, tigerID)
+	}
+	return &preferences, rows.Err()
+}
+
+// SavePreferences replaces the notification preferences and muted tigers of the user.
+func (nr *NotificationRepository) SavePreferences(userID uint, preferences *models.NotificationPreferences) (err error) {
+	tx, err := nr.db.Begin()
+	if err != nil {
+		nr.logger.Error("Error beginning transaction:", err)
+		return err
+	}
+	defer func() { database.RollBack(tx, err) }()
+
+	// last_digest_at is set so the first digest is sent at the next scheduled time, not right away
+	_, err = tx.Exec(`
+		INSERT INTO tigerhall.notification_preferences
+			(user_id, channels, frequency, quiet_hours_start, quiet_hours_end, timezone, digest_hour, digest_weekday, last_digest_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+		ON CONFLICT (user_id) DO UPDATE SET channels = $2, frequency = $3, quiet_hours_start = $4,
+			quiet_hours_end = $5, timezone = $6, digest_hour = $7, digest_weekday = $8, updated_at = NOW(),
+			last_digest_at = COALESCE(notification_preferences.last_digest_at, NOW())`,
+		userID, pq.Array(preferences.Channels), preferences.Frequency, preferences.QuietHoursStart, preferences.QuietHoursEnd,
+		preferences.Timezone, preferences.DigestHour, preferences.DigestWeekday,
+	)
+	if err != nil {
+		nr.logger.Error("Error saving notification preferences:", err)
+		return err
+	}
+	if _, err = tx.Exec("DELETE FROM tigerhall.tiger_mutes WHERE user_id = $1", userID); err != nil {
+		nr.logger.Error("Error deleting muted tigers:", err)
+		return err
+	}
+	// Unknown tigers are ignored rather than failing the whole update
+	_, err = tx.Exec(`
+		INSERT INTO tigerhall.tiger_mutes (user_id, tiger_id)
+		SELECT $1, tiger_id FROM tigerhall.tigers WHERE tiger_id = ANY($2)`,
+		userID, pq.Array(preferences.MutedTigers),
+	)
+	if err != nil {
+		nr.logger.Error("Error muting tigers:", err)
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		nr.logger.Error("Error committing transaction:", err)
+		return err
+	}
+	return nil
+}
+
+// DisableChannel stops notifications on the channel for the user, keeping their other preferences.
+func (nr *NotificationRepository) DisableChannel(userID uint, channel string) error {
+	_, err := nr.db.Exec(`
+		INSERT INTO tigerhall.notification_preferences (user_id, channels) VALUES ($1, array_remove('{email}'::TEXT[], $2))
+		ON CONFLICT (user_id) DO UPDATE SET channels = array_remove(notification_preferences.channels, $2), updated_at = NOW()`,
+		userID, channel,
+	)
+	if err != nil {
+		nr.logger.Error("Error disabling notification channel:", err)
+	}
+	return err
+}
+
+// QueueDigestItems adds the sighting to the next digest of each of the users.
+func (nr *NotificationRepository) QueueDigestItems(userIDs []uint, sightingID int) error {
+	err := queueDigestItems(nr.db, userIDs, sightingID)
+	if err != nil {
+		nr.logger.Error("Error queueing digest items:", err)
+	}
+	return err
+}
+
+// Execer is implemented by both *sql.DB and *sql.Tx, and by fakes in the tests.
+type Execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+func queueDigestItems(db Execer, userIDs []uint, sightingID int) error {
+	ids := make([]int64, len(userIDs))
+	for i, id := range userIDs {
+		ids[i] = int64(id)
+	}
+	_, err := db.Exec(`
+		INSERT INTO tigerhall.digest_items (user_id, sighting_id)
+		SELECT user_id, $2 FROM UNNEST($1::INT[]) AS user_id
+		ON CONFLICT DO NOTHING`,
+		pq.Array(ids), sightingID,
+	)
+	return err
+}
+
+// ListDigestRecipients returns the users with sightings waiting for their digest, along with their preferences.
+func (nr *NotificationRepository) ListDigestRecipients() ([]models.DigestRecipient, error) {
+	rows, err := nr.db.Query(`
+		SELECT u.user_id, u.username, u.email, u.roles, u.language,
+			COALESCE(p.channels, '{email}'), COALESCE(p.frequency, 'immediate'), COALESCE(p.timezone, 'UTC'),
+			COALESCE(p.digest_hour, 8), COALESCE(p.digest_weekday, 1), p.last_digest_at
+		FROM tigerhall.users u
+		LEFT JOIN tigerhall.notification_preferences p ON p.user_id = u.user_id
+		WHERE EXISTS (SELECT 1 FROM tigerhall.digest_items d WHERE d.user_id = u.user_id)`)
+	if err != nil {
+		nr.logger.Error("Error querying digest recipients:", err)
+		return nil, err
+	}
+	defer rows.Close()
+	var recipients []models.DigestRecipient
+	for rows.Next() {
+		var recipient models.DigestRecipient
+		err := rows.Scan(
+			&recipient.User.ID,
+			&recipient.User.Username,
+			&recipient.User.Email,
+			pq.Array(&recipient.User.Roles),
+			&recipient.User.Language,
+			pq.Array(&recipient.Preferences.Channels),
+			&recipient.Preferences.Frequency,
+			&recipient.Preferences.Timezone,
+			&recipient.Preferences.DigestHour,
+			&recipient.Preferences.DigestWeekday,
+			&recipient.LastDigestAt,
+		)
+		if err != nil {
+			nr.logger.Error("Error scanning row:", err)
+			return nil, err
+		}
+		recipients = append(recipients, recipient)
+	}
+	return recipients, rows.Err()
+}
+
+// GetDigestItems returns the sightings waiting for the digest of the user, oldest first. Sightings that are no
+// longer verified are returned as well, so they can be dropped along with the sent ones.
+func (nr *NotificationRepository) GetDigestItems(userID uint) ([]models.DigestItem, error) {
+	rows, err := nr.db.Query(`
+		SELECT d.item_id, s.sighting_id, t.tiger_id, t.name, s.last_seen_timestamp,
+			s.last_seen_coordinates_lat, s.last_seen_coordinates_lon, COALESCE(length(s.image), 0) > 0, s.status = 'verified'
+		FROM tigerhall.digest_items d
+		JOIN tigerhall.sightings s ON s.sighting_id = d.sighting_id
+		JOIN tigerhall.tigers t ON t.tiger_id = s.tiger_id
+		WHERE d.user_id = $1
+		ORDER BY s.last_seen_timestamp ASC`,
+		userID,
+	)
+	if err != nil {
+		nr.logger.Error("Error querying digest items:", err)
+		return nil, err
+	}
+	defer rows.Close()
+	var items []models.DigestItem
+	for rows.Next() {
+		var item models.DigestItem
+		if err := rows.Scan(&item.ItemID, &item.SightingID, &item.TigerID, &item.TigerName, &item.Timestamp,
+			&item.Latitude, &item.Longitude, &item.HasImage, &item.Verified); err != nil {
+			nr.logger.Error("Error scanning row:", err)
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// ClaimDigest records that the digest due at scheduledAt is being sent to the user. It returns false if it
+// was already sent, e.g. by another instance of the service.
+func (nr *NotificationRepository) ClaimDigest(userID uint, scheduledAt time.Time) (bool, error) {
+	var claimed uint
+	err := nr.db.QueryRow(`
+		INSERT INTO tigerhall.notification_preferences (user_id, last_digest_at) VALUES ($1, NOW())
+		ON CONFLICT (user_id) DO UPDATE SET last_digest_at = NOW()
+		WHERE notification_preferences.last_digest_at IS NULL OR notification_preferences.last_digest_at < $2
+		RETURNING user_id`,
+		userID, scheduledAt,
+	).Scan(&claimed)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		nr.logger.Error("Error claiming digest:", err)
+		return false, err
+	}
+	return true, nil
+}
+
+// ReleaseDigest undoes ClaimDigest after the digest failed to send, restoring when the previous one was sent.
+func (nr *NotificationRepository) ReleaseDigest(userID uint, lastDigestAt *time.Time) error {
+	_, err := nr.db.Exec("UPDATE tigerhall.notification_preferences SET last_digest_at = $2 WHERE user_id = $1", userID, lastDigestAt)
+	if err != nil {
+		nr.logger.Error("Error releasing digest:", err)
+	}
+	return err
+}
+
+// DeleteDigestItems removes the items of the user up to and including the given item, once they were sent.
+func (nr *NotificationRepository) DeleteDigestItems(userID uint, lastItemID int) error {
+	_, err := nr.db.Exec("DELETE FROM tigerhall.digest_items WHERE user_id = $1 AND item_id <= $2", userID, lastItemID)
+	if err != nil {
+		nr.logger.Error("Error deleting digest items:", err)
+	}
+	return err
+}
+
+func nullHour(hour sql.NullInt16) *int {
+	if !hour.Valid {
+		return nil
+	}
+	value := int(hour.Int16)
+	return &value
+}
@@ -0,0 +1,224 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrOutboxMessageNotFound is returned when replaying a message that does not exist or is not dead.
+var ErrOutboxMessageNotFound = errors.New("outbox message not found")
+
+const outboxColumns = `message_id, kind, COALESCE(user_id, 0), payload, status, attempts, next_attempt_at,
+	COALESCE(last_error, ''), created_at, processed_at`
+
+type OutboxRepository struct {
+	db     *sql.DB
+	logger *log.Logger
+}
+
+func NewOutboxRepository(db *sql.DB, logger *log.Logger) *OutboxRepository {
+	return &OutboxRepository{db: db, logger: logger}
+}
+
+// enqueueOutboxMessage writes a message to the outbox as part of the caller's transaction. It is delivered once
+// notBefore has passed, right away if it is zero.
+func enqueueOutboxMessage(db Execer, kind string, userID uint, payload any, notBefore time.Time) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	var recipient sql.NullInt64
+	if userID > 0 {
+		recipient = sql.NullInt64{Int64: int64(userID), Valid: true}
+	}
+	deferred := sql.NullTime{Time: notBefore, Valid: !notBefore.IsZero()}
+	_, err = db.Exec(
+		"INSERT INTO tigerhall.notification_outbox (kind, user_id, payload, next_attempt_at) VALUES ($1, $2, $3, COALESCE($4, NOW()))",
+		kind, recipient, data, deferred,
+	)
+	return err
+}
+
+// ClaimOutboxMessages returns up to limit messages that are due and pushes their next attempt back by the lease,
+// so they are retried if the process dies while delivering them. Messages claimed by other instances are skipped.
+func (or *OutboxRepository) ClaimOutboxMessages(limit int, lease time.Duration) ([]models.OutboxMessage, error) {
+	rows, err := or.db.Query(`
+		UPDATE tigerhall.notification_outbox
+		SET attempts = attempts + 1, next_attempt_at = NOW() + $2 * INTERVAL '1 second'
+		WHERE message_id IN (
+			SELECT message_id FROM tigerhall.notification_outbox
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY message_id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+outboxColumns,
+		limit, lease.Seconds(),
+	)
+	if err != nil {
+		or.logger.Error("Error claiming outbox messages:", err)
+		return nil, err
+	}
+	defer rows.Close()
+	return or.scanOutboxMessages(rows)
+}
+
+// MarkOutboxSent records that the message was delivered.
+func (or *OutboxRepository) MarkOutboxSent(messageID int) error {
+	_, err := or.db.Exec(
+		"UPDATE tigerhall.notification_outbox SET status = 'sent', last_error = NULL, processed_at = NOW() WHERE message_id = $1",
+		messageID,
+	)
+	if err != nil {
+		or.logger.Error("Error marking outbox message as sent:", err)
+	}
+	return err
+}
+
+// RetryOutboxMessage records a failed attempt and schedules the next one.
+func (or *OutboxRepository) RetryOutboxMessage(messageID int, failure string, retryAt time.Time) error {
+	_, err := or.db.Exec(
+		"UPDATE tigerhall.notification_outbox SET last_error = $2, next_attempt_at = $3 WHERE message_id = $1",
+		messageID, failure, retryAt,
+	)
+	if err != nil {
+		or.logger.Error("Error scheduling outbox retry:", err)
+	}
+	return err
+}
+
+// MarkOutboxDead records that the message failed permanently.
+func (or *OutboxRepository) MarkOutboxDead(messageID int, failure string) error {
+	_, err := or.db.Exec(
+		"UPDATE tigerhall.notification_outbox SET status = 'dead', last_error = $2, processed_at = NOW() WHERE message_id = $1",
+		messageID, failure,
+	)
+	if err != nil {
+		or.logger.Error("Error marking outbox message as dead:", err)
+	}
+	return err
+}
+
+// ExpandSightingEvent replaces a sighting event with the notifications to its recipients and queues the sighting for
+// the digests of the given users, in one transaction so no recipient is notified twice.
+func (or *OutboxRepository) ExpandSightingEvent(messageID int, sightingID int, deliveries []models.OutboxDelivery, digestUserIDs []uint) (err error) {
+	tx, err := or.db.Begin()
+	if err != nil {
+		or.logger.Error("Error beginning transaction:", err)
+		return err
+	}
+	defer func() { database.RollBack(tx, err) }()
+
+	for _, delivery := range deliveries {
+		if err = enqueueOutboxMessage(tx, delivery.Kind, delivery.UserID, delivery.Payload, delivery.NotBefore); err != nil {
+			or.logger.Error("Error queueing notification:", err)
+			return err
+		}
+	}
+	if len(digestUserIDs) > 0 {
+		if err = queueDigestItems(tx, digestUserIDs, sightingID); err != nil {
+			or.logger.Error("Error queueing digest items:", err)
+			return err
+		}
+	}
+	_, err = tx.Exec(
+		"UPDATE tigerhall.notification_outbox SET status = 'sent', last_error = NULL, processed_at = NOW() WHERE message_id = $1",
+		messageID,
+	)
+	if err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		or.logger.Error("Error committing transaction:", err)
+		return err
+	}
+	return nil
+}
+
+// ListOutboxMessages returns the messages in the given state, newest first. All messages are returned if status is empty.
+func (or *OutboxRepository) ListOutboxMessages(status string, pageSize int, offset int) (*models.OutboxResponse, error) {
+	rows, err := or.db.Query(`
+		SELECT `+outboxColumns+`
+		FROM tigerhall.notification_outbox
+		WHERE $1 = '' OR status = $1
+		ORDER BY message_id DESC
+		LIMIT $2 OFFSET $3`,
+		status, pageSize+1, offset,
+	)
+	if err != nil {
+		or.logger.Error("Error querying outbox messages:", err)
+		return nil, err
+	}
+	defer rows.Close()
+	messages, err := or.scanOutboxMessages(rows)
+	if err != nil {
+		return nil, err
+	}
+	nextOffset := 0
+	if len(messages) > pageSize {
+		nextOffset = offset + pageSize
+		messages = messages[:pageSize]
+	}
+	return &models.OutboxResponse{Messages: messages, Offset: nextOffset}, nil
+}
+
+// DeleteSentOutboxMessages forgets messages delivered longer than retention ago and returns how many were deleted.
+// Dead messages are kept for admins to replay.
+func (or *OutboxRepository) DeleteSentOutboxMessages(retention time.Duration) (int64, error) {
+	result, err := or.db.Exec(
+		"DELETE FROM tigerhall.notification_outbox WHERE status = 'sent' AND processed_at < NOW() - $1 * INTERVAL '1 second'",
+		retention.Seconds(),
+	)
+	if err != nil {
+		or.logger.Error("Error deleting sent outbox messages:", err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ReplayOutboxMessage moves a dead message back to pending with a fresh retry budget.
+func (or *OutboxRepository) ReplayOutboxMessage(messageID int) (*models.OutboxMessage, error) {
+	rows, err := or.db.Query(`
+		UPDATE tigerhall.notification_outbox
+		SET status = 'pending', attempts = 0, next_attempt_at = NOW(), processed_at = NULL
+		WHERE message_id = $1 AND status = 'dead'
+		RETURNING `+outboxColumns,
+		messageID,
+	)
+	if err != nil {
+		or.logger.Error("Error replaying outbox message:", err)
+		return nil, err
+	}
+	defer rows.Close()
+	messages, err := or.scanOutboxMessages(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(messages) == 0 {
+		return nil, ErrOutboxMessageNotFound
+	}
+	return &messages[0], nil
+}
+
+func (or *OutboxRepository) scanOutboxMessages(rows *sql.Rows) ([]models.OutboxMessage, error) {
+	messages := []models.OutboxMessage{}
+	for rows.Next() {
+		var message models.OutboxMessage
+		var payload []byte
+		err := rows.Scan(&message.ID, &message.Kind, &message.UserID, &payload, &message.Status, &message.Attempts,
+			&message.NextAttemptAt, &message.LastError, &message.CreatedAt, &message.ProcessedAt)
+		if err != nil {
+			or.logger.Error("Error scanning row:", err)
+			return nil, err
+		}
+		message.Payload = payload
+		messages = append(messages, message)
+	}
+	return messages, rows.Err()
+}
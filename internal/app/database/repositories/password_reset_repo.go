@@ -0,0 +1,95 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database"
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrPasswordResetTokenInvalid is returned for reset tokens that are unknown, used or expired.
+var ErrPasswordResetTokenInvalid = errors.New("password reset token is invalid or expired")
+
+type PasswordResetRepository struct {
+	db     *sql.DB
+	logger *log.Logger
+}
+
+func NewPasswordResetRepository(db *sql.DB, logger *log.Logger) *PasswordResetRepository {
+	return &PasswordResetRepository{db: db, logger: logger}
+}
+
+// CreatePasswordReset stores the hash of a new reset token. Older tokens of the user stop working,
+// only the most recently mailed one can be used.
+func (pr *PasswordResetRepository) CreatePasswordReset(userID uint, tokenHash string, expiresAt time.Time) (err error) {
+	tx, err := pr.db.Begin()
+	if err != nil {
+		pr.logger.Error("Error beginning transaction:", err)
+		return err
+	}
+	defer func() { database.RollBack(tx, err) }()
+
+	// Expired and used tokens are useless, drop them while we are here
+	_, err = tx.Exec("DELETE FROM tigerhall.password_resets WHERE user_id = $1 AND (used_at IS NOT NULL OR expires_at < NOW())", userID)
+	if err != nil {
+		pr.logger.Error("Error pruning password resets:", err)
+		return err
+	}
+	_, err = tx.Exec("UPDATE tigerhall.password_resets SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL", userID)
+	if err != nil {
+		pr.logger.Error("Error invalidating password resets:", err)
+		return err
+	}
+	_, err = tx.Exec(
+		"INSERT INTO tigerhall.password_resets (token_hash, user_id, expires_at) VALUES ($1, $2, $3)",
+		tokenHash, userID, expiresAt,
+	)
+	if err != nil {
+		pr.logger.Error("Error inserting password reset:", err)
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		pr.logger.Error("Error committing transaction:", err)
+		return err
+	}
+	return nil
+}
+
+// ResetPassword consumes the reset token, sets the new password hash and revokes every session and API key of
+// the user, all in one transaction. It returns the id of the user the token belonged to.
+func (pr *PasswordResetRepository) ResetPassword(tokenHash string, passwordHash string) (userID uint, err error) {
+	tx, err := pr.db.Begin()
+	if err != nil {
+		pr.logger.Error("Error beginning transaction:", err)
+		return 0, err
+	}
+	defer func() { database.RollBack(tx, err) }()
+
+	err = tx.QueryRow(`
+		UPDATE tigerhall.password_resets SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id`,
+		tokenHash,
+	).Scan(&userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = ErrPasswordResetTokenInvalid
+		}
+		return 0, err
+	}
+	if _, err = tx.Exec("UPDATE tigerhall.users SET password_hash = $2 WHERE user_id = $1", userID, passwordHash); err != nil {
+		pr.logger.Error("Error updating password:", err)
+		return 0, err
+	}
+	if err = RevokeCredentials(tx, userID, ""); err != nil {
+		pr.logger.Error(err)
+		return 0, err
+	}
+	if err = tx.Commit(); err != nil {
+		pr.logger.Error("Error committing transaction:", err)
+		return 0, err
+	}
+	return userID, nil
+}
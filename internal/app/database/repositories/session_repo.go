@@ -0,0 +1,280 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrRefreshTokenReused is returned when a refresh token is exchanged a second time. The session is revoked then.
+var ErrRefreshTokenReused = errors.New("refresh token already used")
+
+type SessionRepository struct {
+	db     *sql.DB
+	logger *log.Logger
+}
+
+func NewSessionRepository(db *sql.DB, logger *log.Logger) *SessionRepository {
+	return &SessionRepository{db: db, logger: logger}
+}
+
+// RevokeCredentials revokes the sessions and API keys of the user, except the session keepSessionID, after the
+// password changed. Whoever knew the old password may have logged in or created keys with it.
+func RevokeCredentials(db Execer, userID uint, keepSessionID string) error {
+	_, err := db.Exec(
+		"UPDATE tigerhall.sessions SET revoked_at = NOW() WHERE user_id = $1 AND session_id != $2 AND revoked_at IS NULL",
+		userID, keepSessionID,
+	)
+	if err != nil {
+		return fmt.Errorf("error revoking sessions: %w", err)
+	}
+	if _, err := db.Exec("UPDATE tigerhall.api_keys SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL", userID); err != nil {
+		return fmt.Errorf("error revoking API keys: %w", err)
+	}
+	return nil
+}
+
+// CreateSession stores a new session along with the hash of its refresh token.
+func (sr *SessionRepository) CreateSession(session *models.Session, refreshTokenHash string) error {
+	_, err := sr.db.Exec(`
+		INSERT INTO tigerhall.sessions (session_id, user_id, refresh_token_hash, user_agent, ip_address, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		session.ID, session.UserID, refreshTokenHash, session.UserAgent, session.IPAddress, session.ExpiresAt,
+	)
+	if err != nil {
+		sr.logger.Error("Error inserting session:", err)
+	}
+	return err
+}
+
+// GetActiveSessionByRefreshHash finds the session a refresh token belongs to, if it is neither revoked nor expired.
+func (sr *SessionRepository) GetActiveSessionByRefreshHash(refreshTokenHash string) (*models.Session, error) {
+	query := `
+		SELECT session_id, user_id, COALESCE(user_agent, ''), COALESCE(ip_address, ''), created_at, last_used_at, expires_at
+		FROM tigerhall.sessions
+		WHERE refresh_token_hash = $1 AND revoked_at IS NULL AND expires_at > NOW()
+	`
+	session := &models.Session{}
+	err := sr.db.QueryRow(query, refreshTokenHash).Scan(
+		&session.ID, &session.UserID, &session.UserAgent, &session.IPAddress,
+		&session.CreatedAt, &session.LastUsedAt, &session.ExpiresAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("session not found")
+		}
+		sr.logger.Error("Error fetching session:", err)
+		return nil, err
+	}
+	return session, nil
+}
+
+// GetSessionCreatedAt returns when the user logged in to start the session. Refreshing tokens keeps the session.
+func (sr *SessionRepository) GetSessionCreatedAt(sessionID string, userID uint) (time.Time, error) {
+	var createdAt time.Time
+	err := sr.db.QueryRow(
+		"SELECT created_at FROM tigerhall.sessions WHERE session_id = $1 AND user_id = $2 AND revoked_at IS NULL",
+		sessionID, userID,
+	).Scan(&createdAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return time.Time{}, fmt.Errorf("session not found")
+		}
+		sr.logger.Error("Error fetching session:", err)
+		return time.Time{}, err
+	}
+	return createdAt, nil
+}
+
+// RotateRefreshToken replaces the refresh token of a session and remembers the old one. A refresh token can only be
+// used once: if the old token was rotated already, someone else holds a copy of it, so the session is revoked and
+// ErrRefreshTokenReused returned.
+func (sr *SessionRepository) RotateRefreshToken(sessionID string, oldHash string, newHash string) error {
+	// Tokens of expired sessions are useless, drop them while we are here
+	_, err := sr.db.Exec(`
+		DELETE FROM tigerhall.rotated_refresh_tokens r USING tigerhall.sessions s
+		WHERE r.session_id = s.session_id AND (s.expires_at < NOW() OR s.revoked_at IS NOT NULL)`)
+	if err != nil {
+		sr.logger.Warn("Error pruning rotated refresh tokens:", err)
+	}
+	tx, err := sr.db.Begin()
+	if err != nil {
+		sr.logger.Error("Error beginning transaction:", err)
+		return err
+	}
+	defer func() { database.RollBack(tx, err) }()
+
+	result, err := tx.Exec(`
+		UPDATE tigerhall.sessions SET refresh_token_hash = $3, last_used_at = NOW()
+		WHERE session_id = $1 AND refresh_token_hash = $2 AND revoked_at IS NULL`,
+		sessionID, oldHash, newHash,
+	)
+	if err != nil {
+		sr.logger.Error("Error rotating refresh token:", err)
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		sr.logger.Error("Error rotating refresh token:", err)
+		return err
+	}
+	if rows == 0 {
+		if _, err = tx.Exec("UPDATE tigerhall.sessions SET revoked_at = NOW() WHERE session_id = $1 AND revoked_at IS NULL", sessionID); err != nil {
+			sr.logger.Error("Error revoking session:", err)
+			return err
+		}
+		if err = tx.Commit(); err != nil {
+			sr.logger.Error("Error committing transaction:", err)
+			return err
+		}
+		return ErrRefreshTokenReused
+	}
+	_, err = tx.Exec(
+		"INSERT INTO tigerhall.rotated_refresh_tokens (refresh_token_hash, session_id) VALUES ($1, $2) ON CONFLICT (refresh_token_hash) DO NOTHING",
+		oldHash, sessionID,
+	)
+	if err != nil {
+		sr.logger.Error("Error storing rotated refresh token:", err)
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		sr.logger.Error("Error committing transaction:", err)
+		return err
+	}
+	return nil
+}
+
+// RevokeSessionOfRotatedToken revokes the session a refresh token belonged to before it was rotated and reports
+// whether that session was still live. A rotated token is only presented again if someone else holds a copy of it.
+func (sr *SessionRepository) RevokeSessionOfRotatedToken(refreshTokenHash string) (bool, error) {
+	result, err := sr.db.Exec(`
+		UPDATE tigerhall.sessions SET revoked_at = NOW()
+		WHERE revoked_at IS NULL
+			AND session_id = (SELECT session_id FROM tigerhall.rotated_refresh_tokens WHERE refresh_token_hash = $1)`,
+		refreshTokenHash,
+	)
+	if err != nil {
+		sr.logger.Error("Error revoking session:", err)
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		sr.logger.Error("Error revoking session:", err)
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// ListActiveSessions lists the sessions of a user that are neither revoked nor expired, most recently used first.
+func (sr *SessionRepository) ListActiveSessions(userID uint) ([]models.Session, error) {
+	query := `
+		SELECT session_id, user_id, COALESCE(user_agent, ''), COALESCE(ip_address, ''), created_at, last_used_at, expires_at
+		FROM tigerhall.sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY last_used_at DESC
+	`
+	rows, err := sr.db.Query(query, userID)
+	if err != nil {
+		sr.logger.Error("Error querying sessions:", err)
+		return nil, err
+	}
+	defer rows.Close()
+	sessions := []models.Session{}
+	for rows.Next() {
+		var session models.Session
+		if err := rows.Scan(&session.ID, &session.UserID, &session.UserAgent, &session.IPAddress,
+			&session.CreatedAt, &session.LastUsedAt, &session.ExpiresAt); err != nil {
+			sr.logger.Error("Error scanning row:", err)
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// ListSessionHistory lists every session of a user including revoked and expired ones, newest first.
+func (sr *SessionRepository) ListSessionHistory(userID uint) ([]models.Session, error) {
+	query := `
+		SELECT session_id, user_id, COALESCE(user_agent, ''), COALESCE(ip_address, ''), created_at, last_used_at, expires_at, revoked_at
+		FROM tigerhall.sessions
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+	rows, err := sr.db.Query(query, userID)
+	if err != nil {
+		sr.logger.Error("Error querying sessions:", err)
+		return nil, err
+	}
+	defer rows.Close()
+	sessions := []models.Session{}
+	for rows.Next() {
+		var session models.Session
+		if err := rows.Scan(&session.ID, &session.UserID, &session.UserAgent, &session.IPAddress,
+			&session.CreatedAt, &session.LastUsedAt, &session.ExpiresAt, &session.RevokedAt); err != nil {
+			sr.logger.Error("Error scanning row:", err)
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// RevokeSession revokes a session of the given user.
+func (sr *SessionRepository) RevokeSession(sessionID string, userID uint) error {
+	result, err := sr.db.Exec(
+		"UPDATE tigerhall.sessions SET revoked_at = NOW() WHERE session_id = $1 AND user_id = $2 AND revoked_at IS NULL",
+		sessionID, userID,
+	)
+	if err != nil {
+		sr.logger.Error("Error revoking session:", err)
+		return err
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return fmt.Errorf("session %s not found", sessionID)
+	}
+	return nil
+}
+
+// RevokeAllSessions revokes every session of the given user.
+func (sr *SessionRepository) RevokeAllSessions(userID uint) error {
+	_, err := sr.db.Exec("UPDATE tigerhall.sessions SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL", userID)
+	if err != nil {
+		sr.logger.Error("Error revoking sessions:", err)
+	}
+	return err
+}
+
+// RevokeToken adds an access token to the revocation list until it expires.
+func (sr *SessionRepository) RevokeToken(tokenID string, expiresAt time.Time) error {
+	// Expired entries are useless, drop them while we are here
+	if _, err := sr.db.Exec("DELETE FROM tigerhall.revoked_tokens WHERE expires_at < NOW()"); err != nil {
+		sr.logger.Warn("Error pruning revoked tokens:", err)
+	}
+	_, err := sr.db.Exec(
+		"INSERT INTO tigerhall.revoked_tokens (token_id, expires_at) VALUES ($1, $2) ON CONFLICT (token_id) DO NOTHING",
+		tokenID, expiresAt,
+	)
+	if err != nil {
+		sr.logger.Error("Error revoking token:", err)
+	}
+	return err
+}
+
+// IsRevoked reports whether the access token or the session it was issued for has been revoked.
+func (sr *SessionRepository) IsRevoked(tokenID string, sessionID string) (bool, error) {
+	query := `
+		SELECT EXISTS (SELECT 1 FROM tigerhall.revoked_tokens WHERE token_id = $1)
+			OR EXISTS (SELECT 1 FROM tigerhall.sessions WHERE session_id = $2 AND (revoked_at IS NOT NULL OR expires_at <= NOW()))
+	`
+	var revoked bool
+	if err := sr.db.QueryRow(query, tokenID, sessionID).Scan(&revoked); err != nil {
+		sr.logger.Error("Error checking token revocation:", err)
+		return false, err
+	}
+	return revoked, nil
+}
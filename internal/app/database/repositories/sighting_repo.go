@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"strconv"
 	"sync"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database"
 	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	"github.com/lib/pq"
 	"github.com/patrickmn/go-cache"
 	log "github.com/sirupsen/logrus"
 )
@@ -26,19 +28,27 @@ type cachedSighitingResult struct {
 	expiryTime time.Time
 }
 
+var (
+	// ErrSightingNotFound is returned for moderation of a sighting that does not exist
+	ErrSightingNotFound = errors.New("sighting not found")
+	// ErrSightingStateConflict is returned when a sighting is not in a state the change is allowed from
+	ErrSightingStateConflict = errors.New("sighting is not in a state allowing this change")
+)
+
 var sightingCache *cache.Cache = cache.New(15*time.Minute, 10*time.Minute)
 
 func NewSightingRepository(db *sql.DB, logger *log.Logger) *SightingRepository {
 	return &SightingRepository{db: db, logger: logger}
 }
 
-func (sr *SightingRepository) CreateSight(sighting *models.Sighting) (int, error) {
+// CreateSight stores a new sighting and queues the sighting.created webhook event in the same transaction.
+func (sr *SightingRepository) CreateSight(sighting *models.Sighting) (sightingID int, err error) {
 	tx, err := sr.db.Begin()
 	if err != nil {
 		log.Println("Error beginning sransaction:", err)
 		return 0, err
 	}
-	defer database.RollBack(tx, err)
+	defer func() { database.RollBack(tx, err) }()
 	query := `
 	INSERT INTO tigerhall.sightings (
 		tiger_id,
@@ -49,8 +59,7 @@ func (sr *SightingRepository) CreateSight(sighting *models.Sighting) (int, error
 		image
 	) VALUES ($1, $2, $3, $4, $5, $6) RETURNING sighting_id
 `
-	var sightingID int
-	err = sr.db.QueryRow(
+	err = tx.QueryRow(
 		query,
 		sighting.TigerID,
 		sighting.User.ID,
@@ -62,6 +71,10 @@ func (sr *SightingRepository) CreateSight(sighting *models.Sighting) (int, error
 	if err != nil {
 		return 0, err
 	}
+	if err = enqueueSightingWebhookEvent(tx, models.WebhookSightingCreated, sightingID); err != nil {
+		sr.logger.Error("Error queueing webhook event:", err)
+		return 0, err
+	}
 	err = tx.Commit()
 	if err != nil {
 		log.Println("Error committing sransaction:", err)
@@ -71,7 +84,7 @@ func (sr *SightingRepository) CreateSight(sighting *models.Sighting) (int, error
 }
 
 func (r *SightingRepository) GetPreviousSightingCoordinates(tigerID int) (struct{ Latitude, Longitude float64 }, error) {
-	query := "SELECT last_seen_coordinates_lat, last_seen_coordinates_lon FROM tigerhall.sightings WHERE tiger_id = $1 ORDER BY last_seen_timestamp DESC LIMIT 1"
+	query := "SELECT last_seen_coordinates_lat, last_seen_coordinates_lon FROM tigerhall.sightings WHERE tiger_id = $1 AND status != 'rejected' ORDER BY last_seen_timestamp DESC LIMIT 1"
 	row := r.db.QueryRow(query, tigerID)
 
 	var coordinates struct{ Latitude, Longitude float64 }
@@ -88,8 +101,9 @@ func (r *SightingRepository) GetPreviousSightingCoordinates(tigerID int) (struct
 	return coordinates, nil
 }
 
-func (sr *SightingRepository) ListSightings(tigerId int, pageSize int, offset int) (*models.SightingsResponse, error) {
-	cacheKey := sr.generateCacheKey(tigerId, pageSize, offset)
+// ListSightings lists sightings of a tiger in the given state. Sightings newer than the embargo are left out.
+func (sr *SightingRepository) ListSightings(tigerId int, status string, embargo time.Duration, pageSize int, offset int) (*models.SightingsResponse, error) {
+	cacheKey := sr.generateCacheKey(tigerId, status, embargo, pageSize, offset)
 	sr.CacheMutex.RLock()
 	result, found := sightingCache.Get(cacheKey)
 	sr.CacheMutex.RUnlock()
@@ -103,11 +117,11 @@ func (sr *SightingRepository) ListSightings(tigerId int, pageSize int, offset in
 		return response, nil
 	}
 	// Data not found in the cache, fetch from the database
-	return sr.FetchSightingsAndStore(tigerId, pageSize, offset, cacheKey)
+	return sr.FetchSightingsAndStore(tigerId, status, embargo, pageSize, offset, cacheKey)
 }
 
-func (sr *SightingRepository) FetchSightingsAndStore(tigerId int, pageSize int, offset int, cacheKey string) (*models.SightingsResponse, error) {
-	sightings, nextOffset, err := sr.FetchFromDatabase(tigerId, pageSize, offset)
+func (sr *SightingRepository) FetchSightingsAndStore(tigerId int, status string, embargo time.Duration, pageSize int, offset int, cacheKey string) (*models.SightingsResponse, error) {
+	sightings, nextOffset, err := sr.FetchFromDatabase(tigerId, status, embargo, pageSize, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -124,14 +138,14 @@ func (sr *SightingRepository) FetchSightingsAndStore(tigerId int, pageSize int,
 	return &models.SightingsResponse{Sightings: sightings, Offset: nextOffset}, nil
 }
 
-func (sr *SightingRepository) FetchFromDatabase(tigerId, pageSize int, offset int) (sightings []models.Sighting, nextOffset int, err error) {
+func (sr *SightingRepository) FetchFromDatabase(tigerId int, status string, embargo time.Duration, pageSize int, offset int) (sightings []models.Sighting, nextOffset int, err error) {
 	query := `
-		SELECT sighting_id,tiger_id, last_seen_timestamp, last_seen_coordinates_lat, last_seen_coordinates_lon,image
-		FROM tigerhall.sightings where tiger_id = $1 
+		SELECT sighting_id,tiger_id, last_seen_timestamp, last_seen_coordinates_lat, last_seen_coordinates_lon,image,status
+		FROM tigerhall.sightings where tiger_id = $1 AND status = $2 AND last_seen_timestamp <= $3
 		ORDER BY last_seen_timestamp DESC, sighting_id DESC
-		LIMIT $2 OFFSET $3;
+		LIMIT $4 OFFSET $5;
 	`
-	rows, err := sr.db.Query(query, tigerId, pageSize+1, offset)
+	rows, err := sr.db.Query(query, tigerId, status, time.Now().Add(-embargo), pageSize+1, offset)
 	if err != nil {
 		sr.logger.Error("Error querying sightings:", err)
 		return sightings, 0, err
@@ -147,6 +161,7 @@ func (sr *SightingRepository) FetchFromDatabase(tigerId, pageSize int, offset in
 			&sighting.LastCoordinates.Latitude,
 			&sighting.LastCoordinates.Longitude,
 			&sighting.ImageBlob,
+			&sighting.Status,
 		)
 		if err != nil {
 			sr.logger.Error("Error scanning row:", err)
@@ -164,7 +179,245 @@ func (sr *SightingRepository) FetchFromDatabase(tigerId, pageSize int, offset in
 	return sightings, nextOffset, nil
 }
 
-func (sr *SightingRepository) generateCacheKey(tiger_id int, pageSize int, offset int) string {
+func (sr *SightingRepository) generateCacheKey(tiger_id int, status string, embargo time.Duration, pageSize int, offset int) string {
 	// Customize the cache key based on your specific requirements
-	return "list_sightings:" + "tiger_id:" + strconv.Itoa(tiger_id) + ":" + strconv.Itoa(pageSize) + ":" + fmt.Sprint(offset)
+	return "list_sightings:" + "tiger_id:" + strconv.Itoa(tiger_id) + ":" + status + ":" + embargo.String() + ":" + strconv.Itoa(pageSize) + ":" + fmt.Sprint(offset)
+}
+
+// GetSightingByID fetches a single sighting along with its tiger name and reporter.
+func (sr *SightingRepository) GetSightingByID(sightingID int) (*models.Sighting, error) {
+	query := `
+		SELECT s.sighting_id, s.tiger_id, t.name, COALESCE(s.user_id, 0), s.last_seen_timestamp,
+			s.last_seen_coordinates_lat, s.last_seen_coordinates_lon, s.image, s.status, COALESCE(s.review_reason, '')
+		FROM tigerhall.sightings s
+		JOIN tigerhall.tigers t ON t.tiger_id = s.tiger_id
+		WHERE s.sighting_id = $1
+	`
+	sighting := &models.Sighting{User: &models.User{}}
+	err := sr.db.QueryRow(query, sightingID).Scan(
+		&sighting.ID,
+		&sighting.TigerID,
+		&sighting.TigerName,
+		&sighting.User.ID,
+		&sighting.Timestamp,
+		&sighting.LastCoordinates.Latitude,
+		&sighting.LastCoordinates.Longitude,
+		&sighting.ImageBlob,
+		&sighting.Status,
+		&sighting.ReviewReason,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("sighting %d not found", sightingID)
+		}
+		sr.logger.Error("Error fetching sighting:", err)
+		return nil, err
+	}
+	return sighting, nil
+}
+
+// NextStreamEventID returns the ID of a new live feed event. IDs increase across all instances.
+func (sr *SightingRepository) NextStreamEventID() (int64, error) {
+	var id int64
+	if err := sr.db.QueryRow("SELECT nextval('tigerhall.sighting_stream_event_seq')").Scan(&id); err != nil {
+		sr.logger.Error("Error allocating live feed event id:", err)
+		return 0, err
+	}
+	return id, nil
+}
+
+// GetSightingImage fetches the photo of a sighting, nil if the sighting has none or no longer exists.
+func (sr *SightingRepository) GetSightingImage(sightingID int) ([]byte, error) {
+	var image []byte
+	err := sr.db.QueryRow(`SELECT image FROM tigerhall.sightings WHERE sighting_id = $1`, sightingID).Scan(&image)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return image, err
+}
+
+// UpdateSightingStatus moves a sighting to a new moderation state. The update is
+// only applied when the sighting is currently in one of the allowed states, and
+// the state it was in before the update is returned. Verifying a pending sighting
+// queues its notifications in the outbox within the same transaction.
+func (sr *SightingRepository) UpdateSightingStatus(sightingID int, allowed []string, status string, reason string, reviewerID uint) (previous string, err error) {
+	tx, err := sr.db.Begin()
+	if err != nil {
+		sr.logger.Error("Error beginning transaction:", err)
+		return "", err
+	}
+	defer func() { database.RollBack(tx, err) }()
+
+	err = tx.QueryRow("SELECT status FROM tigerhall.sightings WHERE sighting_id = $1 FOR UPDATE", sightingID).Scan(&previous)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = ErrSightingNotFound
+		}
+		return "", err
+	}
+	if !containsStatus(allowed, previous) {
+		err = fmt.Errorf("%w: cannot move sighting from %s to %s", ErrSightingStateConflict, previous, status)
+		return previous, err
+	}
+	var reviewer sql.NullInt64
+	if reviewerID > 0 {
+		reviewer = sql.NullInt64{Int64: int64(reviewerID), Valid: true}
+	}
+	_, err = tx.Exec(`
+		UPDATE tigerhall.sightings
+		SET status = $2, review_reason = NULLIF($3, ''), reviewed_by = COALESCE($4, reviewed_by), reviewed_at = NOW()
+		WHERE sighting_id = $1`,
+		sightingID, status, reason, reviewer,
+	)
+	if err != nil {
+		return previous, err
+	}
+	// Only the first verification fans out, re-verifying a disputed sighting must not notify twice
+	if previous == models.SightingPending && status == models.SightingVerified {
+		err = enqueueOutboxMessage(tx, models.OutboxSightingVerified, 0, models.SightingVerifiedEvent{SightingID: sightingID}, time.Time{})
+		if err != nil {
+			sr.logger.Error("Error queueing sighting notifications:", err)
+			return previous, err
+		}
+		if err = enqueueSightingWebhookEvent(tx, models.WebhookSightingVerified, sightingID); err != nil {
+			sr.logger.Error("Error queueing webhook event:", err)
+			return previous, err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		sr.logger.Error("Error committing transaction:", err)
+		return previous, err
+	}
+	// Listings are cached per status, drop them so the change is visible immediately
+	sightingCache.Flush()
+	return previous, nil
+}
+
+// enqueueSightingWebhookEvent queues a sighting event with the sighting as stored by the transaction.
+func enqueueSightingWebhookEvent(tx *sql.Tx, eventType string, sightingID int) error {
+	data := models.WebhookSighting{SightingID: sightingID}
+	err := tx.QueryRow(`
+		SELECT s.tiger_id, t.name, s.last_seen_coordinates_lat, s.last_seen_coordinates_lon, s.last_seen_timestamp, s.status
+		FROM tigerhall.sightings s
+		JOIN tigerhall.tigers t ON t.tiger_id = s.tiger_id
+		WHERE s.sighting_id = $1`,
+		sightingID,
+	).Scan(&data.TigerID, &data.TigerName, &data.Latitude, &data.Longitude, &data.SeenAt, &data.Status)
+	if err != nil {
+		return err
+	}
+	return enqueueWebhookEvent(tx, eventType, data)
+}
+
+// ListSightingsByStatus returns sightings across all tigers that are in one of the given states, oldest first.
+func (sr *SightingRepository) ListSightingsByStatus(statuses []string, pageSize int, offset int) (*models.SightingsResponse, error) {
+	query := `
+		SELECT s.sighting_id, s.tiger_id, t.name, s.last_seen_timestamp, s.last_seen_coordinates_lat,
+			s.last_seen_coordinates_lon, s.image, s.status, COALESCE(s.review_reason, '')
+		FROM tigerhall.sightings s
+		JOIN tigerhall.tigers t ON t.tiger_id = s.tiger_id
+		WHERE s.status = ANY($1)
+		ORDER BY s.sighting_id ASC
+		LIMIT $2 OFFSET $3;
+	`
+	rows, err := sr.db.Query(query, pq.Array(statuses), pageSize+1, offset)
+	if err != nil {
+		sr.logger.Error("Error querying sightings:", err)
+		return nil, err
+	}
+	defer rows.Close()
+	sightings := []models.Sighting{}
+	for rows.Next() {
+		var sighting models.Sighting
+		err := rows.Scan(
+			&sighting.ID,
+			&sighting.TigerID,
+			&sighting.TigerName,
+			&sighting.Timestamp,
+			&sighting.LastCoordinates.Latitude,
+			&sighting.LastCoordinates.Longitude,
+			&sighting.ImageBlob,
+			&sighting.Status,
+			&sighting.ReviewReason,
+		)
+		if err != nil {
+			sr.logger.Error("Error scanning row:", err)
+			return nil, err
+		}
+		sightings = append(sightings, sighting)
+	}
+	nextOffset := 0
+	if len(sightings) > pageSize {
+		nextOffset = offset + pageSize
+		sightings = sightings[:pageSize]
+	}
+	return &models.SightingsResponse{Sightings: sightings, Offset: nextOffset}, nil
+}
+
+// ListSightingsByUser returns the sightings reported by the user in every state, newest first.
+func (sr *SightingRepository) ListSightingsByUser(userID uint, pageSize int, offset int) (*models.SightingsResponse, error) {
+	query := `
+		SELECT s.sighting_id, s.tiger_id, t.name, s.last_seen_timestamp, s.last_seen_coordinates_lat,
+			s.last_seen_coordinates_lon, s.image, s.status, COALESCE(s.review_reason, '')
+		FROM tigerhall.sightings s
+		JOIN tigerhall.tigers t ON t.tiger_id = s.tiger_id
+		WHERE s.user_id = $1
+		ORDER BY s.last_seen_timestamp DESC, s.sighting_id DESC
+		LIMIT $2 OFFSET $3;
+	`
+	rows, err := sr.db.Query(query, userID, pageSize+1, offset)
+	if err != nil {
+		sr.logger.Error("Error querying sightings:", err)
+		return nil, err
+	}
+	defer rows.Close()
+	sightings := []models.Sighting{}
+	for rows.Next() {
+		var sighting models.Sighting
+		err := rows.Scan(
+			&sighting.ID,
+			&sighting.TigerID,
+			&sighting.TigerName,
+			&sighting.Timestamp,
+			&sighting.LastCoordinates.Latitude,
+			&sighting.LastCoordinates.Longitude,
+			&sighting.ImageBlob,
+			&sighting.Status,
+			&sighting.ReviewReason,
+		)
+		if err != nil {
+			sr.logger.Error("Error scanning row:", err)
+			return nil, err
+		}
+		sightings = append(sightings, sighting)
+	}
+	nextOffset := 0
+	if len(sightings) > pageSize {
+		nextOffset = offset + pageSize
+		sightings = sightings[:pageSize]
+	}
+	return &models.SightingsResponse{Sightings: sightings, Offset: nextOffset}, nil
+}
+
+// DeleteSighting removes a sighting.
+func (sr *SightingRepository) DeleteSighting(sightingID int) error {
+	result, err := sr.db.Exec("DELETE FROM tigerhall.sightings WHERE sighting_id = $1", sightingID)
+	if err != nil {
+		sr.logger.Error("Error deleting sighting:", err)
+		return err
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return fmt.Errorf("sighting %d not found", sightingID)
+	}
+	sightingCache.Flush()
+	return nil
+}
+
+func containsStatus(statuses []string, status string) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
 }
@@ -34,14 +34,14 @@ func NewTigerRepository(db *sql.DB, logger *log.Logger) *TigerRepository {
 	return &TigerRepository{db: db, logger: logger}
 }
 
-func (tr *TigerRepository) CreateTiger(tiger *models.Tiger) (int, error) {
+// CreateTiger stores a new tiger and queues the tiger.created webhook event in the same transaction.
+func (tr *TigerRepository) CreateTiger(tiger *models.Tiger) (tigerID int, err error) {
 	tx, err := tr.db.Begin()
 	if err != nil {
 		log.Println("Error beginning transaction:", err)
 		return 0, err
 	}
-	defer database.RollBack(tx, err)
-	var tigerID int
+	defer func() { database.RollBack(tx, err) }()
 	err = tx.QueryRow(
 		"INSERT INTO tigerhall.tigers (name, date_of_birth,last_seen_timestamp, last_seen_coordinates_lat, last_seen_coordinates_lon) "+
 			"VALUES ($1, $2, $3, $4, $5) RETURNING tiger_id",
@@ -50,6 +50,17 @@ func (tr *TigerRepository) CreateTiger(tiger *models.Tiger) (int, error) {
 	if err != nil {
 		return 0, err
 	}
+	err = enqueueWebhookEvent(tx, models.WebhookTigerCreated, models.WebhookTiger{
+		TigerID:    tigerID,
+		Name:       tiger.Name,
+		Latitude:   tiger.LastCoordinates.Latitude,
+		Longitude:  tiger.LastCoordinates.Longitude,
+		LastSeenAt: tiger.LastSeenAt.Time,
+	})
+	if err != nil {
+		tr.logger.Error("Error queueing webhook event:", err)
+		return 0, err
+	}
 	err = tx.Commit()
 	if err != nil {
 		log.Println("Error committing transaction:", err)
@@ -172,3 +183,18 @@ func (tr *TigerRepository) generateCacheKey(pageSize int, offset int) string {
 	// Customize the cache key based on your specific requirements
 	return "list_tigers:" + strconv.Itoa(pageSize) + ":" + fmt.Sprint(offset)
 }
+
+// DeleteTiger removes a tiger together with its sightings.
+func (tr *TigerRepository) DeleteTiger(tigerID int) error {
+	result, err := tr.db.Exec("DELETE FROM tigerhall.tigers WHERE tiger_id = $1", tigerID)
+	if err != nil {
+		tr.logger.Error("Error deleting tiger:", err)
+		return err
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return fmt.Errorf("tiger %d not found", tigerID)
+	}
+	tigersCache.Flush()
+	sightingCache.Flush()
+	return nil
+}
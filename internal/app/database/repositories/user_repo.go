@@ -2,14 +2,19 @@ package repositories
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 
 	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database"
 	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	"github.com/lib/pq"
 	"github.com/sirupsen/logrus"
 )
 
+// ErrUserNotFound is returned when no user has the given username.
+var ErrUserNotFound = errors.New("user not found")
+
 type UserRepository struct {
 	db     *sql.DB
 	logger *logrus.Logger
@@ -43,11 +48,11 @@ func (ur *UserRepository) CreateUser(user *models.User) error {
 }
 
 func (ur *UserRepository) GetUserByUserName(username string) (*models.User, error) {
-	query := "SELECT user_id, username, password_hash, email FROM tigerhall.users WHERE username=$1"
+	query := "SELECT user_id, username, COALESCE(password_hash, ''), email, roles, email_verified_at IS NOT NULL, totp_enabled_at IS NOT NULL FROM tigerhall.users WHERE username=$1"
 	row := ur.db.QueryRow(query, username)
 
 	user := &models.User{}
-	err := row.Scan(&user.ID, &user.Username, &user.Password, &user.Email)
+	err := row.Scan(&user.ID, &user.Username, &user.Password, &user.Email, pq.Array(&user.Roles), &user.EmailVerified, &user.TOTPEnabled)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("user not found for username %s", username)
@@ -58,31 +63,232 @@ func (ur *UserRepository) GetUserByUserName(username string) (*models.User, erro
 	return user, nil
 }
 
-func (ur *UserRepository) GetUsersByTigerId(tigerID int, userID int) ([]models.User, error) {
+// GetUserByID fetches a user by id.
+func (ur *UserRepository) GetUserByID(userID uint) (*models.User, error) {
+	query := "SELECT user_id, username, COALESCE(password_hash, ''), email, roles, email_verified_at IS NOT NULL, totp_enabled_at IS NOT NULL FROM tigerhall.users WHERE user_id=$1"
+	user := &models.User{}
+	err := ur.db.QueryRow(query, userID).Scan(&user.ID, &user.Username, &user.Password, &user.Email, pq.Array(&user.Roles), &user.EmailVerified, &user.TOTPEnabled)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("user not found for id %d", userID)
+		}
+		return nil, fmt.Errorf("error scanning user row: %v", err)
+	}
+	return user, nil
+}
+
+// GetUserByEmail fetches a user by email address.
+func (ur *UserRepository) GetUserByEmail(email string) (*models.User, error) {
+	query := "SELECT user_id, username, COALESCE(password_hash, ''), email, roles, email_verified_at IS NOT NULL, totp_enabled_at IS NOT NULL FROM tigerhall.users WHERE email=$1"
+	user := &models.User{}
+	err := ur.db.QueryRow(query, email).Scan(&user.ID, &user.Username, &user.Password, &user.Email, pq.Array(&user.Roles), &user.EmailVerified, &user.TOTPEnabled)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("user not found for email %s", email)
+		}
+		return nil, fmt.Errorf("error scanning user row: %v", err)
+	}
+	return user, nil
+}
+
+// GetTigerSubscribers returns the users to notify about a sighting of the tiger along with their notification
+// preferences: its followers and, unless they opted out, users who reported it before. Users without a verified
+// email, users who muted the tiger and the reporter are left out.
+func (ur *UserRepository) GetTigerSubscribers(tigerID int, reporterID uint) ([]models.TigerSubscriber, error) {
 	query := `
-		SELECT DISTINCT u.user_id, u.username, u.email
+		SELECT u.user_id, u.username, u.email, u.roles, u.language,
+			COALESCE(p.channels, '{email}'), COALESCE(p.frequency, 'immediate'),
+			p.quiet_hours_start, p.quiet_hours_end, COALESCE(p.timezone, 'UTC')
 		FROM tigerhall.users u
-		JOIN tigerhall.sightings s ON u.user_id = s.user_id
-		WHERE s.tiger_id = $1 and u.user_id != $2
+		LEFT JOIN tigerhall.notification_preferences p ON p.user_id = u.user_id
+		WHERE u.user_id != $2 AND u.email_verified_at IS NOT NULL
+		AND NOT EXISTS (SELECT 1 FROM tigerhall.tiger_mutes m WHERE m.user_id = u.user_id AND m.tiger_id = $1)
+		AND (
+			EXISTS (SELECT 1 FROM tigerhall.tiger_follows f WHERE f.user_id = u.user_id AND f.tiger_id = $1)
+			OR (u.notify_reported_tigers AND EXISTS (SELECT 1 FROM tigerhall.sightings s WHERE s.user_id = u.user_id AND s.tiger_id = $1))
+		)
 	`
-	rows, err := ur.db.Query(query, tigerID, userID)
+	rows, err := ur.db.Query(query, tigerID, reporterID)
 	if err != nil {
-		log.Fatal(err)
+		ur.logger.Error("Error querying tiger subscribers:", err)
+		return nil, err
 	}
 	defer rows.Close()
-	// Slice to store users
-	var users []models.User
-	// Iterate over the result set and populate the users slice
+	var subscribers []models.TigerSubscriber
 	for rows.Next() {
-		var user models.User
-		if err := rows.Scan(&user.ID, &user.Username, &user.Email); err != nil {
-			log.Fatal(err)
+		var subscriber models.TigerSubscriber
+		var quietStart, quietEnd sql.NullInt16
+		err := rows.Scan(
+			&subscriber.User.ID,
+			&subscriber.User.Username,
+			&subscriber.User.Email,
+			pq.Array(&subscriber.User.Roles),
+			&subscriber.User.Language,
+			pq.Array(&subscriber.Preferences.Channels),
+			&subscriber.Preferences.Frequency,
+			&quietStart,
+			&quietEnd,
+			&subscriber.Preferences.Timezone,
+		)
+		if err != nil {
+			ur.logger.Error("Error scanning row:", err)
+			return nil, err
+		}
+		subscriber.User.EmailVerified = true
+		subscriber.Preferences.QuietHoursStart, subscriber.Preferences.QuietHoursEnd = nullHour(quietStart), nullHour(quietEnd)
+		subscribers = append(subscribers, subscriber)
+	}
+	return subscribers, rows.Err()
+}
+
+// MarkEmailVerified marks the email of the user as verified. It fails if the user changed their email
+// since the verification link was sent.
+func (ur *UserRepository) MarkEmailVerified(username string, email string) error {
+	result, err := ur.db.Exec(
+		"UPDATE tigerhall.users SET email_verified_at = COALESCE(email_verified_at, NOW()) WHERE username = $1 AND email = $2",
+		username, email,
+	)
+	if err != nil {
+		ur.logger.Error("Error verifying email:", err)
+		return err
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return fmt.Errorf("user not found for username %s and email %s", username, email)
+	}
+	return nil
+}
+
+// GrantRole adds a role to the user and returns the roles the user holds afterwards.
+func (ur *UserRepository) GrantRole(username string, role string) ([]string, error) {
+	query := `
+		UPDATE tigerhall.users
+		SET roles = CASE WHEN $2 = ANY(roles) THEN roles ELSE array_append(roles, $2) END
+		WHERE username = $1
+		RETURNING roles
+	`
+	return ur.updateRoles(query, username, role)
+}
+
+// RevokeRole removes a role from the user and returns the roles the user holds afterwards.
+func (ur *UserRepository) RevokeRole(username string, role string) ([]string, error) {
+	query := "UPDATE tigerhall.users SET roles = array_remove(roles, $2) WHERE username = $1 RETURNING roles"
+	return ur.updateRoles(query, username, role)
+}
+
+func (ur *UserRepository) updateRoles(query string, username string, role string) ([]string, error) {
+	var roles []string
+	err := ur.db.QueryRow(query, username, role).Scan(pq.Array(&roles))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w for username %s", ErrUserNotFound, username)
+		}
+		ur.logger.Error("Error updating user roles:", err)
+		return nil, err
+	}
+	return roles, nil
+}
+
+// GetProfile fetches the account details shown to the user at /api/v1/me.
+func (ur *UserRepository) GetProfile(userID uint) (*models.Profile, error) {
+	query := `
+		SELECT username, COALESCE(display_name, ''), email, email_verified_at IS NOT NULL, roles,
+			password_hash IS NOT NULL, totp_enabled_at IS NOT NULL, notify_reported_tigers, language
+		FROM tigerhall.users WHERE user_id = $1
+	`
+	profile := &models.Profile{}
+	err := ur.db.QueryRow(query, userID).Scan(
+		&profile.Username,
+		&profile.DisplayName,
+		&profile.Email,
+		&profile.EmailVerified,
+		pq.Array(&profile.Roles),
+		&profile.HasPassword,
+		&profile.TOTPEnabled,
+		&profile.NotifyReportedTigers,
+		&profile.Language,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("user not found for id %d", userID)
+		}
+		return nil, fmt.Errorf("error scanning user row: %v", err)
+	}
+	return profile, nil
+}
+
+// UpdateProfile applies the changes in one transaction. A new email has to be verified again, and a new
+// password revokes every API key and every session but keepSessionID. Either change invalidates mailed password reset tokens.
+func (ur *UserRepository) UpdateProfile(userID uint, update models.ProfileUpdate, keepSessionID string) (err error) {
+	tx, err := ur.db.Begin()
+	if err != nil {
+		ur.logger.Error("Error beginning transaction:", err)
+		return err
+	}
+	defer func() { database.RollBack(tx, err) }()
+
+	if update.DisplayName != nil {
+		if _, err = tx.Exec("UPDATE tigerhall.users SET display_name = NULLIF($2, '') WHERE user_id = $1", userID, *update.DisplayName); err != nil {
+			ur.logger.Error("Error updating display name:", err)
+			return err
+		}
+	}
+	if update.NotifyReportedTigers != nil {
+		if _, err = tx.Exec("UPDATE tigerhall.users SET notify_reported_tigers = $2 WHERE user_id = $1", userID, *update.NotifyReportedTigers); err != nil {
+			ur.logger.Error("Error updating notification settings:", err)
+			return err
+		}
+	}
+	if update.Language != nil {
+		if _, err = tx.Exec("UPDATE tigerhall.users SET language = $2 WHERE user_id = $1", userID, *update.Language); err != nil {
+			ur.logger.Error("Error updating language:", err)
+			return err
+		}
+	}
+	if update.Email != nil {
+		_, err = tx.Exec("UPDATE tigerhall.users SET email = $2, email_verified_at = NULL WHERE user_id = $1", userID, *update.Email)
+		if err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+				err = ErrDuplicateUser
+				return err
+			}
+			ur.logger.Error("Error updating email:", err)
+			return err
+		}
+	}
+	if update.PasswordHash != nil {
+		if _, err = tx.Exec("UPDATE tigerhall.users SET password_hash = $2 WHERE user_id = $1", userID, *update.PasswordHash); err != nil {
+			ur.logger.Error("Error updating password:", err)
+			return err
 		}
-		users = append(users, user)
+		// The session making the change stays logged in
+		if err = RevokeCredentials(tx, userID, keepSessionID); err != nil {
+			ur.logger.Error(err)
+			return err
+		}
+	}
+	if update.Email != nil || update.PasswordHash != nil {
+		if _, err = tx.Exec("UPDATE tigerhall.password_resets SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL", userID); err != nil {
+			ur.logger.Error("Error invalidating password resets:", err)
+			return err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		ur.logger.Error("Error committing transaction:", err)
+		return err
 	}
+	return nil
+}
 
-	if err := rows.Err(); err != nil {
-		log.Fatal(err)
+// DeleteUser deletes the account. Sessions, keys and identities go with it, while the sightings
+// the user reported are kept without a reporter.
+func (ur *UserRepository) DeleteUser(userID uint) error {
+	result, err := ur.db.Exec("DELETE FROM tigerhall.users WHERE user_id = $1", userID)
+	if err != nil {
+		ur.logger.Error("Error deleting user:", err)
+		return err
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return fmt.Errorf("user not found for id %d", userID)
 	}
-	return users, nil
+	return nil
 }
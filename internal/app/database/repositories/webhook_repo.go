@@ -0,0 +1,350 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	"github.com/lib/pq"
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrWebhookNotFound is returned for webhooks that do not exist or belong to another user.
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+const webhookColumns = "webhook_id, url, event_types, active, consecutive_failures, created_at, disabled_at"
+
+type WebhookRepository struct {
+	db     *sql.DB
+	logger *log.Logger
+}
+
+func NewWebhookRepository(db *sql.DB, logger *log.Logger) *WebhookRepository {
+	return &WebhookRepository{db: db, logger: logger}
+}
+
+// enqueueWebhookEvent queues a delivery of the event to every active webhook subscribed to its type, as part of
+// the caller's transaction.
+func enqueueWebhookEvent(db Execer, eventType string, data any) error {
+	event, err := models.NewWebhookEvent(eventType, data)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`
+		INSERT INTO tigerhall.webhook_deliveries (webhook_id, event_id, event_type, payload)
+		SELECT webhook_id, $1, $2, $3 FROM tigerhall.webhooks WHERE active AND $2 = ANY(event_types)`,
+		event.ID, event.Type, payload,
+	)
+	return err
+}
+
+// CreateWebhook stores a new webhook of the user and fills in its ID and creation time.
+func (wr *WebhookRepository) CreateWebhook(webhook *models.Webhook, userID uint, secret string) error {
+	err := wr.db.QueryRow(
+		"INSERT INTO tigerhall.webhooks (user_id, url, event_types, secret) VALUES ($1, $2, $3, $4) RETURNING webhook_id, created_at",
+		userID, webhook.URL, pq.Array(webhook.EventTypes), secret,
+	).Scan(&webhook.ID, &webhook.CreatedAt)
+	if err != nil {
+		wr.logger.Error("Error creating webhook:", err)
+		return err
+	}
+	webhook.Active = true
+	return nil
+}
+
+// ListWebhooks returns the webhooks of the user.
+func (wr *WebhookRepository) ListWebhooks(userID uint) ([]models.Webhook, error) {
+	rows, err := wr.db.Query("SELECT "+webhookColumns+" FROM tigerhall.webhooks WHERE user_id = $1 ORDER BY webhook_id", userID)
+	if err != nil {
+		wr.logger.Error("Error querying webhooks:", err)
+		return nil, err
+	}
+	defer rows.Close()
+	webhooks := []models.Webhook{}
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			wr.logger.Error("Error scanning row:", err)
+			return nil, err
+		}
+		webhooks = append(webhooks, *webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+// GetWebhook returns a webhook of the user.
+func (wr *WebhookRepository) GetWebhook(webhookID int, userID uint) (*models.Webhook, error) {
+	row := wr.db.QueryRow("SELECT "+webhookColumns+" FROM tigerhall.webhooks WHERE webhook_id = $1 AND user_id = $2", webhookID, userID)
+	webhook, err := scanWebhook(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		wr.logger.Error("Error fetching webhook:", err)
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// UpdateWebhook applies the changes to a webhook of the user. Enabling a webhook resets its failures.
+func (wr *WebhookRepository) UpdateWebhook(webhookID int, userID uint, update models.UpdateWebhookRequest) (*models.Webhook, error) {
+	var eventTypes any
+	if update.EventTypes != nil {
+		eventTypes = pq.Array(update.EventTypes)
+	}
+	row := wr.db.QueryRow(`
+		UPDATE tigerhall.webhooks SET
+			url = COALESCE($3, url),
+			event_types = COALESCE($4, event_types),
+			active = COALESCE($5, active),
+			consecutive_failures = CASE WHEN $5 THEN 0 ELSE consecutive_failures END,
+			disabled_at = CASE WHEN $5 THEN NULL WHEN NOT $5 THEN COALESCE(disabled_at, NOW()) ELSE disabled_at END
+		WHERE webhook_id = $1 AND user_id = $2
+		RETURNING `+webhookColumns,
+		webhookID, userID, update.URL, eventTypes, update.Active,
+	)
+	webhook, err := scanWebhook(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		wr.logger.Error("Error updating webhook:", err)
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// DeleteWebhook removes a webhook of the user along with its delivery log.
+func (wr *WebhookRepository) DeleteWebhook(webhookID int, userID uint) error {
+	result, err := wr.db.Exec("DELETE FROM tigerhall.webhooks WHERE webhook_id = $1 AND user_id = $2", webhookID, userID)
+	if err != nil {
+		wr.logger.Error("Error deleting webhook:", err)
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// ListWebhookDeliveries returns the delivery log of a webhook of the user, newest first.
+func (wr *WebhookRepository) ListWebhookDeliveries(webhookID int, userID uint, pageSize int, offset int) (*models.WebhookDeliveriesResponse, error) {
+	if _, err := wr.GetWebhook(webhookID, userID); err != nil {
+		return nil, err
+	}
+	rows, err := wr.db.Query(`
+		SELECT delivery_id, event_id, event_type, payload, status, attempts, COALESCE(response_status, 0),
+			COALESCE(last_error, ''), created_at, next_attempt_at, delivered_at
+		FROM tigerhall.webhook_deliveries
+		WHERE webhook_id = $1
+		ORDER BY delivery_id DESC
+		LIMIT $2 OFFSET $3`,
+		webhookID, pageSize+1, offset,
+	)
+	if err != nil {
+		wr.logger.Error("Error querying webhook deliveries:", err)
+		return nil, err
+	}
+	defer rows.Close()
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		var payload []byte
+		var nextAttemptAt time.Time
+		err := rows.Scan(&delivery.ID, &delivery.EventID, &delivery.EventType, &payload, &delivery.Status, &delivery.Attempts,
+			&delivery.ResponseStatus, &delivery.LastError, &delivery.CreatedAt, &nextAttemptAt, &delivery.DeliveredAt)
+		if err != nil {
+			wr.logger.Error("Error scanning row:", err)
+			return nil, err
+		}
+		delivery.Payload = payload
+		if delivery.Status == models.WebhookDeliveryPending {
+			delivery.NextAttemptAt = &nextAttemptAt
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	nextOffset := 0
+	if len(deliveries) > pageSize {
+		nextOffset = offset + pageSize
+		deliveries = deliveries[:pageSize]
+	}
+	return &models.WebhookDeliveriesResponse{Deliveries: deliveries, Offset: nextOffset}, nil
+}
+
+// ClaimWebhookDeliveries returns up to limit deliveries to active webhooks that are due, and pushes their next
+// attempt back by the lease so they are retried if the process dies while sending them.
+func (wr *WebhookRepository) ClaimWebhookDeliveries(limit int, lease time.Duration) ([]models.PendingWebhookDelivery, error) {
+	rows, err := wr.db.Query(`
+		WITH claimed AS (
+			UPDATE tigerhall.webhook_deliveries
+			SET attempts = attempts + 1, next_attempt_at = NOW() + $2 * INTERVAL '1 second'
+			WHERE delivery_id IN (
+				SELECT d.delivery_id FROM tigerhall.webhook_deliveries d
+				JOIN tigerhall.webhooks w ON w.webhook_id = d.webhook_id
+				WHERE d.status = 'pending' AND d.next_attempt_at <= NOW() AND w.active
+				ORDER BY d.delivery_id
+				LIMIT $1
+				FOR UPDATE OF d SKIP LOCKED
+			)
+			RETURNING delivery_id, webhook_id, event_id, event_type, payload, attempts
+		)
+		SELECT c.delivery_id, c.webhook_id, c.event_id, c.event_type, c.payload, c.attempts, w.url, w.secret, u.roles
+		FROM claimed c
+		JOIN tigerhall.webhooks w ON w.webhook_id = c.webhook_id
+		JOIN tigerhall.users u ON u.user_id = w.user_id
+		ORDER BY c.delivery_id`,
+		limit, lease.Seconds(),
+	)
+	if err != nil {
+		wr.logger.Error("Error claiming webhook deliveries:", err)
+		return nil, err
+	}
+	defer rows.Close()
+	var deliveries []models.PendingWebhookDelivery
+	for rows.Next() {
+		var delivery models.PendingWebhookDelivery
+		var payload []byte
+		err := rows.Scan(&delivery.ID, &delivery.WebhookID, &delivery.EventID, &delivery.EventType, &payload,
+			&delivery.Attempts, &delivery.URL, &delivery.Secret, pq.Array(&delivery.OwnerRoles))
+		if err != nil {
+			wr.logger.Error("Error scanning row:", err)
+			return nil, err
+		}
+		delivery.Payload = payload
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// MarkWebhookDelivered records a successful delivery and resets the failures of the webhook.
+func (wr *WebhookRepository) MarkWebhookDelivered(deliveryID int, webhookID int, responseStatus int) (err error) {
+	tx, err := wr.db.Begin()
+	if err != nil {
+		wr.logger.Error("Error beginning transaction:", err)
+		return err
+	}
+	defer func() { database.RollBack(tx, err) }()
+
+	_, err = tx.Exec(`
+		UPDATE tigerhall.webhook_deliveries
+		SET status = 'delivered', response_status = $2, last_error = NULL, delivered_at = NOW()
+		WHERE delivery_id = $1`,
+		deliveryID, responseStatus,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("UPDATE tigerhall.webhooks SET consecutive_failures = 0 WHERE webhook_id = $1", webhookID)
+	if err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		wr.logger.Error("Error committing transaction:", err)
+		return err
+	}
+	return nil
+}
+
+// RecordWebhookFailure records a failed attempt. The delivery is retried at retryAt, or failed for good if
+// retryAt is nil. The webhook is disabled once disableAfter attempts in a row failed, in which case its pending
+// deliveries are failed as well and true is returned.
+func (wr *WebhookRepository) RecordWebhookFailure(deliveryID int, webhookID int, responseStatus int, failure string, retryAt *time.Time, disableAfter int) (disabled bool, err error) {
+	tx, err := wr.db.Begin()
+	if err != nil {
+		wr.logger.Error("Error beginning transaction:", err)
+		return false, err
+	}
+	defer func() { database.RollBack(tx, err) }()
+
+	var status sql.NullInt64
+	if responseStatus > 0 {
+		status = sql.NullInt64{Int64: int64(responseStatus), Valid: true}
+	}
+	_, err = tx.Exec(`
+		UPDATE tigerhall.webhook_deliveries
+		SET status = CASE WHEN $4::TIMESTAMPTZ IS NULL THEN 'failed' ELSE status END,
+			next_attempt_at = COALESCE($4, next_attempt_at), response_status = $2, last_error = $3
+		WHERE delivery_id = $1`,
+		deliveryID, status, failure, retryAt,
+	)
+	if err != nil {
+		return false, err
+	}
+	err = tx.QueryRow(`
+		UPDATE tigerhall.webhooks
+		SET consecutive_failures = consecutive_failures + 1,
+			active = active AND consecutive_failures + 1 < $2,
+			disabled_at = CASE WHEN active AND consecutive_failures + 1 >= $2 THEN NOW() ELSE disabled_at END
+		WHERE webhook_id = $1
+		RETURNING NOT active`,
+		webhookID, disableAfter,
+	).Scan(&disabled)
+	if err != nil {
+		return false, err
+	}
+	if disabled {
+		_, err = tx.Exec(`
+			UPDATE tigerhall.webhook_deliveries SET status = 'failed', last_error = 'webhook disabled after repeated failures'
+			WHERE webhook_id = $1 AND status = 'pending'`,
+			webhookID,
+		)
+		if err != nil {
+			return false, err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		wr.logger.Error("Error committing transaction:", err)
+		return false, err
+	}
+	return disabled, nil
+}
+
+// DisableWebhook disables the webhook right away and fails its pending deliveries with the reason.
+func (wr *WebhookRepository) DisableWebhook(webhookID int, reason string) (err error) {
+	tx, err := wr.db.Begin()
+	if err != nil {
+		wr.logger.Error("Error beginning transaction:", err)
+		return err
+	}
+	defer func() { database.RollBack(tx, err) }()
+
+	_, err = tx.Exec("UPDATE tigerhall.webhooks SET active = FALSE, disabled_at = NOW() WHERE webhook_id = $1 AND active", webhookID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		UPDATE tigerhall.webhook_deliveries SET status = 'failed', last_error = $2
+		WHERE webhook_id = $1 AND status = 'pending'`,
+		webhookID, reason,
+	)
+	if err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		wr.logger.Error("Error committing transaction:", err)
+		return err
+	}
+	return nil
+}
+
+// scanner is implemented by both *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanWebhook(row scanner) (*models.Webhook, error) {
+	var webhook models.Webhook
+	err := row.Scan(&webhook.ID, &webhook.URL, pq.Array(&webhook.EventTypes), &webhook.Active,
+		&webhook.ConsecutiveFailures, &webhook.CreatedAt, &webhook.DisabledAt)
+	if err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
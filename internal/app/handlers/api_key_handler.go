@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/service"
+	log "github.com/sirupsen/logrus"
+)
+
+type APIKeyHandler struct {
+	logger *log.Logger
+}
+
+func NewAPIKeyHandler(logger *log.Logger) *APIKeyHandler {
+	return &APIKeyHandler{logger: logger}
+}
+
+func (ah *APIKeyHandler) CreateAPIKey(rw http.ResponseWriter, req *http.Request) {
+	ah.logger.Info("Creating API key.....")
+	apiKeyService := service.NewAPIKeyService(ah.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	apiKeyService.CreateAPIKey(ctx, rw, req)
+}
+
+func (ah *APIKeyHandler) ListAPIKeys(rw http.ResponseWriter, req *http.Request) {
+	apiKeyService := service.NewAPIKeyService(ah.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	apiKeyService.ListAPIKeys(ctx, rw, req)
+}
+
+func (ah *APIKeyHandler) RevokeAPIKey(rw http.ResponseWriter, req *http.Request) {
+	ah.logger.Info("Revoking API key.....")
+	apiKeyService := service.NewAPIKeyService(ah.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	apiKeyService.RevokeAPIKey(ctx, rw, req)
+}
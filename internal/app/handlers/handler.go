@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	_ "github.com/chegde20121/Tigerhall-Kittens/docs"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
 	"github.com/chegde20121/Tigerhall-Kittens/internal/app/service"
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
@@ -14,12 +15,73 @@ func RegisterApiHandlers(sm *mux.Router) {
 	postMethods := sm.Methods(http.MethodPost).Subrouter()
 	postMethods.HandleFunc("/api/v1/register", NewUserHandler(logrus.New()).RegisterUsers)
 	postMethods.HandleFunc("/api/v1/login", NewUserHandler(logrus.New()).Login)
+	postMethods.HandleFunc("/api/v1/login/mfa", NewUserHandler(logrus.New()).LoginMFA)
 	getMethods := sm.Methods(http.MethodGet).Subrouter()
+	deleteMethods := sm.Methods(http.MethodDelete).Subrouter()
+	putMethods := sm.Methods(http.MethodPut).Subrouter()
+	patchMethods := sm.Methods(http.MethodPatch).Subrouter()
 	getMethods.HandleFunc("/api/v1/logout", NewUserHandler(logrus.New()).Logout)
-	postMethods.HandleFunc("/api/v1/createTigers", service.AuthMiddleware(NewTigerHanlder(logrus.New()).CreateTiger))
-	postMethods.HandleFunc("/api/v1/createSights", service.AuthMiddleware(NewSightingHandler(logrus.New()).CreateSight))
+	getMethods.HandleFunc("/api/v1/verify-email", NewUserHandler(logrus.New()).VerifyEmail)
+	postMethods.HandleFunc("/api/v1/verify-email/resend", service.LoginRequired(NewUserHandler(logrus.New()).ResendVerificationEmail))
+	postMethods.HandleFunc("/api/v1/password/forgot", NewUserHandler(logrus.New()).ForgotPassword)
+	getMethods.HandleFunc("/api/v1/password/reset", NewUserHandler(logrus.New()).ResetPasswordForm)
+	postMethods.HandleFunc("/api/v1/password/reset", NewUserHandler(logrus.New()).ResetPassword)
+	getMethods.HandleFunc("/api/v1/oidc/{provider}/login", NewUserHandler(logrus.New()).OIDCLogin)
+	getMethods.HandleFunc("/api/v1/oidc/{provider}/callback", NewUserHandler(logrus.New()).OIDCCallback)
+	postMethods.HandleFunc("/api/v1/token/refresh", NewUserHandler(logrus.New()).RefreshToken)
+	getMethods.HandleFunc("/api/v1/sessions", service.LoginRequired(NewUserHandler(logrus.New()).ListSessions))
+	deleteMethods.HandleFunc("/api/v1/sessions/{id}", service.LoginRequired(NewUserHandler(logrus.New()).RevokeSession))
+	getMethods.HandleFunc("/api/v1/me", service.AuthMiddleware(NewUserHandler(logrus.New()).GetProfile))
+	patchMethods.HandleFunc("/api/v1/me", service.LoginRequired(NewUserHandler(logrus.New()).UpdateProfile))
+	deleteMethods.HandleFunc("/api/v1/me", service.LoginRequired(NewUserHandler(logrus.New()).DeleteAccount))
+	getMethods.HandleFunc("/api/v1/me/sightings", service.AuthMiddleware(NewUserHandler(logrus.New()).ListMySightings))
+	getMethods.HandleFunc("/api/v1/me/notification-preferences", service.AuthMiddleware(NewUserHandler(logrus.New()).GetNotificationPreferences))
+	putMethods.HandleFunc("/api/v1/me/notification-preferences", service.LoginRequired(NewUserHandler(logrus.New()).UpdateNotificationPreferences))
+	getMethods.HandleFunc("/api/v1/unsubscribe", NewUserHandler(logrus.New()).UnsubscribeConfirmation)
+	postMethods.HandleFunc("/api/v1/unsubscribe", NewUserHandler(logrus.New()).Unsubscribe)
+	getMethods.HandleFunc("/api/v1/me/export", service.LoginRequired(NewUserHandler(logrus.New()).RequestDataExport))
+	getMethods.HandleFunc("/api/v1/me/export/{id}", service.LoginRequired(NewUserHandler(logrus.New()).DownloadDataExport))
+	postMethods.HandleFunc("/api/v1/createTigers", service.RequirePermission(models.PermissionTigersWrite, NewTigerHanlder(logrus.New()).CreateTiger))
+	postMethods.HandleFunc("/api/v1/createSights", service.RequirePermission(models.PermissionSightingsWrite, service.TrackUploadProgress(NewSightingHandler(logrus.New()).CreateSight)))
 	getMethods.HandleFunc("/api/v1/listTigers", NewTigerHanlder(logrus.New()).ListTigers)
 	getMethods.HandleFunc("/api/v1/tigers/{id}/listSightings", NewSightingHandler(logrus.New()).ListAllSightings)
+	getMethods.HandleFunc("/api/v1/stream/sightings", service.StreamSightings)
+	getMethods.HandleFunc("/api/v1/live", service.LoginRequired(service.LiveChannel))
+	postMethods.HandleFunc("/api/v1/tigers/{id}/follow", service.LoginRequired(NewTigerHanlder(logrus.New()).FollowTiger))
+	deleteMethods.HandleFunc("/api/v1/tigers/{id}/follow", service.LoginRequired(NewTigerHanlder(logrus.New()).UnfollowTiger))
+	deleteMethods.HandleFunc("/api/v1/tigers/{id}", service.RequirePermission(models.PermissionTigersDelete, NewTigerHanlder(logrus.New()).DeleteTiger))
+	getMethods.HandleFunc("/api/v1/sightings/{id}/image", NewSightingHandler(logrus.New()).GetSightingImage)
+	getMethods.HandleFunc("/api/v1/sightings/pending", service.RequirePermission(models.PermissionSightingsReview, NewSightingHandler(logrus.New()).ListPendingSightings))
+	postMethods.HandleFunc("/api/v1/sightings/{id}/review", service.RequirePermission(models.PermissionSightingsReview, NewSightingHandler(logrus.New()).ReviewSighting))
+	postMethods.HandleFunc("/api/v1/sightings/{id}/dispute", service.RequirePermission(models.PermissionSightingsWrite, NewSightingHandler(logrus.New()).DisputeSighting))
+	deleteMethods.HandleFunc("/api/v1/sightings/{id}", service.RequirePermission(models.PermissionSightingsDelete, NewSightingHandler(logrus.New()).DeleteSighting))
+	postMethods.HandleFunc("/api/v1/admin/users/{username}/roles", service.RequirePermission(models.PermissionUsersManage, NewUserHandler(logrus.New()).GrantRole))
+	deleteMethods.HandleFunc("/api/v1/admin/users/{username}/roles/{role}", service.RequirePermission(models.PermissionUsersManage, NewUserHandler(logrus.New()).RevokeRole))
+	postMethods.HandleFunc("/api/v1/mfa/totp/enroll", service.LoginRequired(NewUserHandler(logrus.New()).EnrollTOTP))
+	postMethods.HandleFunc("/api/v1/mfa/totp/confirm", service.LoginRequired(NewUserHandler(logrus.New()).ConfirmTOTP))
+	deleteMethods.HandleFunc("/api/v1/mfa/totp", service.LoginRequired(NewUserHandler(logrus.New()).DisableTOTP))
+	postMethods.HandleFunc("/api/v1/mfa/recovery-codes", service.LoginRequired(NewUserHandler(logrus.New()).RegenerateRecoveryCodes))
+	getMethods.HandleFunc("/api/v1/admin/mfa-policy", service.RequirePermission(models.PermissionUsersManage, NewUserHandler(logrus.New()).GetMFAPolicy))
+	putMethods.HandleFunc("/api/v1/admin/mfa-policy", service.RequirePermission(models.PermissionUsersManage, NewUserHandler(logrus.New()).SetMFAPolicy))
+	getMethods.HandleFunc("/api/v1/admin/lockouts", service.RequirePermission(models.PermissionUsersManage, NewUserHandler(logrus.New()).ListLoginThrottles))
+	deleteMethods.HandleFunc("/api/v1/admin/lockouts/{scope}/{key}", service.RequirePermission(models.PermissionUsersManage, NewUserHandler(logrus.New()).ClearLoginThrottle))
+	getMethods.HandleFunc("/api/v1/admin/outbox", service.RequirePermission(models.PermissionNotificationsManage, NewUserHandler(logrus.New()).ListOutboxMessages))
+	getMethods.HandleFunc("/api/v1/admin/messaging", service.RequirePermission(models.PermissionNotificationsManage, service.MessagingStats))
+	getMethods.HandleFunc("/api/v1/admin/email-templates", service.RequirePermission(models.PermissionNotificationsManage, service.ListEmailTemplates))
+	getMethods.HandleFunc("/api/v1/admin/email-templates/{name}/preview", service.RequirePermission(models.PermissionNotificationsManage, service.PreviewEmailTemplate))
+	postMethods.HandleFunc("/api/v1/admin/outbox/{id}/replay", service.RequirePermission(models.PermissionNotificationsManage, NewUserHandler(logrus.New()).ReplayOutboxMessage))
+	postMethods.HandleFunc("/api/v1/admin/email-templates/reload", service.RequirePermission(models.PermissionNotificationsManage, service.ReloadEmailTemplates))
+	postMethods.HandleFunc("/api/v1/api-keys", service.LoginRequired(NewAPIKeyHandler(logrus.New()).CreateAPIKey))
+	getMethods.HandleFunc("/api/v1/api-keys", service.LoginRequired(NewAPIKeyHandler(logrus.New()).ListAPIKeys))
+	deleteMethods.HandleFunc("/api/v1/api-keys/{id}", service.LoginRequired(NewAPIKeyHandler(logrus.New()).RevokeAPIKey))
+	postMethods.HandleFunc("/api/v1/webhooks", service.RequirePermission(models.PermissionWebhooksManage, NewWebhookHandler(logrus.New()).CreateWebhook))
+	getMethods.HandleFunc("/api/v1/webhooks", service.RequirePermission(models.PermissionWebhooksManage, NewWebhookHandler(logrus.New()).ListWebhooks))
+	getMethods.HandleFunc("/api/v1/webhooks/{id}", service.RequirePermission(models.PermissionWebhooksManage, NewWebhookHandler(logrus.New()).GetWebhook))
+	patchMethods.HandleFunc("/api/v1/webhooks/{id}", service.RequirePermission(models.PermissionWebhooksManage, NewWebhookHandler(logrus.New()).UpdateWebhook))
+	deleteMethods.HandleFunc("/api/v1/webhooks/{id}", service.RequirePermission(models.PermissionWebhooksManage, NewWebhookHandler(logrus.New()).DeleteWebhook))
+	getMethods.HandleFunc("/api/v1/webhooks/{id}/deliveries", service.RequirePermission(models.PermissionWebhooksManage, NewWebhookHandler(logrus.New()).ListWebhookDeliveries))
+	getMethods.HandleFunc("/.well-known/jwks.json", service.JWKS)
+	getMethods.HandleFunc("/api/v1/health/notifications", service.NotificationHealth)
 	sm.PathPrefix("/swagger/").Handler(httpSwagger.Handler(
 		httpSwagger.URL("/swagger/doc.json"),
 	))
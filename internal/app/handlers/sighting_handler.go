@@ -31,3 +31,38 @@ func (sh *SightingHandler) ListAllSightings(rw http.ResponseWriter, req *http.Re
 	defer cancel()
 	sightingSvc.ListAllSightings(ctx, rw, req)
 }
+
+func (sh *SightingHandler) ReviewSighting(rw http.ResponseWriter, req *http.Request) {
+	sightingSvc := service.NewSightingService(database.GetDB(), sh.logger)
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	sightingSvc.ReviewSighting(ctx, rw, req)
+}
+
+func (sh *SightingHandler) DisputeSighting(rw http.ResponseWriter, req *http.Request) {
+	sightingSvc := service.NewSightingService(database.GetDB(), sh.logger)
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	sightingSvc.DisputeSighting(ctx, rw, req)
+}
+
+func (sh *SightingHandler) ListPendingSightings(rw http.ResponseWriter, req *http.Request) {
+	sightingSvc := service.NewSightingService(database.GetDB(), sh.logger)
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	sightingSvc.ListPendingSightings(ctx, rw, req)
+}
+
+func (sh *SightingHandler) DeleteSighting(rw http.ResponseWriter, req *http.Request) {
+	sightingSvc := service.NewSightingService(database.GetDB(), sh.logger)
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	sightingSvc.DeleteSighting(ctx, rw, req)
+}
+
+func (sh *SightingHandler) GetSightingImage(rw http.ResponseWriter, req *http.Request) {
+	sightingSvc := service.NewSightingService(database.GetDB(), sh.logger)
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	sightingSvc.GetSightingImage(ctx, rw, req)
+}
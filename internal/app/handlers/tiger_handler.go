@@ -31,3 +31,24 @@ func (t *TigerHandler) ListTigers(rw http.ResponseWriter, req *http.Request) {
 	defer cancel()
 	tigerService.ListAllTigers(ctx, rw, req)
 }
+
+func (t *TigerHandler) DeleteTiger(rw http.ResponseWriter, req *http.Request) {
+	tigerService := service.NewTigerService(database.GetDB(), t.logger)
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	tigerService.DeleteTiger(ctx, rw, req)
+}
+
+func (t *TigerHandler) FollowTiger(rw http.ResponseWriter, req *http.Request) {
+	tigerService := service.NewTigerService(database.GetDB(), t.logger)
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	tigerService.FollowTiger(ctx, rw, req)
+}
+
+func (t *TigerHandler) UnfollowTiger(rw http.ResponseWriter, req *http.Request) {
+	tigerService := service.NewTigerService(database.GetDB(), t.logger)
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	tigerService.UnfollowTiger(ctx, rw, req)
+}
@@ -41,3 +41,259 @@ func (uh *UserHandler) Logout(rw http.ResponseWriter, req *http.Request) {
 	defer cancel()
 	userService.Logout(ctx, rw, req)
 }
+
+func (uh *UserHandler) GrantRole(rw http.ResponseWriter, req *http.Request) {
+	uh.logger.Info("Granting role.....")
+	userService := service.NewUserService(uh.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	userService.GrantRole(ctx, rw, req)
+}
+
+func (uh *UserHandler) RevokeRole(rw http.ResponseWriter, req *http.Request) {
+	uh.logger.Info("Revoking role.....")
+	userService := service.NewUserService(uh.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	userService.RevokeRole(ctx, rw, req)
+}
+
+func (uh *UserHandler) RefreshToken(rw http.ResponseWriter, req *http.Request) {
+	uh.logger.Info("Refreshing token.....")
+	sessionService := service.NewSessionService(uh.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	sessionService.Refresh(ctx, rw, req)
+}
+
+func (uh *UserHandler) ListSessions(rw http.ResponseWriter, req *http.Request) {
+	sessionService := service.NewSessionService(uh.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	sessionService.ListSessions(ctx, rw, req)
+}
+
+func (uh *UserHandler) RevokeSession(rw http.ResponseWriter, req *http.Request) {
+	uh.logger.Info("Revoking session.....")
+	sessionService := service.NewSessionService(uh.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	sessionService.RevokeSession(ctx, rw, req)
+}
+
+func (uh *UserHandler) VerifyEmail(rw http.ResponseWriter, req *http.Request) {
+	uh.logger.Info("Verifying email.....")
+	userService := service.NewUserService(uh.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	userService.VerifyEmail(ctx, rw, req)
+}
+
+func (uh *UserHandler) ResendVerificationEmail(rw http.ResponseWriter, req *http.Request) {
+	uh.logger.Info("Resending verification email.....")
+	userService := service.NewUserService(uh.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	userService.ResendVerificationEmail(ctx, rw, req)
+}
+
+func (uh *UserHandler) ForgotPassword(rw http.ResponseWriter, req *http.Request) {
+	uh.logger.Info("Password reset requested.....")
+	userService := service.NewUserService(uh.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	userService.ForgotPassword(ctx, rw, req)
+}
+
+func (uh *UserHandler) ResetPasswordForm(rw http.ResponseWriter, req *http.Request) {
+	uh.logger.Info("Showing password reset form.....")
+	userService := service.NewUserService(uh.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	userService.ResetPasswordForm(ctx, rw, req)
+}
+
+func (uh *UserHandler) ResetPassword(rw http.ResponseWriter, req *http.Request) {
+	uh.logger.Info("Resetting password.....")
+	userService := service.NewUserService(uh.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	userService.ResetPassword(ctx, rw, req)
+}
+
+func (uh *UserHandler) OIDCLogin(rw http.ResponseWriter, req *http.Request) {
+	uh.logger.Info("Starting OIDC login.....")
+	userService := service.NewUserService(uh.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	userService.OIDCLogin(ctx, rw, req)
+}
+
+func (uh *UserHandler) OIDCCallback(rw http.ResponseWriter, req *http.Request) {
+	uh.logger.Info("Completing OIDC login.....")
+	userService := service.NewUserService(uh.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	userService.OIDCCallback(ctx, rw, req)
+}
+
+func (uh *UserHandler) LoginMFA(rw http.ResponseWriter, req *http.Request) {
+	uh.logger.Info("Completing login with second factor.....")
+	userService := service.NewUserService(uh.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	userService.LoginMFA(ctx, rw, req)
+}
+
+func (uh *UserHandler) EnrollTOTP(rw http.ResponseWriter, req *http.Request) {
+	uh.logger.Info("Enrolling TOTP.....")
+	userService := service.NewUserService(uh.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	userService.EnrollTOTP(ctx, rw, req)
+}
+
+func (uh *UserHandler) ConfirmTOTP(rw http.ResponseWriter, req *http.Request) {
+	uh.logger.Info("Confirming TOTP.....")
+	userService := service.NewUserService(uh.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	userService.ConfirmTOTP(ctx, rw, req)
+}
+
+func (uh *UserHandler) DisableTOTP(rw http.ResponseWriter, req *http.Request) {
+	uh.logger.Info("Disabling TOTP.....")
+	userService := service.NewUserService(uh.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	userService.DisableTOTP(ctx, rw, req)
+}
+
+func (uh *UserHandler) RegenerateRecoveryCodes(rw http.ResponseWriter, req *http.Request) {
+	uh.logger.Info("Regenerating recovery codes.....")
+	userService := service.NewUserService(uh.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	userService.RegenerateRecoveryCodes(ctx, rw, req)
+}
+
+func (uh *UserHandler) GetMFAPolicy(rw http.ResponseWriter, req *http.Request) {
+	userService := service.NewUserService(uh.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	userService.GetMFAPolicy(ctx, rw, req)
+}
+
+func (uh *UserHandler) SetMFAPolicy(rw http.ResponseWriter, req *http.Request) {
+	uh.logger.Info("Updating MFA policy.....")
+	userService := service.NewUserService(uh.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	userService.SetMFAPolicy(ctx, rw, req)
+}
+
+func (uh *UserHandler) ListLoginThrottles(rw http.ResponseWriter, req *http.Request) {
+	userService := service.NewUserService(uh.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	userService.ListLoginThrottles(ctx, rw, req)
+}
+
+func (uh *UserHandler) ClearLoginThrottle(rw http.ResponseWriter, req *http.Request) {
+	uh.logger.Info("Clearing login throttle.....")
+	userService := service.NewUserService(uh.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	userService.ClearLoginThrottle(ctx, rw, req)
+}
+
+func (uh *UserHandler) GetProfile(rw http.ResponseWriter, req *http.Request) {
+	userService := service.NewUserService(uh.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	userService.GetProfile(ctx, rw, req)
+}
+
+func (uh *UserHandler) UpdateProfile(rw http.ResponseWriter, req *http.Request) {
+	uh.logger.Info("Updating account.....")
+	userService := service.NewUserService(uh.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	userService.UpdateProfile(ctx, rw, req)
+}
+
+func (uh *UserHandler) ListMySightings(rw http.ResponseWriter, req *http.Request) {
+	userService := service.NewUserService(uh.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	userService.ListMySightings(ctx, rw, req)
+}
+
+func (uh *UserHandler) DeleteAccount(rw http.ResponseWriter, req *http.Request) {
+	uh.logger.Info("Deleting account.....")
+	userService := service.NewUserService(uh.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	userService.DeleteAccount(ctx, rw, req)
+}
+
+func (uh *UserHandler) RequestDataExport(rw http.ResponseWriter, req *http.Request) {
+	uh.logger.Info("Requesting data export.....")
+	userService := service.NewUserService(uh.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	userService.RequestDataExport(ctx, rw, req)
+}
+
+func (uh *UserHandler) DownloadDataExport(rw http.ResponseWriter, req *http.Request) {
+	userService := service.NewUserService(uh.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	userService.DownloadDataExport(ctx, rw, req)
+}
+
+func (uh *UserHandler) GetNotificationPreferences(rw http.ResponseWriter, req *http.Request) {
+	userService := service.NewUserService(uh.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	userService.GetNotificationPreferences(ctx, rw, req)
+}
+
+func (uh *UserHandler) UpdateNotificationPreferences(rw http.ResponseWriter, req *http.Request) {
+	uh.logger.Info("Updating notification preferences.....")
+	userService := service.NewUserService(uh.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	userService.UpdateNotificationPreferences(ctx, rw, req)
+}
+
+func (uh *UserHandler) UnsubscribeConfirmation(rw http.ResponseWriter, req *http.Request) {
+	uh.logger.Info("Showing unsubscribe confirmation.....")
+	userService := service.NewUserService(uh.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	userService.UnsubscribeConfirmation(ctx, rw, req)
+}
+
+func (uh *UserHandler) Unsubscribe(rw http.ResponseWriter, req *http.Request) {
+	uh.logger.Info("Unsubscribing.....")
+	userService := service.NewUserService(uh.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	userService.Unsubscribe(ctx, rw, req)
+}
+
+func (uh *UserHandler) ListOutboxMessages(rw http.ResponseWriter, req *http.Request) {
+	userService := service.NewUserService(uh.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	userService.ListOutboxMessages(ctx, rw, req)
+}
+
+func (uh *UserHandler) ReplayOutboxMessage(rw http.ResponseWriter, req *http.Request) {
+	uh.logger.Info("Replaying outbox message.....")
+	userService := service.NewUserService(uh.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	userService.ReplayOutboxMessage(ctx, rw, req)
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/service"
+	log "github.com/sirupsen/logrus"
+)
+
+type WebhookHandler struct {
+	logger *log.Logger
+}
+
+func NewWebhookHandler(logger *log.Logger) *WebhookHandler {
+	return &WebhookHandler{logger: logger}
+}
+
+func (wh *WebhookHandler) CreateWebhook(rw http.ResponseWriter, req *http.Request) {
+	wh.logger.Info("Creating webhook.....")
+	webhookService := service.NewWebhookService(wh.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	webhookService.CreateWebhook(ctx, rw, req)
+}
+
+func (wh *WebhookHandler) ListWebhooks(rw http.ResponseWriter, req *http.Request) {
+	webhookService := service.NewWebhookService(wh.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	webhookService.ListWebhooks(ctx, rw, req)
+}
+
+func (wh *WebhookHandler) GetWebhook(rw http.ResponseWriter, req *http.Request) {
+	webhookService := service.NewWebhookService(wh.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	webhookService.GetWebhook(ctx, rw, req)
+}
+
+func (wh *WebhookHandler) UpdateWebhook(rw http.ResponseWriter, req *http.Request) {
+	wh.logger.Info("Updating webhook.....")
+	webhookService := service.NewWebhookService(wh.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	webhookService.UpdateWebhook(ctx, rw, req)
+}
+
+func (wh *WebhookHandler) DeleteWebhook(rw http.ResponseWriter, req *http.Request) {
+	wh.logger.Info("Deleting webhook.....")
+	webhookService := service.NewWebhookService(wh.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	webhookService.DeleteWebhook(ctx, rw, req)
+}
+
+func (wh *WebhookHandler) ListWebhookDeliveries(rw http.ResponseWriter, req *http.Request) {
+	webhookService := service.NewWebhookService(wh.logger, database.GetDB())
+	ctx, cancel := context.WithTimeout(req.Context(), 120*time.Second)
+	defer cancel()
+	webhookService.ListWebhookDeliveries(ctx, rw, req)
+}
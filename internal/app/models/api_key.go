@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// APIKey is a personal API key used by integrations. The key itself is only shown on creation.
+type APIKey struct {
+	ID     string   `json:"id"`
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// Prefix is the start of the key, to tell keys apart
+	Prefix     string     `json:"prefix"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// CreateAPIKeyRequest describes a new API key.
+type CreateAPIKeyRequest struct {
+	// Name to recognise the key by, e.g. the integration using it
+	Name string `json:"name" validate:"required"`
+	// Permissions the key is limited to, e.g. sightings:write
+	Scopes []string `json:"scopes" validate:"required"`
+	// Days until the key expires, the key never expires when omitted
+	ExpiresInDays int `json:"expires_in_days,omitempty"`
+}
+
+// CreateAPIKeyResponse returns the new key. It cannot be retrieved again.
+type CreateAPIKeyResponse struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// APIKeysResponse lists the API keys of a user.
+type APIKeysResponse struct {
+	APIKeys []APIKey `json:"api_keys"`
+}
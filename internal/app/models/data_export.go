@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// States of a personal data export.
+const (
+	DataExportPending = "pending"
+	DataExportReady   = "ready"
+	DataExportFailed  = "failed"
+)
+
+// Delivery states of a notification.
+const (
+	NotificationSent   = "sent"
+	NotificationFailed = "failed"
+)
+
+// DataExport is a zip of everything stored about a user, built in the background.
+// swagger:model
+type DataExport struct {
+	ID          string     `json:"export_id"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	// ExpiresAt is when the zip is deleted, set once it is ready
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	FilePath  string     `json:"-"`
+}
+
+// Notification is an entry of the notification history of a user.
+type Notification struct {
+	Channel string    `json:"channel"`
+	Subject string    `json:"subject"`
+	Status  string    `json:"status"`
+	SentAt  time.Time `json:"sent_at"`
+}
+
+// DataExportContent is everything written into a data export.
+type DataExportContent struct {
+	ExportedAt    time.Time
+	Profile       *Profile
+	Sightings     []Sighting
+	Sessions      []Session
+	Notifications []Notification
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// FollowedTiger is a tiger the user follows.
+// swagger:model
+type FollowedTiger struct {
+	TigerID    int       `json:"tiger_id"`
+	Name       string    `json:"name"`
+	FollowedAt time.Time `json:"followed_at"`
+}
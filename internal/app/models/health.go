@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// States reported by health checks.
+const (
+	HealthOK = "ok"
+	// HealthStalled means the component is running but is stuck in a run or has not completed one for too long
+	HealthStalled = "stalled"
+	HealthStopped = "stopped"
+)
+
+// DispatcherHealth reports the state of the notification dispatcher.
+// swagger:model
+type DispatcherHealth struct {
+	// Status is ok, stalled or stopped
+	//
+	// example: ok
+	Status    string    `json:"status"`
+	StartedAt time.Time `json:"started_at"`
+	// LastOutboxRunAt is when the outbox was last worked through
+	LastOutboxRunAt *time.Time `json:"last_outbox_run_at,omitempty"`
+	// LastWebhookRunAt is when the webhook deliveries were last worked through
+	LastWebhookRunAt *time.Time `json:"last_webhook_run_at,omitempty"`
+	// LastDigestRunAt is when due digests were last sent
+	LastDigestRunAt *time.Time `json:"last_digest_run_at,omitempty"`
+	// OutboxRunStartedAt is when the outbox loop started its current run, unset between runs
+	OutboxRunStartedAt *time.Time `json:"outbox_run_started_at,omitempty"`
+	// WebhookRunStartedAt is when the webhook loop started its current run, unset between runs
+	WebhookRunStartedAt *time.Time `json:"webhook_run_started_at,omitempty"`
+	// InFlight is true while either loop is delivering notifications
+	InFlight bool `json:"in_flight"`
+}
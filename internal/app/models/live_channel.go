@@ -0,0 +1,126 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Messages sent by the mobile app over the live channel.
+const (
+	LiveSubscribe   = "subscribe"
+	LiveUnsubscribe = "unsubscribe"
+	LivePing        = "ping"
+	// LiveAuth carries a fresh access token, the channel lives as long as the latest token the app sent
+	LiveAuth = "auth"
+)
+
+// Messages sent to the mobile app over the live channel.
+const (
+	LiveReady          = "ready"
+	LiveSubscribed     = "subscribed"
+	LiveUnsubscribed   = "unsubscribed"
+	LivePong           = "pong"
+	LiveAuthenticated  = "authenticated"
+	LiveSighting       = "sighting"
+	LiveNotification   = "notification"
+	LiveUploadProgress = "upload_progress"
+	LiveError          = "error"
+)
+
+// Upload states reported over the live channel.
+const (
+	UploadReceiving  = "receiving"
+	UploadProcessing = "processing"
+	UploadCompleted  = "completed"
+	UploadFailed     = "failed"
+)
+
+// maxSubscriptionIDLength keeps the IDs chosen by clients short
+const maxSubscriptionIDLength = 64
+
+// LiveClientMessage is a message of the mobile app.
+type LiveClientMessage struct {
+	Type string `json:"type"`
+	// Subscription is chosen by the app and names the subscription in sighting messages
+	Subscription string `json:"subscription,omitempty"`
+	TigerIDs     []int  `json:"tiger_ids,omitempty"`
+	// BBox is min_lon, min_lat, max_lon, max_lat
+	BBox []float64 `json:"bbox,omitempty"`
+	// Token is the access token of an auth message
+	Token string `json:"token,omitempty"`
+}
+
+// Filter validates a subscribe message and returns the sightings it subscribes to.
+func (m LiveClientMessage) Filter() (SightingStreamFilter, error) {
+	var filter SightingStreamFilter
+	if m.Subscription == "" || len(m.Subscription) > maxSubscriptionIDLength {
+		return filter, fmt.Errorf("subscription must be between 1 and %d characters", maxSubscriptionIDLength)
+	}
+	for _, tigerID := range m.TigerIDs {
+		if tigerID < 1 {
+			return filter, fmt.Errorf("invalid tiger_id %d", tigerID)
+		}
+	}
+	filter.TigerIDs = m.TigerIDs
+	if m.BBox != nil {
+		if len(m.BBox) != 4 {
+			return filter, fmt.Errorf("bbox must be min_lon, min_lat, max_lon, max_lat")
+		}
+		box := NewBoundingBox([4]float64(m.BBox))
+		if err := box.Validate(); err != nil {
+			return filter, err
+		}
+		filter.BBox = &box
+	}
+	return filter, nil
+}
+
+// LiveServerMessage is a message to the mobile app. Only the fields of its type are set.
+type LiveServerMessage struct {
+	Type string `json:"type"`
+	// Subscription is set on subscribed, unsubscribed and errors about a subscription
+	Subscription string `json:"subscription,omitempty"`
+	// Subscriptions are those a sighting matched
+	Subscriptions []string          `json:"subscriptions,omitempty"`
+	Event         string            `json:"event,omitempty"`
+	Sighting      *StreamSighting   `json:"sighting,omitempty"`
+	Notification  *UserNotification `json:"notification,omitempty"`
+	Upload        *UploadProgress   `json:"upload,omitempty"`
+	Message       string            `json:"message,omitempty"`
+	// HeartbeatSeconds is sent on ready, the app is disconnected if it does not answer pings for twice as long
+	HeartbeatSeconds int `json:"heartbeat_seconds,omitempty"`
+	// ExpiresAt is sent on ready and authenticated, the channel is closed then unless the app sends a fresh token
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// UserEvent is published for the live channels of one user, on whichever instance they are connected.
+type UserEvent struct {
+	UserID       uint              `json:"user_id"`
+	Notification *UserNotification `json:"notification,omitempty"`
+	Upload       *UploadProgress   `json:"upload,omitempty"`
+}
+
+// UserNotification tells a user about a verified sighting of a tiger they follow, with the location they may see.
+type UserNotification struct {
+	Type              string  `json:"type"`
+	SightingID        int     `json:"sighting_id"`
+	TigerID           int     `json:"tiger_id"`
+	TigerName         string  `json:"tiger_name"`
+	Latitude          float64 `json:"latitude"`
+	Longitude         float64 `json:"longitude"`
+	LocationPrecision string  `json:"location_precision"`
+	// LocationWithheld is set instead of coordinates for positions still under embargo
+	LocationWithheld bool      `json:"location_withheld,omitempty"`
+	SeenAt           time.Time `json:"seen_at"`
+}
+
+// UploadProgress reports an upload sent with an X-Upload-ID header.
+type UploadProgress struct {
+	UploadID string `json:"upload_id"`
+	State    string `json:"state"`
+	Received int64  `json:"received"`
+	// Total is the size of the upload, -1 if the client did not send it
+	Total int64 `json:"total"`
+	// Status is the HTTP status of the finished upload
+	Status int `json:"status,omitempty"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Scopes failed logins are counted in
+const (
+	ThrottleScopeUser = "user"
+	ThrottleScopeIP   = "ip"
+)
+
+// LoginThrottle is the failed login state of a username or client IP.
+type LoginThrottle struct {
+	Scope         string    `json:"scope"`
+	Key           string    `json:"key"`
+	Failures      int       `json:"failures"`
+	LastFailureAt time.Time `json:"last_failure_at"`
+	// LockedUntil is the time the next attempt is accepted, zero if attempts are accepted right away
+	LockedUntil time.Time `json:"locked_until,omitempty"`
+	// Locked is set once the failures reached the lockout threshold
+	Locked bool `json:"locked"`
+}
+
+// LoginThrottlesResponse lists usernames and client IPs with recent failed logins.
+type LoginThrottlesResponse struct {
+	Throttles []LoginThrottle `json:"throttles"`
+}
@@ -0,0 +1,31 @@
+package models
+
+// TOTPEnrollmentResponse carries the secret of a new authenticator app. The provisioning URI is usually shown as QR code.
+type TOTPEnrollmentResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// TOTPCodeRequest carries a code from the authenticator app.
+type TOTPCodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// RecoveryCodesResponse lists recovery codes. They are only shown once.
+type RecoveryCodesResponse struct {
+	Message       string   `json:"message,omitempty"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// MFALoginRequest completes a login with the MFA token from /api/v1/login and either
+// a code from the authenticator app or a recovery code.
+type MFALoginRequest struct {
+	MFAToken     string `json:"mfa_token" validate:"required"`
+	Code         string `json:"code,omitempty"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+}
+
+// MFAPolicy lists the roles that are only granted to users with two-factor authentication.
+type MFAPolicy struct {
+	RequiredRoles []string `json:"required_roles"`
+}
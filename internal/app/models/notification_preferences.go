@@ -0,0 +1,233 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+)
+
+// Notification channels a user can receive sighting notifications on.
+const (
+	ChannelEmail = "email"
+	// ChannelInApp pushes notifications to the open live channels of the mobile app
+	ChannelInApp = "in_app"
+)
+
+// How often sighting notifications are delivered.
+const (
+	FrequencyImmediate = "immediate"
+	FrequencyDaily     = "daily"
+	FrequencyWeekly    = "weekly"
+)
+
+var channels = []string{ChannelEmail, ChannelInApp}
+
+// NotificationPreferences control which sighting notifications a user receives and when.
+// swagger:model
+type NotificationPreferences struct {
+	// Channels notifications are sent on, empty to receive none
+	//
+	// example: ["email","in_app"]
+	Channels []string `json:"channels"`
+	// Frequency is immediate, daily or weekly
+	//
+	// example: immediate
+	Frequency string `json:"frequency"`
+	// Hour of the day (0-23) from which immediate notifications are held back until the quiet hours end. Quiet hours may wrap past midnight.
+	//
+	// example: 22
+	QuietHoursStart *int `json:"quiet_hours_start,omitempty"`
+	// Hour of the day (0-23) at which quiet hours end
+	//
+	// example: 7
+	QuietHoursEnd *int `json:"quiet_hours_end,omitempty"`
+	// IANA time zone the quiet hours are in
+	//
+	// example: Asia/Kolkata
+	Timezone string `json:"timezone"`
+	// Tigers the user gets no notifications about, even when following or having reported them
+	MutedTigers []int `json:"muted_tigers"`
+	// Hour of the day (0-23) daily and weekly digests are sent at, in the time zone of the user
+	//
+	// example: 8
+	DigestHour int `json:"digest_hour"`
+	// Day of the week weekly digests are sent on, 0 is Sunday
+	//
+	// example: 1
+	DigestWeekday int `json:"digest_weekday"`
+}
+
+// DefaultNotificationPreferences are the preferences of users who never changed them.
+func DefaultNotificationPreferences() NotificationPreferences {
+	return NotificationPreferences{
+		Channels:      []string{ChannelEmail},
+		Frequency:     FrequencyImmediate,
+		Timezone:      "UTC",
+		MutedTigers:   []int{},
+		DigestHour:    8,
+		DigestWeekday: int(time.Monday),
+	}
+}
+
+// Validate checks the preferences sent by a user.
+func (p *NotificationPreferences) Validate() error {
+	for _, channel := range p.Channels {
+		if !slices.Contains(channels, channel) {
+			return fmt.Errorf("unknown channel %s", channel)
+		}
+	}
+	switch p.Frequency {
+	case FrequencyImmediate, FrequencyDaily, FrequencyWeekly:
+	default:
+		return fmt.Errorf("frequency must be immediate, daily or weekly")
+	}
+	if (p.QuietHoursStart == nil) != (p.QuietHoursEnd == nil) {
+		return fmt.Errorf("quiet hours need both a start and an end")
+	}
+	for _, hour := range []*int{p.QuietHoursStart, p.QuietHoursEnd} {
+		if hour != nil && (*hour < 0 || *hour > 23) {
+			return fmt.Errorf("quiet hours must be between 0 and 23")
+		}
+	}
+	if p.DigestHour < 0 || p.DigestHour > 23 {
+		return fmt.Errorf("digest hour must be between 0 and 23")
+	}
+	if p.DigestWeekday < 0 || p.DigestWeekday > 6 {
+		return fmt.Errorf("digest weekday must be between 0 (Sunday) and 6")
+	}
+	if _, err := time.LoadLocation(p.Timezone); err != nil {
+		return fmt.Errorf("unknown time zone %s", p.Timezone)
+	}
+	return nil
+}
+
+// HasChannel reports whether notifications are sent on the channel.
+func (p *NotificationPreferences) HasChannel(channel string) bool {
+	return slices.Contains(p.Channels, channel)
+}
+
+// QuietAt reports whether t falls into the quiet hours of the user.
+func (p *NotificationPreferences) QuietAt(t time.Time) bool {
+	if p.QuietHoursStart == nil || p.QuietHoursEnd == nil || *p.QuietHoursStart == *p.QuietHoursEnd {
+		return false
+	}
+	if location, err := time.LoadLocation(p.Timezone); err == nil {
+		t = t.In(location)
+	}
+	start, end, hour := *p.QuietHoursStart, *p.QuietHoursEnd, t.Hour()
+	if start < end {
+		return hour >= start && hour < end
+	}
+	// e.g. 22 to 7
+	return hour >= start || hour < end
+}
+
+// QuietUntil returns when the quiet hours t falls into end, or the zero time if t is outside the quiet hours.
+func (p *NotificationPreferences) QuietUntil(t time.Time) time.Time {
+	if !p.QuietAt(t) {
+		return time.Time{}
+	}
+	location, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		location = time.UTC
+	}
+	local := t.In(location)
+	end := time.Date(local.Year(), local.Month(), local.Day(), *p.QuietHoursEnd, 0, 0, 0, location)
+	if !end.After(local) {
+		// e.g. at 23:00 during quiet hours from 22 to 7
+		end = end.AddDate(0, 0, 1)
+	}
+	return end
+}
+
+// DigestScheduledAt returns the latest time at or before now a digest was due for the user.
+// A digest is sent once the previous one went out before this time.
+func (p *NotificationPreferences) DigestScheduledAt(now time.Time) time.Time {
+	location, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		location = time.UTC
+	}
+	local := now.In(location)
+	scheduled := time.Date(local.Year(), local.Month(), local.Day(), p.DigestHour, 0, 0, 0, location)
+	if p.Frequency == FrequencyWeekly {
+		scheduled = scheduled.AddDate(0, 0, -((int(local.Weekday()) - p.DigestWeekday + 7) % 7))
+		if scheduled.After(local) {
+			scheduled = scheduled.AddDate(0, 0, -7)
+		}
+		return scheduled
+	}
+	if scheduled.After(local) {
+		scheduled = scheduled.AddDate(0, 0, -1)
+	}
+	return scheduled
+}
+
+// TigerSubscriber is a user to notify about sightings of a tiger, along with their preferences.
+type TigerSubscriber struct {
+	User        User
+	Preferences NotificationPreferences
+}
+
+// DigestRecipient is a user with sightings waiting for their next digest.
+type DigestRecipient struct {
+	User         User
+	Preferences  NotificationPreferences
+	LastDigestAt *time.Time
+}
+
+// DigestItem is a sighting waiting to be sent in a digest.
+type DigestItem struct {
+	ItemID     int
+	SightingID int
+	TigerID    int
+	TigerName  string
+	Timestamp  time.Time
+	Latitude   float64
+	Longitude  float64
+	HasImage   bool
+	// Verified is false for sightings rejected or disputed since they were queued, they are not sent
+	Verified bool
+}
+
+// DigestTiger summarises the sightings of one tiger in a digest.
+type DigestTiger struct {
+	TigerID   int
+	TigerName string
+	Sightings int
+	// Latest is the most recent sighting, its location is shown in the digest
+	Latest DigestItem
+	// ThumbnailSightingID is the most recent sighting with an image, 0 if none has one
+	ThumbnailSightingID int
+}
+
+// GroupDigestItems summarises the items per tiger, the tiger with the most recent sighting first.
+func GroupDigestItems(items []DigestItem) []DigestTiger {
+	var tigers []DigestTiger
+	index := map[int]int{}
+	for _, item := range items {
+		i, ok := index[item.TigerID]
+		if !ok {
+			i = len(tigers)
+			index[item.TigerID] = i
+			tigers = append(tigers, DigestTiger{TigerID: item.TigerID, TigerName: item.TigerName, Latest: item})
+		}
+		tiger := &tigers[i]
+		tiger.Sightings++
+		if item.Timestamp.After(tiger.Latest.Timestamp) {
+			tiger.Latest = item
+		}
+	}
+	for i := range tigers {
+		var thumbnail time.Time
+		for _, item := range items {
+			if item.TigerID == tigers[i].TigerID && item.HasImage && !item.Timestamp.Before(thumbnail) {
+				thumbnail, tigers[i].ThumbnailSightingID = item.Timestamp, item.SightingID
+			}
+		}
+	}
+	sort.SliceStable(tigers, func(i, j int) bool {
+		return tigers[i].Latest.Timestamp.After(tigers[j].Latest.Timestamp)
+	})
+	return tigers
+}
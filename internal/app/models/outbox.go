@@ -0,0 +1,73 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Kinds of messages in the notification outbox.
+const (
+	// OutboxSightingVerified is written when a sighting is verified and expanded into the notifications for it
+	OutboxSightingVerified = "sighting_verified"
+	// OutboxEmail is a rendered email to a single recipient
+	OutboxEmail = "email"
+	// OutboxInApp is an in-app notification held back until the quiet hours of its recipient end
+	OutboxInApp = "in_app"
+)
+
+// Delivery states of an outbox message.
+const (
+	OutboxPending = "pending"
+	OutboxSent    = "sent"
+	// OutboxDead messages failed too often and are only retried when replayed by an admin
+	OutboxDead = "dead"
+)
+
+// OutboxMessage is a notification waiting to be delivered, or a record of its delivery.
+// swagger:model
+type OutboxMessage struct {
+	ID     int    `json:"message_id"`
+	Kind   string `json:"kind"`
+	UserID uint   `json:"user_id,omitempty"`
+	// Payload is a SightingVerifiedEvent, an OutboxEmailMessage or a UserEvent, depending on the kind
+	Payload       json.RawMessage `json:"payload" swaggertype:"object"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	LastError     string          `json:"last_error,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	ProcessedAt   *time.Time      `json:"processed_at,omitempty"`
+}
+
+// OutboxResponse is a page of outbox messages.
+type OutboxResponse struct {
+	Messages []OutboxMessage `json:"messages"`
+	Offset   int             `json:"offset"`
+}
+
+// SightingVerifiedEvent is the payload of OutboxSightingVerified messages.
+type SightingVerifiedEvent struct {
+	SightingID int `json:"sighting_id"`
+}
+
+// OutboxDelivery is a message queued for one recipient when an event is expanded.
+type OutboxDelivery struct {
+	Kind    string
+	UserID  uint
+	Payload any
+	// NotBefore defers the delivery, e.g. until the quiet hours of the recipient end. Zero delivers right away.
+	NotBefore time.Time
+}
+
+// OutboxEmailMessage is the payload of OutboxEmail messages. It is rendered when queued, so retries send the same email.
+type OutboxEmailMessage struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+	// HTMLBody is sent as alternative to the plain text Body if set
+	HTMLBody string `json:"html_body,omitempty"`
+	// SightingID names the sighting whose photo is embedded in the HTML body
+	SightingID int `json:"sighting_id,omitempty"`
+	// UnsubscribeURL is sent as List-Unsubscribe header if set
+	UnsubscribeURL string `json:"unsubscribe_url,omitempty"`
+}
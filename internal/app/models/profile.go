@@ -0,0 +1,47 @@
+package models
+
+// Profile is the account of the authenticated user as returned by /api/v1/me.
+type Profile struct {
+	Username      string   `json:"username"`
+	DisplayName   string   `json:"display_name,omitempty"`
+	Email         string   `json:"email"`
+	EmailVerified bool     `json:"email_verified"`
+	Roles         []string `json:"roles"`
+	// HasPassword is false for accounts created through an OpenID Connect provider until a password is set
+	HasPassword bool `json:"has_password"`
+	TOTPEnabled bool `json:"two_factor_enabled"`
+	// NotifyReportedTigers sends notifications about tigers the user reported, not only those they follow
+	NotifyReportedTigers bool `json:"notify_reported_tigers"`
+	// Language emails are sent in, e.g. en, hi or bn
+	Language       string          `json:"language"`
+	FollowedTigers []FollowedTiger `json:"followed_tigers"`
+}
+
+// UpdateProfileRequest changes the account of the authenticated user. Fields left out are not changed.
+// Changing the email or password requires the current password, if the account has one.
+type UpdateProfileRequest struct {
+	DisplayName          *string `json:"display_name,omitempty"`
+	Email                *string `json:"email,omitempty"`
+	Password             *string `json:"password,omitempty"`
+	NotifyReportedTigers *bool   `json:"notify_reported_tigers,omitempty"`
+	Language             *string `json:"language,omitempty"`
+	CurrentPassword      string  `json:"current_password,omitempty"`
+	// TOTPCode confirms the change instead of the password for accounts without one that use two-factor authentication
+	TOTPCode string `json:"totp_code,omitempty"`
+}
+
+// DeleteAccountRequest confirms the deletion of the account with the current password, or a code of the
+// authenticator app for accounts without one.
+type DeleteAccountRequest struct {
+	CurrentPassword string `json:"current_password"`
+	TOTPCode        string `json:"totp_code,omitempty"`
+}
+
+// ProfileUpdate holds the validated changes applied by the repository. Nil fields are not changed.
+type ProfileUpdate struct {
+	DisplayName          *string
+	Email                *string
+	PasswordHash         *string
+	NotifyReportedTigers *bool
+	Language             *string
+}
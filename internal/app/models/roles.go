@@ -0,0 +1,104 @@
+package models
+
+// Roles a user can hold. New users are reporters.
+const (
+	RoleViewer     = "viewer"
+	RoleReporter   = "reporter"
+	RoleRanger     = "ranger"
+	RoleResearcher = "researcher"
+	RoleAdmin      = "admin"
+)
+
+// Permissions checked by the API routes.
+const (
+	PermissionTigersRead      = "tigers:read"
+	PermissionTigersWrite     = "tigers:write"
+	PermissionTigersDelete    = "tigers:delete"
+	PermissionSightingsRead   = "sightings:read"
+	PermissionSightingsWrite  = "sightings:write"
+	PermissionSightingsReview = "sightings:review"
+	PermissionSightingsDelete = "sightings:delete"
+	PermissionLocationsExact  = "locations:exact"
+	PermissionUsersManage     = "users:manage"
+	// PermissionNotificationsManage allows inspecting and replaying the notification outbox
+	PermissionNotificationsManage = "notifications:manage"
+	// PermissionWebhooksManage allows managing webhooks, whose events carry exact locations
+	PermissionWebhooksManage = "webhooks:manage"
+)
+
+// permissions lists every permission, API keys can be scoped to any of them.
+var permissions = []string{
+	PermissionTigersRead, PermissionTigersWrite, PermissionTigersDelete, PermissionSightingsRead,
+	PermissionSightingsWrite, PermissionSightingsReview, PermissionSightingsDelete, PermissionLocationsExact,
+	PermissionUsersManage, PermissionNotificationsManage, PermissionWebhooksManage,
+}
+
+// rolePermissions maps every role to the permissions it grants.
+var rolePermissions = map[string][]string{
+	RoleViewer: {
+		PermissionTigersRead, PermissionSightingsRead,
+	},
+	RoleReporter: {
+		PermissionTigersRead, PermissionSightingsRead, PermissionSightingsWrite,
+	},
+	RoleRanger: {
+		PermissionTigersRead, PermissionTigersWrite, PermissionSightingsRead, PermissionSightingsWrite,
+		PermissionSightingsReview, PermissionLocationsExact,
+	},
+	RoleResearcher: {
+		PermissionTigersRead, PermissionSightingsRead, PermissionSightingsWrite,
+		PermissionSightingsReview, PermissionLocationsExact,
+	},
+	RoleAdmin: {
+		PermissionTigersRead, PermissionTigersWrite, PermissionTigersDelete, PermissionSightingsRead,
+		PermissionSightingsWrite, PermissionSightingsReview, PermissionSightingsDelete, PermissionLocationsExact,
+		PermissionUsersManage, PermissionNotificationsManage, PermissionWebhooksManage,
+	},
+}
+
+// IsValidRole reports whether the role is known.
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// IsValidPermission reports whether the permission is known.
+func IsValidPermission(permission string) bool {
+	return containsPermission(permissions, permission)
+}
+
+// HasPermission reports whether any of the roles grants the permission.
+func HasPermission(roles []string, permission string) bool {
+	for _, role := range roles {
+		if containsPermission(rolePermissions[role], permission) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsPermission(permissions []string, permission string) bool {
+	for _, granted := range permissions {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// RoleRequest represents a role granted to a user.
+// swagger:model
+type RoleRequest struct {
+	// Role to grant, one of viewer, reporter, ranger, researcher, admin
+	//
+	// required: true
+	// example: ranger
+	Role string `json:"role"`
+}
+
+// UserRolesResponse represents the roles held by a user.
+// swagger:model
+type UserRolesResponse struct {
+	Username string   `json:"username"`
+	Roles    []string `json:"roles"`
+}
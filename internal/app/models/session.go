@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Session represents a device or browser the user is logged in from.
+// swagger:model
+type Session struct {
+	ID         string    `json:"session_id"`
+	UserID     uint      `json:"-"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// RevokedAt is set for sessions that were logged out, only listed in data exports
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	// Current is set for the session the request was made with
+	Current bool `json:"current,omitempty"`
+}
+
+// SessionsResponse represents the active sessions of a user.
+// swagger:model
+type SessionsResponse struct {
+	Sessions []Session `json:"sessions"`
+}
+
+// RefreshRequest represents a request for a new access token.
+// swagger:model
+type RefreshRequest struct {
+	// Refresh token issued at login, can also be sent as the refresh_token cookie
+	//
+	// example: 3q2-7wX0...
+	RefreshToken string `json:"refresh_token"`
+}
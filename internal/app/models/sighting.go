@@ -5,6 +5,15 @@ import (
 	"io"
 )
 
+// Moderation states of a sighting. New sightings start as pending and are
+// only shown to the public once a reviewer has verified them.
+const (
+	SightingPending  = "pending"
+	SightingVerified = "verified"
+	SightingRejected = "rejected"
+	SightingDisputed = "disputed"
+)
+
 // Sighting represents a sighting of a tiger.
 type Sighting struct {
 	ID              int      `json:"sighting_id"`
@@ -18,6 +27,9 @@ type Sighting struct {
 	User      *User  `json:"user,omitempty"` // Relationship with the user who reported the sighting
 	TigerName string `json:"tigername,omitempty"`
 	Image     string `json:"encoded_image,omitempty"`
+	// Moderation state of the sighting (pending, verified, rejected, disputed)
+	Status       string `json:"status,omitempty"`
+	ReviewReason string `json:"review_reason,omitempty"`
 }
 
 func (s *Sighting) FormJson(reader io.Reader) error {
@@ -92,4 +104,34 @@ type SightingsResponse struct {
 	// required: true
 	// example: 0
 	Offset int `json:"offset"`
+
+	// Precision of the returned coordinates, either exact or coarse
+	//
+	// example: coarse
+	LocationPrecision string `json:"location_precision,omitempty"`
+}
+
+// ReviewSightingRequest represents a reviewer decision on a sighting.
+// swagger:model
+type ReviewSightingRequest struct {
+	// Decision of the reviewer, either verified or rejected
+	//
+	// required: true
+	// example: verified
+	Decision string `json:"decision"`
+
+	// Reason for the decision, required when rejecting
+	//
+	// example: Image does not show a tiger
+	Reason string `json:"reason"`
+}
+
+// DisputeSightingRequest represents a user disputing a verified sighting.
+// swagger:model
+type DisputeSightingRequest struct {
+	// Reason why the sighting is disputed
+	//
+	// required: true
+	// example: Same animal was reported twice
+	Reason string `json:"reason"`
 }
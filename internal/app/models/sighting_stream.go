@@ -0,0 +1,117 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Events of the live sighting feed.
+const (
+	// StreamSightingCreated is only streamed to reviewers, new sightings are private until verified
+	StreamSightingCreated  = "sighting.created"
+	StreamSightingVerified = "sighting.verified"
+)
+
+// SightingStreamEvent is published when a sighting is created or verified, and shared between instances.
+type SightingStreamEvent struct {
+	// ID orders the events for Last-Event-ID, it comes from a database sequence shared by all instances
+	ID       int64          `json:"id"`
+	Type     string         `json:"type"`
+	Sighting StreamSighting `json:"sighting"`
+}
+
+// StreamSighting is the data of a live feed event, as seen by the receiving caller.
+// swagger:model
+type StreamSighting struct {
+	SightingID int       `json:"sighting_id"`
+	TigerID    int       `json:"tiger_id"`
+	TigerName  string    `json:"tiger_name"`
+	Latitude   float64   `json:"latitude"`
+	Longitude  float64   `json:"longitude"`
+	SeenAt     time.Time `json:"seen_at"`
+	Status     string    `json:"status"`
+	// LocationPrecision is exact or coarse, depending on the caller
+	LocationPrecision string `json:"location_precision,omitempty"`
+	// LocationWithheld is set instead of coordinates for positions still under embargo
+	LocationWithheld bool `json:"location_withheld,omitempty"`
+}
+
+// BoundingBox is an area between two longitudes and two latitudes.
+type BoundingBox struct {
+	MinLon, MinLat, MaxLon, MaxLat float64
+}
+
+// NewBoundingBox creates the box from min_lon, min_lat, max_lon and max_lat.
+func NewBoundingBox(values [4]float64) BoundingBox {
+	return BoundingBox{MinLon: values[0], MinLat: values[1], MaxLon: values[2], MaxLat: values[3]}
+}
+
+// Validate checks that the box is an area on the map.
+func (b BoundingBox) Validate() error {
+	if b.MinLon > b.MaxLon || b.MinLat > b.MaxLat || b.MinLat < -90 || b.MaxLat > 90 || b.MinLon < -180 || b.MaxLon > 180 {
+		return fmt.Errorf("bbox is not a valid area")
+	}
+	return nil
+}
+
+// Contains reports whether the coordinates are inside the box, edges included.
+func (b BoundingBox) Contains(lat, lon float64) bool {
+	return lat >= b.MinLat && lat <= b.MaxLat && lon >= b.MinLon && lon <= b.MaxLon
+}
+
+// SightingStreamFilter limits the live feed to some tigers or an area. An empty filter matches every sighting.
+type SightingStreamFilter struct {
+	TigerIDs []int
+	BBox     *BoundingBox
+}
+
+// ParseSightingStreamFilter reads the tiger_id (repeated or comma separated) and
+// bbox (min_lon,min_lat,max_lon,max_lat) query parameters.
+func ParseSightingStreamFilter(query url.Values) (SightingStreamFilter, error) {
+	var filter SightingStreamFilter
+	for _, value := range query["tiger_id"] {
+		for _, part := range strings.Split(value, ",") {
+			tigerID, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || tigerID < 1 {
+				return filter, fmt.Errorf("invalid tiger_id %q", part)
+			}
+			filter.TigerIDs = append(filter.TigerIDs, tigerID)
+		}
+	}
+	if bbox := query.Get("bbox"); bbox != "" {
+		parts := strings.Split(bbox, ",")
+		if len(parts) != 4 {
+			return filter, fmt.Errorf("bbox must be min_lon,min_lat,max_lon,max_lat")
+		}
+		var values [4]float64
+		for i, part := range parts {
+			value, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil {
+				return filter, fmt.Errorf("bbox must be min_lon,min_lat,max_lon,max_lat")
+			}
+			values[i] = value
+		}
+		box := NewBoundingBox(values)
+		if err := box.Validate(); err != nil {
+			return filter, err
+		}
+		filter.BBox = &box
+	}
+	return filter, nil
+}
+
+// Matches reports whether the sighting, as seen by the caller, passes the filter. Sightings whose location is
+// withheld never match an area, so the filter cannot be used to narrow down their position.
+func (f SightingStreamFilter) Matches(sighting StreamSighting) bool {
+	if len(f.TigerIDs) > 0 && !slices.Contains(f.TigerIDs, sighting.TigerID) {
+		return false
+	}
+	if f.BBox != nil && (sighting.LocationWithheld || !f.BBox.Contains(sighting.Latitude, sighting.Longitude)) {
+		return false
+	}
+	return true
+}
@@ -20,6 +20,8 @@ type Tiger struct {
 		Longitude float64 `json:"last_seen_coordinates_lon"`
 	} `json:"last_coordinates" validate:"required"`
 	Sightings []Sighting `json:"sightings,omitempty" swaggerignore:"true"` // Relationship with sightings
+	// LocationWithheld is set when the last position is still under embargo for the caller
+	LocationWithheld bool `json:"location_withheld,omitempty"`
 }
 
 // TigersResponse represents the response containing a list of tigers and an offset.
@@ -30,6 +32,9 @@ type TigersResponse struct {
 
 	// Offset is the offset for paginating the list.
 	Offset int `json:"offset"`
+
+	// LocationPrecision is either exact or coarse depending on the caller.
+	LocationPrecision string `json:"location_precision,omitempty"`
 }
 
 type CustomTime struct {
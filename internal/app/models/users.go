@@ -27,6 +27,14 @@ type User struct {
 	// required: true
 	Email     string     `json:"email" validate:"required"`
 	Sightings []Sighting `json:"sightings,omitempty" swaggerignore:"true"` // Relationship with sightings
+	// Roles held by the user, assigned by admins
+	Roles []string `json:"roles,omitempty" swaggerignore:"true"`
+	// EmailVerified is set once the user opened the verification link mailed on registration
+	EmailVerified bool `json:"email_verified" swaggerignore:"true"`
+	// TOTPEnabled is set once the user confirmed their authenticator app
+	TOTPEnabled bool `json:"-"`
+	// Language emails are sent in
+	Language string `json:"-"`
 }
 
 // swagger:parameters CreateUserRequest
@@ -44,10 +52,44 @@ type UserResponse struct {
 }
 
 type Claims struct {
-	Username string `json:"username"`
+	Username string   `json:"username"`
+	Roles    []string `json:"roles"`
+	// SessionID ties the access token to the session it was issued for
+	SessionID string `json:"sid,omitempty"`
+	// Scopes limit the permissions of requests authenticated with an API key
+	Scopes []string `json:"scopes,omitempty"`
+	// APIKeyID is set when the request was authenticated with an API key instead of a login
+	APIKeyID string `json:"-"`
 	jwt.RegisteredClaims
 }
 
+// HasPermission reports whether the roles in the token grant the permission.
+// API keys additionally need the permission in their scopes.
+func (c *Claims) HasPermission(permission string) bool {
+	if c.APIKeyID != "" && !containsPermission(c.Scopes, permission) {
+		return false
+	}
+	return HasPermission(c.Roles, permission)
+}
+
+// ActionClaims are the claims of single purpose tokens mailed to users, such as email verification links.
+// They always carry an audience naming their purpose, which access tokens never do.
+type ActionClaims struct {
+	Email string `json:"email"`
+	jwt.RegisteredClaims
+}
+
+// ForgotPasswordRequest asks for a password reset token to be mailed.
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required"`
+}
+
+// ResetPasswordRequest sets a new password using a mailed reset token.
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
 func (u *User) FormJson(reader io.Reader) error {
 	e := json.NewDecoder(reader)
 	return e.Decode(u)
@@ -60,8 +102,15 @@ func (u *User) ToJSON(w io.Writer) error {
 
 // LoginResponse represents a login response for user login.
 type LoginResponse struct {
-	Message string `json:"message,omitempty"`
-	Token   string `json:"token,omitempty"`
+	Message      string `json:"message,omitempty"`
+	Token        string `json:"token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	// ExpiresIn is the lifetime of the access token in seconds
+	ExpiresIn int `json:"expires_in,omitempty"`
+	// MFARequired is set when the password was correct but a second factor is needed.
+	// The MFA token is then exchanged for the access token at /api/v1/login/mfa.
+	MFARequired bool   `json:"mfa_required,omitempty"`
+	MFAToken    string `json:"mfa_token,omitempty"`
 }
 
 // GeneralResponse represents a login response for user login.
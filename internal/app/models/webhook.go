@@ -0,0 +1,171 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/webhook"
+)
+
+// Events partner organisations can receive with webhooks.
+const (
+	WebhookSightingCreated  = "sighting.created"
+	WebhookSightingVerified = "sighting.verified"
+	WebhookTigerCreated     = "tiger.created"
+)
+
+// Delivery states of a webhook event.
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed"
+)
+
+var webhookEventTypes = []string{WebhookSightingCreated, WebhookSightingVerified, WebhookTigerCreated}
+
+// Webhook is an endpoint receiving events. The secret is only shown on creation.
+// swagger:model
+type Webhook struct {
+	ID  int    `json:"webhook_id"`
+	URL string `json:"url"`
+	// example: ["sighting.verified"]
+	EventTypes []string `json:"event_types"`
+	// Active is false once the webhook was disabled, by its owner or after too many failed deliveries
+	Active              bool       `json:"active"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	CreatedAt           time.Time  `json:"created_at"`
+	DisabledAt          *time.Time `json:"disabled_at,omitempty"`
+}
+
+// CreateWebhookRequest describes a new webhook.
+type CreateWebhookRequest struct {
+	// http(s) URL events are POSTed to
+	URL string `json:"url" validate:"required"`
+	// Events to receive: sighting.created, sighting.verified or tiger.created
+	EventTypes []string `json:"event_types" validate:"required"`
+	// Secret the deliveries are signed with, generated when omitted
+	Secret string `json:"secret,omitempty"`
+}
+
+// CreateWebhookResponse returns the new webhook with its secret, which cannot be retrieved again.
+type CreateWebhookResponse struct {
+	Webhook
+	Secret string `json:"secret"`
+}
+
+// UpdateWebhookRequest changes a webhook, omitted fields are left as they are.
+type UpdateWebhookRequest struct {
+	URL        *string  `json:"url,omitempty"`
+	EventTypes []string `json:"event_types,omitempty"`
+	// Set to true to enable a disabled webhook again, which also resets its failures
+	Active *bool `json:"active,omitempty"`
+}
+
+// WebhooksResponse lists the webhooks of a user.
+type WebhooksResponse struct {
+	Webhooks []Webhook `json:"webhooks"`
+}
+
+// WebhookEvent is the JSON body of a delivery.
+type WebhookEvent struct {
+	// ID is the same for the deliveries of the event to different webhooks, receivers use it to drop duplicates
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	// Data is a WebhookSighting or a WebhookTiger, depending on the type
+	Data any `json:"data"`
+}
+
+// WebhookSighting is the data of sighting events.
+type WebhookSighting struct {
+	SightingID int       `json:"sighting_id"`
+	TigerID    int       `json:"tiger_id"`
+	TigerName  string    `json:"tiger_name"`
+	Latitude   float64   `json:"latitude"`
+	Longitude  float64   `json:"longitude"`
+	SeenAt     time.Time `json:"seen_at"`
+	Status     string    `json:"status"`
+}
+
+// WebhookTiger is the data of tiger events.
+type WebhookTiger struct {
+	TigerID    int       `json:"tiger_id"`
+	Name       string    `json:"name"`
+	Latitude   float64   `json:"latitude"`
+	Longitude  float64   `json:"longitude"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
+
+// WebhookDelivery is an entry of the delivery log of a webhook.
+// swagger:model
+type WebhookDelivery struct {
+	ID        int             `json:"delivery_id"`
+	EventID   string          `json:"event_id"`
+	EventType string          `json:"event_type"`
+	Payload   json.RawMessage `json:"payload" swaggertype:"object"`
+	Status    string          `json:"status"`
+	Attempts  int             `json:"attempts"`
+	// ResponseStatus is the HTTP status of the last attempt, 0 if no response was received
+	ResponseStatus int        `json:"response_status,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
+
+// WebhookDeliveriesResponse is a page of the delivery log.
+type WebhookDeliveriesResponse struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+	Offset     int               `json:"offset"`
+}
+
+// PendingWebhookDelivery is a claimed delivery along with what is needed to send it.
+type PendingWebhookDelivery struct {
+	WebhookDelivery
+	WebhookID int
+	URL       string
+	Secret    string
+	// OwnerRoles are the roles the owner of the webhook holds now
+	OwnerRoles []string
+}
+
+// NewWebhookEvent creates an event with a new random ID.
+func NewWebhookEvent(eventType string, data any) (WebhookEvent, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return WebhookEvent{}, err
+	}
+	return WebhookEvent{ID: hex.EncodeToString(id), Type: eventType, CreatedAt: time.Now().UTC(), Data: data}, nil
+}
+
+// ValidateWebhook checks the URL and event types of a webhook. URLs must use https and must not name a local or
+// private host. Names are checked again when delivering, against the addresses they resolve to then.
+func ValidateWebhook(rawURL string, eventTypes []string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Scheme != "https" || parsed.Hostname() == "" {
+		return fmt.Errorf("url must be an absolute https URL")
+	}
+	host := strings.ToLower(strings.TrimSuffix(parsed.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("url must not point to a local or private address")
+	}
+	if ip := net.ParseIP(host); ip != nil && !webhook.IsPublicAddress(ip) {
+		return fmt.Errorf("url must not point to a local or private address")
+	}
+	if len(eventTypes) == 0 {
+		return fmt.Errorf("at least one event type is required")
+	}
+	for _, eventType := range eventTypes {
+		if !slices.Contains(webhookEventTypes, eventType) {
+			return fmt.Errorf("unknown event type %s", eventType)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database/repositories"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	apiKeyHeader = "X-API-Key"
+	// apiKeyPrefix makes keys recognisable, e.g. for secret scanners
+	apiKeyPrefix = "thk_"
+)
+
+type APIKeyService struct {
+	logger *logrus.Logger
+	db     *sql.DB
+}
+
+func NewAPIKeyService(logger *logrus.Logger, db *sql.DB) *APIKeyService {
+	return &APIKeyService{logger: logger, db: db}
+}
+
+// CreateAPIKey godoc
+// @Summary Create an API key
+// @Description Create a personal API key for integrations, sent in the X-API-Key header. The key is limited to its scopes and the roles of its owner. It is only shown in this response.
+// @Tags API keys
+// @Accept json
+// @Produce json
+// @Param apiKey body models.CreateAPIKeyRequest true "Name, scopes and expiry of the key"
+// @Success 201 {object} models.CreateAPIKeyResponse
+// @Failure 400 {object} models.ErrorResponse "Invalid name, scope or expiry"
+// @Failure 401 {object} models.ErrorResponse
+// @Security Authorization
+// @Router /api/v1/api-keys [post]
+func (as *APIKeyService) CreateAPIKey(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	user, ok := NewUserService(as.logger, as.db).currentUser(w, r)
+	if !ok {
+		return
+	}
+	var keyRequest models.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&keyRequest); err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Invalid JSON format", Status: http.StatusBadRequest})
+		return
+	}
+	keyRequest.Name = strings.TrimSpace(keyRequest.Name)
+	if keyRequest.Name == "" || len(keyRequest.Name) > 100 {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Name is required and at most 100 characters", Status: http.StatusBadRequest})
+		return
+	}
+	if len(keyRequest.Scopes) == 0 {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "At least one scope is required", Status: http.StatusBadRequest})
+		return
+	}
+	if keyRequest.ExpiresInDays < 0 {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Expiry must not be negative", Status: http.StatusBadRequest})
+		return
+	}
+	roles := effectiveRoles(as.db, as.logger, user)
+	for _, scope := range keyRequest.Scopes {
+		if !models.IsValidPermission(scope) {
+			models.HandleErrorResponse(w, models.ErrorResponse{Message: "Unknown scope " + scope, Status: http.StatusBadRequest})
+			return
+		}
+		// A key cannot do more than its owner
+		if !models.HasPermission(roles, scope) {
+			models.HandleErrorResponse(w, models.ErrorResponse{Message: "Your roles do not grant the scope " + scope, Status: http.StatusBadRequest})
+			return
+		}
+	}
+	keyID, err := generateRandomToken(9)
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to create the API key. Please try again", Status: http.StatusInternalServerError})
+		return
+	}
+	secret, err := generateRandomToken(32)
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to create the API key. Please try again", Status: http.StatusInternalServerError})
+		return
+	}
+	key := apiKeyPrefix + keyID + "." + secret
+	apiKey := models.APIKey{ID: keyID, Name: keyRequest.Name, Scopes: keyRequest.Scopes, Prefix: apiKeyPrefix + keyID}
+	if keyRequest.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, keyRequest.ExpiresInDays)
+		apiKey.ExpiresAt = &expiresAt
+	}
+	if err := repositories.NewAPIKeyRepository(as.db, as.logger).CreateAPIKey(&apiKey, user.ID, hashToken(key)); err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to create the API key. Please try again", Status: http.StatusInternalServerError})
+		return
+	}
+	as.logger.Infof("API key %s created for %s with scopes %v", apiKey.ID, user.Username, apiKey.Scopes)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(models.CreateAPIKeyResponse{APIKey: apiKey, Key: key})
+}
+
+// ListAPIKeys godoc
+// @Summary List API keys
+// @Description List the active API keys of the authenticated user. The keys themselves are not returned.
+// @Tags API keys
+// @Produce json
+// @Success 200 {object} models.APIKeysResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Security Authorization
+// @Router /api/v1/api-keys [get]
+func (as *APIKeyService) ListAPIKeys(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	user, ok := NewUserService(as.logger, as.db).currentUser(w, r)
+	if !ok {
+		return
+	}
+	apiKeys, err := repositories.NewAPIKeyRepository(as.db, as.logger).ListAPIKeys(user.ID)
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to fetch API keys. Please try again", Status: http.StatusInternalServerError})
+		return
+	}
+	for i := range apiKeys {
+		apiKeys[i].Prefix = apiKeyPrefix + apiKeys[i].ID
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(models.APIKeysResponse{APIKeys: apiKeys})
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke an API key
+// @Description Revoke an API key of the authenticated user. It stops working immediately.
+// @Tags API keys
+// @Produce json
+// @Param id path string true "API key ID"
+// @Success 200 {object} models.GeneralResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse "API key not found"
+// @Security Authorization
+// @Router /api/v1/api-keys/{id} [delete]
+func (as *APIKeyService) RevokeAPIKey(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	user, ok := NewUserService(as.logger, as.db).currentUser(w, r)
+	if !ok {
+		return
+	}
+	keyID := mux.Vars(r)["id"]
+	if err := repositories.NewAPIKeyRepository(as.db, as.logger).RevokeAPIKey(keyID, user.ID); err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "API key not found", Status: http.StatusNotFound})
+		return
+	}
+	as.logger.Infof("API key %s revoked by %s", keyID, user.Username)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(models.GeneralResponse{Message: "API key revoked"})
+}
+
+// validateAPIKey resolves the claims of a request authenticated with an API key. The roles are read
+// from the owner on every request, so role changes apply to keys right away.
+func validateAPIKey(key string) (*models.Claims, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, errors.New("invalid API key")
+	}
+	db := database.GetDB()
+	if db == nil {
+		return nil, errors.New("database connection is not available")
+	}
+	logger := logrus.StandardLogger()
+	apiKeyRepo := repositories.NewAPIKeyRepository(db, logger)
+	apiKey, user, err := apiKeyRepo.GetActiveAPIKey(hashToken(key))
+	if err != nil {
+		return nil, errors.New("invalid, expired or revoked API key")
+	}
+	apiKeyRepo.TouchAPIKey(apiKey.ID)
+	return &models.Claims{
+		Username: user.Username,
+		Roles:    effectiveRoles(db, logger, user),
+		Scopes:   apiKey.Scopes,
+		APIKeyID: apiKey.ID,
+	}, nil
+}
@@ -0,0 +1,290 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"text/template"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database/repositories"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/config"
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/messaging"
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultDataExportDir = "./exports"
+	defaultDataExportTTL = 72 * time.Hour
+	// defaultDataExportTimeout is how long an export may be pending before it is considered lost
+	defaultDataExportTimeout = 30 * time.Minute
+	// exportPageSize is how many sightings are read from the database at once while building an export
+	exportPageSize = 100
+)
+
+var dataExportEmailTemplate = template.Must(template.New("dataExportEmail").Parse(`
+Dear {{.Username}},
+
+The export of your personal data you requested from {{.Organization}} is ready. Log in and download it from:
+
+{{.Link}}
+
+The export is deleted after {{.ExpiresIn}}. If you did not request it, please change your password.
+
+Best regards,
+
+{{.Organization}}
+`))
+
+// RequestDataExport godoc
+// @Summary Export the personal data of the authenticated user
+// @Description Starts building a zip with the profile, all sightings with their images, the session history and the notification history.
+// @Description A download link is emailed once the export is ready.
+// @Tags Account
+// @Produce json
+// @Success 202 {object} models.DataExport
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse "An export is already being prepared"
+// @Security Authorization
+// @Router /api/v1/me/export [get]
+func (u *UserService) RequestDataExport(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	user, ok := u.currentUser(w, r)
+	if !ok {
+		return
+	}
+	exportRepo := repositories.NewDataExportRepository(u.db, u.logger)
+	// Expired exports are useless, drop them while we are here
+	if paths, err := exportRepo.DeleteExpiredExports(dataExportTimeout()); err == nil {
+		u.removeExportFiles(paths)
+	}
+	exportID, err := generateRandomToken(16)
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to start the export. Please try again", Status: http.StatusInternalServerError})
+		return
+	}
+	export := &models.DataExport{ID: exportID}
+	if err := exportRepo.CreateDataExport(export, user.ID, dataExportTimeout()); err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "An export is already being prepared", Status: http.StatusConflict})
+		return
+	}
+	u.logger.Infof("Data export %s requested by %s", export.ID, user.Username)
+	go u.buildDataExport(*user, export.ID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(export)
+}
+
+// DownloadDataExport godoc
+// @Summary Download a personal data export
+// @Description Returns the zip once the export is ready. While it is being prepared the state of the export is returned instead.
+// @Tags Account
+// @Produce application/zip
+// @Produce json
+// @Param id path string true "Export ID"
+// @Success 200 {file} file "Zip of the personal data"
+// @Success 202 {object} models.DataExport "Export is being prepared"
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse "Export not found or expired"
+// @Failure 500 {object} models.ErrorResponse "Export failed"
+// @Security Authorization
+// @Router /api/v1/me/export/{id} [get]
+func (u *UserService) DownloadDataExport(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	user, ok := u.currentUser(w, r)
+	if !ok {
+		return
+	}
+	export, err := repositories.NewDataExportRepository(u.db, u.logger).GetDataExport(mux.Vars(r)["id"], user.ID)
+	if err != nil || (export.ExpiresAt != nil && export.ExpiresAt.Before(time.Now())) {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Export not found or expired", Status: http.StatusNotFound})
+		return
+	}
+	if export.Status == models.DataExportPending && time.Since(export.CreatedAt) > dataExportTimeout() {
+		export.Status = models.DataExportFailed
+	}
+	switch export.Status {
+	case models.DataExportPending:
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(export)
+		return
+	case models.DataExportFailed:
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "The export failed. Please request a new one", Status: http.StatusInternalServerError})
+		return
+	}
+	file, err := os.Open(export.FilePath)
+	if err != nil {
+		u.logger.Error("Failed to open data export:", err)
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Export not found or expired", Status: http.StatusNotFound})
+		return
+	}
+	defer file.Close()
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "tigerhall-export-"+user.Username+".zip"))
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, file); err != nil {
+		u.logger.Error("Failed to send data export:", err)
+	}
+}
+
+// buildDataExport writes the zip of the user's data to DATA_EXPORT_DIR and mails the download link.
+func (u *UserService) buildDataExport(user models.User, exportID string) {
+	exportRepo := repositories.NewDataExportRepository(u.db, u.logger)
+	filePath, err := u.writeDataExportFile(user.ID, exportID)
+	if err != nil {
+		u.logger.Errorf("Failed to build data export %s: %v", exportID, err)
+		_ = exportRepo.MarkExportFailed(exportID)
+		return
+	}
+	ttl := dataExportTTL()
+	if err := exportRepo.MarkExportReady(exportID, filePath, time.Now().Add(ttl)); err != nil {
+		u.logger.Errorf("Failed to complete data export %s: %v", exportID, err)
+		os.Remove(filePath)
+		return
+	}
+	u.logger.Infof("Data export %s ready", exportID)
+	var body bytes.Buffer
+	err = dataExportEmailTemplate.Execute(&body, map[string]string{
+		"Username":     user.Username,
+		"Link":         publicURL("/api/v1/me/export/"+exportID, nil),
+		"ExpiresIn":    ttl.String(),
+		"Organization": "Tigerhall-Kittens",
+	})
+	if err != nil {
+		u.logger.Error("Error executing email template:", err)
+		return
+	}
+	if err := messaging.NewEmailHandler(u.logger).SendEmailNotification([]string{user.Email}, "Your data export is ready", body.String()); err != nil {
+		u.logger.Error("Failed to send data export email:", err)
+	}
+}
+
+// writeDataExportFile collects the data of the user and writes the zip, returning its path.
+func (u *UserService) writeDataExportFile(userID uint, exportID string) (string, error) {
+	content, err := u.collectDataExport(userID)
+	if err != nil {
+		return "", err
+	}
+	dir := dataExportDir()
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	// Written under a temporary name so a half written zip is never served
+	filePath := filepath.Join(dir, exportID+".zip")
+	file, err := os.OpenFile(filePath+".tmp", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return "", err
+	}
+	if err := WriteDataExport(file, content); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return filePath, os.Rename(file.Name(), filePath)
+}
+
+func (u *UserService) collectDataExport(userID uint) (models.DataExportContent, error) {
+	content := models.DataExportContent{ExportedAt: time.Now().UTC()}
+	var err error
+	if content.Profile, err = u.loadProfile(userID); err != nil {
+		return content, err
+	}
+	sightingRepo := repositories.NewSightingRepository(u.db, u.logger)
+	for offset := 0; ; {
+		page, err := sightingRepo.ListSightingsByUser(userID, exportPageSize, offset)
+		if err != nil {
+			return content, err
+		}
+		content.Sightings = append(content.Sightings, page.Sightings...)
+		if page.Offset == 0 {
+			break
+		}
+		offset = page.Offset
+	}
+	if content.Sessions, err = repositories.NewSessionRepository(u.db, u.logger).ListSessionHistory(userID); err != nil {
+		return content, err
+	}
+	if content.Notifications, err = repositories.NewNotificationRepository(u.db, u.logger).ListNotifications(userID); err != nil {
+		return content, err
+	}
+	return content, nil
+}
+
+// WriteDataExport writes the content as a zip with profile.json, sightings.json, sessions.json and
+// notifications.json. The image of each sighting is stored as images/sighting-<id>.jpg.
+func WriteDataExport(w io.Writer, content models.DataExportContent) error {
+	archive := zip.NewWriter(w)
+	sightings := make([]models.Sighting, len(content.Sightings))
+	for i, sighting := range content.Sightings {
+		if len(sighting.ImageBlob) > 0 {
+			if err := writeZipEntry(archive, fmt.Sprintf("images/sighting-%d.jpg", sighting.ID), content.ExportedAt, sighting.ImageBlob); err != nil {
+				return err
+			}
+		}
+		// The images are separate files, not base64 inside the JSON
+		sighting.ImageBlob = nil
+		sightings[i] = sighting
+	}
+	files := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", content.Profile},
+		{"sightings.json", sightings},
+		{"sessions.json", content.Sessions},
+		{"notifications.json", content.Notifications},
+	}
+	for _, file := range files {
+		data, err := json.MarshalIndent(file.data, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := writeZipEntry(archive, file.name, content.ExportedAt, data); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+func writeZipEntry(archive *zip.Writer, name string, modified time.Time, data []byte) error {
+	entry, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return err
+	}
+	_, err = entry.Write(data)
+	return err
+}
+
+// removeExportFiles deletes the zips of exports that were forgotten.
+func (u *UserService) removeExportFiles(paths []string) {
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			u.logger.Warn("Failed to remove data export:", err)
+		}
+	}
+}
+
+func dataExportDir() string {
+	if dir := config.GetEnvVar("DATA_EXPORT_DIR"); dir != "" {
+		return dir
+	}
+	return defaultDataExportDir
+}
+
+func dataExportTTL() time.Duration {
+	return durationFromEnv("DATA_EXPORT_TTL_HOURS", time.Hour, defaultDataExportTTL)
+}
+
+func dataExportTimeout() time.Duration {
+	return durationFromEnv("DATA_EXPORT_TIMEOUT_MINUTES", time.Minute, defaultDataExportTimeout)
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database/repositories"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/messaging"
+	"github.com/sirupsen/logrus"
+)
+
+// digestEmailData is the data of the digest template.
+type digestEmailData struct {
+	Username  string
+	Frequency string
+	Sightings int
+	Tigers    []digestTigerData
+	// LocationPrecision is exact or coarse, depending on the location access of the recipient
+	LocationPrecision string
+	UnsubscribeURL    string
+	Organization      string
+}
+
+// digestTigerData is a tiger as shown in the digest email.
+type digestTigerData struct {
+	TigerName string
+	Sightings int
+	Latitude  float64
+	Longitude float64
+	// LocationWithheld is set instead of coordinates for positions still under embargo
+	LocationWithheld bool
+	LastSeenAt       string
+	ThumbnailURL     string
+}
+
+// DigestScheduler sends the daily and weekly digests that are due.
+type DigestScheduler struct {
+	db     *sql.DB
+	logger *logrus.Logger
+}
+
+func NewDigestScheduler(db *sql.DB, logger *logrus.Logger) *DigestScheduler {
+	return &DigestScheduler{db: db, logger: logger}
+}
+
+// SendDueDigests sends a digest to every user whose digest is due at now. It returns the error if the recipients
+// could not be listed.
+func (ds *DigestScheduler) SendDueDigests(now time.Time) error {
+	notificationRepo := repositories.NewNotificationRepository(ds.db, ds.logger)
+	recipients, err := notificationRepo.ListDigestRecipients()
+	if err != nil {
+		return err
+	}
+	for _, recipient := range recipients {
+		scheduledAt := recipient.Preferences.DigestScheduledAt(now)
+		// Users who switched back to immediate notifications get what is left right away
+		if recipient.Preferences.Frequency == models.FrequencyImmediate {
+			scheduledAt = now
+		}
+		if recipient.LastDigestAt != nil && !recipient.LastDigestAt.Before(scheduledAt) {
+			continue
+		}
+		claimed, err := notificationRepo.ClaimDigest(recipient.User.ID, scheduledAt)
+		if err != nil || !claimed {
+			continue
+		}
+		ds.sendDigest(notificationRepo, recipient)
+	}
+	return nil
+}
+
+func (ds *DigestScheduler) sendDigest(notificationRepo *repositories.NotificationRepository, recipient models.DigestRecipient) {
+	items, err := notificationRepo.GetDigestItems(recipient.User.ID)
+	if err != nil || len(items) == 0 {
+		return
+	}
+	lastItemID := 0
+	var verified []models.DigestItem
+	for _, item := range items {
+		if item.ItemID > lastItemID {
+			lastItemID = item.ItemID
+		}
+		if item.Verified {
+			verified = append(verified, item)
+		}
+	}
+	// Users who unsubscribed in the meantime only get their queue cleared
+	if len(verified) > 0 && recipient.Preferences.HasChannel(models.ChannelEmail) {
+		subject, err := ds.emailDigest(recipient, verified)
+		if err != nil {
+			// The items are kept and the claim released, so the next check tries again
+			ds.logger.Error("Failed to send digest:", err)
+			_ = notificationRepo.LogNotifications([]uint{recipient.User.ID}, models.ChannelEmail, subject, models.NotificationFailed)
+			_ = notificationRepo.ReleaseDigest(recipient.User.ID, recipient.LastDigestAt)
+			return
+		}
+		_ = notificationRepo.LogNotifications([]uint{recipient.User.ID}, models.ChannelEmail, subject, models.NotificationSent)
+	}
+	_ = notificationRepo.DeleteDigestItems(recipient.User.ID, lastItemID)
+}
+
+// emailDigest sends the digest and returns its subject, for the notification history.
+func (ds *DigestScheduler) emailDigest(recipient models.DigestRecipient, items []models.DigestItem) (string, error) {
+	unsubscribeURL, err := unsubscribeLink(&recipient.User)
+	if err != nil {
+		return "", err
+	}
+	// Recipients without exact access get the same coarse location as anonymous callers
+	privacy := NewLocationPrivacy(locationAccessFor(recipient.User.Roles))
+	var tigers []digestTigerData
+	for _, tiger := range models.GroupDigestItems(items) {
+		lat, lon := privacy.Coordinates(tiger.Latest.Latitude, tiger.Latest.Longitude)
+		data := digestTigerData{
+			TigerName:  tiger.TigerName,
+			Sightings:  tiger.Sightings,
+			Latitude:   lat,
+			Longitude:  lon,
+			LastSeenAt: tiger.Latest.Timestamp.Format("2006-01-02,15:04:05"),
+		}
+		// Positions under embargo are withheld, as they are from the API
+		if privacy.Embargoed(tiger.Latest.Timestamp) {
+			data.Latitude, data.Longitude, data.LocationWithheld = 0, 0, true
+		}
+		if tiger.ThumbnailSightingID != 0 {
+			data.ThumbnailURL = publicURL("/api/v1/sightings/"+strconv.Itoa(tiger.ThumbnailSightingID)+"/image", nil)
+		}
+		tigers = append(tigers, data)
+	}
+	email, err := renderEmail(DigestTemplate, recipient.User.Language, digestEmailData{
+		Username:          recipient.User.Username,
+		Frequency:         recipient.Preferences.Frequency,
+		Sightings:         len(items),
+		Tigers:            tigers,
+		LocationPrecision: privacy.Precision(),
+		UnsubscribeURL:    unsubscribeURL,
+		Organization:      "Tigerhall-Kittens",
+	}, ds.logger)
+	if err != nil {
+		// The history names the template that failed to render
+		return DigestTemplate, err
+	}
+	opts := []messaging.EmailOption{messaging.WithListUnsubscribe(unsubscribeURL)}
+	if email.HTML != "" {
+		opts = append(opts, messaging.WithHTML(email.HTML))
+	}
+	return email.Subject, messaging.NewEmailHandler(ds.logger).SendEmailNotification([]string{recipient.User.Email}, email.Subject, email.Text, opts...)
+}
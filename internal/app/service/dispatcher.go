@@ -0,0 +1,250 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/messaging"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultOutboxPollInterval  = 5 * time.Second
+	defaultWebhookPollInterval = 5 * time.Second
+	defaultDigestCheckInterval = 5 * time.Minute
+	// stalledAfterIntervals is how many poll intervals a run may take, or may pass without a completed run, before
+	// the dispatcher is reported as stalled
+	stalledAfterIntervals = 3
+)
+
+var (
+	dispatcher   *NotificationDispatcher
+	dispatcherMu sync.RWMutex
+)
+
+// NotificationDispatcher is the single background component delivering notifications. It is started once from
+// main, works through the outbox and the digests, and is woken up early by the events published on MessagingQueue.
+// Webhook deliveries are sent by a loop of their own, so slow partner endpoints do not hold up notifications.
+type NotificationDispatcher struct {
+	logger          *logrus.Logger
+	outbox          *OutboxWorker
+	webhooks        *WebhookWorker
+	digests         *DigestScheduler
+	pollInterval    time.Duration
+	webhookInterval time.Duration
+	digestInterval  time.Duration
+	subscription    *messaging.Subscription[models.SightingVerifiedEvent]
+	// cancel stops the dispatcher from claiming new work, done is closed once both loops returned
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu          sync.Mutex
+	outboxLoop  dispatcherLoop
+	webhookLoop dispatcherLoop
+	health      models.DispatcherHealth
+}
+
+// dispatcherLoop is the run in progress on one of the dispatcher loops. runInterval is the poll interval of that
+// kind of run, a digest run on the outbox loop may take longer than an outbox run.
+type dispatcherLoop struct {
+	runStartedAt *time.Time
+	runInterval  time.Duration
+}
+
+// stalled reports whether the loop is stuck in its current run, or, between runs, has not completed one for too
+// long. lastRun is the last completed run of a loop polling every interval.
+func (l dispatcherLoop) stalled(startedAt time.Time, lastRun *time.Time, interval time.Duration) bool {
+	if l.runStartedAt != nil {
+		return time.Since(*l.runStartedAt) > stalledAfterIntervals*l.runInterval
+	}
+	if lastRun != nil {
+		startedAt = *lastRun
+	}
+	return time.Since(startedAt) > stalledAfterIntervals*interval
+}
+
+// StartNotificationDispatcher starts the dispatcher. It polls the outbox every OUTBOX_POLL_INTERVAL_SECONDS, the
+// webhook deliveries every WEBHOOK_POLL_INTERVAL_SECONDS and checks for due digests every
+// DIGEST_CHECK_INTERVAL_MINUTES, deleting old sent messages along the way, until Shutdown is called.
+func StartNotificationDispatcher(db *sql.DB, logger *logrus.Logger) *NotificationDispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	nd := &NotificationDispatcher{
+		logger:          logger,
+		outbox:          NewOutboxWorker(db, logger),
+		webhooks:        NewWebhookWorker(db, logger),
+		digests:         NewDigestScheduler(db, logger),
+		pollInterval:    durationFromEnv("OUTBOX_POLL_INTERVAL_SECONDS", time.Second, defaultOutboxPollInterval),
+		webhookInterval: durationFromEnv("WEBHOOK_POLL_INTERVAL_SECONDS", time.Second, defaultWebhookPollInterval),
+		digestInterval:  durationFromEnv("DIGEST_CHECK_INTERVAL_MINUTES", time.Minute, defaultDigestCheckInterval),
+		// A single pending wake-up is enough, the outbox holds the actual messages
+		subscription: messaging.Subscribe(GetMessagingQueue(), SightingVerifiedTopic, messaging.SubscribeOptions{
+			Name: "notification-dispatcher", Capacity: 1, Overflow: messaging.DropNewest,
+		}),
+		cancel: cancel,
+		done:   make(chan struct{}),
+		health: models.DispatcherHealth{Status: models.HealthOK, StartedAt: time.Now()},
+	}
+	var loops sync.WaitGroup
+	loops.Add(2)
+	go func() {
+		defer loops.Done()
+		nd.run(ctx)
+	}()
+	go func() {
+		defer loops.Done()
+		nd.runWebhooks(ctx)
+	}()
+	go func() {
+		loops.Wait()
+		close(nd.done)
+	}()
+	dispatcherMu.Lock()
+	dispatcher = nd
+	dispatcherMu.Unlock()
+	logger.Infof("Notification dispatcher started, polling the outbox every %s, webhooks every %s and digests every %s", nd.pollInterval, nd.webhookInterval, nd.digestInterval)
+	return nd
+}
+
+func (nd *NotificationDispatcher) run(ctx context.Context) {
+	outboxTicker := time.NewTicker(nd.pollInterval)
+	defer outboxTicker.Stop()
+	digestTicker := time.NewTicker(nd.digestInterval)
+	defer digestTicker.Stop()
+	// Catch up with whatever was left over by the previous run
+	nd.processOutbox(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-outboxTicker.C:
+			nd.processOutbox(ctx)
+		case _, ok := <-nd.subscription.Messages():
+			if !ok {
+				return
+			}
+			// Verified sightings are already in the outbox, deliver them now rather than at the next poll
+			nd.processOutbox(ctx)
+		case <-digestTicker.C:
+			nd.track(&nd.outboxLoop, nd.digestInterval, func() error {
+				if err := nd.digests.SendDueDigests(time.Now()); err != nil {
+					return err
+				}
+				nd.outbox.DeleteSent()
+				return nil
+			}, func(at *time.Time) { nd.health.LastDigestRunAt = at })
+		}
+	}
+}
+
+func (nd *NotificationDispatcher) processOutbox(ctx context.Context) {
+	nd.track(&nd.outboxLoop, nd.pollInterval, func() error { return nd.outbox.ProcessDue(ctx) }, func(at *time.Time) { nd.health.LastOutboxRunAt = at })
+}
+
+// runWebhooks sends the webhook deliveries. Their events are queued along with the change they report, so
+// polling is enough.
+func (nd *NotificationDispatcher) runWebhooks(ctx context.Context) {
+	ticker := time.NewTicker(nd.webhookInterval)
+	defer ticker.Stop()
+	for {
+		nd.track(&nd.webhookLoop, nd.webhookInterval, func() error { return nd.webhooks.ProcessDue(ctx) }, func(at *time.Time) { nd.health.LastWebhookRunAt = at })
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// track runs work on loop, which is reported as stalled once the run takes longer than stalledAfterIntervals
+// times interval, and records when it completed. A run that failed is logged and not recorded, so a dispatcher
+// that cannot reach the database is reported as stalled.
+func (nd *NotificationDispatcher) track(loop *dispatcherLoop, interval time.Duration, work func() error, completed func(at *time.Time)) {
+	startedAt := time.Now()
+	nd.mu.Lock()
+	loop.runStartedAt, loop.runInterval = &startedAt, interval
+	nd.mu.Unlock()
+	err := work()
+	now := time.Now()
+	nd.mu.Lock()
+	defer nd.mu.Unlock()
+	loop.runStartedAt = nil
+	if err != nil {
+		nd.logger.Error("Notification dispatcher run failed:", err)
+		return
+	}
+	completed(&now)
+}
+
+// Shutdown stops claiming new work and waits for the notifications being delivered to be sent. Messages that
+// were not picked up stay in the outbox for the next start. It returns ctx.Err() if the drain took too long, the
+// dispatcher is reported as stopped either way.
+func (nd *NotificationDispatcher) Shutdown(ctx context.Context) error {
+	nd.cancel()
+	nd.subscription.Unsubscribe()
+	nd.mu.Lock()
+	nd.health.Status = models.HealthStopped
+	nd.mu.Unlock()
+	select {
+	case <-nd.done:
+		nd.logger.Info("Notification dispatcher drained")
+		return nil
+	case <-ctx.Done():
+		nd.logger.Warn("Notification dispatcher did not drain in time, remaining messages are retried on the next start")
+		return ctx.Err()
+	}
+}
+
+// Health reports whether the dispatcher is running and keeping up.
+func (nd *NotificationDispatcher) Health() models.DispatcherHealth {
+	nd.mu.Lock()
+	defer nd.mu.Unlock()
+	health := nd.health
+	health.OutboxRunStartedAt = nd.outboxLoop.runStartedAt
+	health.WebhookRunStartedAt = nd.webhookLoop.runStartedAt
+	health.InFlight = health.OutboxRunStartedAt != nil || health.WebhookRunStartedAt != nil
+	select {
+	case <-nd.done:
+		health.Status = models.HealthStopped
+	default:
+	}
+	if health.Status == models.HealthStopped {
+		return health
+	}
+	// Each loop is checked on its own, a busy webhook loop must not hide a hung outbox and the other way round
+	if nd.outboxLoop.stalled(health.StartedAt, health.LastOutboxRunAt, nd.pollInterval) ||
+		nd.webhookLoop.stalled(health.StartedAt, health.LastWebhookRunAt, nd.webhookInterval) {
+		health.Status = models.HealthStalled
+	}
+	return health
+}
+
+// NotificationHealth godoc
+// @Summary Health of the notification dispatcher
+// @Description Reports whether notifications are being delivered, for liveness probes and monitoring.
+// @Tags Health
+// @Produce json
+// @Success 200 {object} models.DispatcherHealth
+// @Failure 503 {object} models.DispatcherHealth "Dispatcher stopped or stalled"
+// @Router /api/v1/health/notifications [get]
+func NotificationHealth(w http.ResponseWriter, r *http.Request) {
+	dispatcherMu.RLock()
+	nd := dispatcher
+	dispatcherMu.RUnlock()
+	health := models.DispatcherHealth{Status: models.HealthStopped}
+	if nd != nil {
+		health = nd.Health()
+	}
+	status := http.StatusOK
+	if health.Status != models.HealthOK {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(health)
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/templates"
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/config"
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/messaging"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// Emails rendered from templates.
+const (
+	SightingNotificationTemplate = "sighting_notification"
+	DigestTemplate               = "digest"
+)
+
+const defaultEmailTemplatesReload = 30 * time.Second
+
+var (
+	emailTemplates   *templates.Store
+	emailTemplatesMu sync.Mutex
+)
+
+// emailTemplateSamples are the data templates are previewed and checked with when they are loaded.
+var emailTemplateSamples = templates.Samples{
+	SightingNotificationTemplate: func() any {
+		return messaging.EmailTemplateData{
+			TigerName:         "Shere Khan",
+			Latitude:          11.6643,
+			Longitude:         76.6261,
+			LocationPrecision: locationPrecisionExact,
+			SightingTime:      "2024-03-01,06:42:10",
+			Organization:      "Tigerhall-Kittens",
+			ContactInfo:       "Contact us at " + config.GetEnvVar("SENDER_EMAIL"),
+			UnsubscribeURL:    publicURL("/api/v1/unsubscribe", nil),
+			MapURL:            sightingMapURL(11.6643, 76.6261, locationPrecisionExact),
+			ImageContentID:    sightingImageContentID(1),
+		}
+	},
+	DigestTemplate: func() any {
+		return digestEmailData{
+			Username:  "ranger",
+			Frequency: models.FrequencyDaily,
+			Sightings: 3,
+			Tigers: []digestTigerData{
+				{TigerName: "Shere Khan", Sightings: 2, Latitude: 11.7, Longitude: 76.6, LastSeenAt: "2024-03-01,06:42:10",
+					ThumbnailURL: publicURL("/api/v1/sightings/1/image", nil)},
+				{TigerName: "Machli", Sightings: 1, Latitude: 26, Longitude: 76.5, LastSeenAt: "2024-02-29,17:05:00"},
+			},
+			LocationPrecision: locationPrecisionCoarse,
+			UnsubscribeURL:    publicURL("/api/v1/unsubscribe", nil),
+			Organization:      "Tigerhall-Kittens",
+		}
+	},
+}
+
+// StartEmailTemplates loads the email templates from EMAIL_TEMPLATES_DIR over the embedded defaults and reloads
+// them when the files change.
+func StartEmailTemplates(logger *logrus.Logger) (*templates.Store, error) {
+	store, err := templates.NewStore(config.GetEnvVar("EMAIL_TEMPLATES_DIR"), emailTemplateSamples, logger)
+	if err != nil {
+		return nil, err
+	}
+	store.Watch(durationFromEnv("EMAIL_TEMPLATES_RELOAD_SECONDS", time.Second, defaultEmailTemplatesReload))
+	emailTemplatesMu.Lock()
+	emailTemplates = store
+	emailTemplatesMu.Unlock()
+	return store, nil
+}
+
+// EmailTemplates returns the templates loaded by StartEmailTemplates, or the embedded defaults if they were not.
+func EmailTemplates() *templates.Store {
+	emailTemplatesMu.Lock()
+	defer emailTemplatesMu.Unlock()
+	if emailTemplates == nil {
+		store, err := templates.NewStore("", emailTemplateSamples, logrus.StandardLogger())
+		if err != nil {
+			// The embedded templates are parsed by the tests, they cannot fail at runtime
+			panic(err)
+		}
+		emailTemplates = store
+	}
+	return emailTemplates
+}
+
+// renderEmail renders the email in the language of the recipient, or in the default language if that fails, so
+// one broken translation does not keep the email from being sent.
+func renderEmail(name string, language string, data any, logger *logrus.Logger) (templates.Rendered, error) {
+	email, err := EmailTemplates().Render(name, language, data)
+	if err == nil || language == templates.DefaultLanguage {
+		return email, err
+	}
+	logger.Warnf("Failed to render %s in %s, falling back to %s: %v", name, language, templates.DefaultLanguage, err)
+	return EmailTemplates().Render(name, templates.DefaultLanguage, data)
+}
+
+// ListEmailTemplates godoc
+// @Summary List email templates
+// @Description Admins see the emails rendered from templates and the languages each is written in.
+// @Tags Admin
+// @Produce json
+// @Success 200 {array} templates.Template
+// @Failure 403 {object} models.ErrorResponse "Caller lacks the notifications:manage permission"
+// @Security Authorization
+// @Security APIKey
+// @Router /api/v1/admin/email-templates [get]
+func ListEmailTemplates(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(EmailTemplates().Templates())
+}
+
+// PreviewEmailTemplate godoc
+// @Summary Preview an email template
+// @Description Admins render a template with sample data, in the language asked for or the one it falls back to. With format=html or format=text the part is returned as is, e.g. to open it in a browser. Inline images are not included.
+// @Tags Admin
+// @Produce json,html,plain
+// @Param name path string true "Template name, e.g. sighting_notification or digest"
+// @Param language query string false "Language, e.g. en, hi or bn" default(en)
+// @Param format query string false "json, html or text" default(json)
+// @Success 200 {object} templates.Rendered
+// @Failure 400 {object} models.ErrorResponse "Unknown format"
+// @Failure 403 {object} models.ErrorResponse "Caller lacks the notifications:manage permission"
+// @Failure 404 {object} models.ErrorResponse "Unknown template"
+// @Failure 500 {object} models.ErrorResponse "Template failed to render"
+// @Security Authorization
+// @Security APIKey
+// @Router /api/v1/admin/email-templates/{name}/preview [get]
+func PreviewEmailTemplate(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	sample, ok := emailTemplateSamples[name]
+	if !ok {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Unknown template", Status: http.StatusNotFound})
+		return
+	}
+	language := r.URL.Query().Get("language")
+	if language == "" {
+		language = templates.DefaultLanguage
+	}
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "html" && format != "text" {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Format must be json, html or text", Status: http.StatusBadRequest})
+		return
+	}
+	email, err := EmailTemplates().Render(name, language, sample())
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to render the template: " + err.Error(), Status: http.StatusInternalServerError})
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Language", email.Language)
+	switch {
+	case format == "html" && email.HTML != "":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(email.HTML))
+	case format == "html" || format == "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(email.Text))
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(email)
+	}
+}
+
+// ReloadEmailTemplates godoc
+// @Summary Reload the email templates
+// @Description Admins load changed templates from EMAIL_TEMPLATES_DIR right away instead of waiting for the next check. Every template is rendered with the preview data, templates that fail to parse or render, or emails without a subject or text in a language, are reported and the previous templates stay in use.
+// @Tags Admin
+// @Produce json
+// @Success 200 {array} templates.Template
+// @Failure 403 {object} models.ErrorResponse "Caller lacks the notifications:manage permission"
+// @Failure 422 {object} models.ErrorResponse "A template failed to parse or render"
+// @Security Authorization
+// @Security APIKey
+// @Router /api/v1/admin/email-templates/reload [post]
+func ReloadEmailTemplates(w http.ResponseWriter, r *http.Request) {
+	store := EmailTemplates()
+	if err := store.Reload(); err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to reload the templates: " + err.Error(), Status: http.StatusUnprocessableEntity})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(store.Templates())
+}
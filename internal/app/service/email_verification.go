@@ -0,0 +1,178 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database/repositories"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/config"
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/messaging"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	audienceEmailVerification = "email-verification"
+
+	defaultEmailVerificationTTL = 24 * time.Hour
+	defaultPublicBaseURL        = "http://localhost:8888"
+)
+
+// verificationEmailTemplate is plain text, links must not be HTML escaped
+var verificationEmailTemplate = template.Must(template.New("verificationEmail").Parse(`
+Dear {{.Username}},
+
+Thank you for joining {{.Organization}}. Please confirm your email address by opening the link below:
+
+{{.Link}}
+
+The link expires in {{.ExpiresIn}}. Until your address is confirmed we will not send you sighting notifications.
+
+If you did not create an account, you can ignore this email.
+
+Best regards,
+
+{{.Organization}}
+`))
+
+// VerifyEmail godoc
+// @Summary Verify the email address of a user
+// @Description Confirm the email address with the signed link mailed on registration. Only verified addresses receive notifications.
+// @Tags User
+// @Produce json
+// @Param token query string true "Verification token from the email"
+// @Success 200 {object} models.GeneralResponse
+// @Failure 400 {object} models.ErrorResponse "Invalid or expired verification link"
+// @Router /api/v1/verify-email [get]
+func (u *UserService) VerifyEmail(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	claims, err := parseActionToken(r.URL.Query().Get("token"), audienceEmailVerification)
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Invalid or expired verification link", Status: http.StatusBadRequest})
+		return
+	}
+	userRepo := repositories.NewUserRepository(u.db, u.logger)
+	if err := userRepo.MarkEmailVerified(claims.Subject, claims.Email); err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Invalid or expired verification link", Status: http.StatusBadRequest})
+		return
+	}
+	u.logger.Infof("Email verified for %s", claims.Subject)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(models.GeneralResponse{Message: "Email verified"})
+}
+
+// ResendVerificationEmail godoc
+// @Summary Resend the email verification link
+// @Description Send a new verification link to the authenticated user, e.g. after the previous one expired.
+// @Tags User
+// @Produce json
+// @Success 200 {object} models.GeneralResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse "Email already verified"
+// @Failure 500 {object} models.ErrorResponse "Failed to send the verification email"
+// @Security Authorization
+// @Router /api/v1/verify-email/resend [post]
+func (u *UserService) ResendVerificationEmail(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	claims, ok := ClaimsFromContext(r.Context())
+	if !ok {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "User not authenticated", Status: http.StatusUnauthorized})
+		return
+	}
+	user, err := repositories.NewUserRepository(u.db, u.logger).GetUserByUserName(claims.Username)
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "User not authenticated", Status: http.StatusUnauthorized})
+		return
+	}
+	if user.EmailVerified {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Email already verified", Status: http.StatusConflict})
+		return
+	}
+	if err := sendVerificationEmail(messaging.NewEmailHandler(u.logger), user); err != nil {
+		u.logger.Error("Failed to send verification email:", err)
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to send the verification email. Please try again", Status: http.StatusInternalServerError})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(models.GeneralResponse{Message: "Verification email sent"})
+}
+
+// sendVerificationEmail mails a signed, expiring verification link to the user.
+func sendVerificationEmail(email *messaging.EmailHandler, user *models.User) error {
+	ttl := emailVerificationTTL()
+	token, err := signActionToken(audienceEmailVerification, user.Username, user.Email, ttl)
+	if err != nil {
+		return err
+	}
+	var body bytes.Buffer
+	err = verificationEmailTemplate.Execute(&body, map[string]string{
+		"Username":     user.Username,
+		"Link":         publicURL("/api/v1/verify-email", url.Values{"token": {token}}),
+		"ExpiresIn":    ttl.String(),
+		"Organization": "Tigerhall-Kittens",
+	})
+	if err != nil {
+		return err
+	}
+	return email.SendEmailNotification([]string{user.Email}, "Verify your email address", body.String())
+}
+
+// signActionToken signs a single purpose token for the user, valid for the given duration.
+func signActionToken(audience string, username string, email string, ttl time.Duration) (string, error) {
+	tokenID, err := generateRandomToken(16)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	return getKeyRing().Sign(&models.ActionClaims{
+		Email: email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			Subject:   username,
+			Audience:  jwt.ClaimStrings{audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	})
+}
+
+// parseActionToken validates a single purpose token and checks that it was issued for the audience.
+func parseActionToken(token string, audience string) (*models.ActionClaims, error) {
+	if token == "" {
+		return nil, errors.New("token is missing")
+	}
+	claims := &models.ActionClaims{}
+	parsedToken, err := jwt.ParseWithClaims(token, claims, getKeyRing().Keyfunc)
+	if err != nil || !parsedToken.Valid {
+		return nil, errors.New("invalid or expired token")
+	}
+	if !claims.VerifyAudience(audience, true) || claims.Subject == "" {
+		return nil, fmt.Errorf("token was not issued for %s", audience)
+	}
+	return claims, nil
+}
+
+// publicURL builds an absolute link to the API as seen by users, e.g. for links in emails.
+func publicURL(path string, query url.Values) string {
+	baseURL := strings.TrimSuffix(config.GetEnvVar("PUBLIC_BASE_URL"), "/")
+	if baseURL == "" {
+		baseURL = defaultPublicBaseURL
+	}
+	link := baseURL + path
+	if len(query) > 0 {
+		link += "?" + query.Encode()
+	}
+	return link
+}
+
+func emailVerificationTTL() time.Duration {
+	return durationFromEnv("EMAIL_VERIFICATION_TTL_HOURS", time.Hour, defaultEmailVerificationTTL)
+}
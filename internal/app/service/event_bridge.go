@@ -0,0 +1,33 @@
+package service
+
+import (
+	"database/sql"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database"
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/config"
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/messaging"
+	"github.com/sirupsen/logrus"
+)
+
+const defaultEventBridgeChannel = "tigerhall_events"
+
+// StartEventBridge shares the topics of MessagingQueue with the other instances of the service through
+// Postgres LISTEN/NOTIFY on EVENT_BRIDGE_CHANNEL, so subscribers see events published on any instance.
+func StartEventBridge(db *sql.DB, logger *logrus.Logger) (*messaging.PostgresBridge, error) {
+	channel := config.GetEnvVar("EVENT_BRIDGE_CHANNEL")
+	if channel == "" {
+		channel = defaultEventBridgeChannel
+	}
+	bridge, err := messaging.NewPostgresBridge(GetMessagingQueue(), db, database.GetDSN(config.GetEnvVar("POSTGRES_DB")), messaging.PostgresBridgeOptions{
+		Channel:      channel,
+		PayloadTable: "tigerhall.event_payloads",
+	}, logger)
+	if err != nil {
+		return nil, err
+	}
+	messaging.Bridge(bridge, SightingVerifiedTopic)
+	messaging.Bridge(bridge, SightingStreamTopic)
+	messaging.Bridge(bridge, UserEventTopic)
+	logger.Infof("Event bridge listening on %s", channel)
+	return bridge, nil
+}
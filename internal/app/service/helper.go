@@ -1,16 +1,14 @@
 package service
 
 import (
-	"bytes"
-	"database/sql"
+	"encoding/json"
 	"fmt"
-	"html/template"
 	"math"
+	"net/http"
 	"sync"
 
-	"github.com/chegde20121/Tigerhall-Kittens/pkg/config"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
 	"github.com/chegde20121/Tigerhall-Kittens/pkg/messaging"
-	log "github.com/sirupsen/logrus"
 )
 
 const (
@@ -22,14 +20,8 @@ var (
 	once           sync.Once
 )
 
-type Notifier struct {
-	db     *sql.DB
-	logger *log.Logger
-}
-
-func NewNotifier(db *sql.DB, logger *log.Logger) *Notifier {
-	return &Notifier{db: db, logger: logger}
-}
+// SightingVerifiedTopic announces sightings that were verified for the first time.
+var SightingVerifiedTopic = messaging.NewTopic[models.SightingVerifiedEvent]("sightings.verified")
 
 // haversineDistance calculates the Haversine distance between two points in kilometers.
 func haversineDistance(lat1, lon1, lat2, lon2 float64) float64 {
@@ -53,46 +45,6 @@ func haversineDistance(lat1, lon1, lat2, lon2 float64) float64 {
 	return distance
 }
 
-type SightingNotification struct {
-	TigerName     string
-	TigerLocation string
-	SightingTime  string
-	userEmails    []string
-}
-
-func (n *Notifier) RegisterSightingSubscriber() chan any {
-	n.logger.Info("Notifier initialized......")
-
-	sightingServiceSubscriber := MessagingQueue.Subscribe()
-	email := messaging.NewEmailHandler(n.logger)
-	go func(subscriber chan any, name string) {
-		for message := range subscriber {
-			sightingNotification := message.(SightingNotification)
-			data := messaging.EmailTemplateData{
-				TigerName:     sightingNotification.TigerName,
-				TigerLocation: sightingNotification.TigerLocation,
-				SightingTime:  sightingNotification.SightingTime,
-				Organization:  "Tigerhall-Kittens",
-				ContactInfo:   fmt.Sprintf("Contact us at %v", config.GetEnvVar("SENDER_EMAIL")),
-			}
-
-			// Execute the email template
-			emailBody, err := executeTemplate(data)
-			if err != nil {
-				n.logger.Error("Error executing email template:", err)
-			} else {
-				err := email.SendEmailNotification(sightingNotification.userEmails, "Tiger Sighting Notification", emailBody)
-				if err != nil {
-					n.logger.Error(err)
-				}
-			}
-
-		}
-		MessagingQueue.Unsubscribe(subscriber)
-	}(sightingServiceSubscriber, "sightingServiceSubscriber")
-	return sightingServiceSubscriber
-}
-
 // degreesToRadians converts degrees to radians.
 func degreesToRadians(degrees float64) float64 {
 	return degrees * (math.Pi / 180)
@@ -105,41 +57,29 @@ func GetMessagingQueue() *messaging.PubSub {
 	return MessagingQueue
 }
 
-func executeTemplate(data messaging.EmailTemplateData) (string, error) {
-	// Define the email template
-	emailTemplate := `
-Dear Sir/Madam,
-
-We hope this message finds you well. We have exciting news to share with you regarding the tiger sighting you reported!
-
-Recently, another sighting of the same tiger ({{.TigerName}}) has been reported by another user. Your dedication to wildlife observation and reporting is invaluable to our community.
-
-Details of the recent sighting:
-- Location: {{.TigerLocation}}
-- Lastseen At: {{.SightingTime}}
-
-We appreciate your commitment to tracking tiger populations in the wild. Your contributions play a crucial role in our conservation efforts.
-
-Thank you for being an active member of our tiger tracking community.
-
-Best regards,
-
-{{.Organization}}
-{{.ContactInfo}}
-`
-
-	// Create a new template and parse the email template
-	tmpl, err := template.New("emailTemplate").Parse(emailTemplate)
-	if err != nil {
-		return "", err
-	}
-
-	// Execute the template with the provided data
-	var emailBodyBuffer bytes.Buffer
-	err = tmpl.Execute(&emailBodyBuffer, data)
-	if err != nil {
-		return "", err
+// sightingMapURL links to the location on OpenStreetMap, zoomed out for coarse locations so the map does not
+// suggest more precision than the coordinates have.
+func sightingMapURL(lat, lon float64, precision string) string {
+	zoom := 15
+	if precision != locationPrecisionExact {
+		zoom = 11
 	}
+	return fmt.Sprintf("https://www.openstreetmap.org/?mlat=%.5f&mlon=%.5f#map=%d/%.5f/%.5f", lat, lon, zoom, lat, lon)
+}
 
-	return emailBodyBuffer.String(), nil
+// MessagingStats godoc
+// @Summary Metrics of the in-process message queues
+// @Description Admins see the depth, capacity, delivered and dropped message counts of every subscriber queue.
+// @Tags Admin
+// @Produce json
+// @Success 200 {array} messaging.SubscriptionStats
+// @Failure 403 {object} models.ErrorResponse "Caller lacks the notifications:manage permission"
+// @Security Authorization
+// @Security APIKey
+// @Router /api/v1/admin/messaging [get]
+func MessagingStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(GetMessagingQueue().Stats())
 }
@@ -0,0 +1,438 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database/repositories"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/config"
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/messaging"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultLiveHeartbeat = 30 * time.Second
+	liveWriteTimeout     = 10 * time.Second
+	liveMaxMessageSize   = 4 << 10
+	liveMaxSubscriptions = 20
+	liveCloseGracePeriod = 5 * time.Second
+	// liveSendQueue is how many messages may wait for a slow client before it is disconnected
+	liveSendQueue = 256
+	// userEventQueue is how many user events may wait to be handed to the live channels
+	userEventQueue = 1024
+)
+
+// UserEventTopic carries the in-app notifications of users to every instance, wherever their live channels are
+// connected. Upload progress does not go through it, see sendUserEvent.
+var UserEventTopic = messaging.NewTopic[models.UserEvent]("users.events")
+
+var (
+	// liveUsers holds the open live channels of this instance by user ID
+	liveUsers     = map[uint]map[*liveClient]struct{}{}
+	liveClientsMu sync.Mutex
+	// forwardUserEventsOnce starts handing the events of UserEventTopic to the live channels
+	forwardUserEventsOnce sync.Once
+)
+
+// liveUpgrader answers the handshakes of the live channel, see sameOrigin.
+var liveUpgrader = websocket.Upgrader{CheckOrigin: sameOrigin}
+
+// liveClient is one open live channel. Only the writer goroutine writes to conn, apart from the pongs and close
+// confirmations the reader sends as control frames.
+type liveClient struct {
+	conn     *websocket.Conn
+	logger   *logrus.Logger
+	userID   uint
+	username string
+	viewer   sightingStreamViewer
+	// send queues the replies to the messages of the client
+	send chan models.LiveServerMessage
+	// events queues the notifications and upload progress of the user
+	events chan models.UserEvent
+	// renewed hands the expiry of a fresh token from the reader to the writer
+	renewed chan time.Time
+	// closed is closed once the connection is being closed, with the close code in closeCode
+	closed    chan struct{}
+	closeOnce sync.Once
+	closeCode int
+	reason    string
+
+	mu sync.Mutex
+	// credentials validates the latest credentials of the app again, those of the handshake or of an auth message
+	credentials   func() (*models.Claims, error)
+	subscriptions map[string]models.SightingStreamFilter
+}
+
+// LiveChannel godoc
+// @Summary Live channel for the mobile app
+// @Description WebSocket carrying live sightings for the tigers and regions the app subscribes to, the in-app notifications of the user and the progress of their uploads. Send {"type":"subscribe","subscription":"<id>","tiger_ids":[1],"bbox":[min_lon,min_lat,max_lon,max_lat]} and {"type":"unsubscribe","subscription":"<id>"}, and {"type":"auth","token":"<access token>"} with a refreshed token before expires_at. The server pings every heartbeat_seconds and closes the connection if the app stays silent for twice as long, with code 1000 once the token expired, with 1008 if the session was revoked, or with 1013 if the app reads too slowly.
+// @Tags Live
+// @Success 101 {object} models.LiveServerMessage "Switching to the WebSocket protocol"
+// @Failure 400 {string} string "Not a WebSocket handshake"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 403 {string} string "Origin not allowed"
+// @Failure 500 {object} models.ErrorResponse "User could not be loaded"
+// @Security Authorization
+// @Router /api/v1/live [get]
+func LiveChannel(w http.ResponseWriter, r *http.Request) {
+	claims, ok := ClaimsFromContext(r.Context())
+	if !ok {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "User not authenticated", Status: http.StatusUnauthorized})
+		return
+	}
+	user, err := repositories.NewUserRepository(database.GetDB(), logrus.StandardLogger()).GetUserByUserName(claims.Username)
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to open the live channel. Please try again", Status: http.StatusInternalServerError})
+		return
+	}
+	forwardUserEventsOnce.Do(func() { go forwardUserEvents() })
+	// The upgrader has answered a failed handshake already
+	conn, err := liveUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	conn.SetReadLimit(liveMaxMessageSize)
+	client := &liveClient{
+		conn:          conn,
+		logger:        logrus.StandardLogger(),
+		userID:        user.ID,
+		username:      claims.Username,
+		send:          make(chan models.LiveServerMessage, liveSendQueue),
+		events:        make(chan models.UserEvent, liveSendQueue),
+		renewed:       make(chan time.Time, 1),
+		closed:        make(chan struct{}),
+		credentials:   func() (*models.Claims, error) { return getClaimsFromRequest(r) },
+		subscriptions: map[string]models.SightingStreamFilter{},
+	}
+	client.applyClaims(claims)
+	heartbeat := durationFromEnv("LIVE_HEARTBEAT_SECONDS", time.Second, defaultLiveHeartbeat)
+	liveClientsMu.Lock()
+	if liveUsers[client.userID] == nil {
+		liveUsers[client.userID] = map[*liveClient]struct{}{}
+	}
+	liveUsers[client.userID][client] = struct{}{}
+	liveClientsMu.Unlock()
+	defer func() {
+		liveClientsMu.Lock()
+		delete(liveUsers[client.userID], client)
+		if len(liveUsers[client.userID]) == 0 {
+			delete(liveUsers, client.userID)
+		}
+		liveClientsMu.Unlock()
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		client.write(heartbeat, tokenExpiry(claims))
+	}()
+	client.read(heartbeat)
+	client.close(websocket.CloseNormalClosure, "")
+	<-done
+	conn.Close()
+}
+
+// read handles the messages of the client until the connection fails or is closed.
+func (c *liveClient) read(heartbeat time.Duration) {
+	// Any frame, including the pongs to our pings, shows the client is still there
+	alive := func() { _ = c.conn.SetReadDeadline(time.Now().Add(2 * heartbeat)) }
+	alive()
+	c.conn.SetPongHandler(func(string) error {
+		alive()
+		return nil
+	})
+	for {
+		messageType, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		alive()
+		var message models.LiveClientMessage
+		if messageType != websocket.TextMessage || json.Unmarshal(data, &message) != nil {
+			c.reply(models.LiveServerMessage{Type: models.LiveError, Message: "Messages must be JSON text"})
+			continue
+		}
+		switch message.Type {
+		case models.LiveSubscribe:
+			c.subscribe(message)
+		case models.LiveUnsubscribe:
+			c.mu.Lock()
+			delete(c.subscriptions, message.Subscription)
+			c.mu.Unlock()
+			c.reply(models.LiveServerMessage{Type: models.LiveUnsubscribed, Subscription: message.Subscription})
+		case models.LivePing:
+			c.reply(models.LiveServerMessage{Type: models.LivePong})
+		case models.LiveAuth:
+			c.reauthenticate(message.Token)
+		default:
+			c.reply(models.LiveServerMessage{Type: models.LiveError, Message: "Unknown message type " + message.Type})
+		}
+	}
+}
+
+// reauthenticate moves the channel to a fresh access token of the same user. A token that is not accepted is
+// answered with an error, the channel keeps running on the previous one until it expires.
+func (c *liveClient) reauthenticate(token string) {
+	claims, err := validateJWT(token)
+	if err != nil || claims.Username != c.username {
+		c.reply(models.LiveServerMessage{Type: models.LiveError, Message: "Invalid or expired token"})
+		return
+	}
+	c.mu.Lock()
+	c.credentials = func() (*models.Claims, error) { return validateJWT(token) }
+	c.mu.Unlock()
+	c.applyClaims(claims)
+	expiresAt := tokenExpiry(claims)
+	// Only the reader sends, so once the expiry the writer has not picked up yet is dropped there is room
+	select {
+	case <-c.renewed:
+	default:
+	}
+	c.renewed <- expiresAt
+	c.reply(models.LiveServerMessage{Type: models.LiveAuthenticated, ExpiresAt: timePointer(expiresAt)})
+}
+
+// revalidate checks the credentials of the channel again, like the live sighting feed does. The session or API
+// key may have been revoked in the meantime, and roles removed from a refreshed token take effect.
+func (c *liveClient) revalidate() {
+	c.mu.Lock()
+	credentials := c.credentials
+	c.mu.Unlock()
+	claims, err := credentials()
+	if err != nil {
+		c.close(websocket.ClosePolicyViolation, "credentials revoked")
+		return
+	}
+	c.applyClaims(claims)
+}
+
+// applyClaims decides what the app receives from the roles the claims carry.
+func (c *liveClient) applyClaims(claims *models.Claims) {
+	access := LocationCoarse
+	if claims.HasPermission(models.PermissionLocationsExact) {
+		access = LocationExact
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.viewer = sightingStreamViewer{
+		reviewer: claims.HasPermission(models.PermissionSightingsReview),
+		privacy:  NewLocationPrivacy(access),
+	}
+}
+
+// tokenExpiry returns when the claims expire, zero for API keys.
+func tokenExpiry(claims *models.Claims) time.Time {
+	if claims.ExpiresAt == nil {
+		return time.Time{}
+	}
+	return claims.ExpiresAt.Time
+}
+
+// timePointer returns nil for the zero time.
+func timePointer(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func (c *liveClient) subscribe(message models.LiveClientMessage) {
+	filter, err := message.Filter()
+	if err != nil {
+		c.reply(models.LiveServerMessage{Type: models.LiveError, Subscription: message.Subscription, Message: err.Error()})
+		return
+	}
+	c.mu.Lock()
+	_, exists := c.subscriptions[message.Subscription]
+	full := !exists && len(c.subscriptions) >= liveMaxSubscriptions
+	if !full {
+		c.subscriptions[message.Subscription] = filter
+	}
+	c.mu.Unlock()
+	if full {
+		c.reply(models.LiveServerMessage{Type: models.LiveError, Subscription: message.Subscription, Message: "Too many subscriptions"})
+		return
+	}
+	c.reply(models.LiveServerMessage{Type: models.LiveSubscribed, Subscription: message.Subscription})
+}
+
+// reply queues a message for the client, a client that does not read its replies is disconnected.
+func (c *liveClient) reply(message models.LiveServerMessage) {
+	select {
+	case c.send <- message:
+	default:
+		c.close(websocket.CloseTryAgainLater, "client too slow")
+	}
+}
+
+// write sends the queued replies, the events for the client and the heartbeats until the connection is closed.
+func (c *liveClient) write(heartbeat time.Duration, expiresAt time.Time) {
+	sightings := messaging.Subscribe(GetMessagingQueue(), SightingStreamTopic, messaging.SubscribeOptions{
+		Name: "live-channel", Capacity: liveSendQueue, Overflow: messaging.DropNewest,
+	})
+	defer sightings.Unsubscribe()
+	pings := time.NewTicker(heartbeat)
+	defer pings.Stop()
+	revalidate := time.NewTicker(sightingStreamRevalidate)
+	defer revalidate.Stop()
+	// The channel lives no longer than the latest token of the app, which sends a fresh one before it expires
+	var expiry *time.Timer
+	var expired <-chan time.Time
+	expireAt := func(at time.Time) {
+		if expiry != nil {
+			expiry.Stop()
+		}
+		expired = nil
+		if !at.IsZero() {
+			expiry = time.NewTimer(time.Until(at))
+			expired = expiry.C
+		}
+	}
+	expireAt(expiresAt)
+	defer func() { expireAt(time.Time{}) }()
+
+	c.writeMessage(models.LiveServerMessage{Type: models.LiveReady, HeartbeatSeconds: int(heartbeat.Seconds()), ExpiresAt: timePointer(expiresAt)})
+	for {
+		var err error
+		select {
+		case <-c.closed:
+			_ = c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(c.closeCode, c.reason), time.Now().Add(liveWriteTimeout))
+			// Give the client a moment to confirm the close before the reader gives up
+			_ = c.conn.SetReadDeadline(time.Now().Add(liveCloseGracePeriod))
+			return
+		case <-expired:
+			c.close(websocket.CloseNormalClosure, "token expired")
+		case at := <-c.renewed:
+			expireAt(at)
+		case <-revalidate.C:
+			c.revalidate()
+		case <-pings.C:
+			err = c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(liveWriteTimeout))
+		case message := <-c.send:
+			err = c.writeMessage(message)
+		case event := <-sightings.Messages():
+			err = c.writeSighting(event)
+		case event := <-c.events:
+			err = c.writeUserEvent(event)
+		}
+		if sightings.Stats().Dropped > 0 {
+			c.close(websocket.CloseTryAgainLater, "client too slow")
+		}
+		if err != nil {
+			// A write timed out or the connection is gone, the reader fails as well once it is closed
+			c.close(websocket.CloseGoingAway, "")
+			c.conn.Close()
+			return
+		}
+	}
+}
+
+func (c *liveClient) writeSighting(event models.SightingStreamEvent) error {
+	c.mu.Lock()
+	viewer := c.viewer
+	var matched []string
+	for id, filter := range c.subscriptions {
+		viewer.filter = filter
+		if _, ok := viewer.view(event); ok {
+			matched = append(matched, id)
+		}
+	}
+	c.mu.Unlock()
+	if len(matched) == 0 {
+		return nil
+	}
+	sort.Strings(matched)
+	sighting, _ := viewer.view(event)
+	return c.writeMessage(models.LiveServerMessage{Type: models.LiveSighting, Subscriptions: matched, Event: event.Type, Sighting: &sighting})
+}
+
+func (c *liveClient) writeUserEvent(event models.UserEvent) error {
+	if event.Upload != nil {
+		return c.writeMessage(models.LiveServerMessage{Type: models.LiveUploadProgress, Upload: event.Upload})
+	}
+	return c.writeMessage(models.LiveServerMessage{Type: models.LiveNotification, Notification: event.Notification})
+}
+
+func (c *liveClient) writeMessage(message models.LiveServerMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	if err := c.conn.SetWriteDeadline(time.Now().Add(liveWriteTimeout)); err != nil {
+		return err
+	}
+	return c.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// close makes the writer send a close frame with the code and stop.
+func (c *liveClient) close(code int, reason string) {
+	c.closeOnce.Do(func() {
+		c.closeCode, c.reason = code, reason
+		close(c.closed)
+		if code == websocket.CloseTryAgainLater {
+			c.logger.Warnf("Closing the live channel of %s: %s", c.username, reason)
+		}
+	})
+}
+
+// sendUserEvent hands the event to the live channels of its user on this instance. A channel that cannot keep
+// up is closed, the app reconnects. Upload progress is sent this way directly, it only reaches channels on the
+// instance receiving the upload.
+func sendUserEvent(event models.UserEvent) {
+	liveClientsMu.Lock()
+	defer liveClientsMu.Unlock()
+	for client := range liveUsers[event.UserID] {
+		select {
+		case client.events <- event:
+		default:
+			client.close(websocket.CloseTryAgainLater, "client too slow")
+		}
+	}
+}
+
+// forwardUserEvents hands the notifications published on UserEventTopic, by this or another instance, to the
+// live channels of their users.
+func forwardUserEvents() {
+	events := messaging.Subscribe(GetMessagingQueue(), UserEventTopic, messaging.SubscribeOptions{
+		Name: "live-channels", Capacity: userEventQueue, Overflow: messaging.DropOldest,
+	})
+	for event := range events.Messages() {
+		sendUserEvent(event)
+	}
+}
+
+// CloseLiveChannels asks the apps to reconnect, e.g. to another instance during a deploy.
+func CloseLiveChannels() {
+	liveClientsMu.Lock()
+	defer liveClientsMu.Unlock()
+	for _, clients := range liveUsers {
+		for client := range clients {
+			client.close(websocket.CloseGoingAway, "server shutting down")
+		}
+	}
+}
+
+// sameOrigin accepts the mobile app, which sends no Origin, and pages of the service itself. Browsers send the
+// session cookie along with cross-site handshakes, which must not open a channel for another site.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	parsed, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if parsed.Host == r.Host {
+		return true
+	}
+	public, err := url.Parse(config.GetEnvVar("PUBLIC_BASE_URL"))
+	return err == nil && public.Host != "" && parsed.Scheme == public.Scheme && parsed.Host == public.Host
+}
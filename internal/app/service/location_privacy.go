@@ -0,0 +1,158 @@
+package service
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// LocationAccess describes how precisely a caller may see tiger coordinates.
+type LocationAccess int
+
+const (
+	// LocationCoarse snaps coordinates to a grid and hides recent positions.
+	LocationCoarse LocationAccess = iota
+	// LocationExact exposes coordinates as reported.
+	LocationExact
+)
+
+const (
+	defaultLocationGridDegrees = 0.1
+	defaultLocationEmbargo     = 72 * time.Hour
+
+	locationPrecisionExact  = "exact"
+	locationPrecisionCoarse = "coarse"
+)
+
+// LocationPrivacy holds the coarse grid size and embargo applied to callers without exact access.
+type LocationPrivacy struct {
+	access      LocationAccess
+	gridDegrees float64
+	embargo     time.Duration
+}
+
+// locationAccessFor returns the access level granted by the roles. Anonymous users (no roles) only get coarse locations.
+func locationAccessFor(roles []string) LocationAccess {
+	if models.HasPermission(roles, models.PermissionLocationsExact) {
+		return LocationExact
+	}
+	return LocationCoarse
+}
+
+var (
+	locationSettings     LocationPrivacy
+	locationSettingsOnce sync.Once
+)
+
+// InitLocationPrivacy reads the grid and embargo settings. It runs once, at the latest when a location is first served.
+func InitLocationPrivacy() {
+	locationSettingsOnce.Do(func() {
+		locationSettings = LocationPrivacy{gridDegrees: defaultLocationGridDegrees, embargo: defaultLocationEmbargo}
+		if value := config.GetEnvVar("PUBLIC_LOCATION_GRID_DEGREES"); value != "" {
+			if grid, err := strconv.ParseFloat(value, 64); err == nil && grid > 0 {
+				locationSettings.gridDegrees = grid
+			} else {
+				log.Warnf("Invalid PUBLIC_LOCATION_GRID_DEGREES, falling back to %v", defaultLocationGridDegrees)
+			}
+		}
+		if value := config.GetEnvVar("PUBLIC_LOCATION_EMBARGO_HOURS"); value != "" {
+			if hours, err := strconv.Atoi(value); err == nil && hours >= 0 {
+				locationSettings.embargo = time.Duration(hours) * time.Hour
+			} else {
+				log.Warnf("Invalid PUBLIC_LOCATION_EMBARGO_HOURS, falling back to %v", defaultLocationEmbargo)
+			}
+		}
+	})
+}
+
+// NewLocationPrivacy returns the grid and embargo settings for the given access level.
+func NewLocationPrivacy(access LocationAccess) LocationPrivacy {
+	InitLocationPrivacy()
+	privacy := locationSettings
+	privacy.access = access
+	return privacy
+}
+
+// requestLocationPrivacy resolves the location privacy of the caller. Invalid or missing tokens are treated as anonymous.
+func requestLocationPrivacy(req *http.Request) LocationPrivacy {
+	access := LocationCoarse
+	if claims, err := getClaimsFromRequest(req); err == nil && claims.HasPermission(models.PermissionLocationsExact) {
+		access = LocationExact
+	}
+	return NewLocationPrivacy(access)
+}
+
+// Embargo returns how long new positions are hidden from the caller.
+func (p LocationPrivacy) Embargo() time.Duration {
+	if p.access == LocationExact {
+		return 0
+	}
+	return p.embargo
+}
+
+// Precision names the precision of the locations served to the caller.
+func (p LocationPrivacy) Precision() string {
+	if p.access == LocationExact {
+		return locationPrecisionExact
+	}
+	return locationPrecisionCoarse
+}
+
+// Coordinates returns the coordinates the caller is allowed to see.
+func (p LocationPrivacy) Coordinates(lat, lon float64) (float64, float64) {
+	if p.access == LocationExact {
+		return lat, lon
+	}
+	return SnapToGrid(lat, lon, p.gridDegrees)
+}
+
+// Embargoed reports whether a position seen at the given time is still hidden from the caller.
+func (p LocationPrivacy) Embargoed(seenAt time.Time) bool {
+	return time.Since(seenAt) < p.Embargo()
+}
+
+// SnapToGrid moves a coordinate to the centre of the grid cell of the given size (in degrees) containing it.
+func SnapToGrid(lat, lon, gridDegrees float64) (float64, float64) {
+	if gridDegrees <= 0 {
+		return lat, lon
+	}
+	snap := func(value float64) float64 {
+		snapped := math.Floor(value/gridDegrees)*gridDegrees + gridDegrees/2
+		// Trim floating point noise so responses do not hint at the original value
+		return math.Round(snapped*1e6) / 1e6
+	}
+	return snap(lat), snap(lon)
+}
+
+// ObfuscateSightings returns a copy of the sightings with coordinates the caller is allowed to see.
+// Cached listings are shared between callers, so they are never modified in place.
+func (p LocationPrivacy) ObfuscateSightings(sightings []models.Sighting) []models.Sighting {
+	result := make([]models.Sighting, len(sightings))
+	for i, sighting := range sightings {
+		sighting.LastCoordinates.Latitude, sighting.LastCoordinates.Longitude = p.Coordinates(sighting.LastCoordinates.Latitude, sighting.LastCoordinates.Longitude)
+		result[i] = sighting
+	}
+	return result
+}
+
+// ObfuscateTigers returns a copy of the tigers with coordinates the caller is allowed to see.
+// Positions still under embargo are withheld entirely.
+func (p LocationPrivacy) ObfuscateTigers(tigers []models.Tiger) []models.Tiger {
+	result := make([]models.Tiger, len(tigers))
+	for i, tiger := range tigers {
+		if p.Embargoed(tiger.LastSeenAt.Time) {
+			tiger.LastCoordinates.Latitude, tiger.LastCoordinates.Longitude = 0, 0
+			tiger.LocationWithheld = true
+		} else {
+			tiger.LastCoordinates.Latitude, tiger.LastCoordinates.Longitude = p.Coordinates(tiger.LastCoordinates.Latitude, tiger.LastCoordinates.Longitude)
+		}
+		result[i] = tiger
+	}
+	return result
+}
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"text/template"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database/repositories"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/config"
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/messaging"
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultLoginMaxFailures      = 5
+	defaultLoginMaxFailuresPerIP = 20
+	defaultLoginBackoffAfter     = 3
+	defaultLoginBackoff          = time.Second
+	defaultLoginLockout          = 15 * time.Minute
+	defaultLoginFailureWindow    = time.Hour
+)
+
+var lockoutEmailTemplate = template.Must(template.New("lockoutEmail").Parse(`
+Dear {{.Username}},
+
+Your {{.Organization}} account was locked after {{.Failures}} failed login attempts. You can log in again after {{.LockedUntil}}.
+
+If these attempts were not made by you, someone may be guessing your password. Please reset it at POST {{.ResetURL}}
+and consider enabling two-factor authentication.
+
+Best regards,
+
+{{.Organization}}
+`))
+
+// loginThrottlePolicy holds the thresholds applied to failed logins.
+type loginThrottlePolicy struct {
+	maxFailures      int
+	maxFailuresPerIP int
+	backoffAfter     int
+	backoff          time.Duration
+	lockout          time.Duration
+	window           time.Duration
+}
+
+// newLoginThrottlePolicy reads the thresholds from the environment.
+func newLoginThrottlePolicy() loginThrottlePolicy {
+	return loginThrottlePolicy{
+		maxFailures:      intFromEnv("LOGIN_MAX_FAILURES", defaultLoginMaxFailures),
+		maxFailuresPerIP: intFromEnv("LOGIN_MAX_FAILURES_PER_IP", defaultLoginMaxFailuresPerIP),
+		backoffAfter:     intFromEnv("LOGIN_BACKOFF_AFTER", defaultLoginBackoffAfter),
+		backoff:          durationFromEnv("LOGIN_BACKOFF_SECONDS", time.Second, defaultLoginBackoff),
+		lockout:          durationFromEnv("LOGIN_LOCKOUT_MINUTES", time.Minute, defaultLoginLockout),
+		window:           durationFromEnv("LOGIN_FAILURE_WINDOW_MINUTES", time.Minute, defaultLoginFailureWindow),
+	}
+}
+
+// maxFailuresFor returns the number of failures that lock the scope.
+func (p loginThrottlePolicy) maxFailuresFor(scope string) int {
+	if scope == models.ThrottleScopeIP {
+		return p.maxFailuresPerIP
+	}
+	return p.maxFailures
+}
+
+// LoginBackoff returns how long further attempts are refused after the given number of consecutive failures.
+// The delay doubles with every failure from backoffAfter on and becomes a lockout once maxFailures is reached.
+func LoginBackoff(failures int, backoffAfter int, maxFailures int, backoff time.Duration, lockout time.Duration) time.Duration {
+	if failures >= maxFailures {
+		return lockout
+	}
+	if failures < backoffAfter {
+		return 0
+	}
+	delay := backoff
+	for i := backoffAfter; i < failures && delay < lockout; i++ {
+		delay *= 2
+	}
+	if delay > lockout {
+		return lockout
+	}
+	return delay
+}
+
+// checkLoginThrottle rejects the attempt if the username or the client IP is backing off or locked.
+// Unknown usernames are throttled as well so the response does not reveal whether an account exists.
+func (u *UserService) checkLoginThrottle(w http.ResponseWriter, r *http.Request, username string) bool {
+	throttleRepo := repositories.NewLoginThrottleRepository(u.db, u.logger)
+	for scope, key := range map[string]string{models.ThrottleScopeUser: username, models.ThrottleScopeIP: clientIP(r)} {
+		lockedUntil, err := throttleRepo.GetLockedUntil(scope, key)
+		if err != nil {
+			models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to log in. Please try again", Status: http.StatusInternalServerError})
+			return false
+		}
+		if !lockedUntil.IsZero() {
+			retryAfter := int(time.Until(lockedUntil).Seconds()) + 1
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			models.HandleErrorResponse(w, models.ErrorResponse{Message: "Too many failed login attempts. Please try again later", Status: http.StatusTooManyRequests})
+			return false
+		}
+	}
+	return true
+}
+
+// recordLoginFailure counts a failed attempt for the username and the client IP. The owner of the account
+// is notified when it gets locked. user is nil for unknown usernames.
+func (u *UserService) recordLoginFailure(r *http.Request, username string, user *models.User) {
+	policy := newLoginThrottlePolicy()
+	throttleRepo := repositories.NewLoginThrottleRepository(u.db, u.logger)
+	for scope, key := range map[string]string{models.ThrottleScopeUser: username, models.ThrottleScopeIP: clientIP(r)} {
+		maxFailures := policy.maxFailuresFor(scope)
+		throttle, err := throttleRepo.RecordFailure(scope, key, policy.window, func(failures int) time.Duration {
+			return LoginBackoff(failures, policy.backoffAfter, maxFailures, policy.backoff, policy.lockout)
+		})
+		if err != nil {
+			continue
+		}
+		// Only the attempt reaching the threshold sends the email, not every attempt while locked
+		if throttle.Failures == maxFailures {
+			u.logger.Warnf("Login locked for %s %s after %d failures", scope, key, throttle.Failures)
+			if scope == models.ThrottleScopeUser && user != nil {
+				go u.sendLockoutEmail(*user, throttle)
+			}
+		}
+	}
+}
+
+// resetLoginFailures forgets the failed attempts of the username after a successful login.
+func (u *UserService) resetLoginFailures(username string) {
+	// Nothing to reset is the common case
+	_ = repositories.NewLoginThrottleRepository(u.db, u.logger).Reset(models.ThrottleScopeUser, username)
+}
+
+func (u *UserService) sendLockoutEmail(user models.User, throttle *models.LoginThrottle) {
+	var body bytes.Buffer
+	err := lockoutEmailTemplate.Execute(&body, map[string]any{
+		"Username":     user.Username,
+		"Failures":     throttle.Failures,
+		"LockedUntil":  throttle.LockedUntil.Format(time.RFC1123),
+		"ResetURL":     publicURL("/api/v1/password/forgot", nil),
+		"Organization": "Tigerhall-Kittens",
+	})
+	if err != nil {
+		u.logger.Error("Error executing email template:", err)
+		return
+	}
+	if err := messaging.NewEmailHandler(u.logger).SendEmailNotification([]string{user.Email}, "Your account has been locked", body.String()); err != nil {
+		u.logger.Error("Failed to send lockout email:", err)
+	}
+}
+
+// ListLoginThrottles godoc
+// @Summary List failed logins
+// @Description Admins list usernames and client IPs with recent failed logins, including current lockouts.
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} models.LoginThrottlesResponse
+// @Failure 403 {object} models.ErrorResponse "Caller is not an admin"
+// @Security Authorization
+// @Router /api/v1/admin/lockouts [get]
+func (u *UserService) ListLoginThrottles(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	policy := newLoginThrottlePolicy()
+	throttles, err := repositories.NewLoginThrottleRepository(u.db, u.logger).ListThrottles(policy.window)
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to fetch lockouts. Please try again", Status: http.StatusInternalServerError})
+		return
+	}
+	for i := range throttles {
+		throttles[i].Locked = throttles[i].Failures >= policy.maxFailuresFor(throttles[i].Scope) && throttles[i].LockedUntil.After(time.Now())
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(models.LoginThrottlesResponse{Throttles: throttles})
+}
+
+// ClearLoginThrottle godoc
+// @Summary Unlock a username or client IP
+// @Description Admins clear the failed logins of a username or client IP, lifting a lockout.
+// @Tags Admin
+// @Produce json
+// @Param scope path string true "user or ip"
+// @Param key path string true "Username or IP address"
+// @Success 200 {object} models.GeneralResponse
+// @Failure 403 {object} models.ErrorResponse "Caller is not an admin"
+// @Failure 404 {object} models.ErrorResponse "No failed logins"
+// @Security Authorization
+// @Router /api/v1/admin/lockouts/{scope}/{key} [delete]
+func (u *UserService) ClearLoginThrottle(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if err := repositories.NewLoginThrottleRepository(u.db, u.logger).Reset(vars["scope"], vars["key"]); err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "No failed logins found", Status: http.StatusNotFound})
+		return
+	}
+	u.logger.Infof("Login throttle cleared for %s %s", vars["scope"], vars["key"])
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(models.GeneralResponse{Message: "Unlocked"})
+}
+
+// intFromEnv reads a positive number from the environment, falling back to the default.
+func intFromEnv(name string, fallback int) int {
+	value, err := strconv.Atoi(config.GetEnvVar(name))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,421 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database/repositories"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/totp"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	audienceMFAChallenge = "mfa-challenge"
+	// Time between a correct password and entering the second factor
+	mfaChallengeTTL = 5 * time.Minute
+	totpIssuer      = "Tigerhall-Kittens"
+	// Codes of the previous and next time step are accepted as well to allow for clock drift
+	totpSkew          = 1
+	recoveryCodeCount = 10
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// completeLogin finishes a login whose first factor succeeded. Users with an authenticator app
+// get an MFA challenge token instead of a session.
+func (u *UserService) completeLogin(w http.ResponseWriter, r *http.Request, user *models.User) {
+	if !user.TOTPEnabled {
+		u.startSession(w, r, user)
+		return
+	}
+	mfaToken, err := signActionToken(audienceMFAChallenge, user.Username, user.Email, mfaChallengeTTL)
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to generate token", Status: http.StatusInternalServerError})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(models.LoginResponse{
+		Message:     "Two-factor authentication required",
+		MFARequired: true,
+		MFAToken:    mfaToken,
+	})
+}
+
+// LoginMFA godoc
+// @Summary Complete a login with the second factor
+// @Description Exchange the MFA token returned by /api/v1/login and a code from the authenticator app (or a recovery code) for the access and refresh tokens.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param request body models.MFALoginRequest true "MFA token and code"
+// @Success 200 {object} models.LoginResponse
+// @Failure 400 {object} models.ErrorResponse "Invalid JSON format"
+// @Failure 401 {object} models.ErrorResponse "Invalid or expired MFA token or code"
+// @Failure 429 {object} models.ErrorResponse "Too many failed login attempts"
+// @Router /api/v1/login/mfa [post]
+func (u *UserService) LoginMFA(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	var mfaRequest models.MFALoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&mfaRequest); err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Invalid JSON format", Status: http.StatusBadRequest})
+		return
+	}
+	claims, err := parseActionToken(mfaRequest.MFAToken, audienceMFAChallenge)
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Invalid or expired MFA token. Please log in again", Status: http.StatusUnauthorized})
+		return
+	}
+	user, err := repositories.NewUserRepository(u.db, u.logger).GetUserByUserName(claims.Subject)
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Invalid or expired MFA token. Please log in again", Status: http.StatusUnauthorized})
+		return
+	}
+	// Codes are guessed more easily than passwords, they count towards the same lockout
+	if !u.checkLoginThrottle(w, r, user.Username) {
+		return
+	}
+	mfaRepo := repositories.NewMFARepository(u.db, u.logger)
+	var valid bool
+	if mfaRequest.RecoveryCode != "" {
+		valid, err = mfaRepo.UseRecoveryCode(user.ID, hashToken(normalizeRecoveryCode(mfaRequest.RecoveryCode)))
+		if valid {
+			u.logger.Warnf("Recovery code used by %s", user.Username)
+		}
+	} else {
+		valid, err = u.checkTOTPCode(mfaRepo, user.ID, mfaRequest.Code)
+	}
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to log in. Please try again", Status: http.StatusInternalServerError})
+		return
+	}
+	if !valid {
+		u.recordLoginFailure(r, user.Username, user)
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Invalid authentication code", Status: http.StatusUnauthorized})
+		return
+	}
+	u.resetLoginFailures(user.Username)
+	u.startSession(w, r, user)
+}
+
+// EnrollTOTP godoc
+// @Summary Start TOTP enrolment
+// @Description Create a secret for an authenticator app. Two-factor authentication is enabled once a code is confirmed at /api/v1/mfa/totp/confirm.
+// @Tags MFA
+// @Produce json
+// @Success 200 {object} models.TOTPEnrollmentResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse "Two-factor authentication already enabled"
+// @Security Authorization
+// @Router /api/v1/mfa/totp/enroll [post]
+func (u *UserService) EnrollTOTP(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	user, ok := u.currentUser(w, r)
+	if !ok {
+		return
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to enrol. Please try again", Status: http.StatusInternalServerError})
+		return
+	}
+	if err := repositories.NewMFARepository(u.db, u.logger).SetPendingTOTPSecret(user.ID, secret); err != nil {
+		if errors.Is(err, repositories.ErrTOTPAlreadyEnabled) {
+			models.HandleErrorResponse(w, models.ErrorResponse{Message: "Two-factor authentication is already enabled", Status: http.StatusConflict})
+			return
+		}
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to enrol. Please try again", Status: http.StatusInternalServerError})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(models.TOTPEnrollmentResponse{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(totpIssuer, user.Username, secret),
+	})
+}
+
+// ConfirmTOTP godoc
+// @Summary Confirm TOTP enrolment
+// @Description Enable two-factor authentication with a code from the authenticator app. The response contains recovery codes, they are only shown once.
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Param request body models.TOTPCodeRequest true "Code from the authenticator app"
+// @Success 200 {object} models.RecoveryCodesResponse
+// @Failure 400 {object} models.ErrorResponse "Invalid authentication code"
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse "Two-factor authentication already enabled or not enrolled"
+// @Security Authorization
+// @Router /api/v1/mfa/totp/confirm [post]
+func (u *UserService) ConfirmTOTP(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	user, ok := u.currentUser(w, r)
+	if !ok {
+		return
+	}
+	var codeRequest models.TOTPCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&codeRequest); err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Invalid JSON format", Status: http.StatusBadRequest})
+		return
+	}
+	mfaRepo := repositories.NewMFARepository(u.db, u.logger)
+	secret, enabled, err := mfaRepo.GetTOTPSecret(user.ID)
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to enable two-factor authentication. Please try again", Status: http.StatusInternalServerError})
+		return
+	}
+	if enabled || secret == "" {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Two-factor authentication is already enabled or enrolment was not started", Status: http.StatusConflict})
+		return
+	}
+	step, valid := totp.Validate(secret, codeRequest.Code, time.Now(), totpSkew)
+	if !valid {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Invalid authentication code", Status: http.StatusBadRequest})
+		return
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to enable two-factor authentication. Please try again", Status: http.StatusInternalServerError})
+		return
+	}
+	if err := mfaRepo.EnableTOTP(user.ID, step, hashes); err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to enable two-factor authentication. Please try again", Status: http.StatusInternalServerError})
+		return
+	}
+	u.logger.Infof("Two-factor authentication enabled for %s", user.Username)
+	writeRecoveryCodes(w, "Two-factor authentication enabled. Store the recovery codes in a safe place", codes)
+}
+
+// DisableTOTP godoc
+// @Summary Disable TOTP
+// @Description Turn off two-factor authentication. Requires a current code from the authenticator app.
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Param request body models.TOTPCodeRequest true "Code from the authenticator app"
+// @Success 200 {object} models.GeneralResponse
+// @Failure 400 {object} models.ErrorResponse "Invalid authentication code"
+// @Failure 401 {object} models.ErrorResponse
+// @Security Authorization
+// @Router /api/v1/mfa/totp [delete]
+func (u *UserService) DisableTOTP(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	user, ok := u.currentUser(w, r)
+	if !ok {
+		return
+	}
+	mfaRepo, ok := u.verifiedTOTPCode(w, r, user)
+	if !ok {
+		return
+	}
+	if err := mfaRepo.DisableTOTP(user.ID); err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to disable two-factor authentication. Please try again", Status: http.StatusInternalServerError})
+		return
+	}
+	u.logger.Warnf("Two-factor authentication disabled for %s", user.Username)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(models.GeneralResponse{Message: "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Regenerate recovery codes
+// @Description Replace all recovery codes. Requires a current code from the authenticator app.
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Param request body models.TOTPCodeRequest true "Code from the authenticator app"
+// @Success 200 {object} models.RecoveryCodesResponse
+// @Failure 400 {object} models.ErrorResponse "Invalid authentication code"
+// @Failure 401 {object} models.ErrorResponse
+// @Security Authorization
+// @Router /api/v1/mfa/recovery-codes [post]
+func (u *UserService) RegenerateRecoveryCodes(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	user, ok := u.currentUser(w, r)
+	if !ok {
+		return
+	}
+	mfaRepo, ok := u.verifiedTOTPCode(w, r, user)
+	if !ok {
+		return
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to generate recovery codes. Please try again", Status: http.StatusInternalServerError})
+		return
+	}
+	if err := mfaRepo.ReplaceRecoveryCodes(user.ID, hashes); err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to generate recovery codes. Please try again", Status: http.StatusInternalServerError})
+		return
+	}
+	writeRecoveryCodes(w, "New recovery codes generated, the previous ones stopped working", codes)
+}
+
+// GetMFAPolicy godoc
+// @Summary Get the two-factor authentication policy
+// @Description List the roles that are only granted to users with two-factor authentication.
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} models.MFAPolicy
+// @Failure 403 {object} models.ErrorResponse "Caller is not an admin"
+// @Security Authorization
+// @Router /api/v1/admin/mfa-policy [get]
+func (u *UserService) GetMFAPolicy(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	roles, err := repositories.NewMFARepository(u.db, u.logger).GetRequiredRoles()
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to fetch the policy. Please try again", Status: http.StatusInternalServerError})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(models.MFAPolicy{RequiredRoles: roles})
+}
+
+// SetMFAPolicy godoc
+// @Summary Set the two-factor authentication policy
+// @Description Admins choose the roles that need two-factor authentication. Users holding them without an authenticator app log in without these roles until they enrol.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param policy body models.MFAPolicy true "Roles requiring two-factor authentication"
+// @Success 200 {object} models.MFAPolicy
+// @Failure 400 {object} models.ErrorResponse "Unknown role"
+// @Failure 403 {object} models.ErrorResponse "Caller is not an admin"
+// @Security Authorization
+// @Router /api/v1/admin/mfa-policy [put]
+func (u *UserService) SetMFAPolicy(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	var policy models.MFAPolicy
+	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Invalid JSON format", Status: http.StatusBadRequest})
+		return
+	}
+	for _, role := range policy.RequiredRoles {
+		if !models.IsValidRole(role) {
+			models.HandleErrorResponse(w, models.ErrorResponse{Message: "Unknown role " + role, Status: http.StatusBadRequest})
+			return
+		}
+	}
+	mfaRepo := repositories.NewMFARepository(u.db, u.logger)
+	if err := mfaRepo.SetRequiredRoles(policy.RequiredRoles); err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to update the policy. Please try again", Status: http.StatusInternalServerError})
+		return
+	}
+	u.logger.Infof("Two-factor authentication required for roles %v", policy.RequiredRoles)
+	u.GetMFAPolicy(ctx, w, r)
+}
+
+// effectiveRoles returns the roles to put into the access token of the user. Roles the policy reserves for
+// users with two-factor authentication are withheld until the user enrols.
+func effectiveRoles(db *sql.DB, logger *logrus.Logger, user *models.User) []string {
+	if user.TOTPEnabled {
+		return user.Roles
+	}
+	required, err := repositories.NewMFARepository(db, logger).GetRequiredRoles()
+	if err != nil {
+		// Fail closed, the privileges are not worth a policy bypass
+		logger.Error("Failed to load MFA policy, withholding roles:", err)
+		return []string{}
+	}
+	roles := []string{}
+	for _, role := range user.Roles {
+		if !containsString(required, role) {
+			roles = append(roles, role)
+		}
+	}
+	if len(roles) < len(user.Roles) {
+		logger.Infof("Withholding roles of %s until two-factor authentication is enabled", user.Username)
+	}
+	return roles
+}
+
+// checkTOTPCode validates a code of the user's authenticator app and makes sure it was not used before.
+func (u *UserService) checkTOTPCode(mfaRepo *repositories.MFARepository, userID uint, code string) (bool, error) {
+	secret, enabled, err := mfaRepo.GetTOTPSecret(userID)
+	if err != nil {
+		return false, err
+	}
+	if !enabled {
+		return false, nil
+	}
+	step, valid := totp.Validate(secret, code, time.Now(), totpSkew)
+	if !valid {
+		return false, nil
+	}
+	return mfaRepo.AcceptTOTPStep(userID, step)
+}
+
+// verifiedTOTPCode reads a TOTP code from the request body and checks it, writing an error response if it is invalid.
+func (u *UserService) verifiedTOTPCode(w http.ResponseWriter, r *http.Request, user *models.User) (*repositories.MFARepository, bool) {
+	var codeRequest models.TOTPCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&codeRequest); err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Invalid JSON format", Status: http.StatusBadRequest})
+		return nil, false
+	}
+	mfaRepo := repositories.NewMFARepository(u.db, u.logger)
+	valid, err := u.checkTOTPCode(mfaRepo, user.ID, codeRequest.Code)
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to verify the code. Please try again", Status: http.StatusInternalServerError})
+		return nil, false
+	}
+	if !valid {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Invalid authentication code", Status: http.StatusBadRequest})
+		return nil, false
+	}
+	return mfaRepo, true
+}
+
+// currentUser loads the user attached to the request by AuthMiddleware, writing an error response if there is none.
+func (u *UserService) currentUser(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	claims, ok := ClaimsFromContext(r.Context())
+	if !ok {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "User not authenticated", Status: http.StatusUnauthorized})
+		return nil, false
+	}
+	user, err := repositories.NewUserRepository(u.db, u.logger).GetUserByUserName(claims.Username)
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "User not authenticated", Status: http.StatusUnauthorized})
+		return nil, false
+	}
+	return user, true
+}
+
+// generateRecoveryCodes returns new recovery codes formatted as xxxxx-xxxxx along with their hashes.
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		buf := make([]byte, 7)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(recoveryCodeEncoding.EncodeToString(buf))[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = hashToken(code)
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode strips formatting users may have added or kept from the displayed code.
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+func writeRecoveryCodes(w http.ResponseWriter, message string, codes []string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(models.RecoveryCodesResponse{Message: message, RecoveryCodes: codes})
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
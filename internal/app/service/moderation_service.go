@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database/repositories"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/config"
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/messaging"
+	"github.com/gorilla/mux"
+)
+
+// ReviewSighting godoc
+// @Summary Verify or reject a sighting
+// @Description Reviewers move a pending or disputed sighting to verified or rejected. Subscribers are notified when a pending sighting gets verified.
+// @Tags Sighting
+// @Accept json
+// @Produce json
+// @Param id path int true "Sighting ID"
+// @Param review body models.ReviewSightingRequest true "Review decision"
+// @Success 200 {object} models.Sighting
+// @Failure 400 {object} models.ErrorResponse "Invalid input format"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 403 {object} models.ErrorResponse "Caller lacks the sightings:review permission"
+// @Failure 404 {object} models.ErrorResponse "Sighting not found"
+// @Failure 409 {object} models.ErrorResponse "Sighting cannot be reviewed in its current state"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Security Authorization
+// @Security APIKey
+// @Router /api/v1/sightings/{id}/review [post]
+func (s *SightingService) ReviewSighting(ctx context.Context, rw http.ResponseWriter, req *http.Request) {
+	sightingID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		models.HandleErrorResponse(rw, models.ErrorResponse{Message: "Invalid sighting id.", Status: http.StatusBadRequest})
+		return
+	}
+	var review models.ReviewSightingRequest
+	if err := json.NewDecoder(req.Body).Decode(&review); err != nil {
+		models.HandleErrorResponse(rw, models.ErrorResponse{Message: "Invalid JSON format.", Status: http.StatusBadRequest})
+		return
+	}
+	review.Reason = strings.TrimSpace(review.Reason)
+	if review.Decision != models.SightingVerified && review.Decision != models.SightingRejected {
+		models.HandleErrorResponse(rw, models.ErrorResponse{Message: "Decision must be either verified or rejected.", Status: http.StatusBadRequest})
+		return
+	}
+	if review.Decision == models.SightingRejected && review.Reason == "" {
+		models.HandleErrorResponse(rw, models.ErrorResponse{Message: "A reason is required when rejecting a sighting.", Status: http.StatusBadRequest})
+		return
+	}
+	reviewer, err := s.currentUser(req)
+	if err != nil {
+		models.HandleErrorResponse(rw, models.ErrorResponse{Message: "User not authenticated", Status: http.StatusUnauthorized})
+		return
+	}
+	sightingRepo := repositories.NewSightingRepository(s.db, s.logger)
+	allowed := []string{models.SightingPending, models.SightingDisputed}
+	previous, err := sightingRepo.UpdateSightingStatus(sightingID, allowed, review.Decision, review.Reason, reviewer.ID)
+	if err != nil {
+		s.logger.Errorf("Failed to review sighting %d: %v", sightingID, err)
+		s.moderationError(rw, err, "Sighting cannot be reviewed in its current state.")
+		return
+	}
+	sighting, err := sightingRepo.GetSightingByID(sightingID)
+	if err != nil {
+		models.HandleErrorResponse(rw, models.ErrorResponse{Message: "Failed to fetch sighting. Please try again.", Status: http.StatusInternalServerError})
+		return
+	}
+	// The notifications are in the outbox already, this only wakes up the dispatcher
+	if previous == models.SightingPending && sighting.Status == models.SightingVerified {
+		messaging.Publish(GetMessagingQueue(), SightingVerifiedTopic, models.SightingVerifiedEvent{SightingID: sighting.ID})
+		publishSightingStreamEvent(sightingRepo, models.StreamSightingVerified, sighting)
+	}
+	s.logger.Infof("Sighting %d moved from %s to %s by %s", sightingID, previous, sighting.Status, reviewer.Username)
+	s.writeSighting(rw, sighting)
+}
+
+// DisputeSighting godoc
+// @Summary Dispute a verified sighting
+// @Description Any authenticated user can flag a verified sighting for another review. Disputed sightings are hidden from the public listing.
+// @Tags Sighting
+// @Accept json
+// @Produce json
+// @Param id path int true "Sighting ID"
+// @Param dispute body models.DisputeSightingRequest true "Dispute reason"
+// @Success 200 {object} models.Sighting
+// @Failure 400 {object} models.ErrorResponse "Invalid input format"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 404 {object} models.ErrorResponse "Sighting not found"
+// @Failure 409 {object} models.ErrorResponse "Only verified sightings can be disputed"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Security Authorization
+// @Security APIKey
+// @Router /api/v1/sightings/{id}/dispute [post]
+func (s *SightingService) DisputeSighting(ctx context.Context, rw http.ResponseWriter, req *http.Request) {
+	sightingID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		models.HandleErrorResponse(rw, models.ErrorResponse{Message: "Invalid sighting id.", Status: http.StatusBadRequest})
+		return
+	}
+	var dispute models.DisputeSightingRequest
+	if err := json.NewDecoder(req.Body).Decode(&dispute); err != nil {
+		models.HandleErrorResponse(rw, models.ErrorResponse{Message: "Invalid JSON format.", Status: http.StatusBadRequest})
+		return
+	}
+	dispute.Reason = strings.TrimSpace(dispute.Reason)
+	if dispute.Reason == "" {
+		models.HandleErrorResponse(rw, models.ErrorResponse{Message: "A reason is required to dispute a sighting.", Status: http.StatusBadRequest})
+		return
+	}
+	user, err := s.currentUser(req)
+	if err != nil {
+		models.HandleErrorResponse(rw, models.ErrorResponse{Message: "User not authenticated", Status: http.StatusUnauthorized})
+		return
+	}
+	sightingRepo := repositories.NewSightingRepository(s.db, s.logger)
+	_, err = sightingRepo.UpdateSightingStatus(sightingID, []string{models.SightingVerified}, models.SightingDisputed, dispute.Reason, 0)
+	if err != nil {
+		s.logger.Errorf("Failed to dispute sighting %d: %v", sightingID, err)
+		s.moderationError(rw, err, "Only verified sightings can be disputed.")
+		return
+	}
+	sighting, err := sightingRepo.GetSightingByID(sightingID)
+	if err != nil {
+		models.HandleErrorResponse(rw, models.ErrorResponse{Message: "Failed to fetch sighting. Please try again.", Status: http.StatusInternalServerError})
+		return
+	}
+	s.logger.Infof("Sighting %d disputed by %s", sightingID, user.Username)
+	s.writeSighting(rw, sighting)
+}
+
+// moderationError answers a failed status change: 404 for unknown sightings, 409 with the conflict message for
+// sightings in the wrong state and 500 for anything else.
+func (s *SightingService) moderationError(rw http.ResponseWriter, err error, conflict string) {
+	switch {
+	case errors.Is(err, repositories.ErrSightingNotFound):
+		models.HandleErrorResponse(rw, models.ErrorResponse{Message: "Sighting not found.", Status: http.StatusNotFound})
+	case errors.Is(err, repositories.ErrSightingStateConflict):
+		models.HandleErrorResponse(rw, models.ErrorResponse{Message: conflict, Status: http.StatusConflict})
+	default:
+		models.HandleErrorResponse(rw, models.ErrorResponse{Message: "Failed to update the sighting. Please try again.", Status: http.StatusInternalServerError})
+	}
+}
+
+// ListPendingSightings godoc
+// @Summary List sightings awaiting review
+// @Description Reviewers get the queue of pending and disputed sightings, oldest first.
+// @Tags Sighting
+// @Produce json
+// @Param status query string false "Restrict the queue to pending or disputed sightings"
+// @Param pageSize query int false "Number of sightings to retrieve per page"
+// @Param offset query int false "Offset for paginating the list"
+// @Success 200 {object} models.SightingsResponse
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 403 {object} models.ErrorResponse "Caller lacks the sightings:review permission"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Security Authorization
+// @Security APIKey
+// @Router /api/v1/sightings/pending [get]
+func (s *SightingService) ListPendingSightings(ctx context.Context, rw http.ResponseWriter, req *http.Request) {
+	statuses := []string{models.SightingPending, models.SightingDisputed}
+	switch status := req.URL.Query().Get("status"); status {
+	case "":
+	case models.SightingPending, models.SightingDisputed:
+		statuses = []string{status}
+	default:
+		models.HandleErrorResponse(rw, models.ErrorResponse{Message: "Status must be either pending or disputed.", Status: http.StatusBadRequest})
+		return
+	}
+	pageSizeStr := req.URL.Query().Get("pageSize")
+	if pageSizeStr == "" {
+		pageSizeStr = config.GetEnvVar("PAGE_SIZE")
+	}
+	pageSize, err := strconv.Atoi(pageSizeStr)
+	if err != nil {
+		models.HandleErrorResponse(rw, models.ErrorResponse{Message: "Failed to parse the request payload. Please try again.", Status: http.StatusBadRequest})
+		return
+	}
+	offsetStr := req.URL.Query().Get("offset")
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil && offsetStr != "" {
+		models.HandleErrorResponse(rw, models.ErrorResponse{Message: "Failed to parse the request payload. Please try again.", Status: http.StatusBadRequest})
+		return
+	}
+	sightingRepo := repositories.NewSightingRepository(s.db, s.logger)
+	response, err := sightingRepo.ListSightingsByStatus(statuses, pageSize, offset)
+	if err != nil {
+		s.logger.Errorf("Failed to fetch pending sightings: %v", err)
+		models.HandleErrorResponse(rw, models.ErrorResponse{Message: "Failed to fetch sightings. Please try again.", Status: http.StatusInternalServerError})
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusOK)
+	json.NewEncoder(rw).Encode(response)
+}
+
+// currentUser loads the user that sent the request.
+func (s *SightingService) currentUser(req *http.Request) (*models.User, error) {
+	claims, err := getClaimsFromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	userRepo := repositories.NewUserRepository(s.db, s.logger)
+	return userRepo.GetUserByUserName(claims.Username)
+}
+
+// isReviewer reports whether the request was sent by a reviewer. Anonymous callers are never reviewers.
+func (s *SightingService) isReviewer(req *http.Request) bool {
+	claims, err := getClaimsFromRequest(req)
+	return err == nil && claims.HasPermission(models.PermissionSightingsReview)
+}
+
+func (s *SightingService) writeSighting(rw http.ResponseWriter, sighting *models.Sighting) {
+	sighting.Image = base64.StdEncoding.EncodeToString(sighting.ImageBlob)
+	sighting.ImageBlob = nil
+	sighting.User = nil
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusOK)
+	json.NewEncoder(rw).Encode(sighting)
+}
+
+// DeleteSighting godoc
+// @Summary Delete a sighting
+// @Description Admins delete a sighting regardless of its moderation state.
+// @Tags Sighting
+// @Produce json
+// @Param id path int true "Sighting ID"
+// @Success 200 {object} models.GeneralResponse
+// @Failure 400 {object} models.ErrorResponse "Invalid sighting id"
+// @Failure 403 {object} models.ErrorResponse "Caller lacks the sightings:delete permission"
+// @Failure 404 {object} models.ErrorResponse "Sighting not found"
+// @Security Authorization
+// @Security APIKey
+// @Router /api/v1/sightings/{id} [delete]
+func (s *SightingService) DeleteSighting(ctx context.Context, rw http.ResponseWriter, req *http.Request) {
+	sightingID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		models.HandleErrorResponse(rw, models.ErrorResponse{Message: "Invalid sighting id.", Status: http.StatusBadRequest})
+		return
+	}
+	sightingRepo := repositories.NewSightingRepository(s.db, s.logger)
+	if err := sightingRepo.DeleteSighting(sightingID); err != nil {
+		s.logger.Errorf("Failed to delete sighting %d: %v", sightingID, err)
+		models.HandleErrorResponse(rw, models.ErrorResponse{Message: "Sighting not found.", Status: http.StatusNotFound})
+		return
+	}
+	s.logger.Infof("Sighting %d deleted", sightingID)
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusOK)
+	json.NewEncoder(rw).Encode(models.GeneralResponse{Message: "Sighting deleted"})
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	htmltemplate "html/template"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database/repositories"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+)
+
+const (
+	audienceUnsubscribe = "unsubscribe"
+	// unsubscribeLinkTTL is long, links in old emails should keep working
+	unsubscribeLinkTTL = 365 * 24 * time.Hour
+	// unsubscribeFromPage marks unsubscribes confirmed on the page, which get a page back instead of JSON
+	unsubscribeFromPage = "page"
+)
+
+// unsubscribePage asks to confirm an unsubscribe link opened in a browser, and shows the outcome.
+var unsubscribePage = htmltemplate.Must(htmltemplate.New("unsubscribePage").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Unsubscribe</title></head>
+<body>
+<h1>Unsubscribe from sighting emails</h1>
+<p>{{.Message}}</p>
+{{if .Action}}<form method="post" action="{{.Action}}">
+<input type="hidden" name="source" value="page">
+<button type="submit">Unsubscribe</button>
+</form>{{end}}
+</body>
+</html>
+`))
+
+// GetNotificationPreferences godoc
+// @Summary Get the notification preferences
+// @Description Returns the channels, frequency, quiet hours and muted tigers of the authenticated user.
+// @Tags Account
+// @Produce json
+// @Success 200 {object} models.NotificationPreferences
+// @Failure 401 {object} models.ErrorResponse
+// @Security Authorization
+// @Security APIKey
+// @Router /api/v1/me/notification-preferences [get]
+func (u *UserService) GetNotificationPreferences(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	user, ok := u.currentUser(w, r)
+	if !ok {
+		return
+	}
+	u.writeNotificationPreferences(w, user.ID)
+}
+
+// UpdateNotificationPreferences godoc
+// @Summary Update the notification preferences
+// @Description Replace the notification preferences of the authenticated user. An empty channel list stops all sighting notifications.
+// @Description No immediate notifications are sent during quiet hours, which are given in the time zone of the user.
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Param preferences body models.NotificationPreferences true "Notification preferences"
+// @Success 200 {object} models.NotificationPreferences
+// @Failure 400 {object} models.ErrorResponse "Invalid channel, frequency, quiet hours or time zone"
+// @Failure 401 {object} models.ErrorResponse
+// @Security Authorization
+// @Router /api/v1/me/notification-preferences [put]
+func (u *UserService) UpdateNotificationPreferences(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	user, ok := u.currentUser(w, r)
+	if !ok {
+		return
+	}
+	preferences := models.DefaultNotificationPreferences()
+	if err := json.NewDecoder(r.Body).Decode(&preferences); err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Invalid JSON format", Status: http.StatusBadRequest})
+		return
+	}
+	if preferences.Channels == nil {
+		preferences.Channels = []string{}
+	}
+	if err := preferences.Validate(); err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: err.Error(), Status: http.StatusBadRequest})
+		return
+	}
+	if err := repositories.NewNotificationRepository(u.db, u.logger).SavePreferences(user.ID, &preferences); err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to save the notification preferences. Please try again", Status: http.StatusInternalServerError})
+		return
+	}
+	u.logger.Infof("Notification preferences of %s updated", user.Username)
+	u.writeNotificationPreferences(w, user.ID)
+}
+
+// UnsubscribeConfirmation godoc
+// @Summary Confirm unsubscribing from sighting notifications
+// @Description Unsubscribe links opened in a browser show a page to confirm with, opening the link changes nothing. Link scanners of mail providers open links too.
+// @Tags Account
+// @Produce html
+// @Param token query string true "Unsubscribe token from the email"
+// @Success 200 {string} string "Confirmation page"
+// @Failure 400 {string} string "Invalid unsubscribe link"
+// @Router /api/v1/unsubscribe [get]
+func (u *UserService) UnsubscribeConfirmation(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if _, err := parseActionToken(token, audienceUnsubscribe); err != nil {
+		writeUnsubscribePage(w, http.StatusBadRequest, "This unsubscribe link is invalid or has expired.", "")
+		return
+	}
+	action := "/api/v1/unsubscribe?" + url.Values{"token": {token}}.Encode()
+	writeUnsubscribePage(w, http.StatusOK, "Stop receiving emails about tiger sightings? In-app notifications are not affected.", action)
+}
+
+// Unsubscribe godoc
+// @Summary Unsubscribe from sighting notifications
+// @Description One-click unsubscribe with the signed link included in every sighting notification. Mail clients POST to it as described in RFC 8058, as does the confirmation page, which gets a page back.
+// @Tags Account
+// @Produce json,html
+// @Param token query string true "Unsubscribe token from the email"
+// @Success 200 {object} models.GeneralResponse
+// @Failure 400 {object} models.ErrorResponse "Invalid unsubscribe link"
+// @Router /api/v1/unsubscribe [post]
+func (u *UserService) Unsubscribe(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	fromPage := r.PostFormValue("source") == unsubscribeFromPage
+	respond := func(status int, message string) {
+		if fromPage {
+			writeUnsubscribePage(w, status, message, "")
+			return
+		}
+		if status != http.StatusOK {
+			models.HandleErrorResponse(w, models.ErrorResponse{Message: message, Status: status})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(models.GeneralResponse{Message: message})
+	}
+	claims, err := parseActionToken(r.URL.Query().Get("token"), audienceUnsubscribe)
+	if err != nil {
+		respond(http.StatusBadRequest, "Invalid unsubscribe link")
+		return
+	}
+	user, err := repositories.NewUserRepository(u.db, u.logger).GetUserByUserName(claims.Subject)
+	if err != nil {
+		respond(http.StatusBadRequest, "Invalid unsubscribe link")
+		return
+	}
+	if err := repositories.NewNotificationRepository(u.db, u.logger).DisableChannel(user.ID, models.ChannelEmail); err != nil {
+		respond(http.StatusInternalServerError, "Failed to unsubscribe. Please try again")
+		return
+	}
+	u.logger.Infof("%s unsubscribed from sighting emails", user.Username)
+	respond(http.StatusOK, "You will no longer receive sighting notifications by email")
+}
+
+// writeUnsubscribePage renders the unsubscribe page with a message, and the confirmation form if action is set.
+func writeUnsubscribePage(w http.ResponseWriter, status int, message string, action string) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	unsubscribePage.Execute(w, map[string]string{"Message": message, "Action": action})
+}
+
+func (u *UserService) writeNotificationPreferences(w http.ResponseWriter, userID uint) {
+	preferences, err := repositories.NewNotificationRepository(u.db, u.logger).GetPreferences(userID)
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to fetch the notification preferences. Please try again", Status: http.StatusInternalServerError})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(preferences)
+}
+
+// unsubscribeLink returns the signed link that stops sighting emails for the user.
+func unsubscribeLink(user *models.User) (string, error) {
+	token, err := signActionToken(audienceUnsubscribe, user.Username, user.Email, unsubscribeLinkTTL)
+	if err != nil {
+		return "", err
+	}
+	return publicURL("/api/v1/unsubscribe", url.Values{"token": {token}}), nil
+}
@@ -0,0 +1,279 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database/repositories"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/config"
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/oidc"
+	"github.com/gorilla/mux"
+	"github.com/patrickmn/go-cache"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	oidcStateCookieName = "oidc_state"
+	oidcCookiePath      = "/api/v1/oidc"
+	// Time a user has to complete the login at the provider
+	oidcLoginTimeout = 10 * time.Minute
+)
+
+var (
+	oidcProviders     map[string]*oidc.Provider
+	oidcProvidersOnce sync.Once
+	oidcProvidersMu   sync.RWMutex
+
+	// pendingOIDCLogins holds the nonce and PKCE verifier of logins in progress, keyed by state
+	pendingOIDCLogins = cache.New(oidcLoginTimeout, oidcLoginTimeout)
+
+	invalidUsernameChars = regexp.MustCompile(`[^a-z0-9._-]+`)
+)
+
+// pendingOIDCLogin is a login started at our end that has not come back from the provider yet.
+type pendingOIDCLogin struct {
+	provider     string
+	nonce        string
+	codeVerifier string
+	// linkUserID is set when a logged in user links an identity to their account
+	linkUserID uint
+}
+
+// loadOIDCProviders reads the providers listed in OIDC_PROVIDERS. Each provider NAME is configured with
+// OIDC_NAME_ISSUER_URL, OIDC_NAME_CLIENT_ID and OIDC_NAME_CLIENT_SECRET.
+func loadOIDCProviders() {
+	oidcProvidersOnce.Do(func() {
+		oidcProvidersMu.Lock()
+		defer oidcProvidersMu.Unlock()
+		if oidcProviders == nil {
+			oidcProviders = map[string]*oidc.Provider{}
+		}
+		for _, name := range strings.Split(config.GetEnvVar("OIDC_PROVIDERS"), ",") {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+			prefix := "OIDC_" + strings.ToUpper(name) + "_"
+			oidcProviders[name] = oidc.NewProvider(oidc.Config{
+				Name:         name,
+				IssuerURL:    config.GetEnvVar(prefix + "ISSUER_URL"),
+				ClientID:     config.GetEnvVar(prefix + "CLIENT_ID"),
+				ClientSecret: config.GetEnvVar(prefix + "CLIENT_SECRET"),
+				RedirectURL:  OIDCRedirectURL(name),
+				Scopes:       []string{"email", "profile"},
+			}, nil)
+			log.Infof("OIDC provider %s configured", name)
+		}
+	})
+}
+
+// RegisterOIDCProvider adds a provider besides the configured ones, e.g. a mock provider in tests.
+func RegisterOIDCProvider(provider *oidc.Provider) {
+	loadOIDCProviders()
+	oidcProvidersMu.Lock()
+	defer oidcProvidersMu.Unlock()
+	oidcProviders[provider.Name()] = provider
+}
+
+// OIDCRedirectURL returns the callback URL to register at the provider.
+func OIDCRedirectURL(name string) string {
+	return publicURL("/api/v1/oidc/"+name+"/callback", nil)
+}
+
+func getOIDCProvider(name string) (*oidc.Provider, bool) {
+	loadOIDCProviders()
+	oidcProvidersMu.RLock()
+	defer oidcProvidersMu.RUnlock()
+	provider, ok := oidcProviders[name]
+	return provider, ok
+}
+
+// OIDCLogin godoc
+// @Summary Log in with an OpenID Connect provider
+// @Description Redirect to the login page of the provider (authorization code flow with PKCE). Logged in users calling it with their access token link the identity to their account, API keys never link.
+// @Tags User
+// @Param provider path string true "Provider name"
+// @Success 302 "Redirect to the provider"
+// @Failure 404 {object} models.ErrorResponse "Unknown provider"
+// @Failure 502 {object} models.ErrorResponse "Provider not reachable"
+// @Router /api/v1/oidc/{provider}/login [get]
+func (u *UserService) OIDCLogin(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	provider, ok := getOIDCProvider(mux.Vars(r)["provider"])
+	if !ok {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Unknown login provider", Status: http.StatusNotFound})
+		return
+	}
+	state, err := oidc.NewState()
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to start the login. Please try again", Status: http.StatusInternalServerError})
+		return
+	}
+	nonce, err := oidc.NewState()
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to start the login. Please try again", Status: http.StatusInternalServerError})
+		return
+	}
+	codeVerifier, err := oidc.NewCodeVerifier()
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to start the login. Please try again", Status: http.StatusInternalServerError})
+		return
+	}
+	pending := pendingOIDCLogin{provider: provider.Name(), nonce: nonce, codeVerifier: codeVerifier}
+	// Only a login links identities, whoever holds a leaked API key must not be able to attach their own identity
+	// and log in interactively as the owner of the key
+	if claims, err := getClaimsFromRequest(r); err == nil && claims.APIKeyID == "" {
+		if user, err := repositories.NewUserRepository(u.db, u.logger).GetUserByUserName(claims.Username); err == nil {
+			pending.linkUserID = user.ID
+		}
+	}
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, codeVerifier)
+	if err != nil {
+		u.logger.Error("Failed to reach OIDC provider:", err)
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Login provider is not available", Status: http.StatusBadGateway})
+		return
+	}
+	pendingOIDCLogins.Set(state, pending, cache.DefaultExpiration)
+	// The cookie ties the state to this browser, so nobody can make a victim complete their login
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookieName,
+		Value:    state,
+		Path:     oidcCookiePath,
+		MaxAge:   int(oidcLoginTimeout.Seconds()),
+		HttpOnly: true,
+		// The callback is a cross site redirect from the provider, Strict would drop the cookie
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// OIDCCallback godoc
+// @Summary Complete an OpenID Connect login
+// @Description The provider redirects here after login. The identity is linked to an existing user with the same verified email or a new user is created.
+// @Tags User
+// @Produce json
+// @Param provider path string true "Provider name"
+// @Param code query string true "Authorization code"
+// @Param state query string true "State"
+// @Success 200 {object} models.LoginResponse
+// @Failure 400 {object} models.ErrorResponse "Invalid or expired login"
+// @Failure 401 {object} models.ErrorResponse "Provider rejected the login"
+// @Failure 409 {object} models.ErrorResponse "Email belongs to another account"
+// @Router /api/v1/oidc/{provider}/callback [get]
+func (u *UserService) OIDCCallback(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["provider"]
+	provider, ok := getOIDCProvider(name)
+	if !ok {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Unknown login provider", Status: http.StatusNotFound})
+		return
+	}
+	query := r.URL.Query()
+	if errorCode := query.Get("error"); errorCode != "" {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Login was not completed: " + errorCode, Status: http.StatusUnauthorized})
+		return
+	}
+	state := query.Get("state")
+	cookie, err := r.Cookie(oidcStateCookieName)
+	if err != nil || state == "" || cookie.Value != state {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Invalid or expired login. Please try again", Status: http.StatusBadRequest})
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookieName, Value: "", Path: oidcCookiePath, MaxAge: -1, HttpOnly: true})
+	value, found := pendingOIDCLogins.Get(state)
+	pendingOIDCLogins.Delete(state)
+	pending, _ := value.(pendingOIDCLogin)
+	if !found || pending.provider != name {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Invalid or expired login. Please try again", Status: http.StatusBadRequest})
+		return
+	}
+	claims, err := provider.Exchange(ctx, query.Get("code"), pending.codeVerifier, pending.nonce)
+	if err != nil {
+		u.logger.Error("OIDC code exchange failed:", err)
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Login provider rejected the login", Status: http.StatusUnauthorized})
+		return
+	}
+	user, err := u.userForIdentity(name, claims, pending.linkUserID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrDuplicateUser) {
+			models.HandleErrorResponse(w, models.ErrorResponse{Message: "An account with this email already exists. Log in with your password to link the identity", Status: http.StatusConflict})
+			return
+		}
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to log in. Please try again", Status: http.StatusInternalServerError})
+		return
+	}
+	u.logger.Infof("User %s logged in with %s", user.Username, name)
+	// From here on the login is the same as with a password
+	u.completeLogin(w, r, user)
+}
+
+// userForIdentity resolves the user of an external identity, linking or creating the user on first login.
+func (u *UserService) userForIdentity(provider string, claims *oidc.IDTokenClaims, linkUserID uint) (*models.User, error) {
+	identityRepo := repositories.NewIdentityRepository(u.db, u.logger)
+	userRepo := repositories.NewUserRepository(u.db, u.logger)
+	if user, err := identityRepo.GetUserByIdentity(provider, claims.Subject); err == nil {
+		return user, nil
+	}
+	if linkUserID != 0 {
+		if err := identityRepo.LinkIdentity(linkUserID, provider, claims.Subject, claims.Email); err != nil {
+			return nil, err
+		}
+		return userRepo.GetUserByID(linkUserID)
+	}
+	if claims.Email == "" {
+		return nil, errors.New("provider did not share an email address")
+	}
+	if existing, err := userRepo.GetUserByEmail(claims.Email); err == nil {
+		if !CanAutoLinkIdentity(claims, existing) {
+			return nil, repositories.ErrDuplicateUser
+		}
+		if err := identityRepo.LinkIdentity(existing.ID, provider, claims.Subject, claims.Email); err != nil {
+			return nil, err
+		}
+		return existing, nil
+	}
+	username := usernameForIdentity(claims)
+	for attempt := 0; ; attempt++ {
+		user := &models.User{Username: username, Email: claims.Email, EmailVerified: claims.EmailVerified}
+		err := identityRepo.CreateUserWithIdentity(user, provider, claims.Subject)
+		if err == nil {
+			return user, nil
+		}
+		if !errors.Is(err, repositories.ErrDuplicateUser) || attempt == 3 {
+			return nil, err
+		}
+		// The username is taken, try again with a random suffix
+		suffix, err := generateRandomToken(3)
+		if err != nil {
+			return nil, err
+		}
+		username = usernameForIdentity(claims) + "-" + strings.ToLower(invalidUsernameChars.ReplaceAllString(suffix, ""))
+	}
+}
+
+// CanAutoLinkIdentity reports whether the first login with an external identity may be linked to the existing account
+// with the same email. Both the provider and we must have verified the address, otherwise whoever registered it first
+// could take over the account. Everyone else links the identity explicitly while logged in.
+func CanAutoLinkIdentity(claims *oidc.IDTokenClaims, existing *models.User) bool {
+	return claims.EmailVerified && existing.EmailVerified && strings.EqualFold(claims.Email, existing.Email)
+}
+
+// usernameForIdentity derives a username from the preferred username or email of the identity.
+func usernameForIdentity(claims *oidc.IDTokenClaims) string {
+	username := claims.PreferredUsername
+	if username == "" {
+		username, _, _ = strings.Cut(claims.Email, "@")
+	}
+	username = strings.Trim(invalidUsernameChars.ReplaceAllString(strings.ToLower(username), "-"), "-")
+	if len(username) > 40 {
+		username = username[:40]
+	}
+	if username == "" {
+		username = "user"
+	}
+	return username
+}
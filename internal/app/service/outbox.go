@@ -0,0 +1,384 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database/repositories"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/config"
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/messaging"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultOutboxMaxAttempts = 8
+	defaultOutboxRetryBase   = 30 * time.Second
+	defaultOutboxRetention   = 14 * 24 * time.Hour
+	// maxOutboxRetryDelay caps the exponential backoff
+	maxOutboxRetryDelay = 6 * time.Hour
+	outboxBatchSize     = 50
+	// outboxLease is how long a claimed message is left alone before another attempt, should the process die
+	outboxLease = 5 * time.Minute
+)
+
+// OutboxWorker delivers the messages of the notification outbox, retrying failed ones with exponential backoff.
+// Delivery is at least once: a message whose email was sent but whose success was not recorded, e.g. because the
+// process died in between, is sent again once its lease runs out.
+type OutboxWorker struct {
+	db          *sql.DB
+	logger      *logrus.Logger
+	maxAttempts int
+	retryBase   time.Duration
+	retention   time.Duration
+}
+
+func NewOutboxWorker(db *sql.DB, logger *logrus.Logger) *OutboxWorker {
+	return &OutboxWorker{
+		db:          db,
+		logger:      logger,
+		maxAttempts: intFromEnv("OUTBOX_MAX_ATTEMPTS", defaultOutboxMaxAttempts),
+		retryBase:   durationFromEnv("OUTBOX_RETRY_BASE_SECONDS", time.Second, defaultOutboxRetryBase),
+		retention:   durationFromEnv("OUTBOX_RETENTION_DAYS", 24*time.Hour, defaultOutboxRetention),
+	}
+}
+
+// DeleteSent removes the messages delivered longer than OUTBOX_RETENTION_DAYS ago, keeping the outbox small.
+func (ow *OutboxWorker) DeleteSent() {
+	deleted, err := repositories.NewOutboxRepository(ow.db, ow.logger).DeleteSentOutboxMessages(ow.retention)
+	if err == nil && deleted > 0 {
+		ow.logger.Infof("Deleted %d sent outbox messages", deleted)
+	}
+}
+
+// ProcessDue delivers the messages that are due, batch by batch, until none are left or ctx is done.
+// A batch that was claimed is always finished. It returns the error if the outbox could not be read.
+func (ow *OutboxWorker) ProcessDue(ctx context.Context) error {
+	outboxRepo := repositories.NewOutboxRepository(ow.db, ow.logger)
+	for ctx.Err() == nil {
+		messages, err := outboxRepo.ClaimOutboxMessages(outboxBatchSize, outboxLease)
+		if err != nil {
+			return err
+		}
+		if len(messages) == 0 {
+			return nil
+		}
+		for _, message := range messages {
+			ow.process(outboxRepo, message)
+		}
+	}
+	return nil
+}
+
+func (ow *OutboxWorker) process(outboxRepo *repositories.OutboxRepository, message models.OutboxMessage) {
+	var err error
+	switch message.Kind {
+	case models.OutboxSightingVerified:
+		err = ow.expandSightingEvent(outboxRepo, message)
+	case models.OutboxEmail:
+		if err = ow.sendEmail(message); err == nil {
+			err = outboxRepo.MarkOutboxSent(message.ID)
+			ow.logNotification(message, models.NotificationSent)
+		}
+	case models.OutboxInApp:
+		var event models.UserEvent
+		if err = json.Unmarshal(message.Payload, &event); err == nil {
+			// Messages queued before events carried the user ID
+			if event.UserID == 0 {
+				event.UserID = message.UserID
+			}
+			messaging.Publish(GetMessagingQueue(), UserEventTopic, event)
+			err = outboxRepo.MarkOutboxSent(message.ID)
+		}
+	default:
+		err = fmt.Errorf("unknown outbox message kind %s", message.Kind)
+	}
+	if err == nil {
+		return
+	}
+	if message.Attempts >= ow.maxAttempts {
+		ow.logger.Errorf("Outbox message %d failed %d times, giving up: %v", message.ID, message.Attempts, err)
+		_ = outboxRepo.MarkOutboxDead(message.ID, err.Error())
+		ow.logNotification(message, models.NotificationFailed)
+		return
+	}
+	retryAt := time.Now().Add(OutboxRetryDelay(message.Attempts, ow.retryBase))
+	ow.logger.Warnf("Outbox message %d failed, retrying at %s: %v", message.ID, retryAt.Format(time.RFC3339), err)
+	_ = outboxRepo.RetryOutboxMessage(message.ID, err.Error(), retryAt)
+}
+
+// expandSightingEvent replaces the event with an email to every subscriber who wants one right away and
+// queues the sighting for the digests of the others.
+func (ow *OutboxWorker) expandSightingEvent(outboxRepo *repositories.OutboxRepository, message models.OutboxMessage) error {
+	var event models.SightingVerifiedEvent
+	if err := json.Unmarshal(message.Payload, &event); err != nil {
+		return err
+	}
+	sighting, err := repositories.NewSightingRepository(ow.db, ow.logger).GetSightingByID(event.SightingID)
+	if err != nil {
+		return err
+	}
+	// Sightings disputed or rejected before the notifications went out are not announced
+	if sighting.Status != models.SightingVerified {
+		return outboxRepo.MarkOutboxSent(message.ID)
+	}
+	deliveries, digestUserIDs, inApp, err := ow.sightingNotifications(sighting)
+	if err != nil {
+		return err
+	}
+	if err := outboxRepo.ExpandSightingEvent(message.ID, sighting.ID, deliveries, digestUserIDs); err != nil {
+		return err
+	}
+	// In-app notifications only reach users who are connected right now, they are not kept
+	for _, event := range inApp {
+		messaging.Publish(GetMessagingQueue(), UserEventTopic, event)
+	}
+	return nil
+}
+
+// sightingNotifications renders the emails about a verified sighting for the followers of the tiger and users who
+// reported it before, and the notifications for those who chose in-app notifications. Users who chose a daily or
+// weekly frequency get the sighting in their next digest instead. Notifications of users in their quiet hours are
+// queued for when the quiet hours end, in-app ones included.
+func (ow *OutboxWorker) sightingNotifications(sighting *models.Sighting) ([]models.OutboxDelivery, []uint, []models.UserEvent, error) {
+	subscribers, err := repositories.NewUserRepository(ow.db, ow.logger).GetTigerSubscribers(sighting.TigerID, sighting.User.ID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var deliveries []models.OutboxDelivery
+	var digestUserIDs []uint
+	var inApp []models.UserEvent
+	now := time.Now()
+	for _, subscriber := range subscribers {
+		preferences := subscriber.Preferences
+		wantsEmail, wantsInApp := preferences.HasChannel(models.ChannelEmail), preferences.HasChannel(models.ChannelInApp)
+		if !wantsEmail && !wantsInApp {
+			continue
+		}
+		if preferences.Frequency != models.FrequencyImmediate {
+			if wantsEmail {
+				digestUserIDs = append(digestUserIDs, subscriber.User.ID)
+			}
+			continue
+		}
+		quietUntil := preferences.QuietUntil(now)
+		// Recipients without exact access get the same coarse location as anonymous callers
+		privacy := NewLocationPrivacy(locationAccessFor(subscriber.User.Roles))
+		lat, lon := privacy.Coordinates(sighting.LastCoordinates.Latitude, sighting.LastCoordinates.Longitude)
+		// Positions under embargo are withheld, as they are from the API
+		withheld := privacy.Embargoed(sighting.Timestamp.Time)
+		if withheld {
+			lat, lon = 0, 0
+		}
+		if wantsInApp {
+			event := models.UserEvent{UserID: subscriber.User.ID, Notification: &models.UserNotification{
+				Type:              models.StreamSightingVerified,
+				SightingID:        sighting.ID,
+				TigerID:           sighting.TigerID,
+				TigerName:         sighting.TigerName,
+				Latitude:          lat,
+				Longitude:         lon,
+				LocationPrecision: privacy.Precision(),
+				LocationWithheld:  withheld,
+				SeenAt:            sighting.Timestamp.Time,
+			}}
+			if quietUntil.IsZero() {
+				inApp = append(inApp, event)
+			} else {
+				deliveries = append(deliveries, models.OutboxDelivery{Kind: models.OutboxInApp, UserID: subscriber.User.ID, Payload: event, NotBefore: quietUntil})
+			}
+		}
+		if !wantsEmail {
+			continue
+		}
+		unsubscribeURL, err := unsubscribeLink(&subscriber.User)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		data := messaging.EmailTemplateData{
+			TigerName:         sighting.TigerName,
+			Latitude:          lat,
+			Longitude:         lon,
+			LocationPrecision: privacy.Precision(),
+			LocationWithheld:  withheld,
+			SightingTime:      sighting.Timestamp.Format("2006-01-02,15:04:05"),
+			Organization:      "Tigerhall-Kittens",
+			ContactInfo:       fmt.Sprintf("Contact us at %v", config.GetEnvVar("SENDER_EMAIL")),
+			UnsubscribeURL:    unsubscribeURL,
+			ImageContentID:    sightingImageContentID(sighting.ID),
+		}
+		if !withheld {
+			data.MapURL = sightingMapURL(lat, lon, privacy.Precision())
+		}
+		email, err := renderEmail(SightingNotificationTemplate, subscriber.User.Language, data, ow.logger)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		deliveries = append(deliveries, models.OutboxDelivery{Kind: models.OutboxEmail, UserID: subscriber.User.ID, NotBefore: quietUntil,
+			Payload: models.OutboxEmailMessage{
+				To:             subscriber.User.Email,
+				Subject:        email.Subject,
+				Body:           email.Text,
+				HTMLBody:       email.HTML,
+				SightingID:     sighting.ID,
+				UnsubscribeURL: unsubscribeURL,
+			}})
+	}
+	return deliveries, digestUserIDs, inApp, nil
+}
+
+func (ow *OutboxWorker) sendEmail(message models.OutboxMessage) error {
+	var email models.OutboxEmailMessage
+	if err := json.Unmarshal(message.Payload, &email); err != nil {
+		return err
+	}
+	var opts []messaging.EmailOption
+	if email.UnsubscribeURL != "" {
+		opts = append(opts, messaging.WithListUnsubscribe(email.UnsubscribeURL))
+	}
+	if email.HTMLBody != "" {
+		opts = append(opts, messaging.WithHTML(email.HTMLBody))
+		if image, err := ow.sightingImage(email.SightingID); err != nil {
+			return err
+		} else if image != nil {
+			opts = append(opts, image)
+		}
+	}
+	return messaging.NewEmailHandler(ow.logger).SendEmailNotification([]string{email.To}, email.Subject, email.Body, opts...)
+}
+
+// sightingImage loads the photo of a sighting to embed in its email. The photo is loaded when sending rather than
+// stored with the queued email, which keeps the outbox small. Sightings without a photo, or deleted since, are
+// sent without one.
+func (ow *OutboxWorker) sightingImage(sightingID int) (messaging.EmailOption, error) {
+	if sightingID == 0 {
+		return nil, nil
+	}
+	image, err := repositories.NewSightingRepository(ow.db, ow.logger).GetSightingImage(sightingID)
+	if err != nil || len(image) == 0 {
+		return nil, err
+	}
+	contentType := http.DetectContentType(image)
+	extension := ".jpg"
+	if contentType == "image/png" {
+		extension = ".png"
+	}
+	return messaging.WithInlineImage(sightingImageContentID(sightingID), contentType, fmt.Sprintf("sighting-%d%s", sightingID, extension), image), nil
+}
+
+// sightingImageContentID names the photo of a sighting within its email.
+func sightingImageContentID(sightingID int) string {
+	return fmt.Sprintf("sighting-%d@tigerhall-kittens", sightingID)
+}
+
+// logNotification keeps the outcome of an email for the notification history in data exports.
+func (ow *OutboxWorker) logNotification(message models.OutboxMessage, status string) {
+	if message.Kind != models.OutboxEmail || message.UserID == 0 {
+		return
+	}
+	var email models.OutboxEmailMessage
+	if err := json.Unmarshal(message.Payload, &email); err != nil {
+		return
+	}
+	_ = repositories.NewNotificationRepository(ow.db, ow.logger).LogNotifications([]uint{message.UserID}, models.ChannelEmail, email.Subject, status)
+}
+
+// OutboxRetryDelay returns how long to wait before the next attempt after the given number of failed attempts.
+// The delay doubles with every attempt, up to six hours.
+func OutboxRetryDelay(attempts int, base time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxOutboxRetryDelay {
+			return maxOutboxRetryDelay
+		}
+	}
+	return min(delay, maxOutboxRetryDelay)
+}
+
+// ListOutboxMessages godoc
+// @Summary List notification outbox messages
+// @Description Admins inspect the notification outbox, e.g. the dead messages that failed too often to be retried.
+// @Tags Admin
+// @Produce json
+// @Param status query string false "pending, sent or dead"
+// @Param pageSize query int false "Number of messages to retrieve per page"
+// @Param offset query int false "Offset for paginating the list"
+// @Success 200 {object} models.OutboxResponse
+// @Failure 400 {object} models.ErrorResponse "Invalid status or pagination"
+// @Failure 403 {object} models.ErrorResponse "Caller lacks the notifications:manage permission"
+// @Security Authorization
+// @Security APIKey
+// @Router /api/v1/admin/outbox [get]
+func (u *UserService) ListOutboxMessages(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", models.OutboxPending, models.OutboxSent, models.OutboxDead:
+	default:
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Status must be pending, sent or dead", Status: http.StatusBadRequest})
+		return
+	}
+	pageSizeStr := r.URL.Query().Get("pageSize")
+	if pageSizeStr == "" {
+		pageSizeStr = config.GetEnvVar("PAGE_SIZE")
+	}
+	pageSize, err := strconv.Atoi(pageSizeStr)
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to parse the request payload. Please try again", Status: http.StatusBadRequest})
+		return
+	}
+	offsetStr := r.URL.Query().Get("offset")
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil && offsetStr != "" {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to parse the request payload. Please try again", Status: http.StatusBadRequest})
+		return
+	}
+	response, err := repositories.NewOutboxRepository(u.db, u.logger).ListOutboxMessages(status, pageSize, offset)
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to fetch the outbox. Please try again", Status: http.StatusInternalServerError})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// ReplayOutboxMessage godoc
+// @Summary Replay a dead outbox message
+// @Description Admins send a dead message back to the outbox, e.g. once the mail server is fixed. It gets a fresh retry budget.
+// @Tags Admin
+// @Produce json
+// @Param id path int true "Message ID"
+// @Success 200 {object} models.OutboxMessage
+// @Failure 400 {object} models.ErrorResponse "Invalid message id"
+// @Failure 403 {object} models.ErrorResponse "Caller lacks the notifications:manage permission"
+// @Failure 404 {object} models.ErrorResponse "No dead message with this id"
+// @Security Authorization
+// @Security APIKey
+// @Router /api/v1/admin/outbox/{id}/replay [post]
+func (u *UserService) ReplayOutboxMessage(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	messageID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Invalid message id", Status: http.StatusBadRequest})
+		return
+	}
+	message, err := repositories.NewOutboxRepository(u.db, u.logger).ReplayOutboxMessage(messageID)
+	if err == repositories.ErrOutboxMessageNotFound {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "No dead message with this id", Status: http.StatusNotFound})
+		return
+	}
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to replay the message. Please try again", Status: http.StatusInternalServerError})
+		return
+	}
+	u.logger.Infof("Outbox message %d replayed", messageID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(message)
+}
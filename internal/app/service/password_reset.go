@@ -0,0 +1,218 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	htmltemplate "html/template"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database/repositories"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/messaging"
+)
+
+const (
+	defaultPasswordResetTTL = 30 * time.Minute
+
+	forgotPasswordMessage = "If an account exists for this email, a password reset token has been sent to it"
+)
+
+var passwordResetEmailTemplate = template.Must(template.New("passwordResetEmail").Parse(`
+Dear {{.Username}},
+
+We received a request to reset the password of your {{.Organization}} account. Open the link below to choose a new password:
+
+{{.ResetLink}}
+
+Apps can instead send the token below along with the new password to POST {{.ResetURL}}:
+
+{{.Token}}
+
+The link and token can be used once and expire in {{.ExpiresIn}}. Resetting your password logs you out on all devices and revokes your API keys.
+
+If you did not ask for a password reset, you can ignore this email. Your password stays unchanged.
+
+Best regards,
+
+{{.Organization}}
+`))
+
+// passwordResetPage is the form the reset link opens, and the page shown once it is submitted.
+var passwordResetPage = htmltemplate.Must(htmltemplate.New("passwordResetPage").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Reset your password</title></head>
+<body>
+<h1>Reset your password</h1>
+{{if .Message}}<p>{{.Message}}</p>{{end}}
+{{if .Token}}<form method="post" action="/api/v1/password/reset">
+<input type="hidden" name="token" value="{{.Token}}">
+<label>New password <input type="password" name="password" autocomplete="new-password" required></label>
+<button type="submit">Reset password</button>
+</form>{{end}}
+</body>
+</html>
+`))
+
+// ForgotPassword godoc
+// @Summary Request a password reset
+// @Description Mail a single-use password reset token. The response is the same whether or not an account exists for the email.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param request body models.ForgotPasswordRequest true "Email of the account"
+// @Success 202 {object} models.GeneralResponse
+// @Failure 400 {object} models.ErrorResponse "Invalid JSON format"
+// @Router /api/v1/password/forgot [post]
+func (u *UserService) ForgotPassword(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	var forgotRequest models.ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&forgotRequest); err != nil || strings.TrimSpace(forgotRequest.Email) == "" {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Invalid JSON format", Status: http.StatusBadRequest})
+		return
+	}
+	// Looking up the account and mailing happen in the background, so the response time
+	// does not reveal whether the account exists either
+	go u.sendPasswordReset(strings.TrimSpace(forgotRequest.Email))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(models.GeneralResponse{Message: forgotPasswordMessage})
+}
+
+// sendPasswordReset stores a new reset token for the account of the email and mails it.
+func (u *UserService) sendPasswordReset(email string) {
+	user, err := repositories.NewUserRepository(u.db, u.logger).GetUserByEmail(email)
+	if err != nil {
+		u.logger.Info("Password reset requested for unknown email")
+		return
+	}
+	token, err := generateRandomToken(32)
+	if err != nil {
+		u.logger.Error("Failed to generate password reset token:", err)
+		return
+	}
+	ttl := passwordResetTTL()
+	resetRepo := repositories.NewPasswordResetRepository(u.db, u.logger)
+	if err := resetRepo.CreatePasswordReset(user.ID, hashToken(token), time.Now().Add(ttl)); err != nil {
+		return
+	}
+	var body bytes.Buffer
+	err = passwordResetEmailTemplate.Execute(&body, map[string]string{
+		"Username":     user.Username,
+		"Token":        token,
+		"ResetLink":    publicURL("/api/v1/password/reset", url.Values{"token": {token}}),
+		"ResetURL":     publicURL("/api/v1/password/reset", nil),
+		"ExpiresIn":    ttl.String(),
+		"Organization": "Tigerhall-Kittens",
+	})
+	if err != nil {
+		u.logger.Error("Error executing email template:", err)
+		return
+	}
+	if err := messaging.NewEmailHandler(u.logger).SendEmailNotification([]string{user.Email}, "Reset your password", body.String()); err != nil {
+		u.logger.Error("Failed to send password reset email:", err)
+	}
+}
+
+// ResetPasswordForm godoc
+// @Summary Open the password reset form
+// @Description The link in the password reset email opens a form to choose a new password, which is submitted to POST /api/v1/password/reset.
+// @Tags User
+// @Produce html
+// @Param token query string true "Reset token from the email"
+// @Success 200 {string} string "Password reset form"
+// @Router /api/v1/password/reset [get]
+func (u *UserService) ResetPasswordForm(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		writePasswordResetPage(w, http.StatusBadRequest, "The reset link is incomplete. Please open the link from the email again", "")
+		return
+	}
+	writePasswordResetPage(w, http.StatusOK, "", token)
+}
+
+// ResetPassword godoc
+// @Summary Reset the password
+// @Description Set a new password with a mailed reset token. The token can be used once and all sessions and API keys of the user are revoked. The form opened by the reset link posts here too and gets an HTML page back.
+// @Tags User
+// @Accept json,x-www-form-urlencoded
+// @Produce json,html
+// @Param request body models.ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} models.GeneralResponse
+// @Failure 400 {object} models.ErrorResponse "Invalid or expired reset token"
+// @Failure 500 {object} models.ErrorResponse "Failed to reset the password"
+// @Router /api/v1/password/reset [post]
+func (u *UserService) ResetPassword(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	var resetRequest models.ResetPasswordRequest
+	var err error
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	form := mediaType == "application/x-www-form-urlencoded"
+	respond := func(status int, message string) {
+		if form {
+			// The form is shown again as long as its token can still be used
+			token := resetRequest.Token
+			if status == http.StatusOK || errors.Is(err, repositories.ErrPasswordResetTokenInvalid) {
+				token = ""
+			}
+			writePasswordResetPage(w, status, message, token)
+			return
+		}
+		if status != http.StatusOK {
+			models.HandleErrorResponse(w, models.ErrorResponse{Message: message, Status: status})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(models.GeneralResponse{Message: message})
+	}
+	if form {
+		resetRequest.Token, resetRequest.Password = r.PostFormValue("token"), r.PostFormValue("password")
+	} else if json.NewDecoder(r.Body).Decode(&resetRequest) != nil {
+		respond(http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+	if resetRequest.Token == "" || resetRequest.Password == "" {
+		respond(http.StatusBadRequest, "Token and password are required")
+		return
+	}
+	passwordHash, err := hashPassword(resetRequest.Password)
+	if err != nil {
+		u.logger.Println("Error hashing password:", err)
+		respond(http.StatusInternalServerError, "Failed to reset the password. Please try again")
+		return
+	}
+	resetRepo := repositories.NewPasswordResetRepository(u.db, u.logger)
+	userID, err := resetRepo.ResetPassword(hashToken(resetRequest.Token), passwordHash)
+	if errors.Is(err, repositories.ErrPasswordResetTokenInvalid) {
+		respond(http.StatusBadRequest, "Invalid or expired reset token")
+		return
+	}
+	if err != nil {
+		u.logger.Error("Error resetting password:", err)
+		respond(http.StatusInternalServerError, "Failed to reset the password. Please try again")
+		return
+	}
+	u.logger.Infof("Password reset for user %d", userID)
+	clearTokenCookies(w)
+	respond(http.StatusOK, "Password reset. Please log in with your new password")
+}
+
+// writePasswordResetPage renders the reset page with a message, and the form when there is a token to submit.
+func writePasswordResetPage(w http.ResponseWriter, status int, message string, token string) {
+	// The token is in the URL of the page, it must not leak through caches or the Referer header
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	passwordResetPage.Execute(w, map[string]string{"Message": message, "Token": token})
+}
+
+func passwordResetTTL() time.Duration {
+	return durationFromEnv("PASSWORD_RESET_TTL_MINUTES", time.Minute, defaultPasswordResetTTL)
+}
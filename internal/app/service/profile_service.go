@@ -0,0 +1,353 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database/repositories"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/config"
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/messaging"
+)
+
+const (
+	maxDisplayNameLength = 100
+	// maxLanguageLength is the longest language tag stored
+	maxLanguageLength = 35
+	// defaultReauthMaxAge is how long after logging in with a provider accounts without password may be changed
+	defaultReauthMaxAge = 5 * time.Minute
+)
+
+// ErrUnsupportedLanguage is returned for languages emails are not written in.
+var ErrUnsupportedLanguage = errors.New("unsupported language")
+
+// languageTag matches language tags such as en, hi or hi-IN.
+var languageTag = regexp.MustCompile(`^[a-zA-Z]{2,3}(?:-[a-zA-Z0-9]{2,8})*$`)
+
+// ValidateLanguage checks the language of a profile and returns it without surrounding spaces. Languages are those
+// emails are written in, regional variants such as hi-IN fall back to hi.
+func ValidateLanguage(language string) (string, error) {
+	language = strings.TrimSpace(language)
+	if len(language) > maxLanguageLength || !languageTag.MatchString(language) || !EmailTemplates().Supports(language) {
+		return "", ErrUnsupportedLanguage
+	}
+	return language, nil
+}
+
+var emailChangedTemplate = template.Must(template.New("emailChanged").Parse(`
+Dear {{.Username}},
+
+The email address of your {{.Organization}} account was changed to {{.NewEmail}}. Notifications are sent to the new address once it is verified.
+
+If you did not make this change, please reset your password at POST {{.ResetURL}} and contact us.
+
+Best regards,
+
+{{.Organization}}
+`))
+
+// GetProfile godoc
+// @Summary Get the account of the authenticated user
+// @Description Returns username, display name, email and its verification state, roles, whether two-factor authentication is enabled and the followed tigers.
+// @Tags Account
+// @Produce json
+// @Success 200 {object} models.Profile
+// @Failure 401 {object} models.ErrorResponse
+// @Security Authorization
+// @Security APIKey
+// @Router /api/v1/me [get]
+func (u *UserService) GetProfile(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	user, ok := u.currentUser(w, r)
+	if !ok {
+		return
+	}
+	u.writeProfile(w, user.ID)
+}
+
+// UpdateProfile godoc
+// @Summary Update the account of the authenticated user
+// @Description Change the display name, email, password or whether to be notified about tigers the user reported. Fields left out are not changed. Changing the email or password requires the current password. Accounts without a password send a totp_code instead, or without two-factor authentication log in with their provider again shortly before.
+// @Description A new email has to be verified again before notifications are sent to it. A new password logs out every other session and revokes all API keys.
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Param profile body models.UpdateProfileRequest true "Changes to the account"
+// @Success 200 {object} models.Profile
+// @Failure 400 {object} models.ErrorResponse "Invalid display name, email, password or language"
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse "Current password or authentication code is incorrect, or the provider login is too old"
+// @Failure 409 {object} models.ErrorResponse "Email already taken"
+// @Failure 429 {object} models.ErrorResponse "Too many failed attempts"
+// @Security Authorization
+// @Router /api/v1/me [patch]
+func (u *UserService) UpdateProfile(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	user, ok := u.currentUser(w, r)
+	if !ok {
+		return
+	}
+	var updateRequest models.UpdateProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&updateRequest); err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Invalid JSON format", Status: http.StatusBadRequest})
+		return
+	}
+	var update models.ProfileUpdate
+	if updateRequest.DisplayName != nil {
+		displayName := strings.TrimSpace(*updateRequest.DisplayName)
+		if len(displayName) > maxDisplayNameLength {
+			models.HandleErrorResponse(w, models.ErrorResponse{Message: "Display name must be at most 100 characters", Status: http.StatusBadRequest})
+			return
+		}
+		update.DisplayName = &displayName
+	}
+	update.NotifyReportedTigers = updateRequest.NotifyReportedTigers
+	if updateRequest.Language != nil {
+		language, err := ValidateLanguage(*updateRequest.Language)
+		if err != nil {
+			models.HandleErrorResponse(w, models.ErrorResponse{Message: "Unsupported language", Status: http.StatusBadRequest})
+			return
+		}
+		update.Language = &language
+	}
+	if updateRequest.Email != nil {
+		email := strings.TrimSpace(*updateRequest.Email)
+		if address, err := mail.ParseAddress(email); err != nil || address.Address != email {
+			models.HandleErrorResponse(w, models.ErrorResponse{Message: "Invalid email address", Status: http.StatusBadRequest})
+			return
+		}
+		if email != user.Email {
+			update.Email = &email
+		}
+	}
+	if updateRequest.Password != nil {
+		if *updateRequest.Password == "" {
+			models.HandleErrorResponse(w, models.ErrorResponse{Message: "Password must not be empty", Status: http.StatusBadRequest})
+			return
+		}
+		passwordHash, err := hashPassword(*updateRequest.Password)
+		if err != nil {
+			u.logger.Println("Error hashing password:", err)
+			models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to update the account. Please try again", Status: http.StatusInternalServerError})
+			return
+		}
+		update.PasswordHash = &passwordHash
+	}
+	if (update.Email != nil || update.PasswordHash != nil) && !u.confirmIdentity(w, r, user, updateRequest.CurrentPassword, updateRequest.TOTPCode) {
+		return
+	}
+	claims, _ := ClaimsFromContext(r.Context())
+	if err := repositories.NewUserRepository(u.db, u.logger).UpdateProfile(user.ID, update, claims.SessionID); err != nil {
+		if errors.Is(err, repositories.ErrDuplicateUser) {
+			models.HandleErrorResponse(w, models.ErrorResponse{Message: "Email already taken", Status: http.StatusConflict})
+			return
+		}
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to update the account. Please try again", Status: http.StatusInternalServerError})
+		return
+	}
+	if update.PasswordHash != nil {
+		u.logger.Infof("Password changed for %s", user.Username)
+	}
+	if update.Email != nil {
+		u.logger.Infof("Email changed for %s", user.Username)
+		// The old address learns about the change, the new one has to be verified
+		go func(user models.User, newEmail string) {
+			u.sendEmailChangedNotice(user, newEmail)
+			user.Email = newEmail
+			if err := sendVerificationEmail(messaging.NewEmailHandler(u.logger), &user); err != nil {
+				u.logger.Error("Failed to send verification email:", err)
+			}
+		}(*user, *update.Email)
+	}
+	u.writeProfile(w, user.ID)
+}
+
+// ListMySightings godoc
+// @Summary List the sightings of the authenticated user
+// @Description Lists the sightings reported by the caller in every moderation state, newest first, with exact coordinates and review reasons.
+// @Tags Account
+// @Produce json
+// @Param pageSize query int false "Page size"
+// @Param offset query int false "Offset"
+// @Success 200 {object} models.SightingsResponse
+// @Failure 400 {object} models.ErrorResponse "Invalid page size or offset"
+// @Failure 401 {object} models.ErrorResponse
+// @Security Authorization
+// @Security APIKey
+// @Router /api/v1/me/sightings [get]
+func (u *UserService) ListMySightings(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	user, ok := u.currentUser(w, r)
+	if !ok {
+		return
+	}
+	pageSizeStr := r.URL.Query().Get("pageSize")
+	if pageSizeStr == "" {
+		pageSizeStr = config.GetEnvVar("PAGE_SIZE")
+	}
+	pageSize, err := strconv.Atoi(pageSizeStr)
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to parse the request payload. Please try again.", Status: http.StatusBadRequest})
+		return
+	}
+	offsetStr := r.URL.Query().Get("offset")
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil && offsetStr != "" {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to parse the request payload. Please try again.", Status: http.StatusBadRequest})
+		return
+	}
+	response, err := repositories.NewSightingRepository(u.db, u.logger).ListSightingsByUser(user.ID, pageSize, offset)
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to fetch sightings. Please try again.", Status: http.StatusInternalServerError})
+		return
+	}
+	// Reporters always see where they saw the tiger
+	response.LocationPrecision = locationPrecisionExact
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// DeleteAccount godoc
+// @Summary Delete the account of the authenticated user
+// @Description Deletes the account along with its sessions, API keys, linked identities and data exports. Reported sightings are kept without a reporter.
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Param confirmation body models.DeleteAccountRequest true "Current password, or a TOTP code for accounts without one"
+// @Success 200 {object} models.GeneralResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse "Current password or authentication code is incorrect, or the provider login is too old"
+// @Failure 429 {object} models.ErrorResponse "Too many failed attempts"
+// @Security Authorization
+// @Router /api/v1/me [delete]
+func (u *UserService) DeleteAccount(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	user, ok := u.currentUser(w, r)
+	if !ok {
+		return
+	}
+	var deleteRequest models.DeleteAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&deleteRequest); err != nil && user.Password != "" {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Invalid JSON format", Status: http.StatusBadRequest})
+		return
+	}
+	if !u.confirmIdentity(w, r, user, deleteRequest.CurrentPassword, deleteRequest.TOTPCode) {
+		return
+	}
+	// The export rows go with the user, their files have to be removed here
+	exportFiles, _ := repositories.NewDataExportRepository(u.db, u.logger).ListExportFiles(user.ID)
+	if err := repositories.NewUserRepository(u.db, u.logger).DeleteUser(user.ID); err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to delete the account. Please try again", Status: http.StatusInternalServerError})
+		return
+	}
+	u.removeExportFiles(exportFiles)
+	// The sessions are gone with the user, the access token has to be revoked explicitly
+	claims, _ := ClaimsFromContext(r.Context())
+	if err := repositories.NewSessionRepository(u.db, u.logger).RevokeToken(claims.ID, claims.ExpiresAt.Time); err != nil {
+		u.logger.Warn("Failed to revoke access token of deleted account:", err)
+	}
+	u.logger.Infof("Account %s deleted", user.Username)
+	clearTokenCookies(w)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(models.GeneralResponse{Message: "Account deleted"})
+}
+
+// confirmIdentity checks the current password before sensitive account changes. Accounts without a password,
+// created through an OpenID Connect provider, confirm with a code of their authenticator app or, without two-factor
+// authentication, by having logged in with the provider within REAUTH_MAX_AGE_MINUTES. Wrong passwords and codes
+// count as failed logins.
+func (u *UserService) confirmIdentity(w http.ResponseWriter, r *http.Request, user *models.User, password string, totpCode string) bool {
+	if !u.checkLoginThrottle(w, r, user.Username) {
+		return false
+	}
+	if user.Password != "" {
+		if err := verifyPassword(password, user.Password); err != nil {
+			u.recordLoginFailure(r, user.Username, user)
+			models.HandleErrorResponse(w, models.ErrorResponse{Message: "Current password is incorrect", Status: http.StatusForbidden})
+			return false
+		}
+		return true
+	}
+	mfaRepo := repositories.NewMFARepository(u.db, u.logger)
+	_, totpEnabled, err := mfaRepo.GetTOTPSecret(user.ID)
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to confirm the change. Please try again", Status: http.StatusInternalServerError})
+		return false
+	}
+	if totpEnabled {
+		valid, err := u.checkTOTPCode(mfaRepo, user.ID, totpCode)
+		if err != nil {
+			models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to confirm the change. Please try again", Status: http.StatusInternalServerError})
+			return false
+		}
+		if !valid {
+			u.recordLoginFailure(r, user.Username, user)
+			models.HandleErrorResponse(w, models.ErrorResponse{Message: "Authentication code is incorrect", Status: http.StatusForbidden})
+			return false
+		}
+		return true
+	}
+	claims, _ := ClaimsFromContext(r.Context())
+	loggedInAt, err := repositories.NewSessionRepository(u.db, u.logger).GetSessionCreatedAt(claims.SessionID, user.ID)
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to confirm the change. Please try again", Status: http.StatusInternalServerError})
+		return false
+	}
+	if time.Since(loggedInAt) > reauthMaxAge() {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Log in with your provider again to confirm this change", Status: http.StatusForbidden})
+		return false
+	}
+	return true
+}
+
+// reauthMaxAge is how recent the provider login of an account without password must be to change or delete it.
+func reauthMaxAge() time.Duration {
+	return durationFromEnv("REAUTH_MAX_AGE_MINUTES", time.Minute, defaultReauthMaxAge)
+}
+
+// loadProfile fetches the account of the user along with the tigers they follow.
+func (u *UserService) loadProfile(userID uint) (*models.Profile, error) {
+	profile, err := repositories.NewUserRepository(u.db, u.logger).GetProfile(userID)
+	if err != nil {
+		return nil, err
+	}
+	if profile.FollowedTigers, err = repositories.NewFollowRepository(u.db, u.logger).ListFollowedTigers(userID); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+func (u *UserService) writeProfile(w http.ResponseWriter, userID uint) {
+	profile, err := u.loadProfile(userID)
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to fetch the account. Please try again", Status: http.StatusInternalServerError})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(profile)
+}
+
+func (u *UserService) sendEmailChangedNotice(user models.User, newEmail string) {
+	var body bytes.Buffer
+	err := emailChangedTemplate.Execute(&body, map[string]string{
+		"Username":     user.Username,
+		"NewEmail":     newEmail,
+		"ResetURL":     publicURL("/api/v1/password/forgot", nil),
+		"Organization": "Tigerhall-Kittens",
+	})
+	if err != nil {
+		u.logger.Error("Error executing email template:", err)
+		return
+	}
+	if err := messaging.NewEmailHandler(u.logger).SendEmailNotification([]string{user.Email}, "Your email address was changed", body.String()); err != nil {
+		u.logger.Error("Failed to send email change notice:", err)
+	}
+}
@@ -0,0 +1,263 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database/repositories"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/config"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	refreshCookieName = "refresh_token"
+	// The refresh cookie is only sent to the refresh endpoint
+	refreshCookiePath = "/api/v1/token"
+
+	defaultAccessTokenTTL  = 5 * time.Minute
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+type SessionService struct {
+	logger *logrus.Logger
+	db     *sql.DB
+}
+
+func NewSessionService(logger *logrus.Logger, db *sql.DB) *SessionService {
+	return &SessionService{logger: logger, db: db}
+}
+
+// startSession creates a session for the user and responds with a fresh access and refresh token.
+func (u *UserService) startSession(w http.ResponseWriter, r *http.Request, user *models.User) {
+	refreshToken, err := generateRandomToken(32)
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to generate token", Status: http.StatusInternalServerError})
+		return
+	}
+	sessionID, err := generateRandomToken(16)
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to generate token", Status: http.StatusInternalServerError})
+		return
+	}
+	session := &models.Session{
+		ID:        sessionID,
+		UserID:    user.ID,
+		UserAgent: r.UserAgent(),
+		IPAddress: clientIP(r),
+		ExpiresAt: time.Now().Add(refreshTokenTTL()),
+	}
+	sessionRepo := repositories.NewSessionRepository(u.db, u.logger)
+	if err := sessionRepo.CreateSession(session, hashToken(refreshToken)); err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to log in. Please try again", Status: http.StatusInternalServerError})
+		return
+	}
+	token, _, err := generateToken(user.Username, effectiveRoles(u.db, u.logger, user), session.ID)
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to generate token", Status: http.StatusInternalServerError})
+		return
+	}
+	setTokenCookie(w, token, refreshToken)
+}
+
+// Refresh godoc
+// @Summary Refresh the access token
+// @Description Exchange a refresh token for a new access token. The refresh token is rotated, the old one stops working. Presenting a rotated refresh token again revokes the session.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param refresh body models.RefreshRequest false "Refresh token, optional when sent as cookie"
+// @Success 200 {object} models.LoginResponse
+// @Failure 401 {object} models.ErrorResponse "Invalid, expired or revoked refresh token"
+// @Failure 500 {object} models.ErrorResponse "Failed to refresh the token"
+// @Router /api/v1/token/refresh [post]
+func (ss *SessionService) Refresh(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	var refreshRequest models.RefreshRequest
+	// The body is optional, browsers rely on the refresh cookie instead
+	if err := json.NewDecoder(r.Body).Decode(&refreshRequest); err != nil && err != io.EOF {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Invalid JSON format", Status: http.StatusBadRequest})
+		return
+	}
+	if refreshRequest.RefreshToken == "" {
+		if cookie, err := r.Cookie(refreshCookieName); err == nil {
+			refreshRequest.RefreshToken = cookie.Value
+		}
+	}
+	if refreshRequest.RefreshToken == "" {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Refresh token is missing", Status: http.StatusUnauthorized})
+		return
+	}
+	sessionRepo := repositories.NewSessionRepository(ss.db, ss.logger)
+	oldHash := hashToken(refreshRequest.RefreshToken)
+	session, err := sessionRepo.GetActiveSessionByRefreshHash(oldHash)
+	if err != nil {
+		// Either the thief or the owner presents a token the other one exchanged already, log both out
+		if revoked, err := sessionRepo.RevokeSessionOfRotatedToken(oldHash); err == nil && revoked {
+			ss.logger.Warn("Rotated refresh token presented again, session revoked")
+		}
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Invalid or expired refresh token", Status: http.StatusUnauthorized})
+		return
+	}
+	// Roles may have changed since login, always issue the token from the current user record
+	user, err := repositories.NewUserRepository(ss.db, ss.logger).GetUserByID(session.UserID)
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Invalid or expired refresh token", Status: http.StatusUnauthorized})
+		return
+	}
+	refreshToken, err := generateRandomToken(32)
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to generate token", Status: http.StatusInternalServerError})
+		return
+	}
+	if err := sessionRepo.RotateRefreshToken(session.ID, oldHash, hashToken(refreshToken)); err != nil {
+		if errors.Is(err, repositories.ErrRefreshTokenReused) {
+			ss.logger.Warnf("Refresh token of user %d used twice, session revoked", session.UserID)
+			models.HandleErrorResponse(w, models.ErrorResponse{Message: "Invalid or expired refresh token", Status: http.StatusUnauthorized})
+			return
+		}
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to refresh the token. Please try again", Status: http.StatusInternalServerError})
+		return
+	}
+	token, _, err := generateToken(user.Username, effectiveRoles(ss.db, ss.logger, user), session.ID)
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to generate token", Status: http.StatusInternalServerError})
+		return
+	}
+	setTokenCookie(w, token, refreshToken)
+}
+
+// ListSessions godoc
+// @Summary List active sessions
+// @Description List the devices and browsers the authenticated user is logged in from.
+// @Tags User
+// @Produce json
+// @Success 200 {object} models.SessionsResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security Authorization
+// @Router /api/v1/sessions [get]
+func (ss *SessionService) ListSessions(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	claims, user, ok := ss.currentUser(w, r)
+	if !ok {
+		return
+	}
+	sessions, err := repositories.NewSessionRepository(ss.db, ss.logger).ListActiveSessions(user.ID)
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to fetch sessions. Please try again", Status: http.StatusInternalServerError})
+		return
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == claims.SessionID
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(models.SessionsResponse{Sessions: sessions})
+}
+
+// RevokeSession godoc
+// @Summary Revoke a session
+// @Description Log out a device or browser of the authenticated user. Its access and refresh tokens stop working immediately.
+// @Tags User
+// @Produce json
+// @Param id path string true "Session ID"
+// @Success 200 {object} models.GeneralResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse "Session not found"
+// @Security Authorization
+// @Router /api/v1/sessions/{id} [delete]
+func (ss *SessionService) RevokeSession(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	claims, user, ok := ss.currentUser(w, r)
+	if !ok {
+		return
+	}
+	sessionID := mux.Vars(r)["id"]
+	if err := repositories.NewSessionRepository(ss.db, ss.logger).RevokeSession(sessionID, user.ID); err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Session not found", Status: http.StatusNotFound})
+		return
+	}
+	if sessionID == claims.SessionID {
+		clearTokenCookies(w)
+	}
+	ss.logger.Infof("Session revoked for %s", user.Username)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(models.GeneralResponse{Message: "Session revoked"})
+}
+
+// currentUser loads the user attached to the request by AuthMiddleware, writing an error response if there is none.
+func (ss *SessionService) currentUser(w http.ResponseWriter, r *http.Request) (*models.Claims, *models.User, bool) {
+	claims, ok := ClaimsFromContext(r.Context())
+	if !ok {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "User not authenticated", Status: http.StatusUnauthorized})
+		return nil, nil, false
+	}
+	user, err := repositories.NewUserRepository(ss.db, ss.logger).GetUserByUserName(claims.Username)
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "User not authenticated", Status: http.StatusUnauthorized})
+		return nil, nil, false
+	}
+	return claims, user, true
+}
+
+// isTokenRevoked checks the access token against the revocation list and the state of its session.
+func isTokenRevoked(claims *models.Claims) (bool, error) {
+	db := database.GetDB()
+	if db == nil {
+		return false, errors.New("database connection is not available")
+	}
+	return repositories.NewSessionRepository(db, logrus.StandardLogger()).IsRevoked(claims.ID, claims.SessionID)
+}
+
+// generateRandomToken returns a URL safe random string built from n random bytes.
+func generateRandomToken(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashToken returns the hex encoded SHA-256 of a token. Only hashes of refresh tokens are stored.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// clientIP returns the address the request came from.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func accessTokenTTL() time.Duration {
+	return durationFromEnv("ACCESS_TOKEN_TTL_MINUTES", time.Minute, defaultAccessTokenTTL)
+}
+
+func refreshTokenTTL() time.Duration {
+	return durationFromEnv("REFRESH_TOKEN_TTL_HOURS", time.Hour, defaultRefreshTokenTTL)
+}
+
+// durationFromEnv reads a positive number of units from the environment, falling back to the default.
+func durationFromEnv(name string, unit time.Duration, fallback time.Duration) time.Duration {
+	value, err := strconv.Atoi(config.GetEnvVar(name))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return time.Duration(value) * unit
+}
@@ -49,6 +49,7 @@ func NewSightingService(db *sql.DB, logger *log.Logger) *SightingService {
 // @Failure 401 {object} models.ErrorResponse "Unauthorized"
 // @Failure 500 {object} models.ErrorResponse "Internal Server Error"
 // @Security Authorization
+// @Security APIKey
 // @Router /api/v1/createSights [post]
 func (s *SightingService) CreateSight(ctx context.Context, rw http.ResponseWriter, req *http.Request) {
 	//Read request and parse payload
@@ -118,31 +119,13 @@ func (s *SightingService) CreateSight(ctx context.Context, rw http.ResponseWrite
 		models.HandleErrorResponse(rw, models.ErrorResponse{Message: "Cannot submit new sighting. A tiger has been sighted within 5 kilometers recently.", Status: http.StatusInternalServerError})
 		return
 	}
-	messagequeue := GetMessagingQueue()
-	notifier := NewNotifier(s.db, s.logger)
-	_ = notifier.RegisterSightingSubscriber()
+	// Sightings stay private until a reviewer verifies them, subscribers are notified then
+	sightings.Status = models.SightingPending
+	publishSightingStreamEvent(sightingRepo, models.StreamSightingCreated, sightings)
 	s.logger.Info("Sighting created successfully")
-	users, err := userRepo.GetUsersByTigerId(sightings.TigerID, int(user.ID))
-	if err != nil {
-		s.logger.Error("Failed to create sightings.", sightings.TigerName)
-		models.HandleErrorResponse(rw, models.ErrorResponse{Message: "Failed to fetch users. Please try again.", Status: http.StatusInternalServerError})
-		return
-	}
-	sightingNotification := SightingNotification{
-		TigerName:     sightings.TigerName,
-		TigerLocation: fmt.Sprintf("Latitude:%v,Longitude:%v", sightings.LastCoordinates.Latitude, sightings.LastCoordinates.Longitude),
-		SightingTime:  sightings.Timestamp.Format("2006-01-02,15:04:05"),
-	}
-	useremails := []string{}
-	for i := range users {
-		useremails = append(useremails, users[i].Email)
-	}
-	sightingNotification.userEmails = useremails
-	messagequeue.Publish(sightingNotification)
 	rw.Header().Set("Content-Type", "application/json")
 	rw.WriteHeader(http.StatusCreated)
 	json.NewEncoder(rw).Encode(sightings)
-
 }
 
 func IsWithinRange(lat, lon, prevLat, prevLon float64) bool {
@@ -220,15 +203,17 @@ func resizeImage(imageFile io.Reader) ([]byte, error) {
 
 // ListAllSightings godoc
 // @Summary List all sightings
-// @Description Get a paginated list of all sightings
+// @Description Get a paginated list of all sightings. Anonymous callers get coordinates snapped to a coarse grid and do not see sightings newer than the embargo period, researchers get exact coordinates.
 // @Tags Sighting
 // @Accept json
 // @Produce json
 // @ID list-tiger-sightings
 // @Param id path int true "Tiger ID"
+// @Param status query string false "Moderation status to list, only reviewers may request anything other than verified"
 // @Param pageSize query int false "Number of sightings to retrieve per page"
 // @Param offset query int false "Offset for paginating the list"
 // @Success 200 {object} models.SightingsResponse "List of sightings"
+// @Failure 403 {object} models.ErrorResponse "Only reviewers can list unverified sightings"
 // @Failure 500 {object} models.ErrorResponse "Internal server error"
 // @Router /api/v1/tigers/:id/listSightings [get]
 func (s *SightingService) ListAllSightings(ctx context.Context, rw http.ResponseWriter, req *http.Request) {
@@ -256,16 +241,59 @@ func (s *SightingService) ListAllSightings(ctx context.Context, rw http.Response
 		models.HandleErrorResponse(rw, models.ErrorResponse{Message: "Failed to parse the request payload. Please try again.", Status: http.StatusInternalServerError})
 		return
 	}
+	status := req.URL.Query().Get("status")
+	if status == "" {
+		status = models.SightingVerified
+	}
+	if status != models.SightingVerified && !s.isReviewer(req) {
+		models.HandleErrorResponse(rw, models.ErrorResponse{Message: "Only reviewers can list unverified sightings.", Status: http.StatusForbidden})
+		return
+	}
+	// Anonymous callers and reporters get coarse coordinates and do not see sightings under embargo
+	privacy := requestLocationPrivacy(req)
 	tigerRepo := repositories.NewSightingRepository(s.db, s.logger)
 	tigerRepo.CacheMutex = sync.RWMutex{}
 	tigerRepo.CacheExpiry = 15 * time.Minute
-	response, err := tigerRepo.ListSightings(tigerID, pageSize, offset)
+	response, err := tigerRepo.ListSightings(tigerID, status, privacy.Embargo(), pageSize, offset)
 	if err != nil {
 		s.logger.Errorf("Failed to fetch all sightings: %v", err)
 		models.HandleErrorResponse(rw, models.ErrorResponse{Message: "Failed to fetch sightings. Please try again.", Status: http.StatusInternalServerError})
 		return
 	}
+	response = &models.SightingsResponse{
+		Sightings:         privacy.ObfuscateSightings(response.Sightings),
+		Offset:            response.Offset,
+		LocationPrecision: privacy.Precision(),
+	}
 	rw.Header().Set("Content-Type", "application/json")
 	rw.WriteHeader(http.StatusOK)
 	json.NewEncoder(rw).Encode(response)
 }
+
+// GetSightingImage godoc
+// @Summary Get the image of a sighting
+// @Description Returns the JPEG image of a verified sighting, e.g. for thumbnails in digest emails.
+// @Tags Sighting
+// @Produce image/jpeg
+// @Param id path int true "Sighting ID"
+// @Success 200 {file} file "JPEG image"
+// @Failure 400 {object} models.ErrorResponse "Invalid sighting id"
+// @Failure 404 {object} models.ErrorResponse "Sighting or image not found"
+// @Router /api/v1/sightings/{id}/image [get]
+func (s *SightingService) GetSightingImage(ctx context.Context, rw http.ResponseWriter, req *http.Request) {
+	sightingID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		models.HandleErrorResponse(rw, models.ErrorResponse{Message: "Invalid sighting id.", Status: http.StatusBadRequest})
+		return
+	}
+	sighting, err := repositories.NewSightingRepository(s.db, s.logger).GetSightingByID(sightingID)
+	// Unverified sightings are private, they are not found for the public
+	if err != nil || sighting.Status != models.SightingVerified || len(sighting.ImageBlob) == 0 {
+		models.HandleErrorResponse(rw, models.ErrorResponse{Message: "Image not found.", Status: http.StatusNotFound})
+		return
+	}
+	rw.Header().Set("Content-Type", "image/jpeg")
+	rw.Header().Set("Cache-Control", "public, max-age=86400")
+	rw.WriteHeader(http.StatusOK)
+	rw.Write(sighting.ImageBlob)
+}
@@ -0,0 +1,286 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database/repositories"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/messaging"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultSightingStreamHistory = 500
+	// sightingStreamKeepAlive keeps proxies from closing idle streams
+	sightingStreamKeepAlive = 15 * time.Second
+	// sightingStreamRetry is how long browsers wait before reconnecting, in milliseconds
+	sightingStreamRetry = 3000
+	// sightingStreamClientQueue is how many events may wait for a slow client before it is disconnected
+	sightingStreamClientQueue = 64
+	// sightingStreamRevalidate is how often the credentials of an open stream are checked again, the session or
+	// API key may be revoked or the roles changed in the meantime
+	sightingStreamRevalidate = time.Minute
+)
+
+// SightingStreamTopic carries the events of the live sighting feed.
+var SightingStreamTopic = messaging.NewTopic[models.SightingStreamEvent]("sightings.stream")
+
+var (
+	sightingStream   *SightingStream
+	sightingStreamMu sync.RWMutex
+)
+
+// SightingStream keeps the recent events of the live feed, so clients reconnecting with Last-Event-ID get the
+// events they missed, and ends open streams on shutdown.
+type SightingStream struct {
+	logger       *logrus.Logger
+	subscription *messaging.Subscription[models.SightingStreamEvent]
+	done         chan struct{}
+	closeOnce    sync.Once
+
+	mu       sync.RWMutex
+	capacity int
+	// history holds the latest events in the order they arrived
+	history []models.SightingStreamEvent
+	// completeSince is the ID from which on the history has every event, it is only known once an event arrived
+	completeSince int64
+}
+
+// StartSightingStream starts recording the events of the feed, keeping the last SIGHTING_STREAM_HISTORY_SIZE.
+func StartSightingStream(logger *logrus.Logger) *SightingStream {
+	ss := &SightingStream{
+		logger: logger,
+		subscription: messaging.Subscribe(GetMessagingQueue(), SightingStreamTopic, messaging.SubscribeOptions{
+			Name: "sighting-stream-history", Capacity: 1024, Overflow: messaging.DropOldest,
+		}),
+		done:          make(chan struct{}),
+		capacity:      intFromEnv("SIGHTING_STREAM_HISTORY_SIZE", defaultSightingStreamHistory),
+		completeSince: math.MaxInt64,
+	}
+	go ss.record()
+	sightingStreamMu.Lock()
+	sightingStream = ss
+	sightingStreamMu.Unlock()
+	return ss
+}
+
+func (ss *SightingStream) record() {
+	for event := range ss.subscription.Messages() {
+		ss.mu.Lock()
+		// Events from before the start are lost, clients resuming from them get a reset
+		if ss.completeSince == math.MaxInt64 {
+			ss.completeSince = event.ID
+		}
+		if len(ss.history) == ss.capacity {
+			ss.completeSince = ss.history[0].ID + 1
+			ss.history = ss.history[1:]
+		}
+		ss.history = append(ss.history, event)
+		ss.mu.Unlock()
+	}
+}
+
+// Close ends the open streams, which would otherwise keep a graceful shutdown waiting.
+func (ss *SightingStream) Close() {
+	ss.closeOnce.Do(func() {
+		ss.subscription.Unsubscribe()
+		close(ss.done)
+	})
+}
+
+// since returns the events that arrived after the one with the given ID. The result is not complete if the
+// event is no longer in the history, in which case the client has to reload the listing.
+func (ss *SightingStream) since(lastID int64) (events []models.SightingStreamEvent, complete bool) {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+	for i, event := range ss.history {
+		if event.ID == lastID {
+			return append(events, ss.history[i+1:]...), true
+		}
+	}
+	for _, event := range ss.history {
+		if event.ID > lastID {
+			events = append(events, event)
+		}
+	}
+	return events, lastID >= ss.completeSince
+}
+
+// publishSightingStreamEvent announces a sighting on the live feed. The event carries the exact location,
+// it is reduced to what each client may see when it is streamed. Its ID comes from a database sequence, so
+// clients can resume from it on any instance. The feed is best effort, without an ID the event is not sent.
+func publishSightingStreamEvent(sightingRepo *repositories.SightingRepository, eventType string, sighting *models.Sighting) {
+	id, err := sightingRepo.NextStreamEventID()
+	if err != nil {
+		return
+	}
+	messaging.Publish(GetMessagingQueue(), SightingStreamTopic, models.SightingStreamEvent{
+		ID:   id,
+		Type: eventType,
+		Sighting: models.StreamSighting{
+			SightingID: sighting.ID,
+			TigerID:    sighting.TigerID,
+			TigerName:  sighting.TigerName,
+			Latitude:   sighting.LastCoordinates.Latitude,
+			Longitude:  sighting.LastCoordinates.Longitude,
+			SeenAt:     sighting.Timestamp.Time,
+			Status:     sighting.Status,
+		},
+	})
+}
+
+// sightingStreamViewer decides which events a client receives and how precisely.
+type sightingStreamViewer struct {
+	reviewer bool
+	privacy  LocationPrivacy
+	filter   models.SightingStreamFilter
+}
+
+// view returns the sighting of the event as the viewer may see it, or false if the viewer does not get the event.
+func (v sightingStreamViewer) view(event models.SightingStreamEvent) (models.StreamSighting, bool) {
+	if event.Type != models.StreamSightingVerified && !v.reviewer {
+		return models.StreamSighting{}, false
+	}
+	sighting := event.Sighting
+	sighting.LocationPrecision = v.privacy.Precision()
+	if v.privacy.Embargoed(sighting.SeenAt) {
+		sighting.Latitude, sighting.Longitude = 0, 0
+		sighting.LocationWithheld = true
+	} else {
+		sighting.Latitude, sighting.Longitude = v.privacy.Coordinates(sighting.Latitude, sighting.Longitude)
+	}
+	return sighting, v.filter.Matches(sighting)
+}
+
+// StreamSightings godoc
+// @Summary Live feed of sightings
+// @Description Server-Sent Events stream of sightings as they are verified, and for reviewers also as they are reported. Each event has the sighting as JSON data, its type as the event name and an ID to resume from with the Last-Event-ID header. A reset event means events were missed and the listing should be reloaded. Anonymous callers get coarse coordinates and no coordinates for positions under embargo. Streams of authenticated callers end when the token expires, or once a minute check finds the session revoked or the permissions changed; reconnect with fresh credentials.
+// @Tags Sighting
+// @Produce text/event-stream
+// @Param tiger_id query []int false "Only sightings of these tigers, repeated or comma separated" collectionFormat(csv)
+// @Param bbox query string false "Only sightings in the area min_lon,min_lat,max_lon,max_lat"
+// @Param Last-Event-ID header string false "ID of the last event received, to resume after a disconnect"
+// @Success 200 {object} models.StreamSighting "Stream of sighting.created, sighting.verified and reset events"
+// @Failure 400 {object} models.ErrorResponse "Invalid tiger_id or bbox"
+// @Failure 503 {object} models.ErrorResponse "Live feed not available"
+// @Router /api/v1/stream/sightings [get]
+func StreamSightings(w http.ResponseWriter, r *http.Request) {
+	filter, err := models.ParseSightingStreamFilter(r.URL.Query())
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: err.Error(), Status: http.StatusBadRequest})
+		return
+	}
+	sightingStreamMu.RLock()
+	ss := sightingStream
+	sightingStreamMu.RUnlock()
+	if ss == nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Live feed not available", Status: http.StatusServiceUnavailable})
+		return
+	}
+	claims, err := getClaimsFromRequest(r)
+	authenticated := err == nil
+	viewer := sightingStreamViewer{
+		reviewer: authenticated && claims.HasPermission(models.PermissionSightingsReview),
+		privacy:  requestLocationPrivacy(r),
+		filter:   filter,
+	}
+	// What the viewer gets was decided by the credentials, the stream must not outlive them
+	var expired, revalidate <-chan time.Time
+	if authenticated {
+		if claims.ExpiresAt != nil {
+			expiry := time.NewTimer(time.Until(claims.ExpiresAt.Time))
+			defer expiry.Stop()
+			expired = expiry.C
+		}
+		ticker := time.NewTicker(sightingStreamRevalidate)
+		defer ticker.Stop()
+		revalidate = ticker.C
+	}
+	// Subscribe before replaying, so no event falls between the history and the live events
+	sub := messaging.Subscribe(GetMessagingQueue(), SightingStreamTopic, messaging.SubscribeOptions{
+		Name: "sighting-stream-client", Capacity: sightingStreamClientQueue, Overflow: messaging.DropNewest,
+	})
+	defer sub.Unsubscribe()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	// Keeps nginx from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", sightingStreamRetry)
+
+	replayed := map[int64]bool{}
+	if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" {
+		lastID, err := strconv.ParseInt(lastEventID, 10, 64)
+		events, complete := ss.since(lastID)
+		if err != nil || !complete {
+			fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+		}
+		for _, event := range events {
+			replayed[event.ID] = true
+			if writeSightingStreamEvent(w, viewer, event) != nil {
+				return
+			}
+		}
+	}
+	if rc.Flush() != nil {
+		return
+	}
+	keepAlive := time.NewTicker(sightingStreamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ss.done:
+			return
+		case <-expired:
+			return
+		case <-revalidate:
+			current, err := getClaimsFromRequest(r)
+			if err != nil || current.HasPermission(models.PermissionSightingsReview) != viewer.reviewer ||
+				requestLocationPrivacy(r) != viewer.privacy {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil || rc.Flush() != nil {
+				return
+			}
+		case event, ok := <-sub.Messages():
+			if !ok {
+				return
+			}
+			// The client fell behind and missed events, it resumes from the history when it reconnects
+			if sub.Stats().Dropped > 0 {
+				return
+			}
+			if replayed[event.ID] {
+				continue
+			}
+			if writeSightingStreamEvent(w, viewer, event) != nil || rc.Flush() != nil {
+				return
+			}
+		}
+	}
+}
+
+// writeSightingStreamEvent writes the event if the viewer receives it.
+func writeSightingStreamEvent(w http.ResponseWriter, viewer sightingStreamViewer, event models.SightingStreamEvent) error {
+	sighting, ok := viewer.view(event)
+	if !ok {
+		return nil
+	}
+	data, err := json.Marshal(sighting)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
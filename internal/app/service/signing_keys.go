@@ -0,0 +1,126 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/config"
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/keyring"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	signingKeys     *keyring.KeyRing
+	signingKeysErr  error
+	signingKeysOnce sync.Once
+)
+
+// InitSigningKeys loads the JWT signing keys from configuration. main calls it at startup
+// so a broken key configuration stops the application before it serves requests.
+func InitSigningKeys() error {
+	signingKeysOnce.Do(func() {
+		signingKeys, signingKeysErr = loadKeyRing()
+		if signingKeysErr == nil {
+			log.Infof("JWT signing key %s loaded", signingKeys.SigningKeyID())
+		}
+	})
+	return signingKeysErr
+}
+
+// getKeyRing returns the key ring used to sign and verify our JWTs.
+func getKeyRing() *keyring.KeyRing {
+	if err := InitSigningKeys(); err != nil {
+		log.Fatalf("Failed to load JWT signing keys: %v", err)
+	}
+	return signingKeys
+}
+
+// loadKeyRing builds the key ring from the environment:
+//
+//	JWT_SIGNING_ALGORITHM  RS256, ES256 or HS256
+//	JWT_SIGNING_KEY_ID     kid of the signing key, defaults to the JWK thumbprint
+//	JWT_SIGNING_KEY_FILE   PEM private key for RS256/ES256
+//	JWT_SIGNING_SECRET     shared secret for HS256
+//	JWT_VERIFICATION_KEYS  comma separated kid=path list of PEM public keys still accepted after a rotation
+//	JWT_ALLOW_EPHEMERAL_KEY  true to generate a key on start when there is no key file, for local development only
+//
+// Ephemeral keys invalidate every token and unsubscribe link on restart and differ between instances, so a missing
+// key file is an error unless they were asked for.
+func loadKeyRing() (*keyring.KeyRing, error) {
+	algorithm := strings.ToUpper(config.GetEnvVar("JWT_SIGNING_ALGORITHM"))
+	if algorithm == "" {
+		algorithm = keyring.ES256
+	}
+	keyID := config.GetEnvVar("JWT_SIGNING_KEY_ID")
+	var signing *keyring.Key
+	var err error
+	switch {
+	case algorithm == keyring.HS256:
+		secret := config.GetEnvVar("JWT_SIGNING_SECRET")
+		if len(secret) < 32 {
+			return nil, fmt.Errorf("JWT_SIGNING_SECRET must be at least 32 characters")
+		}
+		if keyID == "" {
+			keyID = "hs256"
+		}
+		signing = keyring.NewHMACKey(keyID, []byte(secret))
+	case config.GetEnvVar("JWT_SIGNING_KEY_FILE") != "":
+		data, err := os.ReadFile(config.GetEnvVar("JWT_SIGNING_KEY_FILE"))
+		if err != nil {
+			return nil, err
+		}
+		if signing, err = keyring.ParsePrivateKeyPEM(keyID, data); err != nil {
+			return nil, err
+		}
+		if signing.Algorithm != algorithm {
+			return nil, fmt.Errorf("JWT_SIGNING_KEY_FILE holds a %s key but JWT_SIGNING_ALGORITHM is %s", signing.Algorithm, algorithm)
+		}
+	case config.GetEnvVar("JWT_ALLOW_EPHEMERAL_KEY") != "true":
+		return nil, fmt.Errorf("JWT_SIGNING_KEY_FILE is required for %s, or set JWT_ALLOW_EPHEMERAL_KEY=true for local development", algorithm)
+	default:
+		log.Warn("JWT_ALLOW_EPHEMERAL_KEY is set, generating an ephemeral key. Tokens will not survive a restart.")
+		if signing, err = keyring.GenerateKey(keyID, algorithm); err != nil {
+			return nil, err
+		}
+	}
+
+	var verification []*keyring.Key
+	for _, entry := range strings.Split(config.GetEnvVar("JWT_VERIFICATION_KEYS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kid, path, found := strings.Cut(entry, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid JWT_VERIFICATION_KEYS entry %q, expected kid=path", entry)
+		}
+		data, err := os.ReadFile(strings.TrimSpace(path))
+		if err != nil {
+			return nil, err
+		}
+		key, err := keyring.ParsePublicKeyPEM(strings.TrimSpace(kid), data)
+		if err != nil {
+			return nil, err
+		}
+		verification = append(verification, key)
+	}
+	return keyring.New(signing, verification...)
+}
+
+// JWKS godoc
+// @Summary JSON Web Key Set
+// @Description Public keys partner services use to verify tokens issued by this API.
+// @Tags User
+// @Produce json
+// @Success 200 {object} keyring.JWKS
+// @Router /.well-known/jwks.json [get]
+func JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(getKeyRing().JWKS())
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -15,6 +16,7 @@ import (
 	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database/repositories"
 	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
 	"github.com/chegde20121/Tigerhall-Kittens/pkg/config"
+	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 )
 
@@ -36,6 +38,7 @@ func NewTigerService(db *sql.DB, logger *logrus.Logger) *TigerService {
 // @Success 201 {object} models.CreateTigerRequest
 // @Failure 400 {object} models.ErrorResponse
 // @Security Authorization
+// @Security APIKey
 // @Router /api/v1/createTigers [post]
 func (t *TigerService) CreateTiger(ctx context.Context, rw http.ResponseWriter, req *http.Request) {
 	contentType := req.Header.Get("Content-Type")
@@ -179,7 +182,7 @@ func (t *TigerService) CreateTigerFromJson(ctx context.Context, rw http.Response
 
 // ListAllTigers godoc
 // @Summary List all tigers
-// @Description Retrieve a list of tigers with optional pagination.
+// @Description Retrieve a list of tigers with optional pagination. Anonymous callers get coordinates snapped to a coarse grid and positions newer than the embargo period are withheld, researchers get exact coordinates.
 // @Tags Tiger
 // @Accept json
 // @Produce json
@@ -216,7 +219,119 @@ func (t *TigerService) ListAllTigers(ctx context.Context, rw http.ResponseWriter
 		models.HandleErrorResponse(rw, models.ErrorResponse{Message: "Failed to fetch the tigers. Please try again.", Status: http.StatusInternalServerError})
 		return
 	}
+	// Anonymous callers and reporters get coarse coordinates and no position under embargo
+	privacy := requestLocationPrivacy(req)
+	response = &models.TigersResponse{
+		Tigers:            privacy.ObfuscateTigers(response.Tigers),
+		Offset:            response.Offset,
+		LocationPrecision: privacy.Precision(),
+	}
 	rw.Header().Set("Content-Type", "application/json")
 	rw.WriteHeader(http.StatusOK)
 	json.NewEncoder(rw).Encode(response)
 }
+
+// DeleteTiger godoc
+// @Summary Delete a tiger
+// @Description Admins delete a tiger along with all its sightings.
+// @Tags Tiger
+// @Produce json
+// @Param id path int true "Tiger ID"
+// @Success 200 {object} models.GeneralResponse
+// @Failure 400 {object} models.ErrorResponse "Invalid tiger id"
+// @Failure 403 {object} models.ErrorResponse "Caller lacks the tigers:delete permission"
+// @Failure 404 {object} models.ErrorResponse "Tiger not found"
+// @Security Authorization
+// @Security APIKey
+// @Router /api/v1/tigers/{id} [delete]
+func (t *TigerService) DeleteTiger(ctx context.Context, rw http.ResponseWriter, req *http.Request) {
+	tigerID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		models.HandleErrorResponse(rw, models.ErrorResponse{Message: "Invalid tiger id.", Status: http.StatusBadRequest})
+		return
+	}
+	tigerRepo := repositories.NewTigerRepository(t.db, t.logger)
+	if err := tigerRepo.DeleteTiger(tigerID); err != nil {
+		t.logger.Errorf("Failed to delete tiger %d: %v", tigerID, err)
+		models.HandleErrorResponse(rw, models.ErrorResponse{Message: "Tiger not found.", Status: http.StatusNotFound})
+		return
+	}
+	t.logger.Infof("tiger %d deleted", tigerID)
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusOK)
+	json.NewEncoder(rw).Encode(models.GeneralResponse{Message: "Tiger deleted"})
+}
+
+// FollowTiger godoc
+// @Summary Follow a tiger
+// @Description Get notified about every verified sighting of the tiger. The followed tigers are listed at /api/v1/me.
+// @Tags Tiger
+// @Produce json
+// @Param id path int true "Tiger ID"
+// @Success 200 {object} models.GeneralResponse
+// @Failure 400 {object} models.ErrorResponse "Invalid tiger id"
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse "Tiger not found"
+// @Failure 500 {object} models.ErrorResponse "Failed to follow the tiger"
+// @Security Authorization
+// @Router /api/v1/tigers/{id}/follow [post]
+func (t *TigerService) FollowTiger(ctx context.Context, rw http.ResponseWriter, req *http.Request) {
+	tigerID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		models.HandleErrorResponse(rw, models.ErrorResponse{Message: "Invalid tiger id.", Status: http.StatusBadRequest})
+		return
+	}
+	user, ok := NewUserService(t.logger, t.db).currentUser(rw, req)
+	if !ok {
+		return
+	}
+	if err := repositories.NewFollowRepository(t.db, t.logger).FollowTiger(user.ID, tigerID); err != nil {
+		if errors.Is(err, repositories.ErrTigerNotFound) {
+			models.HandleErrorResponse(rw, models.ErrorResponse{Message: "Tiger not found.", Status: http.StatusNotFound})
+			return
+		}
+		models.HandleErrorResponse(rw, models.ErrorResponse{Message: "Failed to follow the tiger. Please try again", Status: http.StatusInternalServerError})
+		return
+	}
+	t.logger.Infof("%s follows tiger %d", user.Username, tigerID)
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusOK)
+	json.NewEncoder(rw).Encode(models.GeneralResponse{Message: "Tiger followed"})
+}
+
+// UnfollowTiger godoc
+// @Summary Unfollow a tiger
+// @Description Stop notifications about sightings of the tiger, unless the user reported it and keeps notify_reported_tigers enabled.
+// @Tags Tiger
+// @Produce json
+// @Param id path int true "Tiger ID"
+// @Success 200 {object} models.GeneralResponse
+// @Failure 400 {object} models.ErrorResponse "Invalid tiger id"
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse "Tiger not followed"
+// @Failure 500 {object} models.ErrorResponse "Failed to unfollow the tiger"
+// @Security Authorization
+// @Router /api/v1/tigers/{id}/follow [delete]
+func (t *TigerService) UnfollowTiger(ctx context.Context, rw http.ResponseWriter, req *http.Request) {
+	tigerID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		models.HandleErrorResponse(rw, models.ErrorResponse{Message: "Invalid tiger id.", Status: http.StatusBadRequest})
+		return
+	}
+	user, ok := NewUserService(t.logger, t.db).currentUser(rw, req)
+	if !ok {
+		return
+	}
+	if err := repositories.NewFollowRepository(t.db, t.logger).UnfollowTiger(user.ID, tigerID); err != nil {
+		if errors.Is(err, repositories.ErrTigerNotFollowed) {
+			models.HandleErrorResponse(rw, models.ErrorResponse{Message: "Tiger not followed.", Status: http.StatusNotFound})
+			return
+		}
+		models.HandleErrorResponse(rw, models.ErrorResponse{Message: "Failed to unfollow the tiger. Please try again", Status: http.StatusInternalServerError})
+		return
+	}
+	t.logger.Infof("%s unfollowed tiger %d", user.Username, tigerID)
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusOK)
+	json.NewEncoder(rw).Encode(models.GeneralResponse{Message: "Tiger unfollowed"})
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database/repositories"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	uploadIDHeader      = "X-Upload-ID"
+	maxUploadIDLength   = 64
+	uploadProgressEvery = 250 * time.Millisecond
+)
+
+// uploadProgress publishes the progress of an upload to the live channels of the uploader.
+type uploadProgress struct {
+	io.ReadCloser
+	userID       uint
+	progress     models.UploadProgress
+	lastReported time.Time
+}
+
+func (u *uploadProgress) Read(p []byte) (int, error) {
+	n, err := u.ReadCloser.Read(p)
+	u.progress.Received += int64(n)
+	if err == io.EOF {
+		u.report(models.UploadProcessing, 0)
+	} else if time.Since(u.lastReported) >= uploadProgressEvery {
+		u.report(models.UploadReceiving, 0)
+	}
+	return n, err
+}
+
+func (u *uploadProgress) report(state string, status int) {
+	if u.progress.State == state && state != models.UploadReceiving {
+		return
+	}
+	u.progress.State, u.progress.Status = state, status
+	u.lastReported = time.Now()
+	progress := u.progress
+	sendUserEvent(models.UserEvent{UserID: u.userID, Upload: &progress})
+}
+
+// statusRecorder remembers the status written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// TrackUploadProgress reports the progress of requests sent with an X-Upload-ID header to the live channels of
+// the authenticated user: while the body is received, while it is processed and once the response is sent.
+// Progress is reported at most every 250ms, and only to the channels connected to the instance receiving the upload.
+func TrackUploadProgress(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		uploadID := r.Header.Get(uploadIDHeader)
+		claims, ok := ClaimsFromContext(r.Context())
+		if uploadID == "" || len(uploadID) > maxUploadIDLength || !ok {
+			next(w, r)
+			return
+		}
+		user, err := repositories.NewUserRepository(database.GetDB(), logrus.StandardLogger()).GetUserByUserName(claims.Username)
+		if err != nil {
+			next(w, r)
+			return
+		}
+		upload := &uploadProgress{
+			ReadCloser: r.Body,
+			userID:     user.ID,
+			progress:   models.UploadProgress{UploadID: uploadID, Total: r.ContentLength},
+		}
+		upload.report(models.UploadReceiving, 0)
+		r.Body = upload
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(recorder, r)
+		if recorder.status >= 200 && recorder.status < 300 {
+			upload.report(models.UploadCompleted, recorder.status)
+		} else {
+			upload.report(models.UploadFailed, recorder.status)
+		}
+	}
+}
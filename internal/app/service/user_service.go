@@ -11,24 +11,27 @@ import (
 
 	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database/repositories"
 	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/messaging"
 	"github.com/golang-jwt/jwt/v4"
-	"github.com/patrickmn/go-cache"
+	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type username string
+type claimsKey string
+
+const (
+	usernameContextKey username  = "username"
+	claimsContextKey   claimsKey = "claims"
+)
+
 type UserService struct {
 	logger *logrus.Logger
 	db     *sql.DB
 }
 
-var (
-	tokenCache = cache.New(5*time.Minute, 10*time.Minute)
-)
-
 const (
-	secretKey  = "3aU2*GdfLs#4Np9!y^q8gFp6vTm"
 	cookieName = "jwt_token"
 )
 
@@ -38,7 +41,7 @@ func NewUserService(logger *logrus.Logger, db *sql.DB) *UserService {
 
 // CreateUser godoc
 // @Summary Create a new user
-// @Description Create a new user with the input paylod
+// @Description Create a new user with the input paylod. A verification link is mailed to the email address.
 // @Tags User
 // @Accept  json
 // @Produce  json
@@ -70,9 +73,16 @@ func (u *UserService) CreateUser(ctx context.Context, rw http.ResponseWriter, re
 		return
 	}
 	u.logger.Info("User created successfully")
+	// SMTP is slow, do not keep the client waiting for the verification email
+	go func(user models.User) {
+		if err := sendVerificationEmail(messaging.NewEmailHandler(u.logger), &user); err != nil {
+			u.logger.Error("Failed to send verification email:", err)
+		}
+	}(*user)
 	rw.Header().Set("Content-Type", "application/json")
 	rw.WriteHeader(http.StatusCreated)
 	user.Password = ""
+	user.Roles = []string{models.RoleReporter}
 	json.NewEncoder(rw).Encode(user)
 }
 
@@ -95,6 +105,7 @@ func hashPassword(password string) (string, error) {
 // @Success 200 {object} models.LoginResponse
 // @Failure 400 {object} models.ErrorResponse "Invalid JSON format"
 // @Failure 401 {object} models.ErrorResponse "Invalid user credentials"
+// @Failure 429 {object} models.ErrorResponse "Too many failed login attempts"
 // @Failure 500 {object} models.ErrorResponse "Failed to log in. Please try again"
 // @Router /api/v1/login [post]
 func (u *UserService) Login(ctx context.Context, w http.ResponseWriter, r *http.Request) {
@@ -107,35 +118,31 @@ func (u *UserService) Login(ctx context.Context, w http.ResponseWriter, r *http.
 		return
 	}
 
-	// Check if the token is already in the cache
-	if token, found := tokenCache.Get(cred.Username); found {
-		setTokenCookie(w, token.(string))
+	if !u.checkLoginThrottle(w, r, cred.Username) {
 		return
 	}
+
 	userRepo := repositories.NewUserRepository(u.db, u.logger)
 	user, err := userRepo.GetUserByUserName(cred.Username)
 	if err != nil {
 		u.logger.Error("Failed to fetch user from repository: ", err)
+		u.recordLoginFailure(r, cred.Username, nil)
 		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Invalid User or failed to fetch user details.Please try again", Status: http.StatusInternalServerError})
 		return
 	}
 	err = verifyPassword(cred.Password, user.Password)
 	if err != nil {
+		u.recordLoginFailure(r, cred.Username, user)
 		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Invalid password", Status: http.StatusUnauthorized})
 		return
 	}
-
-	// Generate JWT token
-	token, err := generateToken(cred.Username)
-	if err != nil {
-		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
-		return
+	// With a second factor the failures are only forgotten once the code was entered too
+	if !user.TOTPEnabled {
+		u.resetLoginFailures(user.Username)
 	}
 
-	// Set the token in the cache
-	tokenCache.Set(cred.Username, token, 15*time.Minute)
-
-	setTokenCookie(w, token)
+	// Every login starts a new session with its own refresh token, after the second factor if the user has one
+	u.completeLogin(w, r, user)
 }
 
 // verifyPassword compares a provided password with a stored bcrypt hash
@@ -144,35 +151,56 @@ func verifyPassword(providedPassword, hashedPassword string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(providedPassword))
 }
 
-// generateToken generates a JWT token for the given username
-func generateToken(username string) (string, error) {
-	expirationTime := time.Now().Add(5 * time.Minute)
+// generateToken generates a short-lived JWT access token for the given user and session
+func generateToken(username string, roles []string, sessionID string) (string, *models.Claims, error) {
+	tokenID, err := generateRandomToken(16)
+	if err != nil {
+		return "", nil, err
+	}
+	now := time.Now()
 	claims := &models.Claims{
-		Username: username,
+		Username:  username,
+		Roles:     roles,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:       tokenID,
+			IssuedAt: jwt.NewNumericDate(now),
 			// In JWT, the expiry time is expressed as unix milliseconds
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
+			ExpiresAt: jwt.NewNumericDate(now.Add(accessTokenTTL())),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signedToken, err := token.SignedString([]byte(secretKey))
+	signedToken, err := getKeyRing().Sign(claims)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
-	return signedToken, nil
+	return signedToken, claims, nil
 }
 
-// setTokenCookie sets the JWT token in an HTTP-only secure cookie
-func setTokenCookie(w http.ResponseWriter, token string) {
+// setTokenCookie sets the access and refresh tokens in HTTP-only cookies and writes the login response
+func setTokenCookie(w http.ResponseWriter, token string, refreshToken string) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     cookieName,
 		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     refreshCookieName,
+		Value:    refreshToken,
+		Path:     refreshCookiePath,
+		Expires:  time.Now().Add(refreshTokenTTL()),
 		HttpOnly: true,
 		SameSite: http.SameSiteStrictMode,
 	})
-	loginResponse := models.LoginResponse{Message: "Login successful", Token: token}
+	loginResponse := models.LoginResponse{
+		Message:      "Login successful",
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(accessTokenTTL().Seconds()),
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(loginResponse)
@@ -190,55 +218,77 @@ func setTokenCookie(w http.ResponseWriter, token string) {
 // @Router /api/v1/logout [get]
 func (u *UserService) Logout(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 	// Check if the user is authenticated
-	username, err := getUsernameFromToken(r)
+	claims, err := getClaimsFromRequest(r)
 	if err != nil {
 		// If the token is not present or invalid, consider the user as not authenticated
 		models.HandleErrorResponse(w, models.ErrorResponse{Message: "User not authenticated", Status: http.StatusUnauthorized})
 		return
 	}
+	if claims.APIKeyID != "" {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "API keys are revoked at DELETE /api/v1/api-keys/{id}", Status: http.StatusBadRequest})
+		return
+	}
 
-	// Remove the token from the cache
-	tokenCache.Delete(username)
+	// Revoke the access token and the session it belongs to, so neither the token nor its refresh token work anymore
+	sessionRepo := repositories.NewSessionRepository(u.db, u.logger)
+	if err := sessionRepo.RevokeToken(claims.ID, claims.ExpiresAt.Time); err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to log out. Please try again", Status: http.StatusInternalServerError})
+		return
+	}
+	if claims.SessionID != "" {
+		if user, err := repositories.NewUserRepository(u.db, u.logger).GetUserByUserName(claims.Username); err == nil {
+			if err := sessionRepo.RevokeSession(claims.SessionID, user.ID); err != nil {
+				u.logger.Warn("Failed to revoke session on logout:", err)
+			}
+		}
+	}
 
-	// Delete the token cookie on the client side
-	http.SetCookie(w, &http.Cookie{
-		Name:     cookieName,
-		Value:    "",
-		Expires:  time.Now().Add(-time.Hour), // Set an expired time in the past to delete the cookie
-		HttpOnly: true,
-		SameSite: http.SameSiteStrictMode,
-	})
+	// Delete the token cookies on the client side
+	clearTokenCookies(w)
 	logoutResponse := models.GeneralResponse{Message: "Logout successful"}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(logoutResponse)
 }
 
+// clearTokenCookies expires the access and refresh token cookies
+func clearTokenCookies(w http.ResponseWriter) {
+	for name, path := range map[string]string{cookieName: "/", refreshCookieName: refreshCookiePath} {
+		http.SetCookie(w, &http.Cookie{
+			Name:     name,
+			Value:    "",
+			Path:     path,
+			Expires:  time.Now().Add(-time.Hour), // Set an expired time in the past to delete the cookie
+			HttpOnly: true,
+			SameSite: http.SameSiteStrictMode,
+		})
+	}
+}
+
 // getUsernameFromToken retrieves the username from the JWT token in the request
 func getUsernameFromToken(r *http.Request) (string, error) {
-	cookie, err := r.Cookie(cookieName)
+	claims, err := getClaimsFromRequest(r)
 	if err != nil {
 		return "", err
 	}
+	return claims.Username, nil
+}
 
-	token, err := jwt.Parse(cookie.Value, func(token *jwt.Token) (interface{}, error) {
-		return []byte(secretKey), nil
-	})
-	if err != nil || !token.Valid {
-		return "", err
+// getClaimsFromRequest authenticates the request with the API key in the X-API-Key header or else the JWT sent
+// either as Bearer token or cookie. It is used by public endpoints whose response depends on who is asking.
+// Claims of API keys carry the APIKeyID, check it where a key must not do what a login does.
+func getClaimsFromRequest(r *http.Request) (*models.Claims, error) {
+	if apiKey := r.Header.Get(apiKeyHeader); apiKey != "" {
+		return validateAPIKey(apiKey)
 	}
-
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return "", errors.New("invalid token claims")
+	if bearerToken := extractBearerToken(r); bearerToken != "" {
+		return validateJWT(bearerToken)
 	}
-
-	username, ok := claims["username"].(string)
-	if !ok {
-		return "", errors.New("username not found in token claims")
+	cookie, err := r.Cookie(cookieName)
+	if err != nil || cookie.Value == "" {
+		return nil, errors.New("user not authenticated")
 	}
-
-	return username, nil
+	return validateJWT(cookie.Value)
 }
 
 // protectedHandler is a sample protected handler that requires authentication
@@ -246,7 +296,16 @@ func getUsernameFromToken(r *http.Request) (string, error) {
 // AuthMiddleware interceptor to authenticate users
 func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var username username = "username"
+		// Integrations authenticate with an API key
+		if apiKey := r.Header.Get(apiKeyHeader); apiKey != "" {
+			claims, err := validateAPIKey(apiKey)
+			if err != nil {
+				models.HandleErrorResponse(w, models.ErrorResponse{Message: "Invalid, expired or revoked API key", Status: http.StatusUnauthorized})
+				return
+			}
+			next(w, r.WithContext(withClaims(r.Context(), claims)))
+			return
+		}
 
 		// Check for Bearer Token in Authorization Header
 		bearerToken := extractBearerToken(r)
@@ -258,9 +317,7 @@ func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 				return
 			}
 
-			// Attach the username to the request context
-			ctx := context.WithValue(r.Context(), username, claims.Username)
-			next(w, r.WithContext(ctx))
+			next(w, r.WithContext(withClaims(r.Context(), claims)))
 			return
 		}
 
@@ -279,12 +336,46 @@ func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
-		// Attach the username to the request context
-		ctx := context.WithValue(r.Context(), username, claims.Username)
-		next(w, r.WithContext(ctx))
+		next(w, r.WithContext(withClaims(r.Context(), claims)))
 	}
 }
 
+// LoginRequired authenticates the request like AuthMiddleware but refuses API keys. It guards account
+// management, such as sessions, API keys and two-factor authentication, which a leaked key must not change.
+func LoginRequired(next http.HandlerFunc) http.HandlerFunc {
+	return AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if claims, ok := ClaimsFromContext(r.Context()); !ok || claims.APIKeyID != "" {
+			models.HandleErrorResponse(w, models.ErrorResponse{Message: "This action requires a login, API keys are not accepted", Status: http.StatusForbidden})
+			return
+		}
+		next(w, r)
+	})
+}
+
+// RequirePermission authenticates the request and rejects callers whose roles do not grant the permission.
+func RequirePermission(permission string, next http.HandlerFunc) http.HandlerFunc {
+	return AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := ClaimsFromContext(r.Context())
+		if !ok || !claims.HasPermission(permission) {
+			models.HandleErrorResponse(w, models.ErrorResponse{Message: "User is not allowed to perform this action", Status: http.StatusForbidden})
+			return
+		}
+		next(w, r)
+	})
+}
+
+// withClaims attaches the username and claims of the authenticated user to the request context.
+func withClaims(ctx context.Context, claims *models.Claims) context.Context {
+	ctx = context.WithValue(ctx, usernameContextKey, claims.Username)
+	return context.WithValue(ctx, claimsContextKey, claims)
+}
+
+// ClaimsFromContext returns the claims attached by AuthMiddleware.
+func ClaimsFromContext(ctx context.Context) (*models.Claims, bool) {
+	claims, ok := ctx.Value(claimsContextKey).(*models.Claims)
+	return claims, ok
+}
+
 // extractBearerToken extracts the JWT from the Authorization header.
 func extractBearerToken(r *http.Request) string {
 	authHeader := r.Header.Get("Authorization")
@@ -298,12 +389,11 @@ func extractBearerToken(r *http.Request) string {
 	return ""
 }
 
-// validateJWT validates the JWT and returns the claims.
+// validateJWT validates the JWT and returns the claims. Tokens on the revocation list
+// or issued for a revoked session are rejected.
 func validateJWT(token string) (*models.Claims, error) {
 	// Validate and parse the JWT
-	parsedToken, err := jwt.ParseWithClaims(token, &models.Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(secretKey), nil
-	})
+	parsedToken, err := jwt.ParseWithClaims(token, &models.Claims{}, getKeyRing().Keyfunc)
 
 	if err != nil || !parsedToken.Valid {
 		return nil, errors.New("invalid or expired token")
@@ -313,6 +403,101 @@ func validateJWT(token string) (*models.Claims, error) {
 	if !ok {
 		return nil, errors.New("invalid token claims")
 	}
+	// Tokens with an audience were mailed for a single purpose and are no access tokens
+	if len(claims.Audience) > 0 {
+		return nil, errors.New("invalid token claims")
+	}
+
+	revoked, err := isTokenRevoked(claims)
+	if err != nil || revoked {
+		return nil, errors.New("token has been revoked")
+	}
 
 	return claims, nil
 }
+
+// GrantRole godoc
+// @Summary Grant a role to a user
+// @Description Admins grant one of viewer, reporter, ranger, researcher or admin. Roles are read again when the user's access token is refreshed, so the role takes effect within ACCESS_TOKEN_TTL_MINUTES.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param username path string true "Username"
+// @Param role body models.RoleRequest true "Role to grant"
+// @Success 200 {object} models.UserRolesResponse
+// @Failure 400 {object} models.ErrorResponse "Unknown role"
+// @Failure 403 {object} models.ErrorResponse "Caller is not an admin"
+// @Failure 404 {object} models.ErrorResponse "User not found"
+// @Failure 500 {object} models.ErrorResponse "Failed to update the roles"
+// @Security Authorization
+// @Router /api/v1/admin/users/{username}/roles [post]
+func (u *UserService) GrantRole(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	username := mux.Vars(r)["username"]
+	var roleRequest models.RoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&roleRequest); err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Invalid JSON format", Status: http.StatusBadRequest})
+		return
+	}
+	if !models.IsValidRole(roleRequest.Role) {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Unknown role " + roleRequest.Role, Status: http.StatusBadRequest})
+		return
+	}
+	userRepo := repositories.NewUserRepository(u.db, u.logger)
+	roles, err := userRepo.GrantRole(username, roleRequest.Role)
+	if err != nil {
+		roleUpdateError(w, err)
+		return
+	}
+	u.logger.Infof("Granted role %s to %s", roleRequest.Role, username)
+	writeUserRoles(w, username, roles)
+}
+
+// RevokeRole godoc
+// @Summary Revoke a role from a user
+// @Description Admins revoke a role from a user. Admins cannot revoke their own admin role. Access tokens issued before keep the role until they are refreshed, within ACCESS_TOKEN_TTL_MINUTES.
+// @Tags Admin
+// @Produce json
+// @Param username path string true "Username"
+// @Param role path string true "Role to revoke"
+// @Success 200 {object} models.UserRolesResponse
+// @Failure 400 {object} models.ErrorResponse "Unknown role"
+// @Failure 403 {object} models.ErrorResponse "Caller is not an admin"
+// @Failure 404 {object} models.ErrorResponse "User not found"
+// @Failure 500 {object} models.ErrorResponse "Failed to update the roles"
+// @Security Authorization
+// @Router /api/v1/admin/users/{username}/roles/{role} [delete]
+func (u *UserService) RevokeRole(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	username, role := vars["username"], vars["role"]
+	if !models.IsValidRole(role) {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Unknown role " + role, Status: http.StatusBadRequest})
+		return
+	}
+	if claims, ok := ClaimsFromContext(r.Context()); ok && claims.Username == username && role == models.RoleAdmin {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Admins cannot revoke their own admin role", Status: http.StatusBadRequest})
+		return
+	}
+	userRepo := repositories.NewUserRepository(u.db, u.logger)
+	roles, err := userRepo.RevokeRole(username, role)
+	if err != nil {
+		roleUpdateError(w, err)
+		return
+	}
+	u.logger.Infof("Revoked role %s from %s", role, username)
+	writeUserRoles(w, username, roles)
+}
+
+// roleUpdateError answers a failed role change: 404 for unknown users and 500 for anything else.
+func roleUpdateError(w http.ResponseWriter, err error) {
+	if errors.Is(err, repositories.ErrUserNotFound) {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "User not found", Status: http.StatusNotFound})
+		return
+	}
+	models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to update the roles. Please try again", Status: http.StatusInternalServerError})
+}
+
+func writeUserRoles(w http.ResponseWriter, username string, roles []string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(models.UserRolesResponse{Username: username, Roles: roles})
+}
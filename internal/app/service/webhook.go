@@ -0,0 +1,409 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database/repositories"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/config"
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/webhook"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultWebhookTimeout              = 10 * time.Second
+	defaultWebhookMaxAttempts          = 8
+	defaultWebhookRetryBase            = time.Minute
+	defaultWebhookDisableAfterFailures = 20
+	webhookBatchSize                   = 50
+	webhookLease                       = 5 * time.Minute
+	minWebhookSecretLength             = 16
+	maxWebhookErrorLength              = 500
+	webhookUserAgent                   = "Tigerhall-Kittens-Webhooks/1.0"
+)
+
+type WebhookService struct {
+	logger *logrus.Logger
+	db     *sql.DB
+}
+
+func NewWebhookService(logger *logrus.Logger, db *sql.DB) *WebhookService {
+	return &WebhookService{logger: logger, db: db}
+}
+
+// CreateWebhook godoc
+// @Summary Create a webhook
+// @Description Subscribe an endpoint to sighting and tiger events. Deliveries are signed with the secret, which is generated when omitted and only shown in this response.
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Param webhook body models.CreateWebhookRequest true "URL, event types and optional secret"
+// @Success 201 {object} models.CreateWebhookResponse
+// @Failure 400 {object} models.ErrorResponse "Invalid URL, event type or secret"
+// @Failure 403 {object} models.ErrorResponse "Caller lacks the webhooks:manage permission"
+// @Security Authorization
+// @Security APIKey
+// @Router /api/v1/webhooks [post]
+func (ws *WebhookService) CreateWebhook(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	user, ok := NewUserService(ws.logger, ws.db).currentUser(w, r)
+	if !ok {
+		return
+	}
+	var webhookRequest models.CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&webhookRequest); err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Invalid JSON format", Status: http.StatusBadRequest})
+		return
+	}
+	if err := models.ValidateWebhook(webhookRequest.URL, webhookRequest.EventTypes); err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: err.Error(), Status: http.StatusBadRequest})
+		return
+	}
+	secret := webhookRequest.Secret
+	if secret == "" {
+		var err error
+		if secret, err = generateRandomToken(32); err != nil {
+			models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to create the webhook. Please try again", Status: http.StatusInternalServerError})
+			return
+		}
+	} else if len(secret) < minWebhookSecretLength {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: fmt.Sprintf("Secret must be at least %d characters", minWebhookSecretLength), Status: http.StatusBadRequest})
+		return
+	}
+	hook := models.Webhook{URL: webhookRequest.URL, EventTypes: webhookRequest.EventTypes}
+	if err := repositories.NewWebhookRepository(ws.db, ws.logger).CreateWebhook(&hook, user.ID, secret); err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to create the webhook. Please try again", Status: http.StatusInternalServerError})
+		return
+	}
+	ws.logger.Infof("Webhook %d created by %s for %v", hook.ID, user.Username, hook.EventTypes)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(models.CreateWebhookResponse{Webhook: hook, Secret: secret})
+}
+
+// ListWebhooks godoc
+// @Summary List webhooks
+// @Description List the webhooks of the authenticated user. Secrets are not returned.
+// @Tags Webhooks
+// @Produce json
+// @Success 200 {object} models.WebhooksResponse
+// @Failure 403 {object} models.ErrorResponse "Caller lacks the webhooks:manage permission"
+// @Security Authorization
+// @Security APIKey
+// @Router /api/v1/webhooks [get]
+func (ws *WebhookService) ListWebhooks(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	user, ok := NewUserService(ws.logger, ws.db).currentUser(w, r)
+	if !ok {
+		return
+	}
+	webhooks, err := repositories.NewWebhookRepository(ws.db, ws.logger).ListWebhooks(user.ID)
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to fetch webhooks. Please try again", Status: http.StatusInternalServerError})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(models.WebhooksResponse{Webhooks: webhooks})
+}
+
+// GetWebhook godoc
+// @Summary Get a webhook
+// @Description Get a webhook of the authenticated user, including whether it was disabled after failed deliveries.
+// @Tags Webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} models.Webhook
+// @Failure 403 {object} models.ErrorResponse "Caller lacks the webhooks:manage permission"
+// @Failure 404 {object} models.ErrorResponse "Webhook not found"
+// @Security Authorization
+// @Security APIKey
+// @Router /api/v1/webhooks/{id} [get]
+func (ws *WebhookService) GetWebhook(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	user, ok := NewUserService(ws.logger, ws.db).currentUser(w, r)
+	if !ok {
+		return
+	}
+	webhookID, ok := webhookIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	hook, err := repositories.NewWebhookRepository(ws.db, ws.logger).GetWebhook(webhookID, user.ID)
+	if !ws.handleRepositoryError(w, err, "Failed to fetch the webhook. Please try again") {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(hook)
+}
+
+// UpdateWebhook godoc
+// @Summary Update a webhook
+// @Description Change the URL or event types of a webhook, or disable and enable it. Enabling a webhook resets its failures, deliveries failed while it was disabled are not retried.
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param webhook body models.UpdateWebhookRequest true "Fields to change"
+// @Success 200 {object} models.Webhook
+// @Failure 400 {object} models.ErrorResponse "Invalid URL or event type"
+// @Failure 403 {object} models.ErrorResponse "Caller lacks the webhooks:manage permission"
+// @Failure 404 {object} models.ErrorResponse "Webhook not found"
+// @Security Authorization
+// @Security APIKey
+// @Router /api/v1/webhooks/{id} [patch]
+func (ws *WebhookService) UpdateWebhook(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	user, ok := NewUserService(ws.logger, ws.db).currentUser(w, r)
+	if !ok {
+		return
+	}
+	webhookID, ok := webhookIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	var update models.UpdateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Invalid JSON format", Status: http.StatusBadRequest})
+		return
+	}
+	webhookRepo := repositories.NewWebhookRepository(ws.db, ws.logger)
+	hook, err := webhookRepo.GetWebhook(webhookID, user.ID)
+	if !ws.handleRepositoryError(w, err, "Failed to update the webhook. Please try again") {
+		return
+	}
+	if update.URL != nil {
+		hook.URL = *update.URL
+	}
+	if update.EventTypes != nil {
+		hook.EventTypes = update.EventTypes
+	}
+	if err := models.ValidateWebhook(hook.URL, hook.EventTypes); err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: err.Error(), Status: http.StatusBadRequest})
+		return
+	}
+	hook, err = webhookRepo.UpdateWebhook(webhookID, user.ID, update)
+	if !ws.handleRepositoryError(w, err, "Failed to update the webhook. Please try again") {
+		return
+	}
+	ws.logger.Infof("Webhook %d updated by %s", webhookID, user.Username)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(hook)
+}
+
+// DeleteWebhook godoc
+// @Summary Delete a webhook
+// @Description Delete a webhook of the authenticated user along with its delivery log. Pending deliveries are not sent.
+// @Tags Webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} models.GeneralResponse
+// @Failure 403 {object} models.ErrorResponse "Caller lacks the webhooks:manage permission"
+// @Failure 404 {object} models.ErrorResponse "Webhook not found"
+// @Security Authorization
+// @Security APIKey
+// @Router /api/v1/webhooks/{id} [delete]
+func (ws *WebhookService) DeleteWebhook(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	user, ok := NewUserService(ws.logger, ws.db).currentUser(w, r)
+	if !ok {
+		return
+	}
+	webhookID, ok := webhookIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	err := repositories.NewWebhookRepository(ws.db, ws.logger).DeleteWebhook(webhookID, user.ID)
+	if !ws.handleRepositoryError(w, err, "Failed to delete the webhook. Please try again") {
+		return
+	}
+	ws.logger.Infof("Webhook %d deleted by %s", webhookID, user.Username)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(models.GeneralResponse{Message: "Webhook deleted"})
+}
+
+// ListWebhookDeliveries godoc
+// @Summary List the deliveries of a webhook
+// @Description The delivery log of a webhook, newest first, with the payload, attempts and the outcome of the last attempt.
+// @Tags Webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param pageSize query int false "Number of deliveries to retrieve per page"
+// @Param offset query int false "Offset for paginating the list"
+// @Success 200 {object} models.WebhookDeliveriesResponse
+// @Failure 400 {object} models.ErrorResponse "Invalid pagination"
+// @Failure 403 {object} models.ErrorResponse "Caller lacks the webhooks:manage permission"
+// @Failure 404 {object} models.ErrorResponse "Webhook not found"
+// @Security Authorization
+// @Security APIKey
+// @Router /api/v1/webhooks/{id}/deliveries [get]
+func (ws *WebhookService) ListWebhookDeliveries(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	user, ok := NewUserService(ws.logger, ws.db).currentUser(w, r)
+	if !ok {
+		return
+	}
+	webhookID, ok := webhookIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	pageSizeStr := r.URL.Query().Get("pageSize")
+	if pageSizeStr == "" {
+		pageSizeStr = config.GetEnvVar("PAGE_SIZE")
+	}
+	pageSize, err := strconv.Atoi(pageSizeStr)
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to parse the request payload. Please try again", Status: http.StatusBadRequest})
+		return
+	}
+	offsetStr := r.URL.Query().Get("offset")
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil && offsetStr != "" {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Failed to parse the request payload. Please try again", Status: http.StatusBadRequest})
+		return
+	}
+	response, err := repositories.NewWebhookRepository(ws.db, ws.logger).ListWebhookDeliveries(webhookID, user.ID, pageSize, offset)
+	if !ws.handleRepositoryError(w, err, "Failed to fetch the deliveries. Please try again") {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func webhookIDFromPath(w http.ResponseWriter, r *http.Request) (int, bool) {
+	webhookID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Invalid webhook id", Status: http.StatusBadRequest})
+		return 0, false
+	}
+	return webhookID, true
+}
+
+// handleRepositoryError writes the error response for err, if any, and reports whether the request can go on.
+func (ws *WebhookService) handleRepositoryError(w http.ResponseWriter, err error, message string) bool {
+	if err == repositories.ErrWebhookNotFound {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: "Webhook not found", Status: http.StatusNotFound})
+		return false
+	}
+	if err != nil {
+		models.HandleErrorResponse(w, models.ErrorResponse{Message: message, Status: http.StatusInternalServerError})
+		return false
+	}
+	return true
+}
+
+// WebhookWorker sends the pending webhook deliveries, retrying failed ones with exponential backoff and disabling
+// webhooks that keep failing.
+type WebhookWorker struct {
+	db           *sql.DB
+	logger       *logrus.Logger
+	client       *http.Client
+	maxAttempts  int
+	retryBase    time.Duration
+	disableAfter int
+}
+
+func NewWebhookWorker(db *sql.DB, logger *logrus.Logger) *WebhookWorker {
+	return &WebhookWorker{
+		db:           db,
+		logger:       logger,
+		client:       webhook.NewClient(durationFromEnv("WEBHOOK_TIMEOUT_SECONDS", time.Second, defaultWebhookTimeout)),
+		maxAttempts:  intFromEnv("WEBHOOK_MAX_ATTEMPTS", defaultWebhookMaxAttempts),
+		retryBase:    durationFromEnv("WEBHOOK_RETRY_BASE_SECONDS", time.Second, defaultWebhookRetryBase),
+		disableAfter: intFromEnv("WEBHOOK_DISABLE_AFTER_FAILURES", defaultWebhookDisableAfterFailures),
+	}
+}
+
+// ProcessDue sends the deliveries that are due, batch by batch, until none are left or ctx is done. It returns the
+// error if the deliveries could not be read.
+func (ww *WebhookWorker) ProcessDue(ctx context.Context) error {
+	webhookRepo := repositories.NewWebhookRepository(ww.db, ww.logger)
+	for ctx.Err() == nil {
+		deliveries, err := webhookRepo.ClaimWebhookDeliveries(webhookBatchSize, webhookLease)
+		if err != nil {
+			return err
+		}
+		if len(deliveries) == 0 {
+			return nil
+		}
+		for _, delivery := range deliveries {
+			ww.deliver(webhookRepo, delivery)
+		}
+	}
+	return nil
+}
+
+func (ww *WebhookWorker) deliver(webhookRepo *repositories.WebhookRepository, delivery models.PendingWebhookDelivery) {
+	// Events carry exact locations, owners who lost the permission since creating the webhook get no more of them
+	if !models.HasPermission(delivery.OwnerRoles, models.PermissionWebhooksManage) {
+		reason := fmt.Sprintf("webhook owner no longer holds the %s permission", models.PermissionWebhooksManage)
+		if err := webhookRepo.DisableWebhook(delivery.WebhookID, reason); err == nil {
+			ww.logger.Warnf("Webhook %d disabled, its owner no longer holds the %s permission", delivery.WebhookID, models.PermissionWebhooksManage)
+		}
+		return
+	}
+	status, err := ww.send(delivery)
+	if err == nil {
+		_ = webhookRepo.MarkWebhookDelivered(delivery.ID, delivery.WebhookID, status)
+		return
+	}
+	failure := err.Error()
+	if len(failure) > maxWebhookErrorLength {
+		failure = failure[:maxWebhookErrorLength]
+	}
+	var retryAt *time.Time
+	if delivery.Attempts < ww.maxAttempts {
+		at := time.Now().Add(OutboxRetryDelay(delivery.Attempts, ww.retryBase))
+		retryAt = &at
+	}
+	disabled, recordErr := webhookRepo.RecordWebhookFailure(delivery.ID, delivery.WebhookID, status, failure, retryAt, ww.disableAfter)
+	if recordErr != nil {
+		return
+	}
+	switch {
+	case disabled:
+		ww.logger.Warnf("Webhook %d disabled after %d failed deliveries in a row: %v", delivery.WebhookID, ww.disableAfter, err)
+	case retryAt == nil:
+		ww.logger.Errorf("Webhook delivery %d failed %d times, giving up: %v", delivery.ID, delivery.Attempts, err)
+	default:
+		ww.logger.Warnf("Webhook delivery %d failed, retrying at %s: %v", delivery.ID, retryAt.Format(time.RFC3339), err)
+	}
+}
+
+// send POSTs the signed event and returns the response status, 0 if there was no response. Only 2xx responses
+// count as delivered.
+func (ww *WebhookWorker) send(delivery models.PendingWebhookDelivery) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	// Webhooks registered before https was required
+	if req.URL.Scheme != "https" {
+		return 0, fmt.Errorf("webhook url must use https")
+	}
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", webhookUserAgent)
+	req.Header.Set(webhook.EventHeader, delivery.EventType)
+	req.Header.Set(webhook.DeliveryHeader, delivery.EventID)
+	req.Header.Set(webhook.TimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(webhook.SignatureHeader, webhook.Sign(delivery.Secret, now, delivery.Payload))
+	resp, err := ww.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Drain a little of the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded with %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,336 @@
+// Package templates renders the emails of the service in the language of the recipient. The default templates are
+// embedded in the binary, a directory can override them and add languages without a rebuild.
+package templates
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultLanguage is used for recipients whose language has no template.
+const DefaultLanguage = "en"
+
+// Parts of an email, each in a file <language>/<name>.<part>.tmpl. The HTML part is optional.
+const (
+	partSubject = "subject"
+	partText    = "txt"
+	partHTML    = "html"
+)
+
+//go:embed emails
+var embedded embed.FS
+
+// templateFile is <language>/<name>.<part>.tmpl
+var templateFile = regexp.MustCompile(`^([a-zA-Z]{2,3}(?:-[a-zA-Z0-9]{2,8})*)/([a-z0-9_]+)\.(subject|txt|html)\.tmpl$`)
+
+// executor is a parsed text or HTML template.
+type executor interface {
+	Execute(w io.Writer, data any) error
+}
+
+// Samples returns, by email name, the data the email is rendered with to check the templates when they are loaded.
+type Samples map[string]func() any
+
+// Rendered is an email rendered for one recipient.
+type Rendered struct {
+	// Language the templates were found in, the default language if the requested one has none
+	Language string `json:"language"`
+	Subject  string `json:"subject"`
+	Text     string `json:"text"`
+	HTML     string `json:"html,omitempty"`
+}
+
+// Store holds the parsed templates. They are replaced as a whole on reload, a reload that fails keeps the templates
+// in use.
+type Store struct {
+	dir     string
+	samples Samples
+	logger  *logrus.Logger
+
+	mu          sync.RWMutex
+	templates   map[string]executor
+	fingerprint string
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewStore parses the embedded templates and those in dir, which take precedence. An empty dir serves the embedded
+// templates only. Every language of an email must have a subject and a text part, and the emails with samples must
+// render with them.
+func NewStore(dir string, samples Samples, logger *logrus.Logger) (*Store, error) {
+	s := &Store{dir: dir, samples: samples, logger: logger, stop: make(chan struct{})}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload parses and checks the templates again. The templates in use are only replaced if all of them are fine.
+func (s *Store) Reload() error {
+	fingerprint, err := s.dirFingerprint()
+	if err != nil {
+		return err
+	}
+	templates := map[string]executor{}
+	if err := parseAll(embedded, "emails", templates); err != nil {
+		return err
+	}
+	if s.dir != "" {
+		if err := parseAll(os.DirFS(s.dir), ".", templates); err != nil {
+			return err
+		}
+	}
+	if err := s.check(templates); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.templates, s.fingerprint = templates, fingerprint
+	s.mu.Unlock()
+	return nil
+}
+
+// Watch reloads the templates when files in the directory change, checking every interval until Close.
+func (s *Store) Watch(interval time.Duration) {
+	if s.dir == "" {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
+			fingerprint, err := s.dirFingerprint()
+			s.mu.RLock()
+			changed := err == nil && fingerprint != s.fingerprint
+			s.mu.RUnlock()
+			if !changed {
+				continue
+			}
+			if err := s.Reload(); err != nil {
+				s.logger.Error("Failed to reload the email templates, keeping the previous ones: ", err)
+				// Do not retry until the files change again
+				s.mu.Lock()
+				s.fingerprint = fingerprint
+				s.mu.Unlock()
+				continue
+			}
+			s.logger.Info("Reloaded the email templates from ", s.dir)
+		}
+	}()
+}
+
+// Close stops watching the directory.
+func (s *Store) Close() {
+	s.stopOnce.Do(func() { close(s.stop) })
+}
+
+// Render renders the email name in the language closest to the one asked for: the language itself, the language
+// without its region (hi for hi-IN) and finally the default language.
+func (s *Store) Render(name string, language string, data any) (Rendered, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, candidate := range fallbacks(language) {
+		text, ok := s.templates[key(candidate, name, partText)]
+		if !ok {
+			continue
+		}
+		subject, ok := s.templates[key(candidate, name, partSubject)]
+		if !ok {
+			return Rendered{}, fmt.Errorf("template %s has no subject in %s", name, candidate)
+		}
+		rendered := Rendered{Language: candidate}
+		var err error
+		if rendered.Subject, err = execute(subject, data); err != nil {
+			return Rendered{}, err
+		}
+		// Subjects are a single line, whatever the template file ends with
+		rendered.Subject = strings.Join(strings.Fields(rendered.Subject), " ")
+		if rendered.Text, err = execute(text, data); err != nil {
+			return Rendered{}, err
+		}
+		if html, ok := s.templates[key(candidate, name, partHTML)]; ok {
+			if rendered.HTML, err = execute(html, data); err != nil {
+				return Rendered{}, err
+			}
+		}
+		return rendered, nil
+	}
+	return Rendered{}, fmt.Errorf("unknown email template %s", name)
+}
+
+// Supports reports whether emails are written in the language, or the language without its region.
+func (s *Store) Supports(language string) bool {
+	candidates := fallbacks(language)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	// The default language every recipient falls back to does not count
+	for _, candidate := range candidates[:len(candidates)-1] {
+		for k := range s.templates {
+			if strings.HasPrefix(k, strings.ToLower(candidate)+"/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Template is an email and the languages it is written in.
+type Template struct {
+	Name      string   `json:"name"`
+	Languages []string `json:"languages"`
+}
+
+// Templates lists the emails by name.
+func (s *Store) Templates() []Template {
+	s.mu.RLock()
+	languages := map[string][]string{}
+	for k := range s.templates {
+		language, name, part := splitKey(k)
+		if part == partText {
+			languages[name] = append(languages[name], language)
+		}
+	}
+	s.mu.RUnlock()
+	templates := make([]Template, 0, len(languages))
+	for name, available := range languages {
+		sort.Strings(available)
+		templates = append(templates, Template{Name: name, Languages: available})
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates
+}
+
+// check renders every language of every email with its sample, so a template referring to data that does not
+// exist fails when it is loaded rather than when the email is sent.
+func (s *Store) check(templates map[string]executor) error {
+	type email struct{ language, name string }
+	emails := map[email]bool{}
+	for k := range templates {
+		language, name, _ := splitKey(k)
+		emails[email{language, name}] = true
+	}
+	var errs []error
+	for email := range emails {
+		for _, part := range []string{partSubject, partText, partHTML} {
+			template, ok := templates[key(email.language, email.name, part)]
+			if !ok {
+				if part != partHTML {
+					errs = append(errs, fmt.Errorf("template %s has no %s part in %s", email.name, part, email.language))
+				}
+				continue
+			}
+			sample, ok := s.samples[email.name]
+			if !ok {
+				continue
+			}
+			if _, err := execute(template, sample()); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	// Sorted, so the same problems are reported the same way
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return errors.Join(errs...)
+}
+
+// parseAll parses the templates below root, replacing those already in templates.
+func parseAll(fsys fs.FS, root string, templates map[string]executor) error {
+	return fs.WalkDir(fsys, root, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		relative := strings.TrimPrefix(file, root+"/")
+		match := templateFile.FindStringSubmatch(relative)
+		if match == nil {
+			return nil
+		}
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+		language, name, part := match[1], match[2], match[3]
+		var parsed executor
+		if part == partHTML {
+			parsed, err = htmltemplate.New(relative).Option("missingkey=error").Parse(string(content))
+		} else {
+			parsed, err = texttemplate.New(relative).Option("missingkey=error").Parse(string(content))
+		}
+		if err != nil {
+			return err
+		}
+		templates[key(language, name, part)] = parsed
+		return nil
+	})
+}
+
+// dirFingerprint changes whenever a file in the directory is added, removed or modified.
+func (s *Store) dirFingerprint() (string, error) {
+	if s.dir == "" {
+		return "", nil
+	}
+	var fingerprint strings.Builder
+	err := fs.WalkDir(os.DirFS(s.dir), ".", func(file string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&fingerprint, "%s:%d:%d;", file, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	return fingerprint.String(), err
+}
+
+// fallbacks lists the languages tried for a recipient, best first.
+func fallbacks(language string) []string {
+	var candidates []string
+	for language != "" {
+		candidates = append(candidates, language)
+		cut := strings.LastIndex(language, "-")
+		if cut < 0 {
+			break
+		}
+		language = language[:cut]
+	}
+	return append(candidates, DefaultLanguage)
+}
+
+func key(language, name, part string) string {
+	return strings.ToLower(language) + "/" + name + "." + part
+}
+
+func splitKey(k string) (language, name, part string) {
+	language, rest, _ := strings.Cut(k, "/")
+	name = path.Ext(rest)
+	return language, strings.TrimSuffix(rest, name), strings.TrimPrefix(name, ".")
+}
+
+func execute(template executor, data any) (string, error) {
+	var buffer bytes.Buffer
+	if err := template.Execute(&buffer, data); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
@@ -8,9 +8,13 @@
 // @securityDefinitions.apikey Authorization
 // @in header
 // @name Authorization
+// @securityDefinitions.apikey APIKey
+// @in header
+// @name X-API-Key
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
@@ -22,12 +26,16 @@ import (
 
 	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database"
 	"github.com/chegde20121/Tigerhall-Kittens/internal/app/handlers"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/service"
 	"github.com/gorilla/mux"
 	"github.com/natefinch/lumberjack"
 	"github.com/rifflock/lfshook"
 	log "github.com/sirupsen/logrus"
 )
 
+// shutdownTimeout is how long in-flight requests and notifications get to finish on SIGTERM
+const shutdownTimeout = 30 * time.Second
+
 func main() {
 	setupLogger()
 	log.Info("starting application")
@@ -35,6 +43,23 @@ func main() {
 		log.Error("Error occurred while creating the database connection")
 	}
 	defer database.CloseDB()
+	service.InitLocationPrivacy()
+	if err := service.InitSigningKeys(); err != nil {
+		log.Fatal("Failed to load JWT signing keys: ", err)
+	}
+	// Broken templates in EMAIL_TEMPLATES_DIR would fail every notification, better not to start at all
+	emailTemplates, err := service.StartEmailTemplates(log.StandardLogger())
+	if err != nil {
+		log.Fatal("Failed to load the email templates: ", err)
+	}
+	defer emailTemplates.Close()
+	dispatcher := service.StartNotificationDispatcher(database.GetDB(), log.StandardLogger())
+	stream := service.StartSightingStream(log.StandardLogger())
+	// Without the bridge every instance still works, it only misses the events of the others
+	bridge, err := service.StartEventBridge(database.GetDB(), log.StandardLogger())
+	if err != nil {
+		log.Error("Failed to start the event bridge, events are not shared between instances: ", err)
+	}
 	serveMux := mux.NewRouter()
 	handlers.RegisterApiHandlers(serveMux)
 	server := &http.Server{
@@ -42,6 +67,10 @@ func main() {
 		Handler:     serveMux,
 		IdleTimeout: 120 * time.Second,
 	}
+	// Live feed streams never finish on their own, end them so the shutdown does not wait for them
+	server.RegisterOnShutdown(stream.Close)
+	// WebSocket connections are not tracked by the server at all, ask the apps to reconnect to another instance
+	server.RegisterOnShutdown(service.CloseLiveChannels)
 	go func() {
 		err := server.ListenAndServe()
 		if err != nil {
@@ -53,6 +82,18 @@ func main() {
 	signal.Notify(sigChan, syscall.SIGTERM)
 	sig := <-sigChan
 	log.Info("Recieved terminate, gracefull shutdown", sig)
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	// Requests still being served may verify sightings, let them finish before the dispatcher drains
+	if err := server.Shutdown(ctx); err != nil {
+		log.Error("failed to shut down the server,", err)
+	}
+	if err := dispatcher.Shutdown(ctx); err != nil {
+		log.Error("failed to drain the notification dispatcher,", err)
+	}
+	if bridge != nil {
+		bridge.Close()
+	}
 }
 
 func setupLogger() {
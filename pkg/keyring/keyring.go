@@ -0,0 +1,234 @@
+package keyring
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Supported signing algorithms
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	ES256 = "ES256"
+)
+
+// Key is a JWT signing or verification key identified by its kid.
+type Key struct {
+	ID        string
+	Algorithm string
+	// private is the key used for signing, nil for verification only keys
+	private any
+	// public is the key used for verification
+	public any
+}
+
+// KeyRing signs tokens with a single active key and verifies tokens signed by any of its keys,
+// which allows rotating the signing key while tokens issued with the previous one are still valid.
+type KeyRing struct {
+	mu           sync.RWMutex
+	signing      *Key
+	verification map[string]*Key
+}
+
+// New creates a key ring signing with the given key. Additional keys are only used for verification.
+func New(signing *Key, verification ...*Key) (*KeyRing, error) {
+	if signing == nil || signing.private == nil {
+		return nil, errors.New("keyring: signing key must contain a private key")
+	}
+	kr := &KeyRing{signing: signing, verification: map[string]*Key{signing.ID: signing}}
+	for _, key := range verification {
+		if _, exists := kr.verification[key.ID]; exists {
+			return nil, fmt.Errorf("keyring: duplicate key id %s", key.ID)
+		}
+		kr.verification[key.ID] = key
+	}
+	return kr, nil
+}
+
+// NewHMACKey creates a symmetric HS256 key. Symmetric keys are never published in the JWKS.
+func NewHMACKey(id string, secret []byte) *Key {
+	return &Key{ID: id, Algorithm: HS256, private: secret, public: secret}
+}
+
+// GenerateKey creates a new random key for the algorithm. When id is empty the JWK thumbprint is used.
+func GenerateKey(id string, algorithm string) (*Key, error) {
+	var private any
+	var err error
+	switch algorithm {
+	case RS256:
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	case ES256:
+		private, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case HS256:
+		secret := make([]byte, 32)
+		_, err = rand.Read(secret)
+		private = secret
+	default:
+		return nil, fmt.Errorf("keyring: unsupported algorithm %s", algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newKey(id, algorithm, private)
+}
+
+// ParsePrivateKeyPEM loads a PKCS#8, PKCS#1 (RSA) or SEC 1 (EC) private key. The algorithm is
+// derived from the key type and the kid defaults to the JWK thumbprint when id is empty.
+func ParsePrivateKeyPEM(id string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("keyring: no PEM block found")
+	}
+	var private any
+	var err error
+	if private, err = x509.ParsePKCS8PrivateKey(block.Bytes); err != nil {
+		if private, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			if private, err = x509.ParseECPrivateKey(block.Bytes); err != nil {
+				return nil, errors.New("keyring: unsupported private key format")
+			}
+		}
+	}
+	algorithm, err := algorithmFor(private)
+	if err != nil {
+		return nil, err
+	}
+	return newKey(id, algorithm, private)
+}
+
+// ParsePublicKeyPEM loads a PKIX public key, used to keep verifying tokens after a key was rotated out.
+func ParsePublicKeyPEM(id string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("keyring: no PEM block found")
+	}
+	public, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("keyring: %v", err)
+	}
+	algorithm, err := algorithmFor(public)
+	if err != nil {
+		return nil, err
+	}
+	key := &Key{ID: id, Algorithm: algorithm, public: public}
+	if key.ID == "" {
+		key.ID = key.JWK().Thumbprint()
+	}
+	return key, nil
+}
+
+func newKey(id string, algorithm string, private any) (*Key, error) {
+	key := &Key{ID: id, Algorithm: algorithm, private: private}
+	switch k := private.(type) {
+	case *rsa.PrivateKey:
+		key.public = &k.PublicKey
+	case *ecdsa.PrivateKey:
+		key.public = &k.PublicKey
+	case []byte:
+		key.public = k
+	}
+	if key.ID == "" {
+		if algorithm == HS256 {
+			return nil, errors.New("keyring: HMAC keys need an explicit key id")
+		}
+		key.ID = key.JWK().Thumbprint()
+	}
+	return key, nil
+}
+
+// algorithmFor maps a key to the algorithm it is used with.
+func algorithmFor(key any) (string, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey, *rsa.PublicKey:
+		return RS256, nil
+	case *ecdsa.PrivateKey:
+		if k.Curve == elliptic.P256() {
+			return ES256, nil
+		}
+	case *ecdsa.PublicKey:
+		if k.Curve == elliptic.P256() {
+			return ES256, nil
+		}
+	}
+	return "", fmt.Errorf("keyring: unsupported key type %T", key)
+}
+
+// SigningKeyID returns the kid of the active signing key.
+func (kr *KeyRing) SigningKeyID() string {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	return kr.signing.ID
+}
+
+// Rotate makes the key the active signing key. The previous key stays available for verification.
+func (kr *KeyRing) Rotate(key *Key) error {
+	if key == nil || key.private == nil {
+		return errors.New("keyring: signing key must contain a private key")
+	}
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	kr.signing = key
+	kr.verification[key.ID] = key
+	return nil
+}
+
+// Retire removes a verification key, tokens signed with it are rejected afterwards.
+func (kr *KeyRing) Retire(id string) error {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	if kr.signing.ID == id {
+		return errors.New("keyring: cannot retire the active signing key")
+	}
+	delete(kr.verification, id)
+	return nil
+}
+
+// Sign signs the claims with the active key and sets the kid header.
+func (kr *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	kr.mu.RLock()
+	key := kr.signing
+	kr.mu.RUnlock()
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.private)
+}
+
+// Keyfunc resolves the verification key of a token from its kid header. It is meant to be passed to jwt.Parse.
+// The algorithm in the token header must match the algorithm of the key to prevent algorithm confusion.
+func (kr *KeyRing) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	kid, _ := token.Header["kid"].(string)
+	key, ok := kr.verification[kid]
+	if !ok {
+		return nil, fmt.Errorf("keyring: unknown key id %q", kid)
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("keyring: unexpected signing method %s for key %s", token.Method.Alg(), kid)
+	}
+	return key.public, nil
+}
+
+// JWKS returns the public keys of the ring. Symmetric keys are left out.
+func (kr *KeyRing) JWKS() JWKS {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	jwks := JWKS{Keys: []JWK{}}
+	for _, key := range kr.verification {
+		if key.Algorithm == HS256 {
+			continue
+		}
+		jwks.Keys = append(jwks.Keys, key.JWK())
+	}
+	sort.Slice(jwks.Keys, func(i, j int) bool { return jwks.Keys[i].KeyID < jwks.Keys[j].KeyID })
+	return jwks
+}
@@ -1,23 +1,106 @@
 package messaging
 
 import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
 	"net/smtp"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/chegde20121/Tigerhall-Kittens/pkg/config"
 	log "github.com/sirupsen/logrus"
 )
 
+const (
+	defaultSenderName = "Tigerhall-Kittens"
+	// base64LineLength is the longest line allowed in base64 encoded parts (RFC 2045)
+	base64LineLength = 76
+	// defaultSMTPTimeout bounds connecting to the SMTP server and the whole conversation with it
+	defaultSMTPTimeout = 30 * time.Second
+)
+
+// headerLineBreaks drops line breaks from header values, they would let a value add headers of its own
+var headerLineBreaks = strings.NewReplacer("\r", "", "\n", "")
+
 type EmailHandler struct {
 	logger *log.Logger
 }
 
 type EmailTemplateData struct {
-	TigerName     string
-	TigerLocation string
-	SightingTime  string
-	Organization  string
-	ContactInfo   string
+	TigerName string
+	Latitude  float64
+	Longitude float64
+	// LocationPrecision is exact or coarse, depending on the location access of the recipient
+	LocationPrecision string
+	// LocationWithheld is set instead of coordinates and map link for positions still under embargo
+	LocationWithheld bool
+	SightingTime     string
+	Organization     string
+	ContactInfo      string
+	// UnsubscribeURL is the signed link the recipient stops these notifications with
+	UnsubscribeURL string
+	// MapURL shows the location of the sighting on a map
+	MapURL string
+	// ImageContentID references the inline sighting photo in the HTML body, empty if there is none
+	ImageContentID string
+}
+
+// Email is a message before it is encoded. Options fill in everything beyond the plain text body.
+type Email struct {
+	From    mail.Address
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+	Date    time.Time
+	Headers map[string]string
+	Inline  []InlineAttachment
+}
+
+// InlineAttachment is a file shown inside the HTML body, referenced as cid:<ContentID>.
+type InlineAttachment struct {
+	ContentID   string
+	ContentType string
+	Filename    string
+	Data        []byte
+}
+
+// EmailOption adds headers, an HTML alternative or inline files to an email.
+type EmailOption func(email *Email)
+
+// WithListUnsubscribe adds the List-Unsubscribe headers (RFC 2369, RFC 8058) so mail clients offer
+// a one-click unsubscribe button that POSTs to the link.
+func WithListUnsubscribe(link string) EmailOption {
+	return func(email *Email) {
+		email.Headers["List-Unsubscribe"] = "<" + link + ">"
+		email.Headers["List-Unsubscribe-Post"] = "List-Unsubscribe=One-Click"
+	}
+}
+
+// WithHTML sends the body as HTML too. Mail clients show the HTML and fall back to the plain text.
+func WithHTML(html string) EmailOption {
+	return func(email *Email) {
+		email.HTML = html
+	}
+}
+
+// WithInlineImage embeds an image the HTML body shows with <img src="cid:contentID">.
+func WithInlineImage(contentID string, contentType string, filename string, data []byte) EmailOption {
+	return func(email *Email) {
+		email.Inline = append(email.Inline, InlineAttachment{ContentID: contentID, ContentType: contentType, Filename: filename, Data: data})
+	}
 }
 
 func NewEmailHandler(logger *log.Logger) *EmailHandler {
@@ -29,25 +112,36 @@ type EmailStatus struct {
 	Email string
 }
 
-func (em *EmailHandler) SendEmailNotification(emails []string, subject string, body string) (err error) {
+func (em *EmailHandler) SendEmailNotification(emails []string, subject string, body string, opts ...EmailOption) (err error) {
 	em.logger.Info("Sending Email Notification")
 	senderEmail := config.GetEnvVar("SENDER_EMAIL")
 	senderPassword := config.GetEnvVar("SENDER_EMAIL_PASSWORD")
 	smtpHost := config.GetEnvVar("SMTP_HOST")
 	smtpPort := config.GetEnvVar("SMTP_PORT")
+	senderName := config.GetEnvVar("SENDER_NAME")
+	if senderName == "" {
+		senderName = defaultSenderName
+	}
+	timeout := defaultSMTPTimeout
+	if seconds, err := strconv.Atoi(config.GetEnvVar("SMTP_TIMEOUT_SECONDS")); err == nil && seconds > 0 {
+		timeout = time.Duration(seconds) * time.Second
+	}
 	emailChan := make(chan EmailStatus)
-	message := "Subject: " + subject + "\r\n" + "\r\n" + body
 	errorMessage := []EmailStatus{}
 	for _, email := range emails {
 		auth := smtp.PlainAuth("", senderEmail, senderPassword, smtpHost)
 		to := email
-		go func(from, to, message string) {
-			err := smtp.SendMail(smtpHost+":"+smtpPort, auth, from, []string{to}, []byte(message))
+		// Every recipient gets its own message, with its own To and Message-ID
+		message, err := ComposeMessage(mail.Address{Name: senderName, Address: senderEmail}, to, subject, body, time.Now(), opts...)
+		go func(from, to string, message []byte, err error) {
+			if err == nil {
+				err = sendMail(smtpHost, smtpPort, auth, from, to, message, timeout)
+			}
 			emailChan <- EmailStatus{
 				Err:   err,
 				Email: to,
 			}
-		}(senderEmail, to, message)
+		}(senderEmail, to, message, err)
 	}
 	for range emails {
 		status := <-emailChan
@@ -60,3 +154,197 @@ func (em *EmailHandler) SendEmailNotification(emails []string, subject string, b
 	}
 	return
 }
+
+// sendMail delivers a message like smtp.SendMail, but connects with a timeout and puts a deadline on the
+// connection, so a hung SMTP server fails the delivery instead of blocking the caller forever.
+func sendMail(host string, port string, auth smtp.Auth, from string, to string, message []byte, timeout time.Duration) error {
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.Dial("tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return err
+	}
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		conn.Close()
+		return err
+	}
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if auth != nil {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return fmt.Errorf("smtp: server doesn't support AUTH")
+		}
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	data, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := data.Write(message); err != nil {
+		return err
+	}
+	if err := data.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// ComposeMessage encodes an email as a MIME message with RFC 5322 headers. Plain text emails are a single
+// text/plain part. With HTML they become multipart/alternative, wrapped in multipart/related when images are
+// embedded.
+func ComposeMessage(from mail.Address, to string, subject string, text string, date time.Time, opts ...EmailOption) ([]byte, error) {
+	email := &Email{From: from, To: to, Subject: subject, Text: text, Date: date, Headers: map[string]string{}}
+	for _, opt := range opts {
+		opt(email)
+	}
+	messageID, err := newMessageID(from.Address)
+	if err != nil {
+		return nil, err
+	}
+	var message bytes.Buffer
+	headers := map[string]string{
+		"From":         from.String(),
+		"To":           (&mail.Address{Address: to}).String(),
+		"Subject":      mime.QEncoding.Encode("utf-8", headerLineBreaks.Replace(subject)),
+		"Date":         date.Format(time.RFC1123Z),
+		"Message-ID":   messageID,
+		"MIME-Version": "1.0",
+	}
+	for name, value := range email.Headers {
+		headers[name] = value
+	}
+	var body bytes.Buffer
+	switch {
+	case email.HTML == "":
+		headers["Content-Type"] = "text/plain; charset=utf-8"
+		headers["Content-Transfer-Encoding"] = "quoted-printable"
+		if err := writeQuotedPrintable(&body, text); err != nil {
+			return nil, err
+		}
+	case len(email.Inline) == 0:
+		contentType, err := writeAlternative(&body, email)
+		if err != nil {
+			return nil, err
+		}
+		headers["Content-Type"] = contentType
+	default:
+		related := multipart.NewWriter(&body)
+		headers["Content-Type"] = mime.FormatMediaType("multipart/related", map[string]string{"boundary": related.Boundary(), "type": "multipart/alternative"})
+		var alternative bytes.Buffer
+		contentType, err := writeAlternative(&alternative, email)
+		if err != nil {
+			return nil, err
+		}
+		part, err := related.CreatePart(textproto.MIMEHeader{"Content-Type": {contentType}})
+		if err != nil {
+			return nil, err
+		}
+		part.Write(alternative.Bytes())
+		for _, inline := range email.Inline {
+			if err := writeInline(related, inline); err != nil {
+				return nil, err
+			}
+		}
+		if err := related.Close(); err != nil {
+			return nil, err
+		}
+	}
+	writeHeaders(&message, headers)
+	message.WriteString("\r\n")
+	message.Write(body.Bytes())
+	return message.Bytes(), nil
+}
+
+// writeAlternative writes the plain text and HTML parts and returns the Content-Type of the multipart.
+func writeAlternative(w *bytes.Buffer, email *Email) (string, error) {
+	alternative := multipart.NewWriter(w)
+	// Clients show the last alternative they understand, so the richest one comes last
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", email.Text},
+		{"text/html; charset=utf-8", email.HTML},
+	} {
+		writer, err := alternative.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return "", err
+		}
+		if err := writeQuotedPrintable(writer, part.content); err != nil {
+			return "", err
+		}
+	}
+	if err := alternative.Close(); err != nil {
+		return "", err
+	}
+	return mime.FormatMediaType("multipart/alternative", map[string]string{"boundary": alternative.Boundary()}), nil
+}
+
+func writeInline(related *multipart.Writer, inline InlineAttachment) error {
+	part, err := related.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {inline.ContentType},
+		"Content-Transfer-Encoding": {"base64"},
+		"Content-ID":                {"<" + inline.ContentID + ">"},
+		"Content-Disposition":       {mime.FormatMediaType("inline", map[string]string{"filename": inline.Filename})},
+	})
+	if err != nil {
+		return err
+	}
+	encoded := base64.StdEncoding.EncodeToString(inline.Data)
+	for len(encoded) > base64LineLength {
+		part.Write([]byte(encoded[:base64LineLength] + "\r\n"))
+		encoded = encoded[base64LineLength:]
+	}
+	_, err = part.Write([]byte(encoded + "\r\n"))
+	return err
+}
+
+func writeQuotedPrintable(w interface{ Write([]byte) (int, error) }, content string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(content)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// writeHeaders writes the headers sorted by name so the output is stable.
+func writeHeaders(message *bytes.Buffer, headers map[string]string) {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := headerLineBreaks.Replace(headers[name])
+		message.WriteString(name + ": " + value + "\r\n")
+	}
+}
+
+// newMessageID returns a unique Message-ID in the domain of the sender.
+func newMessageID(sender string) (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	domain := "localhost"
+	if at := strings.LastIndex(sender, "@"); at >= 0 && at < len(sender)-1 {
+		domain = sender[at+1:]
+	}
+	return "<" + hex.EncodeToString(id) + "@" + domain + ">", nil
+}
@@ -1,48 +1,250 @@
 package messaging
 
-import "sync"
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
 
-// PubSub represents the pub-sub system
+// OverflowPolicy decides what happens when a message is published to a subscriber whose queue is full.
+type OverflowPolicy int
+
+const (
+	// Block makes the publisher wait until the subscriber has room, no message is lost
+	Block OverflowPolicy = iota
+	// DropOldest discards the oldest queued message to make room for the new one
+	DropOldest
+	// DropNewest discards the new message
+	DropNewest
+)
+
+const defaultQueueCapacity = 64
+
+func (p OverflowPolicy) String() string {
+	switch p {
+	case DropOldest:
+		return "drop_oldest"
+	case DropNewest:
+		return "drop_newest"
+	default:
+		return "block"
+	}
+}
+
+// Topic is a named stream of messages of type T. Topics are declared once, e.g. as package variables,
+// so publishers and subscribers agree on the payload type.
+type Topic[T any] struct {
+	name string
+}
+
+// NewTopic declares the topic with the given name.
+func NewTopic[T any](name string) Topic[T] {
+	return Topic[T]{name: name}
+}
+
+// Name returns the name of the topic.
+func (t Topic[T]) Name() string {
+	return t.name
+}
+
+// SubscribeOptions configure the queue of a subscriber.
+type SubscribeOptions struct {
+	// Name identifies the subscriber in the stats
+	Name string
+	// Capacity is the number of messages queued for the subscriber, 64 if not set
+	Capacity int
+	// Overflow is applied when the queue is full
+	Overflow OverflowPolicy
+}
+
+// SubscriptionStats are the metrics of one subscriber queue.
+type SubscriptionStats struct {
+	Topic    string `json:"topic"`
+	Name     string `json:"name"`
+	Overflow string `json:"overflow"`
+	Capacity int    `json:"capacity"`
+	// Depth is the number of messages waiting to be received
+	Depth     int    `json:"depth"`
+	Delivered uint64 `json:"delivered"`
+	Dropped   uint64 `json:"dropped"`
+}
+
+// PubSub delivers the messages published on a topic to every subscriber of that topic. Each subscriber has a
+// bounded queue and receives messages in the order they were published.
 type PubSub struct {
-	mu          sync.RWMutex
-	subscribers map[chan any]struct{}
+	mu     sync.RWMutex
+	topics map[string]map[queue]struct{}
+}
+
+// queue is the part of a subscription the PubSub needs, independent of its payload type.
+type queue interface {
+	enqueue(payload any)
+	stats() SubscriptionStats
 }
 
 // NewPubSub creates a new instance of the PubSub system
 func NewPubSub() *PubSub {
 	return &PubSub{
-		subscribers: make(map[chan any]struct{}),
+		topics: make(map[string]map[queue]struct{}),
 	}
 }
 
-// Subscribe adds a new subscriber to the PubSub system
-func (ps *PubSub) Subscribe() chan any {
+// Subscription receives the messages of a topic until it is unsubscribed.
+type Subscription[T any] struct {
+	ps       *PubSub
+	topic    string
+	name     string
+	overflow OverflowPolicy
+	messages chan T
+	// done is closed first on unsubscribe, so a publisher blocked on a full queue gives up before
+	// messages is closed
+	done      chan struct{}
+	closeOnce sync.Once
+	// mu serialises publishers with each other and with closing messages
+	mu        sync.Mutex
+	closed    bool
+	delivered atomic.Uint64
+	dropped   atomic.Uint64
+}
+
+// Subscribe adds a subscriber to the topic.
+func Subscribe[T any](ps *PubSub, topic Topic[T], opts SubscribeOptions) *Subscription[T] {
+	capacity := opts.Capacity
+	if capacity <= 0 {
+		capacity = defaultQueueCapacity
+	}
+	sub := &Subscription[T]{
+		ps:       ps,
+		topic:    topic.name,
+		name:     opts.Name,
+		overflow: opts.Overflow,
+		messages: make(chan T, capacity),
+		done:     make(chan struct{}),
+	}
 	ps.mu.Lock()
 	defer ps.mu.Unlock()
+	if ps.topics[topic.name] == nil {
+		ps.topics[topic.name] = make(map[queue]struct{})
+	}
+	ps.topics[topic.name][sub] = struct{}{}
+	return sub
+}
 
-	subscriber := make(chan any, 10) // Buffering to avoid blocking publishers
-	ps.subscribers[subscriber] = struct{}{}
+// Publish sends the payload to every subscriber of the topic. It only blocks for subscribers with the Block policy.
+func Publish[T any](ps *PubSub, topic Topic[T], payload T) {
+	ps.deliver(topic.name, payload, nil)
+}
 
-	return subscriber
+// deliver enqueues the payload for every subscriber of the topic except skip.
+func (ps *PubSub) deliver(topic string, payload any, skip queue) {
+	ps.mu.RLock()
+	subscribers := make([]queue, 0, len(ps.topics[topic]))
+	for sub := range ps.topics[topic] {
+		if sub != skip {
+			subscribers = append(subscribers, sub)
+		}
+	}
+	// Not held while delivering, a blocked subscriber must not stop others from (un)subscribing
+	ps.mu.RUnlock()
+	for _, sub := range subscribers {
+		sub.enqueue(payload)
+	}
 }
 
-// Unsubscribe removes a subscriber from the PubSub system
-func (ps *PubSub) Unsubscribe(subscriber chan any) {
-	ps.mu.Lock()
-	defer ps.mu.Unlock()
+// Messages returns the channel messages are received on. It is closed on unsubscribe, after which
+// the messages still queued can be drained.
+func (s *Subscription[T]) Messages() <-chan T {
+	return s.messages
+}
 
-	delete(ps.subscribers, subscriber)
-	close(subscriber)
+// Unsubscribe removes the subscriber from the topic. It is safe to call more than once and while publishing.
+func (s *Subscription[T]) Unsubscribe() {
+	s.closeOnce.Do(func() {
+		s.ps.mu.Lock()
+		delete(s.ps.topics[s.topic], s)
+		s.ps.mu.Unlock()
+		close(s.done)
+		s.mu.Lock()
+		s.closed = true
+		close(s.messages)
+		s.mu.Unlock()
+	})
 }
 
-// Publish sends a message to all subscribers
-func (ps *PubSub) Publish(message any) {
-	ps.mu.RLock()
-	defer ps.mu.RUnlock()
+func (s *Subscription[T]) enqueue(payload any) {
+	message, ok := payload.(T)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	switch s.overflow {
+	case DropNewest:
+		select {
+		case s.messages <- message:
+			s.delivered.Add(1)
+		default:
+			s.dropped.Add(1)
+		}
+	case DropOldest:
+		for {
+			select {
+			case s.messages <- message:
+				s.delivered.Add(1)
+				return
+			default:
+			}
+			select {
+			case <-s.messages:
+				s.dropped.Add(1)
+			default:
+				// The subscriber made room in the meantime
+			}
+		}
+	default:
+		select {
+		case s.messages <- message:
+			s.delivered.Add(1)
+		case <-s.done:
+		}
+	}
+}
+
+// Stats returns the metrics of the subscriber queue.
+func (s *Subscription[T]) Stats() SubscriptionStats {
+	return s.stats()
+}
 
-	for subscriber := range ps.subscribers {
-		go func(s chan any) {
-			s <- message
-		}(subscriber)
+func (s *Subscription[T]) stats() SubscriptionStats {
+	return SubscriptionStats{
+		Topic:     s.topic,
+		Name:      s.name,
+		Overflow:  s.overflow.String(),
+		Capacity:  cap(s.messages),
+		Depth:     len(s.messages),
+		Delivered: s.delivered.Load(),
+		Dropped:   s.dropped.Load(),
+	}
+}
+
+// Stats returns the metrics of every subscriber queue, sorted by topic and name.
+func (ps *PubSub) Stats() []SubscriptionStats {
+	ps.mu.RLock()
+	stats := []SubscriptionStats{}
+	for _, subscribers := range ps.topics {
+		for sub := range subscribers {
+			stats = append(stats, sub.stats())
+		}
 	}
+	ps.mu.RUnlock()
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Topic != stats[j].Topic {
+			return stats[i].Topic < stats[j].Topic
+		}
+		return stats[i].Name < stats[j].Name
+	})
+	return stats
 }
@@ -0,0 +1,257 @@
+package messaging
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// maxNotifyPayload stays below the 8000 byte limit of NOTIFY payloads, larger messages are sent as row references
+	maxNotifyPayload = 7900
+	// bridgeQueueCapacity is how many messages may wait to be forwarded, or to be published locally, before the oldest are dropped
+	bridgeQueueCapacity = 1024
+	// listenerPingInterval checks idle connections, pq only notices dead ones when using them
+	listenerPingInterval = 90 * time.Second
+	// storedPayloadTTL is how long large payloads are kept for other instances to read
+	storedPayloadTTL = time.Hour
+)
+
+// PostgresBridgeOptions configure a PostgresBridge.
+type PostgresBridgeOptions struct {
+	// Channel is the NOTIFY channel shared by all instances
+	Channel string
+	// PayloadTable stores messages too large for NOTIFY. It needs event_id, topic, payload and created_at columns.
+	PayloadTable string
+}
+
+// PostgresBridge forwards the messages of bridged topics to the other instances of the service through Postgres
+// LISTEN/NOTIFY, and publishes the messages of other instances locally. Messages are best effort: those sent
+// while the listener reconnects are not received, and the oldest received ones are dropped when they arrive faster
+// than local subscribers take them.
+type PostgresBridge struct {
+	ps         *PubSub
+	db         *sql.DB
+	listener   *pq.Listener
+	opts       PostgresBridgeOptions
+	instanceID string
+	logger     *log.Logger
+
+	mu     sync.RWMutex
+	topics map[string]bridgedTopic
+	// incoming holds the messages received from other instances until they are published locally, so a slow
+	// subscriber or a large payload being loaded does not hold up the listener
+	incoming chan string
+	wg       sync.WaitGroup
+	done     chan struct{}
+}
+
+// bridgedTopic decodes the messages of a topic received from other instances.
+type bridgedTopic struct {
+	// forwarder is the local subscription sending messages out, it does not get the received ones back
+	forwarder   queue
+	unsubscribe func()
+	decode      func(data []byte) (any, error)
+}
+
+// envelope is the NOTIFY payload. Either Payload or Ref is set.
+type envelope struct {
+	Origin  string          `json:"origin"`
+	Topic   string          `json:"topic"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+	Ref     int64           `json:"ref,omitempty"`
+}
+
+// NewPostgresBridge listens on the channel with its own connection to dsn. The listener reconnects on its own.
+func NewPostgresBridge(ps *PubSub, db *sql.DB, dsn string, opts PostgresBridgeOptions, logger *log.Logger) (*PostgresBridge, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	b := &PostgresBridge{
+		ps:         ps,
+		db:         db,
+		opts:       opts,
+		instanceID: hex.EncodeToString(id),
+		logger:     logger,
+		topics:     make(map[string]bridgedTopic),
+		incoming:   make(chan string, bridgeQueueCapacity),
+		done:       make(chan struct{}),
+	}
+	b.listener = pq.NewListener(dsn, time.Second, time.Minute, b.listenerEvent)
+	if err := b.listener.Listen(opts.Channel); err != nil {
+		b.listener.Close()
+		return nil, err
+	}
+	b.wg.Add(2)
+	go b.receive()
+	go b.publishIncoming()
+	return b, nil
+}
+
+// Bridge forwards the messages published locally on the topic to the other instances and the other way round.
+func Bridge[T any](b *PostgresBridge, topic Topic[T]) {
+	sub := Subscribe(b.ps, topic, SubscribeOptions{Name: "postgres-bridge", Capacity: bridgeQueueCapacity, Overflow: DropOldest})
+	b.mu.Lock()
+	b.topics[topic.name] = bridgedTopic{
+		forwarder:   sub,
+		unsubscribe: sub.Unsubscribe,
+		decode: func(data []byte) (any, error) {
+			var payload T
+			err := json.Unmarshal(data, &payload)
+			return payload, err
+		},
+	}
+	b.mu.Unlock()
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		for payload := range sub.Messages() {
+			if err := b.forward(topic.name, payload); err != nil {
+				b.logger.Errorf("Failed to forward %s message to other instances: %v", topic.name, err)
+			}
+		}
+	}()
+}
+
+// Close stops bridging after the messages waiting to be forwarded were sent.
+func (b *PostgresBridge) Close() error {
+	b.mu.RLock()
+	for _, topic := range b.topics {
+		topic.unsubscribe()
+	}
+	b.mu.RUnlock()
+	close(b.done)
+	err := b.listener.Close()
+	b.wg.Wait()
+	return err
+}
+
+func (b *PostgresBridge) forward(topic string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	message, err := json.Marshal(envelope{Origin: b.instanceID, Topic: topic, Payload: data})
+	if err != nil {
+		return err
+	}
+	if len(message) > maxNotifyPayload {
+		if message, err = b.storePayload(topic, data); err != nil {
+			return err
+		}
+	}
+	_, err = b.db.Exec("SELECT pg_notify($1, $2)", b.opts.Channel, string(message))
+	return err
+}
+
+// storePayload saves a message too large for NOTIFY and returns the envelope referencing it.
+func (b *PostgresBridge) storePayload(topic string, data []byte) ([]byte, error) {
+	var ref int64
+	err := b.db.QueryRow(
+		fmt.Sprintf("INSERT INTO %s (topic, payload) VALUES ($1, $2) RETURNING event_id", b.opts.PayloadTable),
+		topic, data,
+	).Scan(&ref)
+	if err != nil {
+		return nil, err
+	}
+	// Every instance had plenty of time to read the older ones
+	_, _ = b.db.Exec(fmt.Sprintf("DELETE FROM %s WHERE created_at < $1", b.opts.PayloadTable), time.Now().Add(-storedPayloadTTL))
+	return json.Marshal(envelope{Origin: b.instanceID, Topic: topic, Ref: ref})
+}
+
+// receive hands the notifications over to publishIncoming, the listener's connection stalls when they are not
+// read quickly.
+func (b *PostgresBridge) receive() {
+	defer b.wg.Done()
+	defer close(b.incoming)
+	ticker := time.NewTicker(listenerPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.done:
+			return
+		case <-ticker.C:
+			go b.listener.Ping()
+		case notification, ok := <-b.listener.Notify:
+			if !ok {
+				return
+			}
+			// nil is sent after a reconnect
+			if notification != nil {
+				b.enqueueIncoming(notification.Extra)
+			}
+		}
+	}
+}
+
+// enqueueIncoming queues a received message, dropping the oldest one when publishIncoming falls behind.
+func (b *PostgresBridge) enqueueIncoming(message string) {
+	for {
+		select {
+		case b.incoming <- message:
+			return
+		default:
+		}
+		select {
+		case <-b.incoming:
+			b.logger.Warn("Event bridge is falling behind, dropped a message from other instance")
+		default:
+		}
+	}
+}
+
+func (b *PostgresBridge) publishIncoming() {
+	defer b.wg.Done()
+	for message := range b.incoming {
+		b.publishLocally(message)
+	}
+}
+
+func (b *PostgresBridge) publishLocally(message string) {
+	var env envelope
+	if err := json.Unmarshal([]byte(message), &env); err != nil {
+		b.logger.Warn("Ignoring malformed message from other instance:", err)
+		return
+	}
+	// Our own messages were delivered locally when they were published
+	if env.Origin == b.instanceID {
+		return
+	}
+	b.mu.RLock()
+	topic, ok := b.topics[env.Topic]
+	b.mu.RUnlock()
+	if !ok {
+		return
+	}
+	data := []byte(env.Payload)
+	if env.Ref != 0 {
+		err := b.db.QueryRow(fmt.Sprintf("SELECT payload FROM %s WHERE event_id = $1", b.opts.PayloadTable), env.Ref).Scan(&data)
+		if err != nil {
+			b.logger.Errorf("Failed to load %s message %d from other instance: %v", env.Topic, env.Ref, err)
+			return
+		}
+	}
+	payload, err := topic.decode(data)
+	if err != nil {
+		b.logger.Warnf("Ignoring malformed %s message from other instance: %v", env.Topic, err)
+		return
+	}
+	b.ps.deliver(env.Topic, payload, topic.forwarder)
+}
+
+func (b *PostgresBridge) listenerEvent(event pq.ListenerEventType, err error) {
+	switch event {
+	case pq.ListenerEventConnectionAttemptFailed, pq.ListenerEventDisconnected:
+		b.logger.Warnf("Event bridge lost its database connection, reconnecting: %v", err)
+	case pq.ListenerEventReconnected:
+		b.logger.Info("Event bridge reconnected, messages sent in the meantime were missed")
+	}
+}
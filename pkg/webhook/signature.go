@@ -0,0 +1,62 @@
+// Package webhook signs webhook deliveries with HMAC-SHA256 and verifies them, the latter for use by receivers.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers sent with every delivery.
+const (
+	EventHeader     = "X-Tigerhall-Event"
+	DeliveryHeader  = "X-Tigerhall-Delivery"
+	TimestampHeader = "X-Tigerhall-Timestamp"
+	SignatureHeader = "X-Tigerhall-Signature"
+)
+
+const signaturePrefix = "sha256="
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	// ErrStaleTimestamp is returned for deliveries outside the tolerance, e.g. replayed ones
+	ErrStaleTimestamp = errors.New("webhook timestamp outside the tolerance")
+)
+
+// Sign returns the signature header value for a body sent at the given time. The timestamp is signed along with
+// the body, so a captured delivery cannot be replayed with a new timestamp.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	return signaturePrefix + hex.EncodeToString(mac(secret, strconv.FormatInt(timestamp.Unix(), 10), body))
+}
+
+// Verify checks the signature and timestamp headers of a delivery received at now. Deliveries whose timestamp
+// differs from now by more than tolerance are rejected.
+func Verify(secret string, signature string, timestamp string, body []byte, tolerance time.Duration, now time.Time) error {
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrStaleTimestamp
+	}
+	if age := now.Sub(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return ErrStaleTimestamp
+	}
+	got, err := hex.DecodeString(strings.TrimPrefix(signature, signaturePrefix))
+	if err != nil || !strings.HasPrefix(signature, signaturePrefix) {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal(got, mac(secret, timestamp, body)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func mac(secret string, timestamp string, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(timestamp))
+	h.Write([]byte("."))
+	h.Write(body)
+	return h.Sum(nil)
+}
@@ -51,6 +51,14 @@ if [ "$RUN_TESTS" = true ]; then
     go test ./tests/unit_tests/... -coverprofile cover.out
 fi
 
+# The server refuses to start without a JWT signing key, create one for development
+if [ ! -f ./keys/jwt_signing_key.pem ]; then
+    echo "Generating a development JWT signing key in ./keys..."
+    mkdir -p ./keys
+    openssl ecparam -name prime256v1 -genkey -noout | openssl pkcs8 -topk8 -nocrypt -out ./keys/jwt_signing_key.pem
+    chmod 600 ./keys/jwt_signing_key.pem
+fi
+
 # Build the project
 if [ "$RUN_SERVER" != false ]; then
     echo "Building the project..."
@@ -0,0 +1,28 @@
+package unittests
+
+import (
+	"testing"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestClaimsPermissionsOfLogin(t *testing.T) {
+	claims := &models.Claims{Username: "ranger", Roles: []string{models.RoleRanger}}
+	assert.Equal(t, claims.HasPermission(models.PermissionTigersWrite), true)
+	assert.Equal(t, claims.HasPermission(models.PermissionUsersManage), false)
+}
+
+func TestClaimsPermissionsOfAPIKey(t *testing.T) {
+	claims := &models.Claims{
+		Username: "ranger",
+		Roles:    []string{models.RoleRanger},
+		Scopes:   []string{models.PermissionSightingsWrite, models.PermissionUsersManage},
+		APIKeyID: "key",
+	}
+	// Keys are limited to their scopes
+	assert.Equal(t, claims.HasPermission(models.PermissionSightingsWrite), true)
+	assert.Equal(t, claims.HasPermission(models.PermissionTigersWrite), false)
+	// and cannot do more than their owner
+	assert.Equal(t, claims.HasPermission(models.PermissionUsersManage), false)
+}
@@ -0,0 +1,40 @@
+package unittests
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database/repositories"
+	"github.com/magiconair/properties/assert"
+)
+
+// recordingExecer records the statements instead of running them, failing those on failTable.
+type recordingExecer struct {
+	statements []string
+	failTable  string
+}
+
+func (r *recordingExecer) Exec(query string, args ...any) (sql.Result, error) {
+	r.statements = append(r.statements, query)
+	if r.failTable != "" && strings.Contains(query, r.failTable) {
+		return nil, errors.New("connection reset")
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func TestRevokeCredentialsRevokesSessionsAndAPIKeys(t *testing.T) {
+	tx := &recordingExecer{}
+	assert.Equal(t, repositories.RevokeCredentials(tx, 7, "current-session"), nil)
+	assert.Equal(t, len(tx.statements), 2)
+	assert.Equal(t, strings.Contains(tx.statements[0], "UPDATE tigerhall.sessions SET revoked_at"), true)
+	assert.Equal(t, strings.Contains(tx.statements[1], "UPDATE tigerhall.api_keys SET revoked_at"), true)
+
+	// A failure is returned so the password change rolls back with it
+	tx = &recordingExecer{failTable: "tigerhall.api_keys"}
+	err := repositories.RevokeCredentials(tx, 7, "")
+	assert.Equal(t, err != nil, true)
+	assert.Equal(t, strings.Contains(err.Error(), "API keys"), true)
+}
@@ -0,0 +1,59 @@
+package unittests
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/service"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestWriteDataExport(t *testing.T) {
+	content := models.DataExportContent{
+		ExportedAt: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		Profile:    &models.Profile{Username: "jane", Email: "jane@example.com"},
+		Sightings: []models.Sighting{
+			{ID: 7, TigerID: 1, ImageBlob: []byte("jpeg"), Status: models.SightingVerified},
+			{ID: 8, TigerID: 1, Status: models.SightingPending},
+		},
+	}
+	var buf bytes.Buffer
+	if err := service.WriteDataExport(&buf, content); err != nil {
+		t.Fatal(err)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{}
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[file.Name], _ = io.ReadAll(reader)
+		reader.Close()
+	}
+	assert.Equal(t, len(files), 5)
+	assert.Equal(t, string(files["images/sighting-7.jpg"]), "jpeg")
+
+	var sightings []map[string]any
+	if err := json.Unmarshal(files["sightings.json"], &sightings); err != nil {
+		t.Fatal(err)
+	}
+	// Images are only stored as separate files
+	assert.Equal(t, len(sightings), 2)
+	_, hasImage := sightings[0]["image"]
+	assert.Equal(t, hasImage, false)
+
+	var profile models.Profile
+	if err := json.Unmarshal(files["profile.json"], &profile); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, profile.Username, "jane")
+}
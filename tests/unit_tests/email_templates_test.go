@@ -0,0 +1,158 @@
+package unittests
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/service"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/templates"
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/messaging"
+	"github.com/magiconair/properties/assert"
+	"github.com/sirupsen/logrus"
+)
+
+var sightingEmailData = messaging.EmailTemplateData{
+	TigerName:         "Shere <Khan>",
+	Latitude:          11.66,
+	Longitude:         76.62,
+	LocationPrecision: "coarse",
+	SightingTime:      "2024-03-01,06:42:10",
+	MapURL:            "https://www.openstreetmap.org/?mlat=11.66&mlon=76.62",
+	ImageContentID:    "sighting-1@tigerhall-kittens",
+}
+
+func TestEmailTemplatesFallBackToLanguageAndDefault(t *testing.T) {
+	store, err := templates.NewStore("", nil, logrus.New())
+	assert.Equal(t, err, nil)
+
+	// hi-IN has no templates of its own, it is rendered from those of hi
+	for language, want := range map[string]string{"hi": "hi", "hi-IN": "hi", "bn": "bn", "fr": "en", "": "en"} {
+		email, err := store.Render("sighting_notification", language, sightingEmailData)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, email.Language, want)
+	}
+	email, err := store.Render("sighting_notification", "en", sightingEmailData)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, email.Subject, "Tiger Sighting Notification: Shere <Khan>")
+	// Only the HTML part is escaped
+	assert.Equal(t, strings.Contains(email.Text, "(Shere <Khan>)"), true)
+	assert.Equal(t, strings.Contains(email.HTML, "Shere &lt;Khan&gt;"), true)
+	assert.Equal(t, strings.Contains(email.HTML, `src="cid:sighting-1@tigerhall-kittens"`), true)
+
+	// Embargoed positions are left out, along with their map link
+	withheld := sightingEmailData
+	withheld.LocationWithheld, withheld.MapURL = true, ""
+	email, err = store.Render("sighting_notification", "en", withheld)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, strings.Contains(email.Text, "Location: withheld for now"), true)
+	assert.Equal(t, strings.Contains(email.Text, "Latitude:"), false)
+	assert.Equal(t, strings.Contains(email.HTML, "view on map"), false)
+
+	assert.Equal(t, store.Supports("bn"), true)
+	assert.Equal(t, store.Supports("bn-IN"), true)
+	assert.Equal(t, store.Supports("fr"), false)
+	assert.Equal(t, store.Supports(""), false)
+	_, err = store.Render("missing", "en", sightingEmailData)
+	assert.Equal(t, err != nil, true)
+}
+
+func TestEmailTemplatesFromDirectory(t *testing.T) {
+	dir := t.TempDir()
+	write := func(file string, content string) {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, file)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A new language, and an English subject replacing the embedded one
+	write("ta/sighting_notification.subject.tmpl", "புலி: {{.TigerName}}")
+	write("ta/sighting_notification.txt.tmpl", "{{.TigerName}} {{.MapURL}}")
+	write("en/sighting_notification.subject.tmpl", "Spotted:\n{{.TigerName}}\n")
+	store, err := templates.NewStore(dir, nil, logrus.New())
+	assert.Equal(t, err, nil)
+
+	email, err := store.Render("sighting_notification", "ta", sightingEmailData)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, email.Subject, "புலி: Shere <Khan>")
+	assert.Equal(t, email.HTML, "")
+	email, err = store.Render("sighting_notification", "en", sightingEmailData)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, email.Subject, "Spotted: Shere <Khan>")
+	assert.Equal(t, email.HTML != "", true)
+	assert.Equal(t, store.Supports("ta"), true)
+
+	// A broken template is reported and the previous ones stay in use
+	write("ta/sighting_notification.txt.tmpl", "{{.TigerName")
+	assert.Equal(t, store.Reload() != nil, true)
+	email, err = store.Render("sighting_notification", "ta", sightingEmailData)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, email.Text, "Shere <Khan> https://www.openstreetmap.org/?mlat=11.66&mlon=76.62")
+
+	write("ta/sighting_notification.txt.tmpl", "{{.TigerName}}!")
+	assert.Equal(t, store.Reload(), nil)
+	email, _ = store.Render("sighting_notification", "ta", sightingEmailData)
+	assert.Equal(t, email.Text, "Shere <Khan>!")
+}
+
+// writeTemplate writes a template file below dir, creating the language directory.
+func writeTemplate(t *testing.T, dir string, file string, content string) {
+	if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, file)), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEmailTemplatesWatchReloadsChangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "ta/sighting_notification.subject.tmpl", "புலி: {{.TigerName}}")
+	writeTemplate(t, dir, "ta/sighting_notification.txt.tmpl", "{{.TigerName}}")
+	samples := templates.Samples{"sighting_notification": func() any { return sightingEmailData }}
+	store, err := templates.NewStore(dir, samples, logrus.New())
+	assert.Equal(t, err, nil)
+	store.Watch(10 * time.Millisecond)
+	defer store.Close()
+	text := func() string {
+		email, err := store.Render("sighting_notification", "ta", sightingEmailData)
+		assert.Equal(t, err, nil)
+		return email.Text
+	}
+
+	writeTemplate(t, dir, "ta/sighting_notification.txt.tmpl", "{{.TigerName}} was spotted")
+	deadline := time.Now().Add(2 * time.Second)
+	for text() != "Shere <Khan> was spotted" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, text(), "Shere <Khan> was spotted")
+
+	// Templates referring to data the email does not have, or languages without a subject, are rejected
+	writeTemplate(t, dir, "ta/sighting_notification.txt.tmpl", "{{.Tiger}} was spotted")
+	assert.Equal(t, strings.Contains(fmt.Sprint(store.Reload()), "Tiger"), true)
+	writeTemplate(t, dir, "ta/sighting_notification.txt.tmpl", "{{.TigerName}} was seen")
+	if err := os.Remove(filepath.Join(dir, "ta/sighting_notification.subject.tmpl")); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, strings.Contains(fmt.Sprint(store.Reload()), "no subject part in ta"), true)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, text(), "Shere <Khan> was spotted")
+}
+
+func TestValidateLanguage(t *testing.T) {
+	for input, want := range map[string]string{"en": "en", " hi ": "hi", "hi-IN": "hi-IN", "bn": "bn"} {
+		language, err := service.ValidateLanguage(input)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, language, want)
+	}
+	// Languages without emails, and tags that only look like a supported one
+	for _, input := range []string{"fr", "", "en-!!", "hi IN", "en/../../etc", strings.Repeat("en-a", 10)} {
+		_, err := service.ValidateLanguage(input)
+		assert.Equal(t, err, service.ErrUnsupportedLanguage, input)
+	}
+}
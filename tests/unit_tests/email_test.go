@@ -0,0 +1,88 @@
+package unittests
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"testing"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/messaging"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestComposePlainTextMessage(t *testing.T) {
+	from := mail.Address{Name: "Tigerhall-Kittens", Address: "sightings@example.com"}
+	date := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	message, err := messaging.ComposeMessage(from, "ranger@example.com", "Sichtung von Shere Khan\r\nBcc: x@example.com", "Tiger spotted", date)
+	assert.Equal(t, err, nil)
+
+	parsed, err := mail.ReadMessage(bytes.NewReader(message))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, parsed.Header.Get("From"), `"Tigerhall-Kittens" <sightings@example.com>`)
+	assert.Equal(t, parsed.Header.Get("To"), "<ranger@example.com>")
+	assert.Equal(t, parsed.Header.Get("MIME-Version"), "1.0")
+	assert.Equal(t, parsed.Header.Get("Bcc"), "")
+	assert.Matches(t, parsed.Header.Get("Message-ID"), "^<[0-9a-f]{32}@example.com>$")
+	sent, err := parsed.Header.Date()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, sent.Equal(date), true)
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, subject, "Sichtung von Shere KhanBcc: x@example.com")
+	body, _ := io.ReadAll(quotedprintable.NewReader(parsed.Body))
+	assert.Equal(t, string(body), "Tiger spotted")
+}
+
+func TestComposeHTMLMessageWithInlineImage(t *testing.T) {
+	from := mail.Address{Address: "sightings@example.com"}
+	image := bytes.Repeat([]byte{0xff, 0xd8, 0xff}, 100)
+	message, err := messaging.ComposeMessage(from, "ranger@example.com", "Sighting", "Tiger spotted", time.Now(),
+		messaging.WithHTML(`<img src="cid:sighting-1@example.com">`),
+		messaging.WithInlineImage("sighting-1@example.com", "image/jpeg", "sighting-1.jpg", image),
+		messaging.WithListUnsubscribe("https://example.com/unsubscribe"))
+	assert.Equal(t, err, nil)
+
+	parsed, err := mail.ReadMessage(bytes.NewReader(message))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, parsed.Header.Get("List-Unsubscribe"), "<https://example.com/unsubscribe>")
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, mediaType, "multipart/related")
+	related := multipart.NewReader(parsed.Body, params["boundary"])
+
+	// The alternative of plain text and HTML comes first
+	part, err := related.NextPart()
+	assert.Equal(t, err, nil)
+	mediaType, params, _ = mime.ParseMediaType(part.Header.Get("Content-Type"))
+	assert.Equal(t, mediaType, "multipart/alternative")
+	alternative := multipart.NewReader(part, params["boundary"])
+	var contents []string
+	for {
+		// NextPart decodes quoted-printable parts itself
+		alt, err := alternative.NextPart()
+		if err == io.EOF {
+			break
+		}
+		assert.Equal(t, err, nil)
+		content, _ := io.ReadAll(alt)
+		contents = append(contents, alt.Header.Get("Content-Type")+" "+string(content))
+	}
+	assert.Equal(t, contents, []string{
+		"text/plain; charset=utf-8 Tiger spotted",
+		`text/html; charset=utf-8 <img src="cid:sighting-1@example.com">`,
+	})
+
+	// followed by the image it references
+	part, err = related.NextPart()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, part.Header.Get("Content-ID"), "<sighting-1@example.com>")
+	assert.Equal(t, part.Header.Get("Content-Type"), "image/jpeg")
+	data, _ := io.ReadAll(part)
+	for _, line := range bytes.Split(bytes.TrimSpace(data), []byte("\r\n")) {
+		assert.Equal(t, len(line) <= 76, true)
+	}
+}
@@ -0,0 +1,93 @@
+package unittests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/keyring"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/magiconair/properties/assert"
+)
+
+func signAndVerify(t *testing.T, signer *keyring.KeyRing, verifier *keyring.KeyRing) error {
+	claims := jwt.RegisteredClaims{Subject: "ranger", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))}
+	token, err := signer.Sign(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = jwt.ParseWithClaims(token, &jwt.RegisteredClaims{}, verifier.Keyfunc)
+	return err
+}
+
+func TestKeyRingSignAndVerify(t *testing.T) {
+	for _, algorithm := range []string{keyring.ES256, keyring.RS256} {
+		key, err := keyring.GenerateKey("", algorithm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		kr, err := keyring.New(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, signAndVerify(t, kr, kr), nil)
+		assert.Equal(t, kr.SigningKeyID(), key.JWK().Thumbprint())
+	}
+}
+
+func TestKeyRingRotation(t *testing.T) {
+	oldKey, _ := keyring.GenerateKey("old", keyring.ES256)
+	newKey, _ := keyring.GenerateKey("new", keyring.ES256)
+	kr, _ := keyring.New(oldKey)
+	oldRing, _ := keyring.New(oldKey)
+
+	if err := kr.Rotate(newKey); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, kr.SigningKeyID(), "new")
+	// Tokens issued before the rotation are still accepted
+	assert.Equal(t, signAndVerify(t, oldRing, kr), nil)
+
+	if err := kr.Retire("old"); err != nil {
+		t.Fatal(err)
+	}
+	if signAndVerify(t, oldRing, kr) == nil {
+		t.Fatal("token signed with a retired key was accepted")
+	}
+	if kr.Retire("new") == nil {
+		t.Fatal("active signing key was retired")
+	}
+}
+
+func TestKeyRingRejectsUnknownKey(t *testing.T) {
+	key, _ := keyring.GenerateKey("a", keyring.ES256)
+	other, _ := keyring.GenerateKey("b", keyring.ES256)
+	kr, _ := keyring.New(key)
+	otherRing, _ := keyring.New(other)
+	if signAndVerify(t, otherRing, kr) == nil {
+		t.Fatal("token signed with an unknown key was accepted")
+	}
+}
+
+func TestKeyRingJWKS(t *testing.T) {
+	hmac := keyring.NewHMACKey("shared", []byte("0123456789abcdef0123456789abcdef"))
+	ec, _ := keyring.GenerateKey("ec", keyring.ES256)
+	rsa, _ := keyring.GenerateKey("rsa", keyring.RS256)
+	kr, err := keyring.New(hmac, ec, rsa)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwks := kr.JWKS()
+	// Symmetric keys must never be published
+	assert.Equal(t, len(jwks.Keys), 2)
+	_, found := jwks.Lookup("shared")
+	assert.Equal(t, found, false)
+
+	// Published keys convert back into keys able to verify our tokens
+	ecRing, _ := keyring.New(ec)
+	claims := jwt.RegisteredClaims{Subject: "ranger"}
+	token, _ := ecRing.Sign(claims)
+	jwk, found := jwks.Lookup("ec")
+	assert.Equal(t, found, true)
+	_, err = jwt.Parse(token, func(*jwt.Token) (interface{}, error) { return jwk.PublicKey() })
+	assert.Equal(t, err, nil)
+}
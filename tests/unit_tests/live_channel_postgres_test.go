@@ -0,0 +1,58 @@
+package unittests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/service"
+	"github.com/gorilla/websocket"
+	"github.com/magiconair/properties/assert"
+)
+
+// openLiveChannel connects to the live channel with the access token and returns the ready message.
+func openLiveChannel(t *testing.T, token string) (*websocket.Conn, models.LiveServerMessage) {
+	server := httptest.NewServer(service.AuthMiddleware(service.LiveChannel))
+	t.Cleanup(server.Close)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), http.Header{"Authorization": {"Bearer " + token}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, readLiveMessage(t, conn)
+}
+
+func readLiveMessage(t *testing.T, conn *websocket.Conn) models.LiveServerMessage {
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var message models.LiveServerMessage
+	if err := conn.ReadJSON(&message); err != nil {
+		t.Fatal(err)
+	}
+	return message
+}
+
+func TestLiveChannelReauthentication(t *testing.T) {
+	db := serviceDB(t)
+	user := createUser(t, db)
+	other := createUser(t, db)
+	conn, ready := openLiveChannel(t, login(t, db, user.Username).Token)
+	assert.Equal(t, ready.Type, models.LiveReady)
+	assert.Equal(t, ready.ExpiresAt != nil, true)
+
+	for _, token := range []string{"not-a-token", login(t, db, other.Username).Token} {
+		if err := conn.WriteJSON(models.LiveClientMessage{Type: models.LiveAuth, Token: token}); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, readLiveMessage(t, conn).Type, models.LiveError)
+	}
+
+	if err := conn.WriteJSON(models.LiveClientMessage{Type: models.LiveAuth, Token: login(t, db, user.Username).Token}); err != nil {
+		t.Fatal(err)
+	}
+	authenticated := readLiveMessage(t, conn)
+	assert.Equal(t, authenticated.Type, models.LiveAuthenticated)
+	assert.Equal(t, authenticated.ExpiresAt != nil, true)
+}
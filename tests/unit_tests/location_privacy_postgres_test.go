@@ -0,0 +1,46 @@
+package unittests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/service"
+	"github.com/magiconair/properties/assert"
+	"github.com/sirupsen/logrus"
+)
+
+func TestSightingListingsPerLocationAccess(t *testing.T) {
+	db := serviceDB(t)
+	reporter := createUser(t, db)
+	reporterToken := login(t, db, reporter.Username).Token
+	rangerToken := login(t, db, createUser(t, db, models.RoleRanger).Username).Token
+	tigerID, ids := createSightings(t, db, reporter, time.Now().Add(-240*time.Hour), time.Now().Add(-time.Hour))
+	sightings := service.NewSightingService(db, logrus.New())
+	for _, sightingID := range ids {
+		moderate(t, sightings.ReviewSighting, sightingID, rangerToken, verifySighting)
+	}
+
+	// Anonymous callers and reporters list first, the exact listing must not be served from their cache entry
+	for _, token := range []string{"", reporterToken} {
+		code, listed := listSightings(t, db, tigerID, "", token)
+		assert.Equal(t, code, 200)
+		assert.Equal(t, listed.LocationPrecision, "coarse")
+		assert.Equal(t, sightingIDs(listed.Sightings), []int{ids[0]})
+		assert.Equal(t, listed.Sightings[0].LastCoordinates.Latitude, 11.25)
+		assert.Equal(t, listed.Sightings[0].LastCoordinates.Longitude, 77.65)
+	}
+
+	code, listed := listSightings(t, db, tigerID, "", rangerToken)
+	assert.Equal(t, code, 200)
+	assert.Equal(t, listed.LocationPrecision, "exact")
+	assert.Equal(t, sightingIDs(listed.Sightings), []int{ids[1], ids[0]})
+	assert.Equal(t, listed.Sightings[0].LastCoordinates.Latitude, 11.23456)
+	assert.Equal(t, listed.Sightings[0].LastCoordinates.Longitude, 77.65432)
+
+	// Nor the other way round
+	code, listed = listSightings(t, db, tigerID, "", "")
+	assert.Equal(t, code, 200)
+	assert.Equal(t, sightingIDs(listed.Sightings), []int{ids[0]})
+	assert.Equal(t, listed.Sightings[0].LastCoordinates.Latitude, 11.25)
+}
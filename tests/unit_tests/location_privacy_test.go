@@ -0,0 +1,81 @@
+package unittests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/service"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestSnapToGrid(t *testing.T) {
+	lat, lon := service.SnapToGrid(12.9716, 77.5946, 0.1)
+	assert.Equal(t, lat, 12.95)
+	assert.Equal(t, lon, 77.55)
+
+	// Points in the same cell end up on the same coordinates
+	otherLat, otherLon := service.SnapToGrid(12.9001, 77.5999, 0.1)
+	assert.Equal(t, otherLat, lat)
+	assert.Equal(t, otherLon, lon)
+
+	// Negative coordinates snap to the cell below them
+	lat, lon = service.SnapToGrid(-33.8688, -151.2093, 0.5)
+	assert.Equal(t, lat, -33.75)
+	assert.Equal(t, lon, -151.25)
+}
+
+func TestSnapToGridWithoutGrid(t *testing.T) {
+	lat, lon := service.SnapToGrid(12.9716, 77.5946, 0)
+	assert.Equal(t, lat, 12.9716)
+	assert.Equal(t, lon, 77.5946)
+}
+
+func TestObfuscateSightings(t *testing.T) {
+	sightings := make([]models.Sighting, 1)
+	sightings[0].LastCoordinates.Latitude, sightings[0].LastCoordinates.Longitude = 11.23456, 77.65432
+
+	coarse := service.NewLocationPrivacy(service.LocationCoarse)
+	assert.Equal(t, coarse.Precision(), "coarse")
+	obfuscated := coarse.ObfuscateSightings(sightings)
+	assert.Equal(t, obfuscated[0].LastCoordinates.Latitude, 11.25)
+	assert.Equal(t, obfuscated[0].LastCoordinates.Longitude, 77.65)
+	// Listings are cached for every caller, the exact coordinates must survive
+	assert.Equal(t, sightings[0].LastCoordinates.Latitude, 11.23456)
+
+	exact := service.NewLocationPrivacy(service.LocationExact)
+	assert.Equal(t, exact.Precision(), "exact")
+	obfuscated = exact.ObfuscateSightings(sightings)
+	assert.Equal(t, obfuscated[0].LastCoordinates.Latitude, 11.23456)
+	assert.Equal(t, obfuscated[0].LastCoordinates.Longitude, 77.65432)
+}
+
+func TestObfuscateTigersWithholdsPositionsUnderEmbargo(t *testing.T) {
+	tigers := make([]models.Tiger, 2)
+	tigers[0].LastSeenAt.Time = time.Now().Add(-time.Hour)
+	tigers[1].LastSeenAt.Time = time.Now().Add(-240 * time.Hour)
+	for i := range tigers {
+		tigers[i].LastCoordinates.Latitude, tigers[i].LastCoordinates.Longitude = 11.23456, 77.65432
+	}
+
+	coarse := service.NewLocationPrivacy(service.LocationCoarse)
+	assert.Equal(t, coarse.Embargo(), 72*time.Hour)
+	obfuscated := coarse.ObfuscateTigers(tigers)
+	assert.Equal(t, obfuscated[0].LocationWithheld, true)
+	assert.Equal(t, obfuscated[0].LastCoordinates.Latitude, 0.0)
+	assert.Equal(t, obfuscated[0].LastCoordinates.Longitude, 0.0)
+	assert.Equal(t, obfuscated[1].LocationWithheld, false)
+	assert.Equal(t, obfuscated[1].LastCoordinates.Latitude, 11.25)
+	assert.Equal(t, obfuscated[1].LastCoordinates.Longitude, 77.65)
+	assert.Equal(t, tigers[0].LastCoordinates.Latitude, 11.23456)
+
+	// Callers with locations:exact have no embargo
+	exact := service.NewLocationPrivacy(service.LocationExact)
+	assert.Equal(t, exact.Embargo(), time.Duration(0))
+	obfuscated = exact.ObfuscateTigers(tigers)
+	for _, tiger := range obfuscated {
+		assert.Equal(t, tiger.LocationWithheld, false)
+		assert.Equal(t, tiger.LastCoordinates.Latitude, 11.23456)
+		assert.Equal(t, tiger.LastCoordinates.Longitude, 77.65432)
+	}
+}
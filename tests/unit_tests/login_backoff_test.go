@@ -0,0 +1,29 @@
+package unittests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/service"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestLoginBackoff(t *testing.T) {
+	backoff := func(failures int) time.Duration {
+		return service.LoginBackoff(failures, 3, 8, time.Second, 15*time.Minute)
+	}
+	// The first failures are not delayed
+	assert.Equal(t, backoff(1), time.Duration(0))
+	assert.Equal(t, backoff(2), time.Duration(0))
+	// From then on the delay doubles
+	assert.Equal(t, backoff(3), time.Second)
+	assert.Equal(t, backoff(4), 2*time.Second)
+	assert.Equal(t, backoff(7), 16*time.Second)
+	// Reaching the threshold locks
+	assert.Equal(t, backoff(8), 15*time.Minute)
+	assert.Equal(t, backoff(20), 15*time.Minute)
+}
+
+func TestLoginBackoffNeverExceedsLockout(t *testing.T) {
+	assert.Equal(t, service.LoginBackoff(30, 1, 100, time.Second, time.Minute), time.Minute)
+}
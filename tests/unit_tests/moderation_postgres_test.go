@@ -0,0 +1,256 @@
+package unittests
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database/repositories"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/service"
+	"github.com/gorilla/mux"
+	"github.com/magiconair/properties/assert"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testPassword = "correct horse battery staple"
+
+// serviceDB returns the test database and points the services at it, so the tokens issued by login are
+// accepted. They are signed with an ephemeral key. Failed logins of earlier runs are forgotten.
+func serviceDB(t *testing.T) *sql.DB {
+	db := testDB(t)
+	if _, err := db.Exec("DELETE FROM tigerhall.login_throttles"); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("JWT_ALLOW_EPHEMERAL_KEY", "true")
+	if err := service.InitSigningKeys(); err != nil {
+		t.Fatal(err)
+	}
+	database.SetDB(db)
+	t.Cleanup(func() { database.SetDB(nil) })
+	return db
+}
+
+// createUser registers a user with testPassword holding the given roles besides reporter.
+func createUser(t *testing.T, db *sql.DB, roles ...string) *models.User {
+	hash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	username := fmt.Sprintf("user%d", time.Now().UnixNano())
+	userRepo := repositories.NewUserRepository(db, logrus.New())
+	if err := userRepo.CreateUser(&models.User{Username: username, Password: string(hash), Email: username + "@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, role := range roles {
+		if _, err := userRepo.GrantRole(username, role); err != nil {
+			t.Fatal(err)
+		}
+	}
+	user, err := userRepo.GetUserByUserName(username)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// login logs the user in with testPassword and returns the tokens of the new session.
+func login(t *testing.T, db *sql.DB, username string) models.LoginResponse {
+	body, _ := json.Marshal(models.Credentials{Username: username, Password: testPassword})
+	rec := httptest.NewRecorder()
+	service.NewUserService(logrus.New(), db).Login(context.Background(), rec, httptest.NewRequest("POST", "/api/v1/login", bytes.NewReader(body)))
+	assert.Equal(t, rec.Code, 200)
+	var response models.LoginResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+// createSightings stores a tiger and one pending sighting of it, reported by the user, for each of the given times.
+func createSightings(t *testing.T, db *sql.DB, reporter *models.User, seenAt ...time.Time) (int, []int) {
+	tiger := &models.Tiger{Name: fmt.Sprintf("tiger%d", time.Now().UnixNano())}
+	tiger.DateOfBirth.Time = time.Date(2015, 3, 1, 0, 0, 0, 0, time.UTC)
+	tiger.LastSeenAt.Time = seenAt[0]
+	tiger.LastCoordinates.Latitude, tiger.LastCoordinates.Longitude = 11.23456, 77.65432
+	tigerID, err := repositories.NewTigerRepository(db, logrus.New()).CreateTiger(tiger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sightingRepo := repositories.NewSightingRepository(db, logrus.New())
+	ids := []int{}
+	for _, at := range seenAt {
+		sighting := &models.Sighting{TigerID: tigerID, User: reporter}
+		sighting.Timestamp.Time = at
+		sighting.LastCoordinates.Latitude, sighting.LastCoordinates.Longitude = 11.23456, 77.65432
+		sightingID, err := sightingRepo.CreateSight(sighting)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, sightingID)
+	}
+	return tigerID, ids
+}
+
+// moderate sends a review or dispute of the sighting with the access token and returns the status code and
+// the sighting as answered.
+func moderate(t *testing.T, handle func(context.Context, http.ResponseWriter, *http.Request), sightingID int, token string, body any) (int, models.Sighting) {
+	payload, _ := json.Marshal(body)
+	req := httptest.NewRequest("POST", "/api/v1/sightings/"+strconv.Itoa(sightingID), bytes.NewReader(payload))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req = mux.SetURLVars(req, map[string]string{"id": strconv.Itoa(sightingID)})
+	rec := httptest.NewRecorder()
+	handle(context.Background(), rec, req)
+	var sighting models.Sighting
+	if rec.Code == 200 {
+		if err := json.NewDecoder(rec.Body).Decode(&sighting); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return rec.Code, sighting
+}
+
+// listSightings lists the sightings of the tiger in the given state, anonymously when token is empty.
+func listSightings(t *testing.T, db *sql.DB, tigerID int, status string, token string) (int, models.SightingsResponse) {
+	req := httptest.NewRequest("GET", "/api/v1/tigers/"+strconv.Itoa(tigerID)+"/listSightings?pageSize=10&status="+status, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	req = mux.SetURLVars(req, map[string]string{"id": strconv.Itoa(tigerID)})
+	rec := httptest.NewRecorder()
+	service.NewSightingService(db, logrus.New()).ListAllSightings(context.Background(), rec, req)
+	var response models.SightingsResponse
+	if rec.Code == 200 {
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return rec.Code, response
+}
+
+func sightingIDs(sightings []models.Sighting) []int {
+	ids := []int{}
+	for _, sighting := range sightings {
+		ids = append(ids, sighting.ID)
+	}
+	return ids
+}
+
+// verificationMessages counts the notification fan-outs queued for the sighting.
+func verificationMessages(t *testing.T, db *sql.DB, sightingID int) int {
+	var count int
+	err := db.QueryRow(
+		"SELECT COUNT(*) FROM tigerhall.notification_outbox WHERE kind = $1 AND (payload->>'sighting_id')::int = $2",
+		models.OutboxSightingVerified, sightingID,
+	).Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+var (
+	verifySighting  = models.ReviewSightingRequest{Decision: models.SightingVerified}
+	rejectSighting  = models.ReviewSightingRequest{Decision: models.SightingRejected, Reason: "Blurry photo"}
+	disputeSighting = models.DisputeSightingRequest{Reason: "Same animal was reported twice"}
+)
+
+func TestModerationStatusTransitions(t *testing.T) {
+	db := serviceDB(t)
+	reporter := createUser(t, db)
+	reporterToken := login(t, db, reporter.Username).Token
+	rangerToken := login(t, db, createUser(t, db, models.RoleRanger).Username).Token
+	_, ids := createSightings(t, db, reporter, time.Now().Add(-240*time.Hour), time.Now().Add(-240*time.Hour))
+	sightings := service.NewSightingService(db, logrus.New())
+
+	code, sighting := moderate(t, sightings.ReviewSighting, ids[0], rangerToken, verifySighting)
+	assert.Equal(t, code, 200)
+	assert.Equal(t, sighting.Status, models.SightingVerified)
+	// Verified sightings go back to review by a dispute only
+	code, _ = moderate(t, sightings.ReviewSighting, ids[0], rangerToken, rejectSighting)
+	assert.Equal(t, code, 409)
+	code, sighting = moderate(t, sightings.DisputeSighting, ids[0], reporterToken, disputeSighting)
+	assert.Equal(t, code, 200)
+	assert.Equal(t, sighting.Status, models.SightingDisputed)
+	code, _ = moderate(t, sightings.DisputeSighting, ids[0], reporterToken, disputeSighting)
+	assert.Equal(t, code, 409)
+	code, sighting = moderate(t, sightings.ReviewSighting, ids[0], rangerToken, rejectSighting)
+	assert.Equal(t, code, 200)
+	assert.Equal(t, sighting.Status, models.SightingRejected)
+	assert.Equal(t, sighting.ReviewReason, rejectSighting.Reason)
+
+	// Rejections are final
+	code, sighting = moderate(t, sightings.ReviewSighting, ids[1], rangerToken, rejectSighting)
+	assert.Equal(t, code, 200)
+	assert.Equal(t, sighting.Status, models.SightingRejected)
+	code, _ = moderate(t, sightings.ReviewSighting, ids[1], rangerToken, verifySighting)
+	assert.Equal(t, code, 409)
+	code, _ = moderate(t, sightings.DisputeSighting, ids[1], reporterToken, disputeSighting)
+	assert.Equal(t, code, 409)
+
+	code, _ = moderate(t, sightings.ReviewSighting, 0, rangerToken, verifySighting)
+	assert.Equal(t, code, 404)
+	code, _ = moderate(t, sightings.ReviewSighting, ids[1], rangerToken, models.ReviewSightingRequest{Decision: models.SightingRejected})
+	assert.Equal(t, code, 400)
+}
+
+func TestPublicListingShowsVerifiedSightingsOnly(t *testing.T) {
+	db := serviceDB(t)
+	reporter := createUser(t, db)
+	reporterToken := login(t, db, reporter.Username).Token
+	rangerToken := login(t, db, createUser(t, db, models.RoleRanger).Username).Token
+	seenAt := time.Now().Add(-240 * time.Hour)
+	tigerID, ids := createSightings(t, db, reporter, seenAt, seenAt, seenAt, seenAt)
+	sightings := service.NewSightingService(db, logrus.New())
+	// ids[0] stays pending
+	moderate(t, sightings.ReviewSighting, ids[1], rangerToken, verifySighting)
+	moderate(t, sightings.ReviewSighting, ids[2], rangerToken, rejectSighting)
+	moderate(t, sightings.ReviewSighting, ids[3], rangerToken, verifySighting)
+	moderate(t, sightings.DisputeSighting, ids[3], reporterToken, disputeSighting)
+
+	code, listed := listSightings(t, db, tigerID, "", "")
+	assert.Equal(t, code, 200)
+	assert.Equal(t, sightingIDs(listed.Sightings), []int{ids[1]})
+	code, listed = listSightings(t, db, tigerID, "", reporterToken)
+	assert.Equal(t, code, 200)
+	assert.Equal(t, sightingIDs(listed.Sightings), []int{ids[1]})
+
+	// Only reviewers see the other states
+	code, _ = listSightings(t, db, tigerID, models.SightingPending, "")
+	assert.Equal(t, code, 403)
+	code, _ = listSightings(t, db, tigerID, models.SightingPending, reporterToken)
+	assert.Equal(t, code, 403)
+	code, listed = listSightings(t, db, tigerID, models.SightingPending, rangerToken)
+	assert.Equal(t, code, 200)
+	assert.Equal(t, sightingIDs(listed.Sightings), []int{ids[0]})
+}
+
+func TestModerationFansOutOnVerificationOnly(t *testing.T) {
+	db := serviceDB(t)
+	reporter := createUser(t, db)
+	reporterToken := login(t, db, reporter.Username).Token
+	rangerToken := login(t, db, createUser(t, db, models.RoleRanger).Username).Token
+	_, ids := createSightings(t, db, reporter, time.Now().Add(-time.Hour), time.Now().Add(-time.Hour))
+	sightings := service.NewSightingService(db, logrus.New())
+	assert.Equal(t, verificationMessages(t, db, ids[0]), 0)
+
+	moderate(t, sightings.ReviewSighting, ids[0], rangerToken, verifySighting)
+	assert.Equal(t, verificationMessages(t, db, ids[0]), 1)
+	moderate(t, sightings.ReviewSighting, ids[1], rangerToken, rejectSighting)
+	assert.Equal(t, verificationMessages(t, db, ids[1]), 0)
+
+	// Subscribers heard of the sighting already, verifying it again after a dispute must not notify twice
+	moderate(t, sightings.DisputeSighting, ids[0], reporterToken, disputeSighting)
+	code, _ := moderate(t, sightings.ReviewSighting, ids[0], rangerToken, verifySighting)
+	assert.Equal(t, code, 200)
+	assert.Equal(t, verificationMessages(t, db, ids[0]), 1)
+}
@@ -0,0 +1,90 @@
+package unittests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	"github.com/magiconair/properties/assert"
+)
+
+func hour(h int) *int {
+	return &h
+}
+
+func TestQuietHoursWrapPastMidnight(t *testing.T) {
+	preferences := models.DefaultNotificationPreferences()
+	preferences.QuietHoursStart, preferences.QuietHoursEnd = hour(22), hour(7)
+	preferences.Timezone = "Asia/Kolkata"
+
+	// 17:00 UTC is 22:30 in Kolkata
+	assert.Equal(t, preferences.QuietAt(time.Date(2024, 3, 1, 17, 0, 0, 0, time.UTC)), true)
+	// 01:00 UTC is 06:30 in Kolkata
+	assert.Equal(t, preferences.QuietAt(time.Date(2024, 3, 1, 1, 0, 0, 0, time.UTC)), true)
+	// 02:00 UTC is 07:30 in Kolkata
+	assert.Equal(t, preferences.QuietAt(time.Date(2024, 3, 1, 2, 0, 0, 0, time.UTC)), false)
+}
+
+func TestQuietHoursDeferUntilTheyEnd(t *testing.T) {
+	preferences := models.DefaultNotificationPreferences()
+	preferences.QuietHoursStart, preferences.QuietHoursEnd = hour(22), hour(7)
+	preferences.Timezone = "Asia/Kolkata"
+	kolkata, _ := time.LoadLocation("Asia/Kolkata")
+
+	// 17:00 UTC is 22:30 in Kolkata, notifications wait for 07:00 the next morning
+	until := preferences.QuietUntil(time.Date(2024, 3, 1, 17, 0, 0, 0, time.UTC))
+	assert.Equal(t, until.Equal(time.Date(2024, 3, 2, 7, 0, 0, 0, kolkata)), true)
+	// 01:00 UTC is 06:30 in Kolkata, half an hour before the quiet hours end
+	until = preferences.QuietUntil(time.Date(2024, 3, 1, 1, 0, 0, 0, time.UTC))
+	assert.Equal(t, until.Equal(time.Date(2024, 3, 1, 7, 0, 0, 0, kolkata)), true)
+	// Outside the quiet hours nothing is deferred
+	assert.Equal(t, preferences.QuietUntil(time.Date(2024, 3, 1, 2, 0, 0, 0, time.UTC)).IsZero(), true)
+}
+
+func TestValidateNotificationPreferences(t *testing.T) {
+	preferences := models.DefaultNotificationPreferences()
+	assert.Equal(t, preferences.Validate(), nil)
+
+	preferences.Frequency = "hourly"
+	assert.Equal(t, preferences.Validate() != nil, true)
+
+	preferences = models.DefaultNotificationPreferences()
+	preferences.QuietHoursStart = hour(22)
+	assert.Equal(t, preferences.Validate() != nil, true)
+
+	preferences = models.DefaultNotificationPreferences()
+	preferences.Channels = []string{"carrier-pigeon"}
+	assert.Equal(t, preferences.Validate() != nil, true)
+}
+
+func TestDigestScheduledAt(t *testing.T) {
+	preferences := models.DefaultNotificationPreferences()
+	preferences.Frequency = models.FrequencyWeekly
+	preferences.Timezone = "Asia/Kolkata"
+	kolkata, _ := time.LoadLocation("Asia/Kolkata")
+
+	// Friday 1 March 2024, the last weekly digest was due on Monday 26 February at 08:00
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, preferences.DigestScheduledAt(now).Equal(time.Date(2024, 2, 26, 8, 0, 0, 0, kolkata)), true)
+
+	// 02:00 UTC is 07:30 in Kolkata, before today's daily digest
+	preferences.Frequency = models.FrequencyDaily
+	now = time.Date(2024, 3, 1, 2, 0, 0, 0, time.UTC)
+	assert.Equal(t, preferences.DigestScheduledAt(now).Equal(time.Date(2024, 2, 29, 8, 0, 0, 0, kolkata)), true)
+}
+
+func TestGroupDigestItems(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 0, 0, 0, 0, time.UTC) }
+	tigers := models.GroupDigestItems([]models.DigestItem{
+		{SightingID: 1, TigerID: 1, TigerName: "Sher Khan", Timestamp: day(1), HasImage: true},
+		{SightingID: 2, TigerID: 2, TigerName: "Rajah", Timestamp: day(2), HasImage: true},
+		{SightingID: 3, TigerID: 1, TigerName: "Sher Khan", Timestamp: day(3)},
+	})
+
+	assert.Equal(t, len(tigers), 2)
+	assert.Equal(t, tigers[0].TigerName, "Sher Khan")
+	assert.Equal(t, tigers[0].Sightings, 2)
+	assert.Equal(t, tigers[0].Latest.SightingID, 3)
+	assert.Equal(t, tigers[0].ThumbnailSightingID, 1)
+	assert.Equal(t, tigers[1].Sightings, 1)
+}
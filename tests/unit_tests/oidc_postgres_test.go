@@ -0,0 +1,103 @@
+package unittests
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database/repositories"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/service"
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/oidc/oidctest"
+	"github.com/gorilla/mux"
+	"github.com/magiconair/properties/assert"
+	"github.com/sirupsen/logrus"
+)
+
+// createAPIKey creates a key with the scopes for the user logged in with the access token.
+func createAPIKey(t *testing.T, db *sql.DB, token string, scopes ...string) string {
+	body, _ := json.Marshal(models.CreateAPIKeyRequest{Name: "integration", Scopes: scopes})
+	req := httptest.NewRequest("POST", "/api/v1/api-keys", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	keys := service.NewAPIKeyService(logrus.New(), db)
+	service.LoginRequired(func(w http.ResponseWriter, r *http.Request) {
+		keys.CreateAPIKey(r.Context(), w, r)
+	})(rec, req)
+	assert.Equal(t, rec.Code, 201)
+	var response models.CreateAPIKeyResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	return response.Key
+}
+
+// oidcLogin logs in at the partner provider, starting the login with the header set unless it is empty, and
+// returns the answer of the callback.
+func oidcLogin(t *testing.T, users *service.UserService, header string, value string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/api/v1/oidc/partner/login", nil)
+	if header != "" {
+		req.Header.Set(header, value)
+	}
+	req = mux.SetURLVars(req, map[string]string{"provider": "partner"})
+	rec := httptest.NewRecorder()
+	users.OIDCLogin(context.Background(), rec, req)
+	assert.Equal(t, rec.Code, http.StatusFound)
+	stateCookies := rec.Result().Cookies()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req = httptest.NewRequest("GET", callback.RequestURI(), nil)
+	for _, cookie := range stateCookies {
+		req.AddCookie(cookie)
+	}
+	req = mux.SetURLVars(req, map[string]string{"provider": "partner"})
+	rec = httptest.NewRecorder()
+	users.OIDCCallback(context.Background(), rec, req)
+	return rec
+}
+
+func TestOIDCLoginWithAPIKeyDoesNotLinkIdentity(t *testing.T) {
+	db := serviceDB(t)
+	mock, provider := newMockOIDC(t)
+	service.RegisterOIDCProvider(provider)
+	users := service.NewUserService(logrus.New(), db)
+	identityRepo := repositories.NewIdentityRepository(db, logrus.New())
+	owner := createUser(t, db)
+	token := login(t, db, owner.Username).Token
+	apiKey := createAPIKey(t, db, token, models.PermissionTigersRead)
+
+	// Whoever holds the key logs in with their own account at the provider
+	subject := fmt.Sprintf("other%d", time.Now().UnixNano())
+	mock.SetUser(oidctest.User{Subject: subject, Email: subject + "@example.org", EmailVerified: true, PreferredUsername: subject})
+	rec := oidcLogin(t, users, "X-API-Key", apiKey)
+	assert.Equal(t, rec.Code, 200)
+	user, err := identityRepo.GetUserByIdentity("partner", subject)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, user.ID != owner.ID, true)
+
+	// The owner logged in with a password links
+	subject = fmt.Sprintf("owner%d", time.Now().UnixNano())
+	mock.SetUser(oidctest.User{Subject: subject, Email: subject + "@example.org", EmailVerified: true, PreferredUsername: subject})
+	rec = oidcLogin(t, users, "Authorization", "Bearer "+token)
+	assert.Equal(t, rec.Code, 200)
+	user, err = identityRepo.GetUserByIdentity("partner", subject)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, user.ID, owner.ID)
+}
@@ -0,0 +1,113 @@
+package unittests
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/service"
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/oidc"
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/oidc/oidctest"
+	"github.com/magiconair/properties/assert"
+)
+
+const oidcRedirectURL = "http://localhost:8888/api/v1/oidc/partner/callback"
+
+// authorize follows the flow up to the redirect back to us and returns the code and state.
+func authorize(t *testing.T, provider *oidc.Provider, nonce string, codeVerifier string) (string, string) {
+	authURL, err := provider.AuthCodeURL(context.Background(), "state-1", nonce, codeVerifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, resp.StatusCode, http.StatusFound)
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return location.Query().Get("code"), location.Query().Get("state")
+}
+
+func newMockOIDC(t *testing.T) (*oidctest.Provider, *oidc.Provider) {
+	mock, err := oidctest.NewProvider("tigerhall", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(mock.Close)
+	return mock, oidc.NewProvider(mock.Config("partner", oidcRedirectURL), nil)
+}
+
+func TestOIDCAuthorizationCodeFlow(t *testing.T) {
+	mock, provider := newMockOIDC(t)
+	mock.SetUser(oidctest.User{Subject: "42", Email: "ranger@ngo.example", EmailVerified: true, PreferredUsername: "ranger"})
+	verifier, _ := oidc.NewCodeVerifier()
+
+	code, state := authorize(t, provider, "nonce-1", verifier)
+	assert.Equal(t, state, "state-1")
+	claims, err := provider.Exchange(context.Background(), code, verifier, "nonce-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, claims.Subject, "42")
+	assert.Equal(t, claims.Email, "ranger@ngo.example")
+	assert.Equal(t, claims.EmailVerified, true)
+
+	// Codes can only be redeemed once
+	_, err = provider.Exchange(context.Background(), code, verifier, "nonce-1")
+	if err == nil {
+		t.Fatal("authorization code was redeemed twice")
+	}
+}
+
+func TestOIDCRejectsWrongCodeVerifier(t *testing.T) {
+	_, provider := newMockOIDC(t)
+	verifier, _ := oidc.NewCodeVerifier()
+	otherVerifier, _ := oidc.NewCodeVerifier()
+
+	code, _ := authorize(t, provider, "nonce-1", verifier)
+	if _, err := provider.Exchange(context.Background(), code, otherVerifier, "nonce-1"); err == nil {
+		t.Fatal("code was redeemed with the wrong PKCE verifier")
+	}
+}
+
+func TestOIDCRejectsNonceMismatch(t *testing.T) {
+	_, provider := newMockOIDC(t)
+	verifier, _ := oidc.NewCodeVerifier()
+
+	code, _ := authorize(t, provider, "nonce-1", verifier)
+	if _, err := provider.Exchange(context.Background(), code, verifier, "nonce-2"); err == nil {
+		t.Fatal("id token with a foreign nonce was accepted")
+	}
+}
+
+func TestOIDCRejectsOtherClient(t *testing.T) {
+	mock, _ := newMockOIDC(t)
+	config := mock.Config("partner", oidcRedirectURL)
+	config.ClientSecret = "wrong"
+	provider := oidc.NewProvider(config, nil)
+	verifier, _ := oidc.NewCodeVerifier()
+
+	code, _ := authorize(t, provider, "nonce-1", verifier)
+	if _, err := provider.Exchange(context.Background(), code, verifier, "nonce-1"); err == nil {
+		t.Fatal("code was redeemed with a wrong client secret")
+	}
+}
+
+func TestOIDCAutoLinksVerifiedEmailsOnly(t *testing.T) {
+	claims := &oidc.IDTokenClaims{Email: "Ranger@NGO.example", EmailVerified: true}
+	assert.Equal(t, service.CanAutoLinkIdentity(claims, &models.User{Email: "ranger@ngo.example", EmailVerified: true}), true)
+
+	// Someone may have registered the address without owning it
+	assert.Equal(t, service.CanAutoLinkIdentity(claims, &models.User{Email: "ranger@ngo.example"}), false)
+
+	unverified := *claims
+	unverified.EmailVerified = false
+	assert.Equal(t, service.CanAutoLinkIdentity(&unverified, &models.User{Email: "ranger@ngo.example", EmailVerified: true}), false)
+}
@@ -0,0 +1,161 @@
+package unittests
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database/repositories"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/service"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/magiconair/properties/assert"
+	"github.com/sirupsen/logrus"
+)
+
+// testDB opens the database TEST_POSTGRES_DSN points to, with the migrations applied and an empty outbox. The
+// tests using it are skipped when it is not set, it must name a database used for nothing else.
+func testDB(t *testing.T) *sql.DB {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	driver, err := postgres.WithInstance(db, &postgres.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := migrate.NewWithDatabaseInstance("file://../../internal/app/database/migrations", "postgres", driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("DELETE FROM tigerhall.notification_outbox"); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// insertOutboxMessage queues a message of the given kind, due right away.
+func insertOutboxMessage(t *testing.T, db *sql.DB, kind string) int {
+	var messageID int
+	if err := db.QueryRow("INSERT INTO tigerhall.notification_outbox (kind, payload) VALUES ($1, '{}') RETURNING message_id", kind).Scan(&messageID); err != nil {
+		t.Fatal(err)
+	}
+	return messageID
+}
+
+// messageIDs lists the ids of the messages in ascending order, claims return them in no particular order.
+func messageIDs(messages []models.OutboxMessage) []int {
+	ids := []int{}
+	for _, message := range messages {
+		ids = append(ids, message.ID)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func TestOutboxClaimSkipsLockedAndLeasedMessages(t *testing.T) {
+	db := testDB(t)
+	outboxRepo := repositories.NewOutboxRepository(db, logrus.New())
+	first := insertOutboxMessage(t, db, models.OutboxEmail)
+	second := insertOutboxMessage(t, db, models.OutboxEmail)
+	third := insertOutboxMessage(t, db, models.OutboxEmail)
+
+	// Another instance is in the middle of claiming the first message
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("SELECT 1 FROM tigerhall.notification_outbox WHERE message_id = $1 FOR UPDATE", first); err != nil {
+		t.Fatal(err)
+	}
+	claimed, err := outboxRepo.ClaimOutboxMessages(10, time.Minute)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, messageIDs(claimed), []int{second, third})
+	for _, message := range claimed {
+		assert.Equal(t, message.Attempts, 1)
+		assert.Equal(t, message.NextAttemptAt.After(time.Now().Add(50*time.Second)), true)
+	}
+
+	// Leased messages are left alone until the lease runs out, even once the lock is gone
+	assert.Equal(t, tx.Rollback(), nil)
+	claimed, err = outboxRepo.ClaimOutboxMessages(10, time.Minute)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, messageIDs(claimed), []int{first})
+
+	if _, err := db.Exec("UPDATE tigerhall.notification_outbox SET next_attempt_at = NOW() WHERE message_id = $1", second); err != nil {
+		t.Fatal(err)
+	}
+	claimed, err = outboxRepo.ClaimOutboxMessages(10, time.Minute)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, messageIDs(claimed), []int{second})
+	assert.Equal(t, claimed[0].Attempts, 2)
+}
+
+func TestOutboxDeadLettersAndReplays(t *testing.T) {
+	db := testDB(t)
+	t.Setenv("OUTBOX_MAX_ATTEMPTS", "2")
+	outboxRepo := repositories.NewOutboxRepository(db, logrus.New())
+	worker := service.NewOutboxWorker(db, logrus.New())
+	// Messages of an unknown kind fail on every attempt
+	messageID := insertOutboxMessage(t, db, "unknown")
+
+	worker.ProcessDue(context.Background())
+	pending, err := outboxRepo.ListOutboxMessages(models.OutboxPending, 10, 0)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, messageIDs(pending.Messages), []int{messageID})
+	assert.Equal(t, pending.Messages[0].LastError != "", true)
+
+	// The second failure uses up OUTBOX_MAX_ATTEMPTS
+	if _, err := db.Exec("UPDATE tigerhall.notification_outbox SET next_attempt_at = NOW() WHERE message_id = $1", messageID); err != nil {
+		t.Fatal(err)
+	}
+	worker.ProcessDue(context.Background())
+	dead, err := outboxRepo.ListOutboxMessages(models.OutboxDead, 10, 0)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, messageIDs(dead.Messages), []int{messageID})
+	assert.Equal(t, dead.Messages[0].Attempts, 2)
+
+	replayed, err := outboxRepo.ReplayOutboxMessage(messageID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, replayed.Status, models.OutboxPending)
+	assert.Equal(t, replayed.Attempts, 0)
+	// Only dead messages can be replayed
+	_, err = outboxRepo.ReplayOutboxMessage(messageID)
+	assert.Equal(t, err, repositories.ErrOutboxMessageNotFound)
+}
+
+func TestOutboxDeletesOldSentMessages(t *testing.T) {
+	db := testDB(t)
+	outboxRepo := repositories.NewOutboxRepository(db, logrus.New())
+	old := insertOutboxMessage(t, db, models.OutboxEmail)
+	recent := insertOutboxMessage(t, db, models.OutboxEmail)
+	dead := insertOutboxMessage(t, db, models.OutboxEmail)
+	_, err := db.Exec(`
+		UPDATE tigerhall.notification_outbox SET status = CASE WHEN message_id = $3 THEN 'dead' ELSE 'sent' END,
+			processed_at = CASE WHEN message_id = $2 THEN NOW() ELSE NOW() - INTERVAL '30 days' END
+		WHERE message_id IN ($1, $2, $3)`, old, recent, dead)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deleted, err := outboxRepo.DeleteSentOutboxMessages(14 * 24 * time.Hour)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, deleted, int64(1))
+	remaining, err := outboxRepo.ListOutboxMessages("", 10, 0)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, messageIDs(remaining.Messages), []int{recent, dead})
+}
@@ -0,0 +1,26 @@
+package unittests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/service"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestOutboxRetryDelay(t *testing.T) {
+	assert.Equal(t, service.OutboxRetryDelay(1, 30*time.Second), 30*time.Second)
+	assert.Equal(t, service.OutboxRetryDelay(2, 30*time.Second), time.Minute)
+	assert.Equal(t, service.OutboxRetryDelay(4, 30*time.Second), 4*time.Minute)
+
+	// The backoff is capped
+	assert.Equal(t, service.OutboxRetryDelay(30, 30*time.Second), 6*time.Hour)
+}
+
+func TestNotificationHealthWithoutDispatcher(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	service.NotificationHealth(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/health/notifications", nil))
+	assert.Equal(t, recorder.Code, http.StatusServiceUnavailable)
+}
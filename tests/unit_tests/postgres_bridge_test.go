@@ -0,0 +1,111 @@
+package unittests
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/messaging"
+	"github.com/magiconair/properties/assert"
+	"github.com/sirupsen/logrus"
+)
+
+type bridgedNote struct {
+	Text string `json:"text"`
+}
+
+var bridgedNotesTopic = messaging.NewTopic[bridgedNote]("bridged-notes")
+
+// bridgedInstances starts two bridges sharing a channel of their own, each with a PubSub standing in for an
+// instance of the service, and subscribes to bridgedNotesTopic on both.
+func bridgedInstances(t *testing.T, db *sql.DB) (*messaging.PubSub, *messaging.Subscription[bridgedNote], *messaging.Subscription[bridgedNote], string) {
+	channel := fmt.Sprintf("bridge_test_%d", time.Now().UnixNano())
+	opts := messaging.PostgresBridgeOptions{Channel: channel, PayloadTable: "tigerhall.event_payloads"}
+	var subs []*messaging.Subscription[bridgedNote]
+	var first *messaging.PubSub
+	for i := 0; i < 2; i++ {
+		ps := messaging.NewPubSub()
+		bridge, err := messaging.NewPostgresBridge(ps, db, os.Getenv("TEST_POSTGRES_DSN"), opts, logrus.New())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { bridge.Close() })
+		messaging.Bridge(bridge, bridgedNotesTopic)
+		subs = append(subs, messaging.Subscribe(ps, bridgedNotesTopic, messaging.SubscribeOptions{Capacity: 10, Overflow: messaging.Block}))
+		if first == nil {
+			first = ps
+		}
+	}
+	return first, subs[0], subs[1], channel
+}
+
+// nextNote waits a little for the next note, it returns false if none arrived.
+func nextNote(sub *messaging.Subscription[bridgedNote]) (bridgedNote, bool) {
+	select {
+	case note := <-sub.Messages():
+		return note, true
+	case <-time.After(2 * time.Second):
+		return bridgedNote{}, false
+	}
+}
+
+func TestPostgresBridgeForwardsToOtherInstancesOnly(t *testing.T) {
+	ps, local, remote, _ := bridgedInstances(t, testDB(t))
+	messaging.Publish(ps, bridgedNotesTopic, bridgedNote{Text: "hello"})
+
+	note, ok := nextNote(remote)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, note.Text, "hello")
+	// The publishing instance does not get its own message back from Postgres
+	note, ok = nextNote(local)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, note.Text, "hello")
+	_, ok = nextNote(local)
+	assert.Equal(t, ok, false)
+}
+
+func TestPostgresBridgeStoresLargeMessages(t *testing.T) {
+	db := testDB(t)
+	ps, _, remote, _ := bridgedInstances(t, db)
+	var before int
+	if err := db.QueryRow("SELECT COUNT(*) FROM tigerhall.event_payloads WHERE topic = $1", bridgedNotesTopic.Name()).Scan(&before); err != nil {
+		t.Fatal(err)
+	}
+	// Above the 7900 byte NOTIFY limit of the bridge
+	text := strings.Repeat("x", 10000)
+	messaging.Publish(ps, bridgedNotesTopic, bridgedNote{Text: text})
+
+	note, ok := nextNote(remote)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, note.Text, text)
+	var after int
+	if err := db.QueryRow("SELECT COUNT(*) FROM tigerhall.event_payloads WHERE topic = $1", bridgedNotesTopic.Name()).Scan(&after); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, after, before+1)
+}
+
+func TestPostgresBridgeDecodesEnvelopes(t *testing.T) {
+	db := testDB(t)
+	_, local, remote, channel := bridgedInstances(t, db)
+	for _, message := range []string{
+		"not json",
+		`{"origin":"elsewhere","topic":"unbridged","payload":{"text":"ignored"}}`,
+		`{"origin":"elsewhere","topic":"bridged-notes","payload":"not a note"}`,
+		`{"origin":"elsewhere","topic":"bridged-notes","payload":{"text":"from elsewhere"}}`,
+	} {
+		if _, err := db.Exec("SELECT pg_notify($1, $2)", channel, message); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Malformed messages and unknown topics are skipped, both instances get the valid message
+	for _, sub := range []*messaging.Subscription[bridgedNote]{local, remote} {
+		note, ok := nextNote(sub)
+		assert.Equal(t, ok, true)
+		assert.Equal(t, note.Text, "from elsewhere")
+	}
+}
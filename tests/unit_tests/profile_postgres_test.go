@@ -0,0 +1,159 @@
+package unittests
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database/repositories"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/service"
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/oidc/oidctest"
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/totp"
+	"github.com/magiconair/properties/assert"
+	"github.com/sirupsen/logrus"
+)
+
+// updateProfile sends the changes with the access token and returns the status code and the account as answered.
+func updateProfile(t *testing.T, users *service.UserService, token string, update models.UpdateProfileRequest) (int, models.Profile) {
+	body, _ := json.Marshal(update)
+	req := httptest.NewRequest("PATCH", "/api/v1/me", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	service.LoginRequired(func(w http.ResponseWriter, r *http.Request) {
+		users.UpdateProfile(r.Context(), w, r)
+	})(rec, req)
+	var profile models.Profile
+	if rec.Code == 200 {
+		if err := json.NewDecoder(rec.Body).Decode(&profile); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return rec.Code, profile
+}
+
+// authenticateAPIKey sends a request with the API key through AuthMiddleware and returns the status code.
+func authenticateAPIKey(key string) int {
+	req := httptest.NewRequest("GET", "/api/v1/me", nil)
+	req.Header.Set("X-API-Key", key)
+	rec := httptest.NewRecorder()
+	service.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})(rec, req)
+	return rec.Code
+}
+
+// createPasswordReset stores a reset token for the user as if it was mailed and returns its hash.
+func createPasswordReset(t *testing.T, resets *repositories.PasswordResetRepository, userID uint) string {
+	sum := sha256.Sum256([]byte(fmt.Sprint("reset", time.Now().UnixNano())))
+	tokenHash := hex.EncodeToString(sum[:])
+	if err := resets.CreatePasswordReset(userID, tokenHash, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	return tokenHash
+}
+
+func stringPointer(value string) *string {
+	return &value
+}
+
+func TestProfileEmailChange(t *testing.T) {
+	db := serviceDB(t)
+	users := service.NewUserService(logrus.New(), db)
+	resets := repositories.NewPasswordResetRepository(db, logrus.New())
+	user := createUser(t, db)
+	assert.Equal(t, repositories.NewUserRepository(db, logrus.New()).MarkEmailVerified(user.Username, user.Email), nil)
+	token := login(t, db, user.Username).Token
+	resetHash := createPasswordReset(t, resets, user.ID)
+	newEmail := "new." + user.Email
+
+	code, _ := updateProfile(t, users, token, models.UpdateProfileRequest{Email: &newEmail, CurrentPassword: "wrong"})
+	assert.Equal(t, code, 403)
+	code, profile := updateProfile(t, users, token, models.UpdateProfileRequest{Email: &newEmail, CurrentPassword: testPassword})
+	assert.Equal(t, code, 200)
+	assert.Equal(t, profile.Email, newEmail)
+	assert.Equal(t, profile.EmailVerified, false)
+
+	// A reset link mailed to the old address must not take the account back
+	_, err := resets.ResetPassword(resetHash, "hash")
+	assert.Equal(t, err, repositories.ErrPasswordResetTokenInvalid)
+}
+
+func TestProfilePasswordChange(t *testing.T) {
+	db := serviceDB(t)
+	users := service.NewUserService(logrus.New(), db)
+	sessions := service.NewSessionService(logrus.New(), db)
+	user := createUser(t, db)
+	current := login(t, db, user.Username)
+	other := login(t, db, user.Username)
+	apiKey := createAPIKey(t, db, current.Token, models.PermissionTigersRead)
+	resetHash := createPasswordReset(t, repositories.NewPasswordResetRepository(db, logrus.New()), user.ID)
+	assert.Equal(t, authenticateAPIKey(apiKey), 204)
+
+	code, _ := updateProfile(t, users, current.Token, models.UpdateProfileRequest{Password: stringPointer("new password"), CurrentPassword: testPassword})
+	assert.Equal(t, code, 200)
+
+	// Whoever knew the old password is logged out, the session making the change is not
+	assert.Equal(t, authenticate(current.Token), 204)
+	code, _ = refresh(t, sessions, current.RefreshToken)
+	assert.Equal(t, code, 200)
+	assert.Equal(t, authenticate(other.Token), 401)
+	code, _ = refresh(t, sessions, other.RefreshToken)
+	assert.Equal(t, code, 401)
+	assert.Equal(t, authenticateAPIKey(apiKey), 401)
+	_, err := repositories.NewPasswordResetRepository(db, logrus.New()).ResetPassword(resetHash, "hash")
+	assert.Equal(t, err, repositories.ErrPasswordResetTokenInvalid)
+}
+
+func TestProfileChangesWithoutPassword(t *testing.T) {
+	db := serviceDB(t)
+	mock, provider := newMockOIDC(t)
+	service.RegisterOIDCProvider(provider)
+	users := service.NewUserService(logrus.New(), db)
+
+	// Accounts created through the provider confirm changes by having logged in there shortly before
+	subject := fmt.Sprintf("provider%d", time.Now().UnixNano())
+	mock.SetUser(oidctest.User{Subject: subject, Email: subject + "@example.org", EmailVerified: true, PreferredUsername: subject})
+	rec := oidcLogin(t, users, "", "")
+	assert.Equal(t, rec.Code, 200)
+	var tokens models.LoginResponse
+	if err := json.NewDecoder(rec.Body).Decode(&tokens); err != nil {
+		t.Fatal(err)
+	}
+	user, err := repositories.NewIdentityRepository(db, logrus.New()).GetUserByIdentity("partner", subject)
+	assert.Equal(t, err, nil)
+	account, err := repositories.NewUserRepository(db, logrus.New()).GetProfile(user.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, account.HasPassword, false)
+	code, _ := updateProfile(t, users, tokens.Token, models.UpdateProfileRequest{Email: stringPointer("first." + user.Email)})
+	assert.Equal(t, code, 200)
+	if _, err := db.Exec("UPDATE tigerhall.sessions SET created_at = NOW() - INTERVAL '1 hour' WHERE user_id = $1", user.ID); err != nil {
+		t.Fatal(err)
+	}
+	code, _ = updateProfile(t, users, tokens.Token, models.UpdateProfileRequest{Email: stringPointer("second." + user.Email)})
+	assert.Equal(t, code, 403)
+
+	// With an authenticator app they send a code instead, however long ago they logged in
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mfaRepo := repositories.NewMFARepository(db, logrus.New())
+	assert.Equal(t, mfaRepo.SetPendingTOTPSecret(user.ID, secret), nil)
+	assert.Equal(t, mfaRepo.EnableTOTP(user.ID, totp.Step(time.Now())-2, nil), nil)
+	code, _ = updateProfile(t, users, tokens.Token, models.UpdateProfileRequest{Email: stringPointer("second." + user.Email), TOTPCode: "000000"})
+	assert.Equal(t, code, 403)
+	totpCode, err := totp.CodeAt(secret, totp.Step(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, profile := updateProfile(t, users, tokens.Token, models.UpdateProfileRequest{Email: stringPointer("second." + user.Email), TOTPCode: totpCode})
+	assert.Equal(t, code, 200)
+	assert.Equal(t, profile.Email, "second."+user.Email)
+}
@@ -0,0 +1,67 @@
+package unittests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/messaging"
+	"github.com/magiconair/properties/assert"
+)
+
+var numbersTopic = messaging.NewTopic[int]("numbers")
+
+func received(sub *messaging.Subscription[int]) []int {
+	var values []int
+	for {
+		select {
+		case value := <-sub.Messages():
+			values = append(values, value)
+		default:
+			return values
+		}
+	}
+}
+
+func TestPubSubOverflowPolicies(t *testing.T) {
+	ps := messaging.NewPubSub()
+	oldest := messaging.Subscribe(ps, numbersTopic, messaging.SubscribeOptions{Name: "oldest", Capacity: 2, Overflow: messaging.DropOldest})
+	newest := messaging.Subscribe(ps, numbersTopic, messaging.SubscribeOptions{Name: "newest", Capacity: 2, Overflow: messaging.DropNewest})
+	for i := 1; i <= 4; i++ {
+		messaging.Publish(ps, numbersTopic, i)
+	}
+
+	stats := ps.Stats()
+	assert.Equal(t, len(stats), 2)
+	assert.Equal(t, stats[0].Name, "newest")
+	assert.Equal(t, stats[0].Depth, 2)
+	assert.Equal(t, stats[0].Dropped, uint64(2))
+
+	// Messages arrive in the order they were published
+	assert.Equal(t, received(oldest), []int{3, 4})
+	assert.Equal(t, received(newest), []int{1, 2})
+}
+
+func TestPubSubUnsubscribeWhilePublisherBlocks(t *testing.T) {
+	ps := messaging.NewPubSub()
+	sub := messaging.Subscribe(ps, numbersTopic, messaging.SubscribeOptions{Capacity: 1, Overflow: messaging.Block})
+	messaging.Publish(ps, numbersTopic, 1)
+
+	published := make(chan struct{})
+	go func() {
+		// Blocks on the full queue until the subscriber is gone
+		messaging.Publish(ps, numbersTopic, 2)
+		close(published)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	sub.Unsubscribe()
+	sub.Unsubscribe()
+	<-published
+
+	// Queued messages can still be drained after unsubscribing
+	value, ok := <-sub.Messages()
+	assert.Equal(t, value, 1)
+	assert.Equal(t, ok, true)
+	_, ok = <-sub.Messages()
+	assert.Equal(t, ok, false)
+	assert.Equal(t, len(ps.Stats()), 0)
+}
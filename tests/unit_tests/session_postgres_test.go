@@ -0,0 +1,114 @@
+package unittests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/database/repositories"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/service"
+	"github.com/magiconair/properties/assert"
+	"github.com/sirupsen/logrus"
+)
+
+// refresh exchanges the refresh token and returns the status code and the new tokens.
+func refresh(t *testing.T, sessions *service.SessionService, refreshToken string) (int, models.LoginResponse) {
+	body, _ := json.Marshal(models.RefreshRequest{RefreshToken: refreshToken})
+	rec := httptest.NewRecorder()
+	sessions.Refresh(context.Background(), rec, httptest.NewRequest("POST", "/api/v1/token/refresh", bytes.NewReader(body)))
+	var response models.LoginResponse
+	if rec.Code == 200 {
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return rec.Code, response
+}
+
+// authenticate sends a request with the access token through AuthMiddleware and returns the status code.
+func authenticate(token string) int {
+	req := httptest.NewRequest("GET", "/api/v1/me", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	service.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})(rec, req)
+	return rec.Code
+}
+
+func TestRefreshRotatesTheRefreshToken(t *testing.T) {
+	db := serviceDB(t)
+	sessions := service.NewSessionService(logrus.New(), db)
+	tokens := login(t, db, createUser(t, db).Username)
+
+	code, refreshed := refresh(t, sessions, tokens.RefreshToken)
+	assert.Equal(t, code, 200)
+	assert.Equal(t, refreshed.RefreshToken != tokens.RefreshToken, true)
+	assert.Equal(t, authenticate(refreshed.Token), 204)
+	code, refreshed = refresh(t, sessions, refreshed.RefreshToken)
+	assert.Equal(t, code, 200)
+	assert.Equal(t, authenticate(refreshed.Token), 204)
+
+	code, _ = refresh(t, sessions, "unknown")
+	assert.Equal(t, code, 401)
+}
+
+func TestReusedRefreshTokenRevokesTheSession(t *testing.T) {
+	db := serviceDB(t)
+	sessions := service.NewSessionService(logrus.New(), db)
+	user := createUser(t, db)
+	tokens := login(t, db, user.Username)
+
+	// The thief refreshes first, the owner's copy is rotated already when they use it
+	code, stolen := refresh(t, sessions, tokens.RefreshToken)
+	assert.Equal(t, code, 200)
+	code, _ = refresh(t, sessions, tokens.RefreshToken)
+	assert.Equal(t, code, 401)
+	code, _ = refresh(t, sessions, stolen.RefreshToken)
+	assert.Equal(t, code, 401)
+	assert.Equal(t, authenticate(stolen.Token), 401)
+	assert.Equal(t, authenticate(tokens.Token), 401)
+
+	// Both refreshing at once, the one losing the race ends the session
+	login(t, db, user.Username)
+	sessionRepo := repositories.NewSessionRepository(db, logrus.New())
+	active, err := sessionRepo.ListActiveSessions(user.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(active), 1)
+	assert.Equal(t, sessionRepo.RotateRefreshToken(active[0].ID, "stale", "next"), repositories.ErrRefreshTokenReused)
+	active, err = sessionRepo.ListActiveSessions(user.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(active), 0)
+}
+
+func TestValidateJWTRejectsRevokedTokens(t *testing.T) {
+	db := serviceDB(t)
+	users := service.NewUserService(logrus.New(), db)
+	user := createUser(t, db)
+
+	// Logging out puts the access token on the revocation list
+	tokens := login(t, db, user.Username)
+	assert.Equal(t, authenticate(tokens.Token), 204)
+	req := httptest.NewRequest("GET", "/api/v1/logout", nil)
+	req.Header.Set("Authorization", "Bearer "+tokens.Token)
+	rec := httptest.NewRecorder()
+	users.Logout(context.Background(), rec, req)
+	assert.Equal(t, rec.Code, 200)
+	assert.Equal(t, authenticate(tokens.Token), 401)
+
+	// Revoking a session rejects the access tokens issued for it
+	tokens = login(t, db, user.Username)
+	other := login(t, db, user.Username)
+	sessionRepo := repositories.NewSessionRepository(db, logrus.New())
+	active, err := sessionRepo.ListActiveSessions(user.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(active), 2)
+	// Sessions are listed most recently used first
+	assert.Equal(t, sessionRepo.RevokeSession(active[0].ID, user.ID), nil)
+	assert.Equal(t, authenticate(other.Token), 401)
+	assert.Equal(t, authenticate(tokens.Token), 204)
+}
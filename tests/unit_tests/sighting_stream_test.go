@@ -0,0 +1,168 @@
+package unittests
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/service"
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/messaging"
+	"github.com/magiconair/properties/assert"
+	"github.com/sirupsen/logrus"
+)
+
+// streamedEvent is an event read back from the live feed.
+type streamedEvent struct {
+	ID       string
+	Type     string
+	Sighting models.StreamSighting
+}
+
+// publishStreamEvents publishes verified sightings of tiger 1 seen at the given times, numbered from firstID on,
+// and gives the stream a moment to record them.
+func publishStreamEvents(firstID int64, eventType string, seenAt ...time.Time) {
+	for i, at := range seenAt {
+		messaging.Publish(service.GetMessagingQueue(), service.SightingStreamTopic, models.SightingStreamEvent{
+			ID:   firstID + int64(i),
+			Type: eventType,
+			Sighting: models.StreamSighting{
+				SightingID: int(firstID) + i, TigerID: 1, Latitude: 11.23456, Longitude: 77.65432, SeenAt: at, Status: models.SightingVerified,
+			},
+		})
+	}
+	time.Sleep(50 * time.Millisecond)
+}
+
+// streamSightings opens the feed anonymously for a moment and returns the events it sent.
+func streamSightings(t *testing.T, lastEventID string) []streamedEvent {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	req := httptest.NewRequest("GET", "/api/v1/stream/sightings", nil).WithContext(ctx)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	rec := httptest.NewRecorder()
+	service.StreamSightings(rec, req)
+	assert.Equal(t, rec.Code, 200)
+
+	var events []streamedEvent
+	var event streamedEvent
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			event.ID = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event.Type = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event.Sighting); err != nil {
+				t.Fatal(err)
+			}
+		case line == "" && event.Type != "":
+			events = append(events, event)
+			event = streamedEvent{}
+		}
+	}
+	return events
+}
+
+func eventIDs(events []streamedEvent) []string {
+	ids := []string{}
+	for _, event := range events {
+		if event.Type == "reset" {
+			ids = append(ids, "reset")
+		} else {
+			ids = append(ids, event.ID)
+		}
+	}
+	return ids
+}
+
+func TestParseSightingStreamFilter(t *testing.T) {
+	filter, err := models.ParseSightingStreamFilter(url.Values{"tiger_id": {"1,2", "5"}, "bbox": {"77.0,11.5,78.0,12.5"}})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, filter.TigerIDs, []int{1, 2, 5})
+	assert.Equal(t, *filter.BBox, models.BoundingBox{MinLon: 77, MinLat: 11.5, MaxLon: 78, MaxLat: 12.5})
+
+	_, err = models.ParseSightingStreamFilter(url.Values{"tiger_id": {"one"}})
+	assert.Equal(t, err != nil, true)
+	_, err = models.ParseSightingStreamFilter(url.Values{"bbox": {"78,11,77,12"}})
+	assert.Equal(t, err != nil, true)
+	_, err = models.ParseSightingStreamFilter(url.Values{"bbox": {"77,11,78"}})
+	assert.Equal(t, err != nil, true)
+}
+
+func TestSightingStreamFilterMatches(t *testing.T) {
+	filter := models.SightingStreamFilter{TigerIDs: []int{1}, BBox: &models.BoundingBox{MinLon: 77, MinLat: 11, MaxLon: 78, MaxLat: 12}}
+	inside := models.StreamSighting{TigerID: 1, Latitude: 11.5, Longitude: 77.5}
+	assert.Equal(t, filter.Matches(inside), true)
+
+	otherTiger := inside
+	otherTiger.TigerID = 2
+	assert.Equal(t, filter.Matches(otherTiger), false)
+
+	outside := inside
+	outside.Longitude = 79
+	assert.Equal(t, filter.Matches(outside), false)
+
+	// A withheld location must not be narrowed down by trying areas
+	withheld := models.StreamSighting{TigerID: 1, LocationWithheld: true}
+	assert.Equal(t, filter.Matches(withheld), false)
+	assert.Equal(t, models.SightingStreamFilter{}.Matches(withheld), true)
+}
+
+func TestLiveSubscriptionFilter(t *testing.T) {
+	filter, err := models.LiveClientMessage{Type: models.LiveSubscribe, Subscription: "south", TigerIDs: []int{3}, BBox: []float64{77, 11, 78, 12}}.Filter()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, filter.TigerIDs, []int{3})
+	assert.Equal(t, *filter.BBox, models.BoundingBox{MinLon: 77, MinLat: 11, MaxLon: 78, MaxLat: 12})
+
+	_, err = models.LiveClientMessage{Type: models.LiveSubscribe, TigerIDs: []int{3}}.Filter()
+	assert.Equal(t, err != nil, true)
+	_, err = models.LiveClientMessage{Type: models.LiveSubscribe, Subscription: "south", BBox: []float64{77, 11}}.Filter()
+	assert.Equal(t, err != nil, true)
+}
+
+func TestSightingStreamObfuscatesForAnonymousCallers(t *testing.T) {
+	stream := service.StartSightingStream(logrus.New())
+	defer stream.Close()
+	publishStreamEvents(1, models.StreamSightingCreated, time.Now().AddDate(0, 0, -30))
+	publishStreamEvents(2, models.StreamSightingVerified, time.Now(), time.Now().AddDate(0, 0, -30))
+
+	events := streamSightings(t, "1")
+	// New sightings are only streamed to reviewers
+	assert.Equal(t, eventIDs(events), []string{"2", "3"})
+	// Recent positions are under embargo
+	assert.Equal(t, events[0].Sighting.LocationWithheld, true)
+	assert.Equal(t, events[0].Sighting.Latitude, 0.0)
+	assert.Equal(t, events[0].Sighting.Longitude, 0.0)
+	// Older ones are snapped to the public grid
+	assert.Equal(t, events[1].Sighting.LocationWithheld, false)
+	assert.Equal(t, events[1].Sighting.LocationPrecision, "coarse")
+	assert.Equal(t, events[1].Sighting.Latitude != 11.23456, true)
+	assert.Equal(t, events[1].Sighting.Longitude != 77.65432, true)
+}
+
+func TestSightingStreamResumesFromLastEventID(t *testing.T) {
+	t.Setenv("SIGHTING_STREAM_HISTORY_SIZE", "2")
+	stream := service.StartSightingStream(logrus.New())
+	defer stream.Close()
+	old := time.Now().AddDate(0, 0, -30)
+	publishStreamEvents(10, models.StreamSightingVerified, old, old, old)
+
+	assert.Equal(t, eventIDs(streamSightings(t, "11")), []string{"12"})
+	assert.Equal(t, eventIDs(streamSightings(t, "12")), []string{})
+	// Event 11 dropped out of the history, so did whatever came before
+	assert.Equal(t, eventIDs(streamSightings(t, "10")), []string{"reset", "11", "12"})
+	assert.Equal(t, eventIDs(streamSightings(t, "5")), []string{"reset", "11", "12"})
+	assert.Equal(t, eventIDs(streamSightings(t, "not-an-id")), []string{"reset", "11", "12"})
+	// A new client starts with the live events only
+	assert.Equal(t, eventIDs(streamSightings(t, "")), []string{})
+}
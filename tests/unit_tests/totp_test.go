@@ -0,0 +1,57 @@
+package unittests
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/totp"
+	"github.com/magiconair/properties/assert"
+)
+
+// Secret of the SHA-1 test vectors in RFC 6238 appendix B
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCodeMatchesRFCVectors(t *testing.T) {
+	// The RFC lists 8 digit codes, ours are their last 6 digits
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, expected := range vectors {
+		code, err := totp.CodeAt(rfcSecret, totp.Step(time.Unix(unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, code, expected)
+	}
+}
+
+func TestTOTPValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	code, _ := totp.CodeAt(rfcSecret, totp.Step(now))
+
+	step, ok := totp.Validate(rfcSecret, code, now, 1)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, step, totp.Step(now))
+
+	// A code of the previous step is still accepted with a skew of one step
+	_, ok = totp.Validate(rfcSecret, code, now.Add(totp.Period), 1)
+	assert.Equal(t, ok, true)
+	_, ok = totp.Validate(rfcSecret, code, now.Add(2*totp.Period), 1)
+	assert.Equal(t, ok, false)
+
+	_, ok = totp.Validate(rfcSecret, "12345", now, 1)
+	assert.Equal(t, ok, false)
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := totp.ProvisioningURI("Tigerhall-Kittens", "ranger one", "ABC")
+	assert.Equal(t, strings.HasPrefix(uri, "otpauth://totp/Tigerhall-Kittens:ranger%20one?"), true)
+	assert.Equal(t, strings.Contains(uri, "secret=ABC"), true)
+}
@@ -0,0 +1,59 @@
+package unittests
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chegde20121/Tigerhall-Kittens/internal/app/models"
+	"github.com/chegde20121/Tigerhall-Kittens/pkg/webhook"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestWebhookSignature(t *testing.T) {
+	secret := "0123456789abcdef"
+	body := []byte(`{"id":"abc","type":"sighting.verified"}`)
+	sentAt := time.Unix(1700000000, 0)
+	signature := webhook.Sign(secret, sentAt, body)
+	timestamp := strconv.FormatInt(sentAt.Unix(), 10)
+
+	assert.Equal(t, webhook.Verify(secret, signature, timestamp, body, 5*time.Minute, sentAt.Add(time.Minute)), nil)
+	// A tampered body, another secret or a timestamp swapped in by a replay do not verify
+	assert.Equal(t, webhook.Verify(secret, signature, timestamp, []byte(`{"id":"abd"}`), 5*time.Minute, sentAt), webhook.ErrInvalidSignature)
+	assert.Equal(t, webhook.Verify("another secret!!", signature, timestamp, body, 5*time.Minute, sentAt), webhook.ErrInvalidSignature)
+	assert.Equal(t, webhook.Verify(secret, signature, "1700000060", body, 5*time.Minute, sentAt), webhook.ErrInvalidSignature)
+	// Old deliveries are rejected even with a valid signature
+	assert.Equal(t, webhook.Verify(secret, signature, timestamp, body, 5*time.Minute, sentAt.Add(time.Hour)), webhook.ErrStaleTimestamp)
+}
+
+func TestValidateWebhook(t *testing.T) {
+	assert.Equal(t, models.ValidateWebhook("https://partner.example/hooks", []string{models.WebhookSightingVerified}), nil)
+	assert.Equal(t, models.ValidateWebhook("ftp://partner.example/hooks", []string{models.WebhookSightingVerified}) != nil, true)
+	assert.Equal(t, models.ValidateWebhook("/hooks", []string{models.WebhookSightingVerified}) != nil, true)
+	assert.Equal(t, models.ValidateWebhook("https://partner.example/hooks", nil) != nil, true)
+	assert.Equal(t, models.ValidateWebhook("https://partner.example/hooks", []string{"tiger.deleted"}) != nil, true)
+	// Plain http and local or private hosts are refused
+	for _, rawURL := range []string{
+		"http://partner.example/hooks", "https://localhost/hooks", "https://api.localhost./hooks", "https://127.0.0.1/hooks",
+		"https://10.0.0.5/hooks", "https://[::1]:8443/hooks", "https://169.254.169.254/latest/meta-data", "https://0.0.0.0/hooks",
+	} {
+		assert.Equal(t, models.ValidateWebhook(rawURL, []string{models.WebhookSightingVerified}) != nil, true, rawURL)
+	}
+	assert.Equal(t, models.ValidateWebhook("https://203.0.113.10/hooks", []string{models.WebhookSightingVerified}), nil)
+}
+
+func TestWebhookClientRefusesInternalAddresses(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	// The test server listens on a loopback address, as would a name resolving to one
+	_, err := webhook.NewClient(time.Second).Post(server.URL, "application/json", strings.NewReader("{}"))
+	assert.Equal(t, errors.Is(err, webhook.ErrBlockedAddress), true)
+	_, err = webhook.NewClient(time.Second).Post(strings.Replace(server.URL, "127.0.0.1", "localhost", 1), "application/json", strings.NewReader("{}"))
+	assert.Equal(t, errors.Is(err, webhook.ErrBlockedAddress), true)
+}